@@ -542,6 +542,29 @@ func gatewayCmd() {
 	}
 
 	msgBus := bus.NewMessageBus()
+	if cfg.Agents.Defaults.MessageCoalesceMS > 0 {
+		msgBus.SetCoalesceWindow(time.Duration(cfg.Agents.Defaults.MessageCoalesceMS) * time.Millisecond)
+	}
+	if cfg.Agents.Defaults.OutboundDedupMS > 0 {
+		msgBus.SetOutboundDedupWindow(time.Duration(cfg.Agents.Defaults.OutboundDedupMS) * time.Millisecond)
+	}
+	if cfg.Agents.Defaults.InterceptorTimeoutMS > 0 {
+		msgBus.SetInterceptorTimeout(time.Duration(cfg.Agents.Defaults.InterceptorTimeoutMS) * time.Millisecond)
+	}
+	if q := cfg.Agents.Defaults.OutboundQuota; q.Messages > 0 || q.Bytes > 0 {
+		msgBus.SetOutboundQuota(bus.OutboundQuota{
+			Messages: q.Messages,
+			Bytes:    q.Bytes,
+			Window:   time.Duration(q.WindowSeconds) * time.Second,
+		})
+	}
+	if r := cfg.Agents.Defaults.InboundRateLimit; r.PerMinute > 0 {
+		msgBus.SetInboundRateLimit(bus.InboundRateLimit{
+			Rate:     r.PerMinute / 60,
+			Burst:    r.Burst,
+			MaxDelay: time.Duration(r.MaxDelaySeconds) * time.Second,
+		})
+	}
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
 
 	// Print agent startup info
@@ -993,16 +1016,30 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 
 	// Create cron service
 	cronService := cron.NewCronService(cronStorePath, nil)
+	cronService.SetMaxConcurrent(cfg.Tools.Cron.MaxConcurrent)
 
 	// Create PolicyEngine for cron exec tool
 	pe := security.NewPolicyEngine(&cfg.Security, msgBus)
+	if path := cfg.AuditLogPath(); path != "" {
+		if sink, err := security.NewFileAuditSink(path); err != nil {
+			logger.WarnCF("cron", "Security audit log disabled", map[string]interface{}{"path": path, "error": err.Error()})
+		} else {
+			pe.SetAuditSink(sink)
+		}
+	}
+	pe.SetApprovalAllowlist(security.NewApprovalAllowlist(cfg.ApprovalAllowlistPath()))
 
 	execCfg := tools.ExecToolConfig{
-		DenyPatterns:  cfg.Tools.Exec.DenyPatterns,
-		AllowPatterns: cfg.Tools.Exec.AllowPatterns,
-		MaxTimeout:    cfg.Tools.Exec.MaxTimeout,
-		PolicyEngine:  pe,
-		ExecGuardMode: pe.GetMode("exec_guard"),
+		DenyPatterns:      cfg.Tools.Exec.DenyPatterns,
+		AllowPatterns:     cfg.Tools.Exec.AllowPatterns,
+		ShadowPatterns:    cfg.Tools.Exec.ShadowPatterns,
+		MaxTimeout:        cfg.Tools.Exec.MaxTimeout,
+		KillGracePeriod:   cfg.Tools.Exec.KillGracePeriod,
+		ScrubEnv:          cfg.Tools.Exec.ScrubEnv,
+		CleanEnv:          cfg.Tools.Exec.CleanEnv,
+		KillOnOutputLimit: cfg.Tools.Exec.KillOnOutputLimit,
+		PolicyEngine:      pe,
+		ExecGuardMode:     pe.GetMode("exec_guard"),
 	}
 
 	cronTool := tools.NewCronToolWithConfig(cronService, agentLoop, msgBus, workspace, restrict, execCfg)
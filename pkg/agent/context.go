@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ type ContextBuilder struct {
 	skillsLoader *skills.SkillsLoader
 	memory       *MemoryStore
 	tools        *tools.ToolRegistry // Direct reference to tool registry
+	roots        map[string]string   // named workspace roots, listed in the identity
 }
 
 func getGlobalConfigDir() string {
@@ -48,6 +50,12 @@ func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
 }
 
+// SetWorkspaceRoots sets the named roots the file tools accept, so the agent
+// is told how to address them.
+func (cb *ContextBuilder) SetWorkspaceRoots(roots map[string]string) {
+	cb.roots = roots
+}
+
 func (cb *ContextBuilder) getIdentity() string {
 	now := time.Now().Format("2006-01-02 15:04 (Monday)")
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
@@ -56,6 +64,21 @@ func (cb *ContextBuilder) getIdentity() string {
 	// Build tools section dynamically
 	toolsSection := cb.buildToolsSection()
 
+	var rootsSection string
+	if roots := cb.roots; len(roots) > 0 {
+		names := make([]string, 0, len(roots))
+		for name := range roots {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var sb strings.Builder
+		sb.WriteString("- Other roots (address files as name:path, e.g. " + names[0] + ":notes.txt):\n")
+		for _, name := range names {
+			fmt.Fprintf(&sb, "  - %s: %s\n", name, roots[name])
+		}
+		rootsSection = sb.String()
+	}
+
 	return fmt.Sprintf(`# picoclaw 🦞
 
 You are picoclaw, a helpful AI assistant.
@@ -71,7 +94,7 @@ Your workspace is at: %s
 - Memory: %s/memory/MEMORY.md
 - Daily Notes: %s/memory/YYYYMM/YYYYMMDD.md
 - Skills: %s/skills/{skill-name}/SKILL.md
-
+%s
 %s
 
 ## Important Rules
@@ -81,7 +104,7 @@ Your workspace is at: %s
 2. **Be helpful and accurate** - When using tools, briefly explain what you're doing.
 
 3. **Memory** - When remembering something, write to %s/memory/MEMORY.md`,
-		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
+		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, rootsSection, toolsSection, workspacePath)
 }
 
 func (cb *ContextBuilder) buildToolsSection() string {
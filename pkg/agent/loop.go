@@ -42,6 +42,9 @@ type AgentLoop struct {
 	state          *state.Manager
 	contextBuilder *ContextBuilder
 	tools          *tools.ToolRegistry
+	policyEngine   *security.PolicyEngine
+	policyLists    []security.ListCount // exec allow/deny list sizes shown by /security
+	history        *tools.CommandHistory
 	running        atomic.Bool
 	summarizing    sync.Map // Tracks which sessions are currently being summarized
 	channelManager *channels.Manager
@@ -60,33 +63,66 @@ type processOptions struct {
 }
 
 // createToolRegistry creates a tool registry with common tools.
-// This is shared between main agent and subagents.
-func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus) *tools.ToolRegistry {
+// This is shared between main agent and subagents, which also share pe and
+// the named workspace roots.
+func createToolRegistry(workspace string, restrict bool, roots map[string]string, cfg *config.Config, msgBus *bus.MessageBus, pe *security.PolicyEngine) *tools.ToolRegistry {
 	registry := tools.NewToolRegistry()
-
-	// Create shared PolicyEngine from security config
-	pe := security.NewPolicyEngine(&cfg.Security, msgBus)
+	registry.SetPolicyEngine(pe)
 
 	pathOpts := tools.PathPolicyOpts{
-		PathMode:     pe.GetMode("path_validation"),
-		PolicyEngine: pe,
+		PathMode:         pe.GetMode("path_validation"),
+		PolicyEngine:     pe,
+		Roots:            roots,
+		MaxAffectedFiles: cfg.Tools.MaxAffectedFiles,
+		FSRetries:        cfg.Tools.FSRetries,
+		MaxLineLength:    cfg.Tools.MaxLineLength,
+		ShowHidden:       cfg.Tools.ShowHidden,
+		MaxReadBytes:     cfg.Tools.MaxReadBytes,
+		MaxArchiveBytes:  cfg.Tools.MaxArchiveBytes,
+		MaxArchiveRatio:  cfg.Tools.MaxArchiveRatio,
 	}
 
 	// File system tools
 	registry.Register(tools.NewReadFileToolWithPolicy(workspace, restrict, pathOpts))
 	registry.Register(tools.NewWriteFileToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewPreviewWriteToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewSwapFilesToolWithPolicy(workspace, restrict, pathOpts))
 	registry.Register(tools.NewListDirToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewGlobToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewGrepToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewRepoSummaryToolWithPolicy(workspace, restrict, pathOpts))
 	registry.Register(tools.NewEditFileToolWithPolicy(workspace, restrict, pathOpts))
 	registry.Register(tools.NewAppendFileToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewManifestToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewScaffoldToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewFileTimesToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewFileOwnerToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewHexDumpToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewListArchiveToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewFileInfoToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewReadLinkToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewStatFileToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewCheckPathToolWithPolicy(workspace, restrict, pathOpts))
+	registry.Register(tools.NewRegexReplaceToolWithPolicy(workspace, restrict, pathOpts))
+	trashTool := tools.NewTrashToolWithPolicy(workspace, restrict, pathOpts)
+	trashTool.SetTrashDir(cfg.Tools.TrashDir)
+	registry.Register(trashTool)
 
 	// Shell execution
-	registry.Register(tools.NewExecToolWithConfig(workspace, restrict, tools.ExecToolConfig{
-		DenyPatterns:  cfg.Tools.Exec.DenyPatterns,
-		AllowPatterns: cfg.Tools.Exec.AllowPatterns,
-		MaxTimeout:    cfg.Tools.Exec.MaxTimeout,
-		PolicyEngine:  pe,
-		ExecGuardMode: pe.GetMode("exec_guard"),
-	}))
+	execTool := tools.NewExecToolWithConfig(workspace, restrict, tools.ExecToolConfig{
+		DenyPatterns:      cfg.Tools.Exec.DenyPatterns,
+		AllowPatterns:     cfg.Tools.Exec.AllowPatterns,
+		ShadowPatterns:    cfg.Tools.Exec.ShadowPatterns,
+		MaxTimeout:        cfg.Tools.Exec.MaxTimeout,
+		KillGracePeriod:   cfg.Tools.Exec.KillGracePeriod,
+		ScrubEnv:          cfg.Tools.Exec.ScrubEnv,
+		CleanEnv:          cfg.Tools.Exec.CleanEnv,
+		KillOnOutputLimit: cfg.Tools.Exec.KillOnOutputLimit,
+		PolicyEngine:      pe,
+		ExecGuardMode:     pe.GetMode("exec_guard"),
+	})
+	registry.Register(execTool)
+	registry.Register(tools.NewExplainCommandTool(execTool))
 
 	if searchTool := tools.NewWebSearchTool(tools.WebSearchToolOptions{
 		BraveAPIKey:          cfg.Tools.Web.Brave.APIKey,
@@ -100,11 +136,23 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	}); searchTool != nil {
 		registry.Register(searchTool)
 	}
+	registry.Register(tools.NewDownloadToolWithPolicy(tools.DownloadToolOptions{
+		Workspace:    workspace,
+		Restrict:     restrict,
+		Roots:        roots,
+		PathMode:     pathOpts.PathMode,
+		PolicyEngine: pe,
+		SSRFMode:     pe.GetMode("ssrf"),
+	}))
 	registry.Register(tools.NewWebFetchToolWithPolicy(tools.WebFetchToolOptions{
 		MaxChars:     50000,
 		PolicyEngine: pe,
 		SSRFMode:     pe.GetMode("ssrf"),
 	}))
+	registry.Register(tools.NewProbeURLToolWithPolicy(tools.ProbeURLToolOptions{
+		PolicyEngine: pe,
+		SSRFMode:     pe.GetMode("ssrf"),
+	}))
 
 	// Hardware tools (I2C, SPI) - Linux only, returns error on other platforms
 	registry.Register(tools.NewI2CTool())
@@ -131,13 +179,35 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	os.MkdirAll(workspace, 0755)
 
 	restrict := cfg.Agents.Defaults.RestrictToWorkspace
+	roots, err := tools.ResolveWorkspaceRoots(cfg.WorkspaceRootPaths())
+	if err != nil {
+		logger.WarnCF("agent", "Ignoring workspace roots", map[string]interface{}{"error": err.Error()})
+	}
+
+	// Create shared PolicyEngine from security config
+	pe := security.NewPolicyEngine(&cfg.Security, msgBus)
+	if path := cfg.AuditLogPath(); path != "" {
+		if sink, err := security.NewFileAuditSink(path); err != nil {
+			logger.WarnCF("agent", "Security audit log disabled", map[string]interface{}{"path": path, "error": err.Error()})
+		} else {
+			pe.SetAuditSink(sink)
+		}
+	}
+	pe.SetApprovalAllowlist(security.NewApprovalAllowlist(cfg.ApprovalAllowlistPath()))
+	// Answered on the bus so it works while the agent is blocked on an approval.
+	// Registered before any approval listener of the same priority, so these
+	// commands are still answered while an approval is pending.
+	msgBus.AddInterceptorWithPriority(pe.InterceptApprovalsCommand, bus.InterceptorPrioritySecurity)
+	msgBus.AddInterceptorWithPriority(pe.InterceptAuditCommand, bus.InterceptorPrioritySecurity)
+	msgBus.AddInterceptorWithPriority(pe.InterceptPolicyCommand, bus.InterceptorPrioritySecurity)
+	msgBus.AddInterceptorWithPriority(pe.InterceptAllowlistCommand, bus.InterceptorPrioritySecurity)
 
 	// Create tool registry for main agent
-	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
+	toolsRegistry := createToolRegistry(workspace, restrict, roots, cfg, msgBus, pe)
 
 	// Create subagent manager with its own tool registry
 	subagentManager := tools.NewSubagentManager(provider, cfg.Agents.Defaults.Model, workspace, msgBus)
-	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus)
+	subagentTools := createToolRegistry(workspace, restrict, roots, cfg, msgBus, pe)
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
 
@@ -149,6 +219,10 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	subagentTool := tools.NewSubagentTool(subagentManager)
 	toolsRegistry.Register(subagentTool)
 
+	// Per-session tool call history (main agent only)
+	history := tools.NewCommandHistory(cfg.Tools.CommandHistory)
+	toolsRegistry.Register(tools.NewCommandHistoryTool(history))
+
 	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
 
 	// Create state manager for atomic state persistence
@@ -157,6 +231,7 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	// Create context builder and set tools registry
 	contextBuilder := NewContextBuilder(workspace)
 	contextBuilder.SetToolsRegistry(toolsRegistry)
+	contextBuilder.SetWorkspaceRoots(roots)
 
 	return &AgentLoop{
 		bus:            msgBus,
@@ -169,10 +244,22 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		state:          stateManager,
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
-		summarizing:    sync.Map{},
+		policyEngine:   pe,
+		policyLists: []security.ListCount{
+			{Name: "exec allow patterns", Count: len(cfg.Tools.Exec.AllowPatterns)},
+			{Name: "exec deny patterns (besides built-ins)", Count: len(cfg.Tools.Exec.DenyPatterns)},
+		},
+		history:     history,
+		summarizing: sync.Map{},
 	}
 }
 
+// RegisterGuard adds a custom security guard that runs before every tool call
+// made by the agent and its subagents.
+func (al *AgentLoop) RegisterGuard(g security.Guard, mode security.PolicyMode) {
+	al.policyEngine.RegisterGuard(g, mode)
+}
+
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
 
@@ -376,6 +463,11 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 
 	// 1. Update tool contexts
 	al.updateToolContexts(opts.Channel, opts.ChatID)
+	if tool, ok := al.tools.Get("command_history"); ok {
+		if ht, ok := tool.(*tools.CommandHistoryTool); ok {
+			ht.SetSession(opts.SessionKey)
+		}
+	}
 
 	// 2. Build messages (skip history for heartbeat)
 	var history []providers.Message
@@ -685,6 +777,9 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			}
 
 			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+			if tc.Name != "command_history" {
+				al.history.Record(opts.SessionKey, tc.Name, tc.Arguments, toolResult)
+			}
 
 			// Send ForUser content to user immediately if not Silent
 			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
@@ -1050,6 +1145,11 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 			return fmt.Sprintf("Unknown list target: %s", args[0]), true
 		}
 
+	case "/security":
+		posture := al.policyEngine.Posture(msg.Channel, msg.ChatID)
+		posture.Lists = append(posture.Lists, al.policyLists...)
+		return posture.Format(), true
+
 	case "/switch":
 		if len(args) < 3 || args[1] != "to" {
 			return "Usage: /switch [model|channel] to <name>", true
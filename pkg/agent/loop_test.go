@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -624,3 +625,68 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 		t.Errorf("Expected history to be compressed (len < 8), got %d", len(finalHistory))
 	}
 }
+
+// toolCallingMockProvider asks for one tool call per turn, then answers
+type toolCallingMockProvider struct {
+	tool string
+	args map[string]interface{}
+}
+
+func (m *toolCallingMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	if last := messages[len(messages)-1]; last.Role == "tool" {
+		return &providers.LLMResponse{Content: "done"}, nil
+	}
+	return &providers.LLMResponse{
+		ToolCalls: []providers.ToolCall{{ID: "call_1", Name: m.tool, Arguments: m.args}},
+	}, nil
+}
+
+func (m *toolCallingMockProvider) GetDefaultModel() string {
+	return "mock-tool-model"
+}
+
+// TestAgentLoop_CommandHistoryPerSession verifies executed tool calls are
+// recorded in the history of the session that made them
+func TestAgentLoop_CommandHistoryPerSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	provider := &toolCallingMockProvider{tool: "list_dir", args: map[string]interface{}{"path": "."}}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	helper := testHelper{al: al}
+	ctx := context.Background()
+
+	helper.executeAndGetResponse(t, ctx, bus.InboundMessage{Channel: "test", ChatID: "a", Content: "list", SessionKey: "session-a"})
+	provider.tool, provider.args = "read_file", map[string]interface{}{"path": "missing.txt"}
+	helper.executeAndGetResponse(t, ctx, bus.InboundMessage{Channel: "test", ChatID: "b", Content: "read", SessionKey: "session-b"})
+
+	a := al.history.Entries("session-a")
+	if len(a) != 1 || a[0].Tool != "list_dir" || a[0].Outcome != "ok" {
+		t.Errorf("unexpected history for session-a: %+v", a)
+	}
+	b := al.history.Entries("session-b")
+	if len(b) != 1 || b[0].Tool != "read_file" || b[0].Outcome != "error" {
+		t.Errorf("unexpected history for session-b: %+v", b)
+	}
+
+	// The history tool reports only the calling session's entries.
+	provider.tool, provider.args = "command_history", map[string]interface{}{}
+	helper.executeAndGetResponse(t, ctx, bus.InboundMessage{Channel: "test", ChatID: "a", Content: "history", SessionKey: "session-a"})
+	history := al.sessions.GetHistory("session-a")
+	out := history[len(history)-2].Content
+	if !strings.Contains(out, "list_dir") || strings.Contains(out, "read_file") {
+		t.Errorf("expected only session-a calls, got:\n%s", out)
+	}
+	if got := al.history.Entries("session-a"); len(got) != 1 {
+		t.Errorf("command_history calls should not be recorded, got %+v", got)
+	}
+}
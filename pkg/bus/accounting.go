@@ -0,0 +1,101 @@
+package bus
+
+import (
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// OutboundQuotaNotice is sent once to a chat when its outbound quota is
+// exhausted; later messages in the same window are dropped silently. The
+// notice itself is not counted.
+const OutboundQuotaNotice = "⚠️ Output limit reached for this chat; further replies are suppressed for now."
+
+// OutboundStats is the outbound traffic sent to one session ("channel:chatID").
+type OutboundStats struct {
+	Messages   int   // messages delivered
+	Bytes      int64 // content bytes delivered
+	Suppressed int   // messages dropped by the quota
+}
+
+// OutboundQuota limits what a single session may be sent per window. Zero
+// limits are unlimited; a zero window applies the limits for the lifetime of
+// the bus.
+type OutboundQuota struct {
+	Messages int
+	Bytes    int64
+	Window   time.Duration
+}
+
+// sessionUsage tracks one session's totals and its current quota window.
+type sessionUsage struct {
+	total       OutboundStats
+	windowStart time.Time
+	windowMsgs  int
+	windowBytes int64
+	notified    bool
+}
+
+// SetOutboundQuota enforces quota on every session. Counting starts afresh
+// for the quota, but the totals reported by OutboundStats are kept.
+func (mb *MessageBus) SetOutboundQuota(quota OutboundQuota) {
+	mb.statsMu.Lock()
+	defer mb.statsMu.Unlock()
+	mb.quota = quota
+	for _, u := range mb.usage {
+		u.windowStart, u.windowMsgs, u.windowBytes, u.notified = time.Time{}, 0, 0, false
+	}
+}
+
+// OutboundStats returns the outbound totals for sessionKey.
+func (mb *MessageBus) OutboundStats(sessionKey string) OutboundStats {
+	mb.statsMu.Lock()
+	defer mb.statsMu.Unlock()
+	if u, ok := mb.usage[sessionKey]; ok {
+		return u.total
+	}
+	return OutboundStats{}
+}
+
+// accountOutbound records msg against its session. It returns false when the
+// quota suppresses msg, and notice=true the first time that happens in a
+// window, so the caller can tell the chat once.
+func (mb *MessageBus) accountOutbound(msg OutboundMessage) (send, notice bool) {
+	key := msg.Channel + ":" + msg.ChatID
+	size := int64(len(msg.Content))
+
+	mb.statsMu.Lock()
+	defer mb.statsMu.Unlock()
+	if mb.usage == nil {
+		mb.usage = make(map[string]*sessionUsage)
+	}
+	u, ok := mb.usage[key]
+	if !ok {
+		u = &sessionUsage{}
+		mb.usage[key] = u
+	}
+
+	now := time.Now()
+	if mb.quota.Window > 0 && now.Sub(u.windowStart) >= mb.quota.Window {
+		u.windowStart, u.windowMsgs, u.windowBytes, u.notified = now, 0, 0, false
+	}
+	if (mb.quota.Messages > 0 && u.windowMsgs+1 > mb.quota.Messages) ||
+		(mb.quota.Bytes > 0 && u.windowBytes+size > mb.quota.Bytes) {
+		u.total.Suppressed++
+		notice = !u.notified
+		u.notified = true
+		logger.WarnCF("bus", "Outbound quota exceeded; message suppressed",
+			map[string]interface{}{
+				"session_key": key,
+				"content_len": len(msg.Content),
+				"suppressed":  u.total.Suppressed,
+			})
+		return false, notice
+	}
+
+	u.windowMsgs++
+	u.windowBytes += size
+	u.total.Messages++
+	u.total.Bytes += size
+	return true, false
+}
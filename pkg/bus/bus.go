@@ -2,13 +2,17 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type interceptorEntry struct {
-	id uint64
-	fn InboundInterceptor
+	id       uint64
+	priority int
+	fn       InboundInterceptor
 }
 
 type MessageBus struct {
@@ -16,27 +20,69 @@ type MessageBus struct {
 	outbound     chan OutboundMessage
 	handlers     map[string]MessageHandler
 	interceptors []*interceptorEntry
+	observers    []*observerEntry
 	nextID       uint64
 	closed       bool
+	done         chan struct{} // closed by Close, for senders not holding mu
 	mu           sync.RWMutex
+
+	interceptorTimeout time.Duration
+
+	coalesceWindow time.Duration
+	batches        map[string]*coalesceBatch // pending coalesced messages by "channel:chatID"
+
+	dedupMu     sync.Mutex
+	dedupWindow time.Duration
+	lastSent    map[string]lastOutbound // last outbound content by "channel:chatID"
+
+	statsMu sync.Mutex
+	quota   OutboundQuota
+	usage   map[string]*sessionUsage // outbound accounting by "channel:chatID"
+
+	rateMu    sync.Mutex
+	rateLimit InboundRateLimit
+	buckets   map[string]*tokenBucket // inbound rate limiting by "channel:chatID"
+	delayed   chan InboundMessage     // admitted messages: rate-limited ones whose turn has come, coalesced batches
+
+	published   atomic.Uint64 // see Stats
+	intercepted atomic.Uint64
+	consumed    atomic.Uint64
 }
 
 func NewMessageBus() *MessageBus {
 	return &MessageBus{
 		inbound:  make(chan InboundMessage, 100),
 		outbound: make(chan OutboundMessage, 100),
+		delayed:  make(chan InboundMessage, 100),
+		done:     make(chan struct{}),
 		handlers: make(map[string]MessageHandler),
+
+		interceptorTimeout: DefaultInterceptorTimeout,
 	}
 }
 
-// AddInterceptor registers an interceptor that inspects inbound messages before
-// they reach the main consumer queue. Returns a removal function.
+// AddInterceptor registers an interceptor with InterceptorPriorityDefault; see
+// AddInterceptorWithPriority.
 func (mb *MessageBus) AddInterceptor(fn InboundInterceptor) func() {
+	return mb.AddInterceptorWithPriority(fn, InterceptorPriorityDefault)
+}
+
+// AddInterceptorWithPriority registers an interceptor that inspects inbound
+// messages before they reach the main consumer queue. Interceptors run one
+// after another in the publishing goroutine, highest priority first and in
+// registration order among equal priorities, and must decide quickly; see
+// SetInterceptorTimeout. Work that does not need to consume messages belongs
+// in AddObserver. Returns a removal function.
+func (mb *MessageBus) AddInterceptorWithPriority(fn InboundInterceptor, priority int) func() {
 	id := atomic.AddUint64(&mb.nextID, 1)
-	entry := &interceptorEntry{id: id, fn: fn}
+	entry := &interceptorEntry{id: id, priority: priority, fn: fn}
 
 	mb.mu.Lock()
-	mb.interceptors = append(mb.interceptors, entry)
+	i := len(mb.interceptors)
+	for i > 0 && mb.interceptors[i-1].priority < priority {
+		i--
+	}
+	mb.interceptors = slices.Insert(mb.interceptors, i, entry)
 	mb.mu.Unlock()
 
 	return func() {
@@ -51,37 +97,87 @@ func (mb *MessageBus) AddInterceptor(fn InboundInterceptor) func() {
 	}
 }
 
+// ErrClosed is returned by PublishInboundContext once the bus is closed.
+var ErrClosed = errors.New("message bus closed")
+
+// PublishInbound is PublishInboundContext without a deadline: it blocks while
+// the inbound queue is full and drops msg if the bus is closed.
 func (mb *MessageBus) PublishInbound(msg InboundMessage) {
+	_ = mb.PublishInboundContext(context.Background(), msg)
+}
+
+// PublishInboundContext runs msg through the interceptors and enqueues it for
+// ConsumeInbound. While the inbound queue is full it waits for room, giving
+// up with ctx's error once ctx is done, so callers can push back on their
+// source instead of losing messages. A message consumed by an interceptor or
+// held for coalescing counts as published.
+func (mb *MessageBus) PublishInboundContext(ctx context.Context, msg InboundMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	mb.mu.RLock()
 	if mb.closed {
 		mb.mu.RUnlock()
-		return
+		return ErrClosed
 	}
 	interceptors := make([]*interceptorEntry, len(mb.interceptors))
 	copy(interceptors, mb.interceptors)
+	timeout := mb.interceptorTimeout
+	mb.notifyObservers(msg)
 	mb.mu.RUnlock()
 
 	for _, entry := range interceptors {
-		if entry.fn(msg) {
-			return
+		if runInterceptor(entry.fn, msg, timeout) {
+			mb.published.Add(1)
+			mb.intercepted.Add(1)
+			return nil
 		}
 	}
-	mb.inbound <- msg
+	if mb.coalesce(msg) {
+		mb.published.Add(1)
+		return nil
+	}
+	select {
+	case mb.inbound <- msg:
+		mb.published.Add(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
+// ConsumeInbound returns the next inbound message, applying the inbound rate
+// limit if one is set; see SetInboundRateLimit.
 func (mb *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, bool) {
-	select {
-	case msg := <-mb.inbound:
-		return msg, true
-	case <-ctx.Done():
-		return InboundMessage{}, false
+	for {
+		select {
+		case msg, ok := <-mb.inbound:
+			if !ok {
+				return msg, true
+			}
+			if mb.admitInbound(msg) {
+				mb.consumed.Add(1)
+				return msg, true
+			}
+		case msg := <-mb.delayed:
+			mb.consumed.Add(1)
+			return msg, true
+		case <-ctx.Done():
+			return InboundMessage{}, false
+		}
 	}
 }
 
 func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
 	mb.mu.RLock()
 	defer mb.mu.RUnlock()
-	if mb.closed {
+	if mb.closed || mb.isDuplicateOutbound(msg) {
+		return
+	}
+	if send, notice := mb.accountOutbound(msg); !send {
+		if notice {
+			mb.outbound <- OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: OutboundQuotaNotice}
+		}
 		return
 	}
 	mb.outbound <- msg
@@ -116,6 +212,11 @@ func (mb *MessageBus) Close() {
 		return
 	}
 	mb.closed = true
+	close(mb.done)
+	for _, e := range mb.observers {
+		close(e.queue)
+	}
+	mb.observers = nil
 	close(mb.inbound)
 	close(mb.outbound)
 }
@@ -2,6 +2,9 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -133,6 +136,40 @@ func TestMessageBus_MultipleInterceptors(t *testing.T) {
 	}
 }
 
+func TestMessageBus_InterceptorPriority(t *testing.T) {
+	mb := NewMessageBus()
+	var mu sync.Mutex
+	order := []string{}
+	record := func(name string, consume bool) InboundInterceptor {
+		return func(msg InboundMessage) bool {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return consume && msg.Content == "approve"
+		}
+	}
+
+	mb.AddInterceptor(record("low", false))
+	mb.AddInterceptorWithPriority(record("high", true), InterceptorPrioritySecurity)
+	mb.AddInterceptorWithPriority(record("high-later", false), InterceptorPrioritySecurity)
+	mb.AddInterceptorWithPriority(record("below", false), -1)
+
+	mb.PublishInbound(InboundMessage{Content: "pass"})
+	mu.Lock()
+	if want := []string{"high", "high-later", "low", "below"}; !slices.Equal(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+	order = nil
+	mu.Unlock()
+
+	mb.PublishInbound(InboundMessage{Content: "approve"})
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"high"}; !slices.Equal(order, want) {
+		t.Errorf("expected only the high-priority interceptor to see the message, got %v", order)
+	}
+}
+
 func TestMessageBus_InterceptorConcurrency(t *testing.T) {
 	mb := NewMessageBus()
 	var wg sync.WaitGroup
@@ -176,3 +213,462 @@ func TestMessageBus_InterceptorConcurrency(t *testing.T) {
 	}
 	_ = intercepted // just ensure no panics
 }
+
+func TestMessageBus_CoalescesQuickMessages(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetCoalesceWindow(100 * time.Millisecond)
+
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u1", Content: "first part"})
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u1", Content: "second part"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("expected a merged message")
+	}
+	if msg.Content != "first part\nsecond part" {
+		t.Errorf("expected merged content, got %q", msg.Content)
+	}
+
+	short, shortCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer shortCancel()
+	if extra, ok := mb.ConsumeInbound(short); ok {
+		t.Errorf("expected a single message, got extra %q", extra.Content)
+	}
+}
+
+func TestMessageBus_SpacedMessagesStaySeparate(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetCoalesceWindow(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u1", Content: "one"})
+	first, _ := mb.ConsumeInbound(ctx)
+	time.Sleep(100 * time.Millisecond)
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u1", Content: "two"})
+	second, _ := mb.ConsumeInbound(ctx)
+
+	if first.Content != "one" || second.Content != "two" {
+		t.Errorf("expected separate messages, got %q and %q", first.Content, second.Content)
+	}
+}
+
+func TestMessageBus_CoalesceSkipsInterceptedReplies(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetCoalesceWindow(100 * time.Millisecond)
+
+	approvals := make(chan string, 1)
+	mb.AddInterceptor(func(msg InboundMessage) bool {
+		if msg.Content == "approve" {
+			approvals <- msg.Content
+			return true
+		}
+		return false
+	})
+
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u1", Content: "hello"})
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u1", Content: "approve"})
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u2", Content: "from someone else"})
+
+	if got := <-approvals; got != "approve" {
+		t.Errorf("expected interceptor to see the approval reply, got %q", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	first, _ := mb.ConsumeInbound(ctx)
+	second, _ := mb.ConsumeInbound(ctx)
+	if first.Content != "hello" || second.Content != "from someone else" {
+		t.Errorf("expected approval excluded and senders kept apart, got %q and %q", first.Content, second.Content)
+	}
+}
+
+func TestMessageBus_CloseNotBlockedByStalledCoalesceFlush(t *testing.T) {
+	mb := NewMessageBus()
+	for i := 0; i < cap(mb.inbound); i++ {
+		mb.inbound <- InboundMessage{Content: "queued"}
+	}
+	for i := 0; i < cap(mb.delayed); i++ {
+		mb.delayed <- InboundMessage{Content: "queued"}
+	}
+	mb.SetCoalesceWindow(10 * time.Millisecond)
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", SenderID: "u1", Content: "held"})
+	time.Sleep(50 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		mb.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked behind a coalesce flush waiting for queue room")
+	}
+}
+
+func TestMessageBus_OutboundDedupSuppressesRepeats(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetOutboundDedupWindow(time.Second)
+
+	for i := 0; i < 3; i++ {
+		mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "Approval required"})
+	}
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "something else"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c2", Content: "something else"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "Approval required"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var got []string
+	for {
+		msg, ok := mb.SubscribeOutbound(ctx)
+		if !ok {
+			break
+		}
+		got = append(got, msg.ChatID+":"+msg.Content)
+	}
+
+	want := []string{"c1:Approval required", "c1:something else", "c2:something else", "c1:Approval required"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMessageBus_OutboundDedupWindowExpires(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetOutboundDedupWindow(50 * time.Millisecond)
+
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "ping"})
+	time.Sleep(100 * time.Millisecond)
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "ping"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if _, ok := mb.SubscribeOutbound(ctx); !ok {
+			t.Fatalf("expected message %d to be delivered after the window", i+1)
+		}
+	}
+}
+
+func TestMessageBus_SlowObserverDoesNotStallPublishing(t *testing.T) {
+	mb := NewMessageBus()
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var seen []string
+	mb.AddObserver(func(msg InboundMessage) {
+		<-release
+		mu.Lock()
+		seen = append(seen, msg.Content)
+		mu.Unlock()
+	})
+
+	start := time.Now()
+	for _, c := range []string{"one", "two", "three"} {
+		mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: c})
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("publishing waited %v for a slow observer", elapsed)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, want := range []string{"one", "two", "three"} {
+		if msg, ok := mb.ConsumeInbound(ctx); !ok || msg.Content != want {
+			t.Fatalf("expected %q to reach the consumer, got %q (ok=%v)", want, msg.Content, ok)
+		}
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		got := strings.Join(seen, ",")
+		mu.Unlock()
+		if got == "one,two,three" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("observer should see every message in order, got %q", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMessageBus_HungInterceptorTimesOut(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetInterceptorTimeout(50 * time.Millisecond)
+
+	hang := make(chan struct{})
+	defer close(hang)
+	mb.AddInterceptor(func(msg InboundMessage) bool {
+		if msg.Content == "hang" {
+			<-hang
+		}
+		return false
+	})
+	mb.AddInterceptor(func(msg InboundMessage) bool {
+		return msg.Content == "consume-me"
+	})
+
+	start := time.Now()
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: "hang"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("publishing waited %v for a hung interceptor", elapsed)
+	}
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: "consume-me"})
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: "after"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, want := range []string{"hang", "after"} {
+		if msg, ok := mb.ConsumeInbound(ctx); !ok || msg.Content != want {
+			t.Fatalf("expected %q, got %q (ok=%v)", want, msg.Content, ok)
+		}
+	}
+}
+
+func TestMessageBus_RemovedObserverStops(t *testing.T) {
+	mb := NewMessageBus()
+
+	calls := make(chan string, 10)
+	remove := mb.AddObserver(func(msg InboundMessage) { calls <- msg.Content })
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: "first"})
+	select {
+	case got := <-calls:
+		if got != "first" {
+			t.Errorf("expected first, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("observer was not called")
+	}
+
+	remove()
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: "second"})
+	select {
+	case got := <-calls:
+		t.Errorf("removed observer still called with %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	mb.Close()
+}
+
+func TestMessageBus_OutboundStatsPerSession(t *testing.T) {
+	mb := NewMessageBus()
+
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "hello"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "world!"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c2", Content: "hi"})
+
+	if got := mb.OutboundStats("telegram:c1"); got != (OutboundStats{Messages: 2, Bytes: 11}) {
+		t.Errorf("unexpected stats for c1: %+v", got)
+	}
+	if got := mb.OutboundStats("telegram:c2"); got != (OutboundStats{Messages: 1, Bytes: 2}) {
+		t.Errorf("unexpected stats for c2: %+v", got)
+	}
+	if got := mb.OutboundStats("discord:c1"); got != (OutboundStats{}) {
+		t.Errorf("expected no stats for an unknown session, got %+v", got)
+	}
+}
+
+func TestMessageBus_OutboundQuotaSuppresses(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetOutboundQuota(OutboundQuota{Bytes: 10})
+
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "12345"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "67890"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "over"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "still over"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c2", Content: "other chat"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var got []string
+	for {
+		msg, ok := mb.SubscribeOutbound(ctx)
+		if !ok {
+			break
+		}
+		got = append(got, msg.ChatID+":"+msg.Content)
+	}
+
+	want := []string{"c1:12345", "c1:67890", "c1:" + OutboundQuotaNotice, "c2:other chat"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if stats := mb.OutboundStats("telegram:c1"); stats != (OutboundStats{Messages: 2, Bytes: 10, Suppressed: 2}) {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMessageBus_OutboundQuotaWindowResets(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetOutboundQuota(OutboundQuota{Messages: 1, Window: 50 * time.Millisecond})
+
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "first"})
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "dropped"})
+	time.Sleep(100 * time.Millisecond)
+	mb.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "c1", Content: "next window"})
+
+	if stats := mb.OutboundStats("telegram:c1"); stats.Messages != 2 || stats.Suppressed != 1 {
+		t.Errorf("expected the quota to reset with the window, got %+v", stats)
+	}
+}
+
+func TestMessageBus_InboundRateLimitDelaysPerChat(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetInboundRateLimit(InboundRateLimit{Rate: 10, Burst: 1})
+
+	start := time.Now()
+	for _, content := range []string{"a", "b", "c"} {
+		mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: content})
+	}
+	mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c2", Content: "x"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var got []string
+	for range 4 {
+		msg, ok := mb.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatalf("expected four messages, got %v", got)
+		}
+		got = append(got, msg.ChatID+":"+msg.Content)
+	}
+
+	want := []string{"c1:a", "c2:x", "c1:b", "c1:c"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the flooding chat to be throttled, took only %v", elapsed)
+	}
+}
+
+func TestMessageBus_InboundRateLimitShedsBeyondMaxDelay(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SetInboundRateLimit(InboundRateLimit{Rate: 1, Burst: 2, MaxDelay: 100 * time.Millisecond})
+
+	for _, content := range []string{"a", "b", "c", "d"} {
+		mb.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "c1", Content: content})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	var got []string
+	for {
+		msg, ok := mb.ConsumeInbound(ctx)
+		if !ok {
+			break
+		}
+		got = append(got, msg.Content)
+	}
+	if strings.Join(got, "") != "ab" {
+		t.Errorf("expected the burst only, got %v", got)
+	}
+}
+
+func TestMessageBus_DelayedDeliveryNeverBlocksClose(t *testing.T) {
+	mb := NewMessageBus()
+	for i := 0; i < cap(mb.delayed); i++ {
+		mb.delayed <- InboundMessage{Content: "queued"}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		mb.deliverDelayed("telegram:c1", InboundMessage{Content: "late"})
+		mb.Close()
+		mb.deliverDelayed("telegram:c1", InboundMessage{Content: "after close"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("delayed delivery blocked with nobody consuming")
+	}
+	if n := len(mb.delayed); n != cap(mb.delayed) {
+		t.Errorf("expected the extra messages to be dropped, queue has %d", n)
+	}
+}
+
+func TestMessageBus_Stats(t *testing.T) {
+	mb := NewMessageBus()
+	mb.AddInterceptor(func(msg InboundMessage) bool { return msg.Content == "/cmd" })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			content := "hello"
+			if i%2 == 0 {
+				content = "/cmd"
+			}
+			mb.PublishInbound(InboundMessage{Channel: "test", ChatID: "1", Content: content})
+			mb.Stats()
+		}(i)
+	}
+	wg.Wait()
+	mb.PublishOutbound(OutboundMessage{Channel: "test", ChatID: "1", Content: "reply"})
+
+	got := mb.Stats()
+	want := Stats{InboundQueued: 5, OutboundQueued: 1, Interceptors: 1, Published: 10, Intercepted: 5}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 5; i++ {
+		if _, ok := mb.ConsumeInbound(ctx); !ok {
+			t.Fatal("expected a queued message")
+		}
+	}
+	if got := mb.Stats(); got.InboundQueued != 0 || got.Consumed != 5 {
+		t.Errorf("expected the queue drained and 5 consumed, got %+v", got)
+	}
+}
+
+func TestMessageBus_PublishInboundContextBackpressure(t *testing.T) {
+	mb := NewMessageBus()
+	for i := 0; i < cap(mb.inbound); i++ {
+		if err := mb.PublishInboundContext(context.Background(), InboundMessage{Content: "fill"}); err != nil {
+			t.Fatalf("unexpected error filling the queue: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := mb.PublishInboundContext(ctx, InboundMessage{Content: "overflow"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected a prompt return, took %v", elapsed)
+	}
+	if got := mb.Stats(); got.Published != uint64(cap(mb.inbound)) {
+		t.Errorf("expected the rejected message not to count as published, got %+v", got)
+	}
+
+	// Room in the queue lets a waiting publisher through.
+	done := make(chan error, 1)
+	go func() {
+		done <- mb.PublishInboundContext(context.Background(), InboundMessage{Content: "waited"})
+	}()
+	if _, ok := mb.ConsumeInbound(context.Background()); !ok {
+		t.Fatal("expected a queued message")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected the waiting publish to succeed, got %v", err)
+	}
+
+	mb.Close()
+	if err := mb.PublishInboundContext(context.Background(), InboundMessage{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after Close, got %v", err)
+	}
+}
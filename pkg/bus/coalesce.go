@@ -0,0 +1,98 @@
+package bus
+
+import (
+	"time"
+)
+
+// coalesceBatch is an inbound message being held while more parts may arrive.
+type coalesceBatch struct {
+	msg   InboundMessage
+	timer *time.Timer
+}
+
+// SetCoalesceWindow makes the bus merge messages from the same chat and sender
+// that arrive within window of each other into one InboundMessage, joined by
+// newlines. Interceptors (such as approval listeners) still see every message
+// individually first, so replies they consume are never merged. Zero disables
+// coalescing and delivers any held messages immediately.
+func (mb *MessageBus) SetCoalesceWindow(window time.Duration) {
+	mb.mu.Lock()
+	mb.coalesceWindow = window
+	var flush []string
+	if window <= 0 {
+		for key := range mb.batches {
+			flush = append(flush, key)
+		}
+	}
+	mb.mu.Unlock()
+
+	for _, key := range flush {
+		mb.flushBatch(key)
+	}
+}
+
+// coalesce either merges msg into the pending batch for its chat or starts a
+// new batch. It returns false when coalescing is disabled.
+func (mb *MessageBus) coalesce(msg InboundMessage) bool {
+	key := msg.Channel + ":" + msg.ChatID
+
+	mb.mu.Lock()
+	if mb.coalesceWindow <= 0 || mb.closed {
+		mb.mu.Unlock()
+		return false
+	}
+	window := mb.coalesceWindow
+
+	if b, ok := mb.batches[key]; ok {
+		if b.msg.SenderID == msg.SenderID && b.timer.Stop() {
+			b.msg.Content += "\n" + msg.Content
+			b.msg.Media = append(b.msg.Media, msg.Media...)
+			b.timer.Reset(window)
+			mb.mu.Unlock()
+			return true
+		}
+		// A different sender (or a batch already being flushed) ends the
+		// current batch; deliver it first to keep order.
+		mb.mu.Unlock()
+		mb.flushBatch(key)
+		mb.mu.Lock()
+	}
+
+	if mb.batches == nil {
+		mb.batches = make(map[string]*coalesceBatch)
+	}
+	mb.batches[key] = &coalesceBatch{
+		msg:   msg,
+		timer: time.AfterFunc(window, func() { mb.flushBatch(key) }),
+	}
+	mb.mu.Unlock()
+	return true
+}
+
+// flushBatch delivers the pending batch for key, if any.
+func (mb *MessageBus) flushBatch(key string) {
+	mb.mu.Lock()
+	b, ok := mb.batches[key]
+	if ok {
+		delete(mb.batches, key)
+		b.timer.Stop()
+	}
+	mb.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	mb.mu.RLock()
+	closed := mb.closed
+	mb.mu.RUnlock()
+	if closed || !mb.admitInbound(b.msg) {
+		return
+	}
+	// The batch has passed the rate limit, so it takes the delayed queue,
+	// which is never closed and can be sent to without holding mu. Close
+	// abandons a send still waiting for room.
+	select {
+	case mb.delayed <- b.msg:
+	case <-mb.done:
+	}
+}
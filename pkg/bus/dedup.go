@@ -0,0 +1,52 @@
+package bus
+
+import (
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// lastOutbound is the most recent message content sent to a chat.
+type lastOutbound struct {
+	content string
+	at      time.Time
+}
+
+// SetOutboundDedupWindow makes the bus drop an outbound message whose content
+// is identical to the previous message sent to the same chat within window,
+// so a stuck loop cannot spam the user. Each suppressed repeat extends the
+// window. Zero disables deduplication.
+func (mb *MessageBus) SetOutboundDedupWindow(window time.Duration) {
+	mb.dedupMu.Lock()
+	defer mb.dedupMu.Unlock()
+	mb.dedupWindow = window
+	mb.lastSent = nil
+}
+
+// isDuplicateOutbound records msg as the latest content for its chat and
+// reports whether it repeats the previous message within the dedup window.
+func (mb *MessageBus) isDuplicateOutbound(msg OutboundMessage) bool {
+	mb.dedupMu.Lock()
+	defer mb.dedupMu.Unlock()
+	if mb.dedupWindow <= 0 {
+		return false
+	}
+	key := msg.Channel + ":" + msg.ChatID
+	now := time.Now()
+	prev, ok := mb.lastSent[key]
+	if mb.lastSent == nil {
+		mb.lastSent = make(map[string]lastOutbound)
+	}
+	mb.lastSent[key] = lastOutbound{content: msg.Content, at: now}
+
+	if !ok || prev.content != msg.Content || now.Sub(prev.at) >= mb.dedupWindow {
+		return false
+	}
+	logger.WarnCF("bus", "Suppressed duplicate outbound message",
+		map[string]interface{}{
+			"channel":     msg.Channel,
+			"chat_id":     msg.ChatID,
+			"content_len": len(msg.Content),
+		})
+	return true
+}
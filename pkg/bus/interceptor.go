@@ -0,0 +1,117 @@
+package bus
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Interceptor priorities for AddInterceptorWithPriority; higher runs first.
+const (
+	InterceptorPriorityDefault = 0
+	// InterceptorPrioritySecurity is for security commands and approval
+	// listeners, which must see a chat's messages before anything else.
+	InterceptorPrioritySecurity = 100
+)
+
+// DefaultInterceptorTimeout is how long PublishInbound waits for a single
+// interceptor before treating the message as not consumed.
+const DefaultInterceptorTimeout = 5 * time.Second
+
+// observerQueueSize bounds how many messages an async observer may lag behind
+// before further messages are dropped for it.
+const observerQueueSize = 64
+
+// InboundObserver sees inbound messages without being able to consume them.
+type InboundObserver func(msg InboundMessage)
+
+type observerEntry struct {
+	id    uint64
+	queue chan InboundMessage
+}
+
+// AddObserver registers fn to see every inbound message asynchronously, in
+// order, on its own goroutine, so slow work such as I/O never delays
+// publishing. An observer that falls more than observerQueueSize messages
+// behind misses messages until it catches up. Returns a removal function.
+func (mb *MessageBus) AddObserver(fn InboundObserver) func() {
+	id := atomic.AddUint64(&mb.nextID, 1)
+	entry := &observerEntry{id: id, queue: make(chan InboundMessage, observerQueueSize)}
+
+	go func() {
+		for msg := range entry.queue {
+			fn(msg)
+		}
+	}()
+
+	mb.mu.Lock()
+	if mb.closed {
+		close(entry.queue)
+	} else {
+		mb.observers = append(mb.observers, entry)
+	}
+	mb.mu.Unlock()
+
+	return func() {
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+		for i, e := range mb.observers {
+			if e.id == id {
+				mb.observers = append(mb.observers[:i], mb.observers[i+1:]...)
+				close(e.queue)
+				break
+			}
+		}
+	}
+}
+
+// SetInterceptorTimeout bounds how long PublishInbound waits for each
+// interceptor. An interceptor that does not decide in time is logged and
+// treated as not consuming the message, so a hung interceptor cannot block
+// the bus. Zero waits indefinitely.
+func (mb *MessageBus) SetInterceptorTimeout(timeout time.Duration) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.interceptorTimeout = timeout
+}
+
+// notifyObservers queues msg for every observer. The caller holds mb.mu
+// for reading, which keeps queues from being closed underneath it.
+func (mb *MessageBus) notifyObservers(msg InboundMessage) {
+	for _, e := range mb.observers {
+		select {
+		case e.queue <- msg:
+		default:
+			logger.WarnCF("bus", "Inbound observer is falling behind; message dropped for it",
+				map[string]interface{}{
+					"channel": msg.Channel,
+					"chat_id": msg.ChatID,
+				})
+		}
+	}
+}
+
+// runInterceptor calls fn, giving up after timeout when it is positive.
+func runInterceptor(fn InboundInterceptor, msg InboundMessage, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return fn(msg)
+	}
+	done := make(chan bool, 1)
+	go func() { done <- fn(msg) }()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case consumed := <-done:
+		return consumed
+	case <-timer.C:
+		logger.WarnCF("bus", "Inbound interceptor timed out; delivering message",
+			map[string]interface{}{
+				"channel":    msg.Channel,
+				"chat_id":    msg.ChatID,
+				"timeout_ms": timeout.Milliseconds(),
+			})
+		return false
+	}
+}
@@ -0,0 +1,104 @@
+package bus
+
+import (
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// InboundRateLimit is a token bucket applied to each session
+// ("channel:chatID") as ConsumeInbound hands out its messages. A zero Rate
+// disables it.
+type InboundRateLimit struct {
+	Rate  float64 // messages per second refilled into each session's bucket
+	Burst int     // bucket size: messages a session may send at once; at least 1
+	// MaxDelay is how long a message over the rate may be held back before
+	// it is dropped instead. Zero holds messages however long it takes.
+	MaxDelay time.Duration
+}
+
+// tokenBucket is one session's allowance.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// SetInboundRateLimit throttles each session to limit. Messages over the rate
+// are delivered late, after messages from other sessions, or dropped once
+// they would wait longer than limit.MaxDelay or when too many are already
+// waiting to be consumed. Buckets start full.
+func (mb *MessageBus) SetInboundRateLimit(limit InboundRateLimit) {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	mb.rateMu.Lock()
+	defer mb.rateMu.Unlock()
+	mb.rateLimit = limit
+	mb.buckets = nil
+}
+
+// admitInbound takes a token for msg's session. It returns false when msg
+// has to wait, in which case it is handed to ConsumeInbound again once its
+// turn comes, or has been dropped.
+func (mb *MessageBus) admitInbound(msg InboundMessage) bool {
+	key := msg.Channel + ":" + msg.ChatID
+
+	mb.rateMu.Lock()
+	limit := mb.rateLimit
+	if limit.Rate <= 0 {
+		mb.rateMu.Unlock()
+		return true
+	}
+	if mb.buckets == nil {
+		mb.buckets = make(map[string]*tokenBucket)
+	}
+	now := time.Now()
+	b, ok := mb.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		mb.buckets[key] = b
+	}
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	b.tokens--
+	// A negative balance reserves a future token for this message.
+	wait := time.Duration(-b.tokens / limit.Rate * float64(time.Second))
+	if wait > 0 && limit.MaxDelay > 0 && wait > limit.MaxDelay {
+		b.tokens++
+		mb.rateMu.Unlock()
+		logger.WarnCF("bus", "Inbound rate limit exceeded; message dropped",
+			map[string]interface{}{
+				"session_key": key,
+				"content_len": len(msg.Content),
+			})
+		return false
+	}
+	mb.rateMu.Unlock()
+	if wait <= 0 {
+		return true
+	}
+
+	time.AfterFunc(wait, func() { mb.deliverDelayed(key, msg) })
+	return false
+}
+
+// deliverDelayed hands a rate-limited message back to ConsumeInbound. It
+// never blocks: with nobody consuming and the queue full the message is
+// dropped, so a stalled consumer cannot pile up timers or hold up Close.
+func (mb *MessageBus) deliverDelayed(key string, msg InboundMessage) {
+	mb.mu.RLock()
+	closed := mb.closed
+	mb.mu.RUnlock()
+	if closed {
+		return
+	}
+	select {
+	case mb.delayed <- msg:
+	default:
+		logger.WarnCF("bus", "Delayed inbound queue full; message dropped",
+			map[string]interface{}{
+				"session_key": key,
+				"content_len": len(msg.Content),
+			})
+	}
+}
@@ -0,0 +1,30 @@
+package bus
+
+// Stats is a snapshot of the bus's queues and traffic. Counts are cumulative
+// since the bus was created.
+type Stats struct {
+	InboundQueued  int // messages waiting for ConsumeInbound, including rate-limited ones whose turn has come
+	OutboundQueued int // messages waiting for SubscribeOutbound
+	Interceptors   int // registered interceptors
+
+	Published   uint64 // inbound messages accepted by PublishInbound or PublishInboundContext
+	Intercepted uint64 // inbound messages consumed by an interceptor
+	Consumed    uint64 // inbound messages handed out by ConsumeInbound; a coalesced batch counts once
+}
+
+// Stats returns the current queue lengths and message counts. It is safe to
+// call concurrently with publishing and consuming, e.g. from a health check.
+func (mb *MessageBus) Stats() Stats {
+	mb.mu.RLock()
+	interceptors := len(mb.interceptors)
+	mb.mu.RUnlock()
+
+	return Stats{
+		InboundQueued:  len(mb.inbound) + len(mb.delayed),
+		OutboundQueued: len(mb.outbound),
+		Interceptors:   interceptors,
+		Published:      mb.published.Load(),
+		Intercepted:    mb.intercepted.Load(),
+		Consumed:       mb.consumed.Load(),
+	}
+}
@@ -67,6 +67,23 @@ type AgentDefaults struct {
 	MaxTokens           int     `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
 	Temperature         float64 `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
 	MaxToolIterations   int     `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	// MessageCoalesceMS merges messages from the same chat and sender that
+	// arrive within this many milliseconds of each other. 0 disables it.
+	MessageCoalesceMS int `json:"message_coalesce_ms" env:"PICOCLAW_AGENTS_DEFAULTS_MESSAGE_COALESCE_MS"`
+	// OutboundDedupMS drops a reply identical to the previous one sent to the
+	// same chat within this many milliseconds. 0 disables it.
+	OutboundDedupMS int `json:"outbound_dedup_ms" env:"PICOCLAW_AGENTS_DEFAULTS_OUTBOUND_DEDUP_MS"`
+	// InterceptorTimeoutMS bounds how long an inbound message waits for each
+	// interceptor (approval replies, /approvals, ...). 0 keeps the default.
+	InterceptorTimeoutMS int `json:"interceptor_timeout_ms" env:"PICOCLAW_AGENTS_DEFAULTS_INTERCEPTOR_TIMEOUT_MS"`
+	// OutboundQuota caps what each chat may be sent; see OutboundQuotaConfig.
+	OutboundQuota OutboundQuotaConfig `json:"outbound_quota"`
+	// InboundRateLimit throttles how fast each chat's messages are processed;
+	// see InboundRateLimitConfig.
+	InboundRateLimit InboundRateLimitConfig `json:"inbound_rate_limit"`
+	// WorkspaceRoots names extra project roots the file tools can address as
+	// "name:path", e.g. {"data": "~/datasets"}. Paths never leave their root.
+	WorkspaceRoots map[string]string `json:"workspace_roots,omitempty"`
 }
 
 type ChannelsConfig struct {
@@ -226,29 +243,186 @@ type WebToolsConfig struct {
 
 type CronToolsConfig struct {
 	ExecTimeoutMinutes int `json:"exec_timeout_minutes" env:"PICOCLAW_TOOLS_CRON_EXEC_TIMEOUT_MINUTES"` // 0 means no timeout
+	// MaxConcurrent caps how many cron jobs run at once; jobs that come due
+	// while the cap is reached wait their turn. 0 means unlimited.
+	MaxConcurrent int `json:"max_concurrent" env:"PICOCLAW_TOOLS_CRON_MAX_CONCURRENT"`
 }
 
 type ExecConfig struct {
-	DenyPatterns  []string `json:"deny_patterns"`  // Additional regex deny patterns
-	AllowPatterns []string `json:"allow_patterns"` // If set, only matching commands are allowed
-	MaxTimeout    int      `json:"max_timeout"`    // Seconds, default 60
+	DenyPatterns    []string `json:"deny_patterns"`     // Additional regex deny patterns
+	AllowPatterns   []string `json:"allow_patterns"`    // If set, only matching commands are allowed
+	ShadowPatterns  []string `json:"shadow_patterns"`   // Regex patterns audited as "would_block" but never enforced
+	MaxTimeout      int      `json:"max_timeout"`       // Seconds, default 60
+	KillGracePeriod int      `json:"kill_grace_period"` // Seconds between SIGTERM and SIGKILL on timeout, default 5
+	ScrubEnv        []string `json:"scrub_env"`         // Extra env var names/globs removed from commands (merged with built-ins)
+	CleanEnv        bool     `json:"clean_env"`         // Pass only a minimal environment (PATH, HOME, locale...) to commands
+	// KillOnOutputLimit stops a command whose stdout or stderr exceeds the
+	// 10000-byte output cap; by default it keeps running and the excess is discarded.
+	KillOnOutputLimit bool `json:"kill_on_output_limit"`
 }
 
 type ToolsConfig struct {
 	Web  WebToolsConfig  `json:"web"`
 	Cron CronToolsConfig `json:"cron"`
 	Exec ExecConfig      `json:"exec"`
+	// MaxAffectedFiles caps how many files a single bulk tool call may create or
+	// modify; larger requests are refused and must be split. 0 means unlimited.
+	MaxAffectedFiles int `json:"max_affected_files" env:"PICOCLAW_TOOLS_MAX_AFFECTED_FILES"`
+	// TrashDir is where the trash tool moves files, relative to the workspace
+	// unless absolute. Default ".trash".
+	TrashDir string `json:"trash_dir" env:"PICOCLAW_TOOLS_TRASH_DIR"`
+	// FSRetries retries file reads, writes and directory listings that fail
+	// with a transient network-filesystem error (ESTALE, EINTR, EAGAIN), with
+	// exponential backoff. 0 disables retries; at most 5 are made.
+	FSRetries int `json:"fs_retries" env:"PICOCLAW_TOOLS_FS_RETRIES"`
+	// CommandHistory is how many tool calls are remembered per session for the
+	// command_history tool. Default 50.
+	CommandHistory int `json:"command_history" env:"PICOCLAW_TOOLS_COMMAND_HISTORY"`
+	// MaxLineLength is the longest line, in bytes, that line-based reads such
+	// as read_file with start_line return. 0 uses the built-in default (1 MiB).
+	MaxLineLength int `json:"max_line_length" env:"PICOCLAW_TOOLS_MAX_LINE_LENGTH"`
+	// MaxReadBytes is how much of a file read_file returns, in bytes; longer
+	// files are cut off with a truncation notice. 0 uses the built-in default
+	// (1 MiB).
+	MaxReadBytes int `json:"max_read_bytes" env:"PICOCLAW_TOOLS_MAX_READ_BYTES"`
+	// MaxArchiveBytes is the total uncompressed size past which list_archive
+	// reports an archive as a possible zip bomb. Default 1 GiB.
+	MaxArchiveBytes int64 `json:"max_archive_bytes" env:"PICOCLAW_TOOLS_MAX_ARCHIVE_BYTES"`
+	// MaxArchiveRatio is the compression ratio (uncompressed:compressed) past
+	// which a zip member or a whole tar.gz is reported as a possible zip bomb.
+	// Default 100.
+	MaxArchiveRatio int `json:"max_archive_ratio" env:"PICOCLAW_TOOLS_MAX_ARCHIVE_RATIO"`
+	// ShowHidden is the default for the show_hidden option of list_dir, glob
+	// and repo_summary: when false, entries whose names start with '.' are
+	// left out. Default false.
+	ShowHidden bool `json:"show_hidden" env:"PICOCLAW_TOOLS_SHOW_HIDDEN"`
 }
 
 // SecurityConfig controls optional security features.
 // All modes default to "off" to preserve pre-security-modification behavior.
 // Supported modes: "off" (disabled), "block" (reject), "approve" (IM-based approval).
 type SecurityConfig struct {
-	ExecGuard       string `json:"exec_guard" env:"PICOCLAW_SECURITY_EXEC_GUARD"`             // "off" | "block" | "approve"
+	ExecGuard       string `json:"exec_guard" env:"PICOCLAW_SECURITY_EXEC_GUARD"`             // "off" | "block" | "approve" | "first_seen"
 	SSRFProtection  string `json:"ssrf_protection" env:"PICOCLAW_SECURITY_SSRF_PROTECTION"`   // "off" | "block" | "approve"
 	PathValidation  string `json:"path_validation" env:"PICOCLAW_SECURITY_PATH_VALIDATION"`   // "off" | "block" | "approve"
 	SkillValidation string `json:"skill_validation" env:"PICOCLAW_SECURITY_SKILL_VALIDATION"` // "off" | "block" | "approve"
 	ApprovalTimeout int    `json:"approval_timeout" env:"PICOCLAW_SECURITY_APPROVAL_TIMEOUT"` // seconds, default 300
+	// ApprovalTimeouts overrides ApprovalTimeout per category, in seconds, e.g.
+	// {"exec_guard": 300, "ssrf": 30}. Unlisted categories use ApprovalTimeout.
+	ApprovalTimeouts map[string]int `json:"approval_timeouts,omitempty"`
+	// MaxConcurrentApprovals caps outstanding approval prompts per chat; further
+	// requests queue and are presented in turn. 0 means unlimited.
+	MaxConcurrentApprovals int `json:"max_concurrent_approvals" env:"PICOCLAW_SECURITY_MAX_CONCURRENT_APPROVALS"`
+	// ApprovalDelegates sends approval prompts for a category to another chat,
+	// e.g. {"exec_guard": "telegram:123"}; only that chat's reply counts. The
+	// key "*" applies to categories without their own entry.
+	ApprovalDelegates map[string]string `json:"approval_delegates,omitempty"`
+	// TrustedChats lists "channel:chatID" entries whose approve-mode violations are
+	// auto-approved. "telegram:*" matches any chat on a channel and "feishu:123*"
+	// matches chat IDs by prefix.
+	TrustedChats []string `json:"trusted_chats" env:"PICOCLAW_SECURITY_TRUSTED_CHATS"`
+	// ChannelModes overrides category modes per channel, e.g.
+	// {"telegram": {"exec_guard": "approve"}}. Unlisted categories use the global mode.
+	ChannelModes map[string]map[string]string `json:"channel_modes,omitempty"`
+	// StrictSymlinks makes path validation deny a restricted path whose symlinks
+	// cannot be resolved, instead of checking the unresolved path.
+	StrictSymlinks bool `json:"strict_symlinks" env:"PICOCLAW_SECURITY_STRICT_SYMLINKS"`
+	// DeniedPaths are .gitignore-style patterns, relative to the workspace, of
+	// files the filesystem tools never touch, e.g. ".env" or ".git/config".
+	// Matches are blocked, or prompt when path_validation is "approve".
+	DeniedPaths []string `json:"denied_paths" env:"PICOCLAW_SECURITY_DENIED_PATHS"`
+	// SSRFAllowlist lists IPs, CIDRs and host names that SSRF protection lets
+	// through even though they are private, loopback or link-local, e.g.
+	// "10.1.2.3" for a self-hosted service. Matched against resolved addresses.
+	SSRFAllowlist []string `json:"ssrf_allowlist" env:"PICOCLAW_SECURITY_SSRF_ALLOWLIST"`
+	// SSRFAllowedPorts restricts URLs checked by SSRF protection to ports 80,
+	// 443 and these. Empty allows any port.
+	SSRFAllowedPorts []int `json:"ssrf_allowed_ports" env:"PICOCLAW_SECURITY_SSRF_ALLOWED_PORTS"`
+	// HoldMessagesDuringApproval queues other messages from a chat while an
+	// approval is pending there and delivers them in order once it resolves.
+	HoldMessagesDuringApproval bool `json:"hold_messages_during_approval" env:"PICOCLAW_SECURITY_HOLD_MESSAGES_DURING_APPROVAL"`
+	// ApprovalKeywords adds replies accepted as approve or deny on top of the
+	// built-in keywords.
+	ApprovalKeywords ApprovalKeywordsConfig `json:"approval_keywords"`
+	// VerboseCLIBlocks explains approve-mode violations that are blocked in the
+	// CLI, where no approval prompt is possible, and suggests how to permit them.
+	VerboseCLIBlocks bool `json:"verbose_cli_blocks" env:"PICOCLAW_SECURITY_VERBOSE_CLI_BLOCKS"`
+	// RememberApprovals turns "always" and "session" approvals on or off per
+	// category, e.g. {"ssrf": false} so every SSRF violation prompts. Unlisted
+	// categories remember. "session" approvals, like first_seen ones, are kept
+	// in memory until the process restarts.
+	RememberApprovals map[string]bool `json:"remember_approvals,omitempty"`
+	// Schedules switch a category's mode by time of day, e.g.
+	// {"exec_guard": {"window": "09:00-18:00", "inside": "approve", "outside": "block"}}.
+	Schedules map[string]ModeSchedule `json:"schedules,omitempty"`
+	// MaxApprovalMessageLength caps approval prompts, in characters, by
+	// shortening the action and reason; instructions are always kept. 0 means unlimited.
+	MaxApprovalMessageLength int `json:"max_approval_message_length" env:"PICOCLAW_SECURITY_MAX_APPROVAL_MESSAGE_LENGTH"`
+	// DecisionWebhook delegates decisions on violations to an external policy
+	// service such as OPA. Disabled when URL is empty.
+	DecisionWebhook DecisionWebhookConfig `json:"decision_webhook"`
+	// AuditOperators lists "channel:chatID" entries (same patterns as
+	// TrustedChats) allowed to stream security decisions with /audit tail.
+	AuditOperators []string `json:"audit_operators" env:"PICOCLAW_SECURITY_AUDIT_OPERATORS"`
+	// ModeOverrideOperators lists "channel:chatID" entries (same patterns as
+	// TrustedChats) allowed to change a category's mode for their own session
+	// for a limited time with /policy.
+	ModeOverrideOperators []string `json:"mode_override_operators" env:"PICOCLAW_SECURITY_MODE_OVERRIDE_OPERATORS"`
+	// ApprovalAllowlist is the file that actions approved with "always" are
+	// kept in, so they survive restarts. Relative paths are resolved against
+	// the workspace. Empty keeps them in memory only.
+	ApprovalAllowlist string `json:"approval_allowlist" env:"PICOCLAW_SECURITY_APPROVAL_ALLOWLIST"`
+	// AuditLog is a file that every security decision is appended to as a JSON
+	// line. Relative paths are resolved against the workspace. Empty disables it.
+	AuditLog string `json:"audit_log" env:"PICOCLAW_SECURITY_AUDIT_LOG"`
+	// AuditFailClosed blocks an otherwise allowed action when the audit sink
+	// cannot record it; by default a warning is logged and the action proceeds.
+	AuditFailClosed bool `json:"audit_fail_closed" env:"PICOCLAW_SECURITY_AUDIT_FAIL_CLOSED"`
+}
+
+// OutboundQuotaConfig limits the messages and bytes sent to a single chat per
+// window. Zero limits are unlimited; a zero window never resets.
+type OutboundQuotaConfig struct {
+	Messages      int   `json:"messages" env:"PICOCLAW_AGENTS_DEFAULTS_OUTBOUND_QUOTA_MESSAGES"`
+	Bytes         int64 `json:"bytes" env:"PICOCLAW_AGENTS_DEFAULTS_OUTBOUND_QUOTA_BYTES"`
+	WindowSeconds int   `json:"window_seconds" env:"PICOCLAW_AGENTS_DEFAULTS_OUTBOUND_QUOTA_WINDOW_SECONDS"`
+}
+
+// ApprovalKeywordsConfig lists extra approval replies. ASCII keywords match
+// case-insensitively; others, such as CJK, must match exactly.
+type ApprovalKeywordsConfig struct {
+	Approve []string `json:"approve" env:"PICOCLAW_SECURITY_APPROVAL_KEYWORDS_APPROVE"`
+	Deny    []string `json:"deny" env:"PICOCLAW_SECURITY_APPROVAL_KEYWORDS_DENY"`
+}
+
+// InboundRateLimitConfig is a token bucket per chat: PerMinute messages are
+// refilled each minute, up to Burst at once. Messages over the rate wait
+// their turn, or are dropped once they would wait more than MaxDelaySeconds
+// (0 waits however long it takes). A zero PerMinute disables it.
+type InboundRateLimitConfig struct {
+	PerMinute       float64 `json:"per_minute" env:"PICOCLAW_AGENTS_DEFAULTS_INBOUND_RATE_LIMIT_PER_MINUTE"`
+	Burst           int     `json:"burst" env:"PICOCLAW_AGENTS_DEFAULTS_INBOUND_RATE_LIMIT_BURST"`
+	MaxDelaySeconds int     `json:"max_delay_seconds" env:"PICOCLAW_AGENTS_DEFAULTS_INBOUND_RATE_LIMIT_MAX_DELAY_SECONDS"`
+}
+
+// DecisionWebhookConfig configures the external policy decision point. The
+// endpoint receives each violation as JSON and answers "allow", "deny" or
+// "approve".
+type DecisionWebhookConfig struct {
+	URL     string `json:"url" env:"PICOCLAW_SECURITY_DECISION_WEBHOOK_URL"`
+	Timeout int    `json:"timeout" env:"PICOCLAW_SECURITY_DECISION_WEBHOOK_TIMEOUT"` // seconds, default 5
+	// FailClosed denies the action when the webhook cannot be reached or
+	// answers invalidly; otherwise the locally configured mode applies.
+	FailClosed bool `json:"fail_closed" env:"PICOCLAW_SECURITY_DECISION_WEBHOOK_FAIL_CLOSED"`
+}
+
+// ModeSchedule selects a policy mode depending on whether the current time of
+// day falls inside Window. An empty Inside or Outside keeps the configured mode.
+type ModeSchedule struct {
+	Window   string `json:"window"`             // "HH:MM-HH:MM"; may wrap past midnight
+	Inside   string `json:"inside,omitempty"`   // mode inside the window
+	Outside  string `json:"outside,omitempty"`  // mode outside the window
+	Timezone string `json:"timezone,omitempty"` // IANA zone, default local time
 }
 
 func DefaultConfig() *Config {
@@ -363,21 +537,35 @@ func DefaultConfig() *Config {
 					MaxResults: 5,
 				},
 			},
-		Cron: CronToolsConfig{
-			ExecTimeoutMinutes: 5,
-		},
-		Exec: ExecConfig{
-			DenyPatterns:  []string{},
-			AllowPatterns: []string{},
-			MaxTimeout:    60,
-		},
+			Cron: CronToolsConfig{
+				ExecTimeoutMinutes: 5,
+				MaxConcurrent:      0,
+			},
+			Exec: ExecConfig{
+				DenyPatterns:    []string{},
+				AllowPatterns:   []string{},
+				ShadowPatterns:  []string{},
+				ScrubEnv:        []string{},
+				MaxTimeout:      60,
+				KillGracePeriod: 5,
+			},
+			MaxAffectedFiles: 100,
+			TrashDir:         ".trash",
+			CommandHistory:   50,
 		},
 		Security: SecurityConfig{
-			ExecGuard:       "off",
-			SSRFProtection:  "off",
-			PathValidation:  "off",
-			SkillValidation: "off",
-			ApprovalTimeout: 300,
+			ExecGuard:                "off",
+			SSRFProtection:           "off",
+			PathValidation:           "off",
+			SkillValidation:          "off",
+			ApprovalTimeout:          300,
+			ApprovalAllowlist:        "~/.picoclaw/approval_allowlist.json",
+			MaxApprovalMessageLength: 4000,
+			TrustedChats:             []string{},
+			AuditOperators:           []string{},
+			ModeOverrideOperators:    []string{},
+			SSRFAllowlist:            []string{},
+			SSRFAllowedPorts:         []int{},
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
@@ -435,6 +623,43 @@ func (c *Config) WorkspacePath() string {
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// WorkspaceRootPaths returns the named workspace roots with "~" expanded.
+func (c *Config) WorkspaceRootPaths() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	roots := make(map[string]string, len(c.Agents.Defaults.WorkspaceRoots))
+	for name, dir := range c.Agents.Defaults.WorkspaceRoots {
+		roots[name] = expandHome(dir)
+	}
+	return roots
+}
+
+// AuditLogPath returns security.audit_log with "~" expanded and relative paths
+// resolved against the workspace, or "" when the audit log is disabled.
+func (c *Config) AuditLogPath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.workspaceRelative(c.Security.AuditLog)
+}
+
+// ApprovalAllowlistPath returns security.approval_allowlist resolved like
+// AuditLogPath, or "" when "always" approvals are kept in memory.
+func (c *Config) ApprovalAllowlistPath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.workspaceRelative(c.Security.ApprovalAllowlist)
+}
+
+// workspaceRelative expands "~" in path and resolves a relative path against
+// the workspace. The caller holds c.mu.
+func (c *Config) workspaceRelative(path string) string {
+	path = expandHome(path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(expandHome(c.Agents.Defaults.Workspace), path)
+}
+
 func (c *Config) GetAPIKey() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
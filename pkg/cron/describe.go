@@ -0,0 +1,166 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adhocore/gronx"
+)
+
+// cronFields names the segments returned by gronx.Segments, which always
+// include a leading seconds field and may include a trailing year.
+var cronFields = []string{"second", "minute", "hour", "day-of-month", "month", "day-of-week", "year"}
+
+var monthNames = []string{"", "January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+var weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// ValidateExpr parses a cron expression with the same parser the scheduler
+// uses and returns an error naming the offending field, or nil when valid.
+func ValidateExpr(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return fmt.Errorf("cron expression is empty")
+	}
+	segs, err := gronx.Segments(expr)
+	if err != nil {
+		return err
+	}
+
+	checker := &gronx.SegmentChecker{}
+	for pos, seg := range segs {
+		for _, part := range strings.Split(seg, ",") {
+			if part == "" {
+				return fmt.Errorf("invalid %s field %q: empty list item", cronFields[pos], seg)
+			}
+			if _, err := checker.CheckDue(part, pos); err != nil {
+				return fmt.Errorf("invalid %s field %q: %v", cronFields[pos], part, err)
+			}
+		}
+	}
+
+	if !gronx.IsValid(expr) {
+		return fmt.Errorf("invalid cron expression %q", expr)
+	}
+	return nil
+}
+
+// DescribeExpr returns a plain-English reading of a valid cron expression,
+// e.g. "At 09:00, Monday through Friday". The expression must already have
+// passed ValidateExpr.
+func DescribeExpr(expr string) string {
+	segs, err := gronx.Segments(expr)
+	if err != nil {
+		return expr
+	}
+	sec, min, hour, dom, month, dow := segs[0], segs[1], segs[2], segs[3], segs[4], segs[5]
+
+	var parts []string
+
+	// Time of day
+	switch {
+	case isNumber(min) && isNumber(hour):
+		h, _ := strconv.Atoi(hour)
+		m, _ := strconv.Atoi(min)
+		t := fmt.Sprintf("At %02d:%02d", h, m)
+		if s, err := strconv.Atoi(sec); err == nil && s != 0 {
+			t += fmt.Sprintf(":%02d", s)
+		}
+		parts = append(parts, t)
+	case isNumber(min):
+		t := "At minute " + min
+		if hour == "*" {
+			t += " of every hour"
+		} else {
+			t += " past " + describeField(hour, "hour", nil)
+		}
+		parts = append(parts, t)
+	default:
+		if sec != "0" {
+			parts = append(parts, describeField(sec, "second", nil))
+		}
+		parts = append(parts, describeField(min, "minute", nil))
+		if hour != "*" {
+			parts = append(parts, describeField(hour, "hour", nil))
+		}
+		parts[0] = strings.ToUpper(parts[0][:1]) + parts[0][1:]
+	}
+
+	// Days
+	if dom != "*" && dom != "?" {
+		parts = append(parts, "on "+describeField(dom, "day", nil)+" of the month")
+	}
+	if month != "*" {
+		parts = append(parts, "in "+describeField(month, "month", monthNames))
+	}
+	if dow != "*" && dow != "?" {
+		parts = append(parts, describeWeekdays(dow))
+	}
+	if len(segs) == 7 && segs[6] != "*" {
+		parts = append(parts, "in "+describeField(segs[6], "year", nil))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// describeWeekdays recognises the common weekday/weekend shorthands before
+// falling back to the generic field description.
+func describeWeekdays(dow string) string {
+	switch dow {
+	case "1-5":
+		return "Monday through Friday"
+	case "0,6", "6,0", "6,7", "6-7":
+		return "on weekends"
+	}
+	return "on " + describeField(dow, "day-of-week", weekdayNames)
+}
+
+// describeField renders one cron field: "*", "*/n", "a-b", "a-b/n", lists
+// and single values. names, when given, maps numeric values to words.
+func describeField(seg, unit string, names []string) string {
+	name := func(v string) string {
+		if n, err := strconv.Atoi(v); err == nil && names != nil && n >= 0 && n < len(names) {
+			return names[n]
+		}
+		return v
+	}
+	plural := unit + "s"
+	if unit == "day-of-week" {
+		plural = "days"
+	}
+
+	switch {
+	case seg == "*" || seg == "?":
+		return "every " + unit
+	case strings.Contains(seg, ","):
+		items := strings.Split(seg, ",")
+		for i, it := range items {
+			items[i] = describeField(it, unit, names)
+		}
+		return strings.Join(items, " and ")
+	case strings.Contains(seg, "/"):
+		base, step, _ := strings.Cut(seg, "/")
+		out := fmt.Sprintf("every %s %s", step, plural)
+		if base != "*" && base != "0" {
+			out += " from " + describeField(base, unit, names)
+		}
+		return out
+	case strings.Contains(seg, "-"):
+		from, to, _ := strings.Cut(seg, "-")
+		if names != nil {
+			return fmt.Sprintf("%s through %s", name(from), name(to))
+		}
+		return fmt.Sprintf("%s %s through %s", plural, from, to)
+	default:
+		if names != nil {
+			return name(seg)
+		}
+		return fmt.Sprintf("%s %s", unit, seg)
+	}
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
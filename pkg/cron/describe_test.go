@@ -0,0 +1,41 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDescribeExpr_Common verifies common expressions get readable descriptions
+func TestDescribeExpr_Common(t *testing.T) {
+	tests := map[string]string{
+		"0 9 * * 1-5":  "At 09:00, Monday through Friday",
+		"*/15 * * * *": "Every 15 minutes",
+		"30 * * * *":   "At minute 30 of every hour",
+		"0 8 1 * *":    "At 08:00, on day 1 of the month",
+	}
+	for expr, want := range tests {
+		if err := ValidateExpr(expr); err != nil {
+			t.Errorf("ValidateExpr(%q) = %v, want nil", expr, err)
+			continue
+		}
+		if got := DescribeExpr(expr); got != want {
+			t.Errorf("DescribeExpr(%q) = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+// TestValidateExpr_NamesOffendingField verifies parse errors point at the bad field
+func TestValidateExpr_NamesOffendingField(t *testing.T) {
+	tests := map[string]string{
+		"61 * * * *": `invalid minute field "61"`,
+		"0 25 * * *": `invalid hour field "25"`,
+		"* * *":      "5-7 segments",
+		"":           "empty",
+	}
+	for expr, want := range tests {
+		err := ValidateExpr(expr)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateExpr(%q) = %v, want error containing %q", expr, err, want)
+		}
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/adhocore/gronx"
+
+	"github.com/sipeed/picoclaw/pkg/clock"
 )
 
 type CronSchedule struct {
@@ -65,6 +67,13 @@ type CronService struct {
 	running   bool
 	stopChan  chan struct{}
 	gronx     *gronx.Gronx
+	clock     clock.Clock
+
+	// maxConcurrent caps how many jobs execute at once; 0 means unlimited.
+	// Due jobs beyond the cap wait in queue, in the order they came due.
+	maxConcurrent int
+	active        int
+	queue         []string
 }
 
 func NewCronService(storePath string, onJob JobHandler) *CronService {
@@ -72,12 +81,34 @@ func NewCronService(storePath string, onJob JobHandler) *CronService {
 		storePath: storePath,
 		onJob:     onJob,
 		gronx:     gronx.New(),
+		clock:     clock.Real(),
 	}
 	// Initialize and load store on creation
 	cs.loadStore()
 	return cs
 }
 
+// SetClock replaces the time source used for scheduling. Call it before
+// Start; tests pass a clock.Fake to run jobs without waiting.
+func (cs *CronService) SetClock(c clock.Clock) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.clock = c
+}
+
+// SetMaxConcurrent limits how many jobs may execute at the same time; jobs
+// that come due while the limit is reached are queued until a slot frees up.
+// n <= 0 removes the limit.
+func (cs *CronService) SetMaxConcurrent(n int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	cs.maxConcurrent = n
+	cs.dispatchUnsafe()
+}
+
 func (cs *CronService) Start() error {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
@@ -111,6 +142,8 @@ func (cs *CronService) Stop() {
 	}
 
 	cs.running = false
+	// Queued jobs are dropped; Start recomputes their next runs.
+	cs.queue = nil
 	if cs.stopChan != nil {
 		close(cs.stopChan)
 		cs.stopChan = nil
@@ -118,14 +151,11 @@ func (cs *CronService) Stop() {
 }
 
 func (cs *CronService) runLoop(stopChan chan struct{}) {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
 	for {
 		select {
 		case <-stopChan:
 			return
-		case <-ticker.C:
+		case <-cs.clock.After(1 * time.Second):
 			cs.checkJobs()
 		}
 	}
@@ -139,7 +169,7 @@ func (cs *CronService) checkJobs() {
 		return
 	}
 
-	now := time.Now().UnixMilli()
+	now := cs.clock.Now().UnixMilli()
 	var dueJobIDs []string
 
 	// Collect jobs that are due (we need to copy them to execute outside lock)
@@ -165,16 +195,33 @@ func (cs *CronService) checkJobs() {
 		log.Printf("[cron] failed to save store: %v", err)
 	}
 
+	// A job is never queued twice: its next run stays unset until it has
+	// finished, so it cannot come due again while waiting or running.
+	cs.queue = append(cs.queue, dueJobIDs...)
+	cs.dispatchUnsafe()
 	cs.mu.Unlock()
+}
 
-	// Execute jobs outside lock.
-	for _, jobID := range dueJobIDs {
-		cs.executeJobByID(jobID)
+// dispatchUnsafe starts queued jobs until the concurrency limit is reached.
+// Jobs execute outside the lock; each one dispatches the next when it ends.
+func (cs *CronService) dispatchUnsafe() {
+	for len(cs.queue) > 0 && (cs.maxConcurrent == 0 || cs.active < cs.maxConcurrent) {
+		jobID := cs.queue[0]
+		cs.queue = cs.queue[1:]
+		cs.active++
+		go func() {
+			cs.executeJobByID(jobID)
+
+			cs.mu.Lock()
+			cs.active--
+			cs.dispatchUnsafe()
+			cs.mu.Unlock()
+		}()
 	}
 }
 
 func (cs *CronService) executeJobByID(jobID string) {
-	startTime := time.Now().UnixMilli()
+	startTime := cs.clock.Now().UnixMilli()
 
 	cs.mu.RLock()
 	var callbackJob *CronJob
@@ -214,7 +261,7 @@ func (cs *CronService) executeJobByID(jobID string) {
 	}
 
 	job.State.LastRunAtMS = &startTime
-	job.UpdatedAtMS = time.Now().UnixMilli()
+	job.UpdatedAtMS = cs.clock.Now().UnixMilli()
 
 	if err != nil {
 		job.State.LastStatus = "error"
@@ -233,7 +280,7 @@ func (cs *CronService) executeJobByID(jobID string) {
 			job.State.NextRunAtMS = nil
 		}
 	} else {
-		nextRun := cs.computeNextRun(&job.Schedule, time.Now().UnixMilli())
+		nextRun := cs.computeNextRun(&job.Schedule, cs.clock.Now().UnixMilli())
 		job.State.NextRunAtMS = nextRun
 	}
 
@@ -279,7 +326,7 @@ func (cs *CronService) computeNextRun(schedule *CronSchedule, nowMS int64) *int6
 }
 
 func (cs *CronService) recomputeNextRuns() {
-	now := time.Now().UnixMilli()
+	now := cs.clock.Now().UnixMilli()
 	for i := range cs.store.Jobs {
 		job := &cs.store.Jobs[i]
 		if job.Enabled {
@@ -347,7 +394,7 @@ func (cs *CronService) AddJob(name string, schedule CronSchedule, message string
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
-	now := time.Now().UnixMilli()
+	now := cs.clock.Now().UnixMilli()
 
 	// One-time tasks (at) should be deleted after execution
 	deleteAfterRun := (schedule.Kind == "at")
@@ -387,7 +434,7 @@ func (cs *CronService) UpdateJob(job *CronJob) error {
 	for i := range cs.store.Jobs {
 		if cs.store.Jobs[i].ID == job.ID {
 			cs.store.Jobs[i] = *job
-			cs.store.Jobs[i].UpdatedAtMS = time.Now().UnixMilli()
+			cs.store.Jobs[i].UpdatedAtMS = cs.clock.Now().UnixMilli()
 			return cs.saveStoreUnsafe()
 		}
 	}
@@ -429,10 +476,10 @@ func (cs *CronService) EnableJob(jobID string, enabled bool) *CronJob {
 		job := &cs.store.Jobs[i]
 		if job.ID == jobID {
 			job.Enabled = enabled
-			job.UpdatedAtMS = time.Now().UnixMilli()
+			job.UpdatedAtMS = cs.clock.Now().UnixMilli()
 
 			if enabled {
-				job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, time.Now().UnixMilli())
+				job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, cs.clock.Now().UnixMilli())
 			} else {
 				job.State.NextRunAtMS = nil
 			}
@@ -1,10 +1,15 @@
 package cron
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/clock"
 )
 
 func TestSaveStore_FilePermissions(t *testing.T) {
@@ -33,6 +38,113 @@ func TestSaveStore_FilePermissions(t *testing.T) {
 	}
 }
 
+func TestCronService_RunsJobsOnFakeClock(t *testing.T) {
+	ran := make(chan string, 4)
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		ran <- job.Name
+		return "", nil
+	})
+	fake := clock.NewFake(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	cs.SetClock(fake)
+
+	job, err := cs.AddJob("hourly", CronSchedule{Kind: "every", EveryMS: int64Ptr(int64(time.Hour / time.Millisecond))}, "ping", false, "cli", "direct")
+	if err != nil {
+		t.Fatalf("AddJob failed: %v", err)
+	}
+	if err := cs.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer cs.Stop()
+
+	// Not due yet: the loop wakes but runs nothing.
+	fake.BlockUntil(1)
+	fake.Advance(30 * time.Minute)
+	fake.BlockUntil(1)
+	select {
+	case name := <-ran:
+		t.Fatalf("job %s ran before it was due", name)
+	default:
+	}
+
+	fake.Advance(30 * time.Minute)
+	select {
+	case name := <-ran:
+		if name != job.Name {
+			t.Errorf("ran %q, want %q", name, job.Name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not run once the clock reached its schedule")
+	}
+}
+
 func int64Ptr(v int64) *int64 {
 	return &v
 }
+
+func TestCronService_MaxConcurrent(t *testing.T) {
+	const jobs, limit = 5, 2
+	var active, peak, done int32
+	release := make(chan struct{})
+	finished := make(chan string, jobs)
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&active, -1)
+		atomic.AddInt32(&done, 1)
+		finished <- job.Name
+		return "", nil
+	})
+	cs.SetMaxConcurrent(limit)
+	fake := clock.NewFake(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	cs.SetClock(fake)
+
+	for i := 0; i < jobs; i++ {
+		if _, err := cs.AddJob(fmt.Sprintf("job-%d", i), CronSchedule{Kind: "every", EveryMS: int64Ptr(int64(time.Hour / time.Millisecond))}, "ping", false, "cli", "direct"); err != nil {
+			t.Fatalf("AddJob failed: %v", err)
+		}
+	}
+	if err := cs.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer cs.Stop()
+
+	// Every job comes due on the same tick.
+	fake.BlockUntil(1)
+	fake.Advance(time.Hour)
+
+	deadline := time.After(5 * time.Second)
+	for ran := 0; ran < jobs; ran++ {
+		// Let whichever jobs hold a slot settle before releasing one.
+		for atomic.LoadInt32(&active) == 0 {
+			select {
+			case <-deadline:
+				t.Fatalf("only %d of %d jobs ran", ran, jobs)
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		release <- struct{}{}
+		select {
+		case <-finished:
+		case <-deadline:
+			t.Fatalf("only %d of %d jobs ran", ran, jobs)
+		}
+	}
+
+	if got := atomic.LoadInt32(&peak); got > limit {
+		t.Errorf("peak concurrency %d, want at most %d", got, limit)
+	}
+	if got := atomic.LoadInt32(&peak); got < limit {
+		t.Errorf("peak concurrency %d, expected jobs to use all %d slots", got, limit)
+	}
+	if got := atomic.LoadInt32(&done); got != jobs {
+		t.Errorf("%d jobs ran, want %d", got, jobs)
+	}
+}
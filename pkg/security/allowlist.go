@@ -0,0 +1,244 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// AllowlistEntry is an action a chat approved permanently with "always".
+type AllowlistEntry struct {
+	Session  string    `json:"session"` // "channel:chatID" that asked for the action
+	Category string    `json:"category"`
+	Action   string    `json:"action"` // whitespace-normalised
+	Tool     string    `json:"tool,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// ApprovalAllowlist stores "always" approvals. With a path it is a JSON file
+// that is re-read on every lookup, so engines sharing the file see each
+// other's changes and entries survive restarts; without one it lives in
+// memory only.
+type ApprovalAllowlist struct {
+	mu      sync.Mutex
+	path    string
+	entries []AllowlistEntry // used when path is empty
+}
+
+// NewApprovalAllowlist returns an allowlist stored at path, or in memory when
+// path is empty. The file is created on the first "always" approval.
+func NewApprovalAllowlist(path string) *ApprovalAllowlist {
+	return &ApprovalAllowlist{path: path}
+}
+
+func allowlistAction(action string) string {
+	return strings.Join(strings.Fields(action), " ")
+}
+
+// Contains reports whether session approved v's category and action with
+// "always".
+func (a *ApprovalAllowlist) Contains(session string, v Violation) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries, err := a.load()
+	if err != nil {
+		return false, err
+	}
+	action := allowlistAction(v.Action)
+	return slices.ContainsFunc(entries, func(e AllowlistEntry) bool {
+		return e.Session == session && e.Category == v.Category && e.Action == action
+	}), nil
+}
+
+// Add records that session approved v's category and action permanently.
+func (a *ApprovalAllowlist) Add(session string, v Violation, now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries, err := a.load()
+	if err != nil {
+		return err
+	}
+	action := allowlistAction(v.Action)
+	for _, e := range entries {
+		if e.Session == session && e.Category == v.Category && e.Action == action {
+			return nil
+		}
+	}
+	entries = append(entries, AllowlistEntry{Session: session, Category: v.Category, Action: action, Tool: v.Tool, AddedAt: now})
+	return a.save(entries)
+}
+
+// Entries returns the entries of session, or of every session when session
+// is empty, oldest first.
+func (a *ApprovalAllowlist) Entries(session string) ([]AllowlistEntry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+	var out []AllowlistEntry
+	for _, e := range entries {
+		if session == "" || e.Session == session {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// Clear removes the entries of session for category, or for every category
+// when category is empty, and returns how many were removed.
+func (a *ApprovalAllowlist) Clear(session, category string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries, err := a.load()
+	if err != nil {
+		return 0, err
+	}
+	kept := slices.DeleteFunc(slices.Clone(entries), func(e AllowlistEntry) bool {
+		return e.Session == session && (category == "" || e.Category == category)
+	})
+	removed := len(entries) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, a.save(kept)
+}
+
+func (a *ApprovalAllowlist) load() ([]AllowlistEntry, error) {
+	if a.path == "" {
+		return a.entries, nil
+	}
+	data, err := os.ReadFile(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read approval allowlist: %w", err)
+	}
+	var entries []AllowlistEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse approval allowlist %s: %w", a.path, err)
+	}
+	return entries, nil
+}
+
+// save replaces the file through a temporary file so a crash never leaves a
+// truncated allowlist behind.
+func (a *ApprovalAllowlist) save(entries []AllowlistEntry) error {
+	if a.path == "" {
+		a.entries = entries
+		return nil
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0700); err != nil {
+		return fmt.Errorf("create approval allowlist directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), ".allowlist-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), a.path)
+}
+
+// SetApprovalAllowlist replaces where "always" approvals are kept; engines
+// start with an in-memory allowlist.
+func (pe *PolicyEngine) SetApprovalAllowlist(a *ApprovalAllowlist) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.allowlist = a
+}
+
+func (pe *PolicyEngine) approvalAllowlist() *ApprovalAllowlist {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	return pe.allowlist
+}
+
+// isAllowlisted reports whether the session approved v with "always". An
+// unreadable allowlist allows nothing.
+func (pe *PolicyEngine) isAllowlisted(session string, v Violation) bool {
+	if !pe.remembersApprovals(v.Category) {
+		return false
+	}
+	ok, err := pe.approvalAllowlist().Contains(session, v)
+	if err != nil {
+		logger.WarnCF("security", "Approval allowlist unavailable", map[string]interface{}{"error": err.Error()})
+	}
+	return ok
+}
+
+// InterceptAllowlistCommand handles "/allowlist", which lists the actions the
+// chat approved with "always", and "/allowlist clear [category]", which
+// removes them so they prompt again.
+func (pe *PolicyEngine) InterceptAllowlistCommand(msg bus.InboundMessage) bool {
+	fields := strings.Fields(msg.Content)
+	if len(fields) == 0 || fields[0] != "/allowlist" || pe.bus == nil {
+		return false
+	}
+	pe.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: pe.handleAllowlistCommand(sessionKey(msg.Channel, msg.ChatID), fields[1:]),
+	})
+	return true
+}
+
+func (pe *PolicyEngine) handleAllowlistCommand(session string, args []string) string {
+	allowlist := pe.approvalAllowlist()
+	switch {
+	case len(args) == 0:
+		entries, err := allowlist.Entries(session)
+		if err != nil {
+			return err.Error()
+		}
+		if len(entries) == 0 {
+			return "No actions are always allowed in this chat."
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Always allowed in this chat (%d):\n", len(entries))
+		for _, e := range entries {
+			fmt.Fprintf(&b, "[%s] %s\n", e.Category, truncateMiddle(e.Action, 80))
+		}
+		b.WriteString("Send /allowlist clear [category] to remove them.")
+		return b.String()
+
+	case args[0] == "clear" && len(args) <= 2:
+		category := ""
+		if len(args) == 2 {
+			category = args[1]
+			if !slices.Contains(Categories, category) {
+				return fmt.Sprintf("Unknown category %q. Categories: %s.", category, strings.Join(Categories, ", "))
+			}
+		}
+		n, err := allowlist.Clear(session, category)
+		if err != nil {
+			return err.Error()
+		}
+		if n == 0 {
+			return "Nothing to clear."
+		}
+		return fmt.Sprintf("Removed %d always-allowed action(s); they will ask for approval again.", n)
+	}
+	return "Usage: /allowlist | /allowlist clear [category]"
+}
@@ -0,0 +1,124 @@
+package security
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// answerPrompt evaluates v in approve mode, answers the prompt with reply and
+// returns the result.
+func answerPrompt(t *testing.T, pe *PolicyEngine, msgBus *bus.MessageBus, v Violation, reply string) error {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1")
+	}()
+	if _, ok := nextOutbound(t, msgBus, 2*time.Second); !ok {
+		t.Fatalf("expected an approval prompt for %q", v.Action)
+	}
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: reply})
+	return <-errCh
+}
+
+func TestAllowlist_AlwaysPersistsAcrossEngines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 2}, msgBus)
+	pe.SetApprovalAllowlist(NewApprovalAllowlist(path))
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+
+	if err := answerPrompt(t, pe, msgBus, v, "总是允许"); err != nil {
+		t.Fatalf("expected approval, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the allowlist to be written: %v", err)
+	}
+
+	// A new engine, as after a restart, allows the same action without asking.
+	restarted := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 2}, msgBus)
+	restarted.SetApprovalAllowlist(NewApprovalAllowlist(path))
+	sink := &recordingSink{}
+	restarted.SetAuditSink(sink)
+	v.Action = "make   deploy"
+	if err := restarted.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1"); err != nil {
+		t.Fatalf("expected the allowlisted action to run, got %v", err)
+	}
+	if got := sink.decisions(); len(got) != 1 || got[0] != DecisionAllowlisted {
+		t.Errorf("expected an allowlisted decision, got %v", got)
+	}
+	if !strings.Contains(restarted.Posture("telegram", "chat1").Format(), "always allowed: 1 action(s)") {
+		t.Error("expected the posture to count the allowlisted action")
+	}
+
+	// Other actions and other chats still ask.
+	if restarted.isAllowlisted("telegram:chat1", Violation{Category: "exec_guard", Action: "make release"}) {
+		t.Error("expected a different action not to be allowlisted")
+	}
+	if restarted.isAllowlisted("telegram:chat2", v) {
+		t.Error("expected another chat not to be allowlisted")
+	}
+	if restarted.isAllowlisted("telegram:chat1", Violation{Category: "ssrf", Action: "make deploy"}) {
+		t.Error("expected another category not to be allowlisted")
+	}
+}
+
+func TestAllowlist_PlainApproveIsOneShot(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 2}, msgBus)
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+
+	if err := answerPrompt(t, pe, msgBus, v, "approve"); err != nil {
+		t.Fatalf("expected approval, got %v", err)
+	}
+	if pe.isAllowlisted("telegram:chat1", v) || pe.isSessionAllowed("telegram:chat1", v) {
+		t.Error("expected a plain approval not to be remembered")
+	}
+	if err := answerPrompt(t, pe, msgBus, v, "no"); err == nil {
+		t.Error("expected the second request to prompt and be denied")
+	}
+}
+
+func TestAllowlistCommand_ListsAndClears(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{}, msgBus)
+	msgBus.AddInterceptor(pe.InterceptAllowlistCommand)
+	allowlist := NewApprovalAllowlist(filepath.Join(t.TempDir(), "allowlist.json"))
+	pe.SetApprovalAllowlist(allowlist)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	allowlist.Add("telegram:chat1", Violation{Category: "exec_guard", Action: "make deploy"}, now)
+	allowlist.Add("telegram:chat1", Violation{Category: "ssrf", Action: "http://10.0.0.1/"}, now)
+	allowlist.Add("telegram:chat2", Violation{Category: "exec_guard", Action: "make deploy"}, now)
+
+	send := func(content string) string {
+		t.Helper()
+		msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: content})
+		reply, ok := nextOutbound(t, msgBus, time.Second)
+		if !ok {
+			t.Fatalf("expected a reply to %q", content)
+		}
+		return reply.Content
+	}
+
+	if reply := send("/allowlist"); !strings.Contains(reply, "(2)") || !strings.Contains(reply, "[exec_guard] make deploy") {
+		t.Errorf("unexpected listing:\n%s", reply)
+	}
+	if reply := send("/allowlist clear ssrf"); !strings.Contains(reply, "Removed 1") {
+		t.Errorf("expected one entry removed, got %q", reply)
+	}
+	if reply := send("/allowlist clear"); !strings.Contains(reply, "Removed 1") {
+		t.Errorf("expected the rest removed, got %q", reply)
+	}
+	if reply := send("/allowlist"); !strings.Contains(reply, "No actions") {
+		t.Errorf("expected an empty listing, got %q", reply)
+	}
+	if entries, _ := allowlist.Entries(""); len(entries) != 1 || entries[0].Session != "telegram:chat2" {
+		t.Errorf("expected other chats' entries to be kept, got %+v", entries)
+	}
+}
@@ -2,72 +2,294 @@ package security
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 // ApprovalResult carries the user's decision on a security approval request.
 type ApprovalResult struct {
 	Approved bool
+	Session  bool // approved for the rest of the session
+	Always   bool // approved permanently for this action
 	Reason   string
 }
 
+// approverFor returns the chat whose reply decides v: its Delegate, else the
+// delegate configured for its category or "*", else the requesting chat.
+func (pe *PolicyEngine) approverFor(v Violation, channel, chatID string) (string, string) {
+	delegate := v.Delegate
+	if delegate == "" && pe.config != nil {
+		var ok bool
+		if delegate, ok = pe.config.ApprovalDelegates[v.Category]; !ok {
+			delegate = pe.config.ApprovalDelegates["*"]
+		}
+	}
+	if ch, id, ok := strings.Cut(delegate, ":"); ok && ch != "" && id != "" {
+		return ch, id
+	}
+	return channel, chatID
+}
+
 // requestApproval sends an approval notification via IM and blocks until the
-// user responds with an approval/denial keyword or the timeout expires.
+// user responds with an approval/denial keyword or the timeout expires. When
+// approval is delegated, the prompt goes to the delegate chat, only its reply
+// counts, and the requesting chat is told who was asked.
 func (pe *PolicyEngine) requestApproval(ctx context.Context, v Violation, channel, chatID string) error {
+	timeout := pe.ApprovalTimeout(v.Category)
+	// The timer covers both queueing for a slot and waiting for the reply.
+	deadline := pe.clock.Now().Add(timeout)
+	timer := pe.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	approverChannel, approverChatID := pe.approverFor(v, channel, chatID)
+	approver := sessionKey(approverChannel, approverChatID)
+	delegated := approver != sessionKey(channel, chatID)
+
+	id, presented, done := pe.trackApproval(v, channel, chatID, approver, deadline, pe.config.MaxConcurrentApprovals > 0)
+	defer done()
+
+	release, err := pe.acquireApprovalSlot(ctx, approver, timer.C())
+	if err != nil {
+		if err == errApprovalQueueTimeout {
+			return fmt.Errorf("%w after %v", errApprovalTimedOut, timeout)
+		}
+		return err
+	}
+	defer release()
+	presented()
+
 	resultCh := make(chan ApprovalResult, 1)
 
-	// Register an interceptor to capture the approval reply from the same chat
-	removeInterceptor := pe.bus.AddInterceptor(func(msg bus.InboundMessage) bool {
-		if msg.Channel != channel || msg.ChatID != chatID {
+	// Messages held back while the approval is pending. Once released, late
+	// interceptor calls pass messages through rather than holding them.
+	var heldMu sync.Mutex
+	var held []bus.InboundMessage
+	released := false
+
+	// Register an interceptor to capture the approval reply from the approver
+	removeInterceptor := pe.bus.AddInterceptorWithPriority(func(msg bus.InboundMessage) bool {
+		if msg.Channel != approverChannel || msg.ChatID != approverChatID {
 			return false
 		}
-		content := strings.TrimSpace(msg.Content)
-		lower := strings.ToLower(content)
-		if isApproveKeyword(lower) || isApproveKeywordCJK(content) {
-			resultCh <- ApprovalResult{Approved: true}
-			return true
+		content, target := pe.approvalTarget(strings.TrimSpace(msg.Content), approver)
+		if target != 0 && target != id {
+			return false // answers another request in this chat
 		}
-		if isDenyKeyword(lower) || isDenyKeywordCJK(content) {
-			resultCh <- ApprovalResult{Approved: false, Reason: "denied by user"}
+		if result, ok := parseApprovalReply(content, pe.config.ApprovalKeywords); ok {
+			// A bare reply is only unambiguous while one request is shown.
+			if ids := pe.presentedApprovals(approver); target == 0 && len(ids) > 1 {
+				pe.bus.PublishOutbound(bus.OutboundMessage{
+					Channel: approverChannel,
+					ChatID:  approverChatID,
+					Content: formatAmbiguousReply(ids),
+				})
+				return true
+			}
+			resultCh <- result
 			return true
 		}
+		if pe.config.HoldMessagesDuringApproval {
+			heldMu.Lock()
+			defer heldMu.Unlock()
+			if !released {
+				held = append(held, msg)
+				return true
+			}
+		}
 		return false // not an approval keyword, pass through
-	})
-	defer removeInterceptor()
+	}, bus.InterceptorPrioritySecurity)
+	defer func() {
+		removeInterceptor()
+		heldMu.Lock()
+		released = true
+		pending := held
+		held = nil
+		heldMu.Unlock()
+		for _, msg := range pending {
+			pe.bus.PublishInbound(msg)
+		}
+	}()
 
 	// Send approval request notification to the user via IM
+	requester := ""
+	if delegated {
+		requester = sessionKey(channel, chatID)
+	}
 	pe.bus.PublishOutbound(bus.OutboundMessage{
-		Channel: channel,
-		ChatID:  chatID,
-		Content: formatApprovalMessage(v, pe.config.ApprovalTimeout),
+		Channel: approverChannel,
+		ChatID:  approverChatID,
+		Content: formatApprovalMessage(id, v, int(deadline.Sub(pe.clock.Now()).Round(time.Second)/time.Second), pe.remembersApprovals(v.Category), pe.config.MaxApprovalMessageLength, requester),
 	})
-
-	timeout := time.Duration(pe.config.ApprovalTimeout) * time.Second
-	if timeout <= 0 {
-		timeout = 300 * time.Second
+	if delegated {
+		pe.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: fmt.Sprintf("⏳ Approval for [%s] %s was requested from %s; waiting for a reply there.", v.Category, truncateMiddle(v.Action, 80), approver),
+		})
 	}
 
 	select {
 	case result := <-resultCh:
+		// "always" and "session" replies in a category that does not remember
+		// approvals approve this call only.
+		if result.Session && pe.remembersApprovals(v.Category) {
+			pe.allowForSession(sessionKey(channel, chatID), v)
+		}
+		if result.Always && pe.remembersApprovals(v.Category) {
+			if err := pe.approvalAllowlist().Add(sessionKey(channel, chatID), v, pe.clock.Now()); err != nil {
+				logger.WarnCF("security", "Failed to record always-allowed action",
+					map[string]interface{}{"category": v.Category, "error": err.Error()})
+			}
+		}
 		if result.Approved {
 			return nil
 		}
+		if result.Reason == "" {
+			return errors.New("denied by user")
+		}
 		return fmt.Errorf("denied by user: %s", result.Reason)
-	case <-time.After(timeout):
-		return fmt.Errorf("approval timed out after %v", timeout)
+	case <-timer.C():
+		return fmt.Errorf("%w after %v", errApprovalTimedOut, timeout)
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 }
 
-// formatApprovalMessage builds a human-readable approval notification.
-func formatApprovalMessage(v Violation, timeoutSec int) string {
+var (
+	errApprovalQueueTimeout = errors.New("approval queue timed out")
+	// errApprovalTimedOut wraps the error returned when nobody answered an
+	// approval request in time.
+	errApprovalTimedOut = errors.New("approval timed out")
+)
+
+// acquireApprovalSlot blocks until the chat identified by key has fewer than
+// MaxConcurrentApprovals outstanding approvals. Waiters are not strictly FIFO
+// but each is presented only once it holds a slot. It returns a release func.
+func (pe *PolicyEngine) acquireApprovalSlot(ctx context.Context, key string, expired <-chan time.Time) (func(), error) {
+	limit := pe.config.MaxConcurrentApprovals
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	pe.mu.Lock()
+	slots, ok := pe.approvalSlots[key]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		pe.approvalSlots[key] = slots
+	}
+	pe.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-expired:
+		return nil, errApprovalQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// formatApprovalMessage builds a human-readable approval notification for
+// request id. The "always" and "session" options are only offered when
+// remember is set. With maxLen > 0 the violation details are shortened to
+// fit, never the reply instructions. A non-empty requester names the chat a
+// delegated request comes from.
+func formatApprovalMessage(id uint64, v Violation, timeoutSec int, remember bool, maxLen int, requester string) string {
+	header := fmt.Sprintf("⚠️ Security Approval Required / 安全审批请求 #%d\n\n", id)
+	if requester != "" {
+		header += fmt.Sprintf("Requested from: %s\n", requester)
+	}
+
+	var footer strings.Builder
+	footer.WriteString(fmt.Sprintf("\nReply \"approve\" to allow or \"deny\" to block.\n"))
+	footer.WriteString(fmt.Sprintf("回复 \"批准\" 允许执行，回复 \"拒绝\" 阻止执行。\n"))
+	if remember {
+		footer.WriteString("Reply \"always\" to allow this action from now on, or \"session\" to allow this tool until restart / 回复 \"总是\" 永久允许此操作，回复 \"本次会话\" 在重启前允许此工具。\n")
+	}
+	footer.WriteString(fmt.Sprintf("If several requests are pending, add the number: \"approve %d\" / 多个请求时请带上编号：\"批准 %d\"。\n", id, id))
+	if timeoutSec > 0 {
+		footer.WriteString(fmt.Sprintf("Auto-deny in %d seconds.\n", timeoutSec))
+	}
+
+	if maxLen > 0 {
+		v = fitViolation(v, maxLen-utf8.RuneCountInString(header)-utf8.RuneCountInString(footer.String()))
+	}
+
 	var b strings.Builder
-	b.WriteString("⚠️ Security Approval Required / 安全审批请求\n\n")
+	b.WriteString(header)
+	writeViolationDetails(&b, v)
+	b.WriteString(footer.String())
+	return b.String()
+}
+
+// minTruncatedField is the shortest a field is cut down to when fitting a
+// violation into an approval message.
+const minTruncatedField = 32
+
+// fitViolation shortens the explanation, then the action, the reason and the
+// rule name until the rendered details fit in budget characters, or each is as
+// short as allowed.
+func fitViolation(v Violation, budget int) Violation {
+	fields := []*string{&v.Details, &v.Action, &v.Reason, &v.RuleName}
+	for _, f := range fields {
+		var b strings.Builder
+		writeViolationDetails(&b, v)
+		excess := utf8.RuneCountInString(b.String()) - budget
+		if excess <= 0 {
+			break
+		}
+		n := utf8.RuneCountInString(*f)
+		*f = truncateMiddle(*f, max(n-excess, minTruncatedField))
+	}
+	return v
+}
+
+// truncateMiddle keeps the start and end of s, which for commands and URLs
+// carry the most meaning, and marks how much was cut from the middle.
+func truncateMiddle(s string, maxRunes int) string {
+	r := []rune(s)
+	if len(r) <= maxRunes {
+		return s
+	}
+	omitted := len(r) - maxRunes
+	marker := fmt.Sprintf(" …[%d chars omitted]… ", omitted)
+	keep := maxRunes - utf8.RuneCountInString(marker)
+	if keep < 2 {
+		keep = 2
+	}
+	omitted = len(r) - keep
+	marker = fmt.Sprintf(" …[%d chars omitted]… ", omitted)
+	head := keep * 2 / 3
+	return string(r[:head]) + marker + string(r[len(r)-(keep-head):])
+}
+
+// formatCLIBlockMessage explains an approve-mode violation that was blocked
+// because the CLI cannot prompt for approval, and how to permit it.
+func formatCLIBlockMessage(v Violation) string {
+	var b strings.Builder
+	b.WriteString("blocked by security policy: approval is required but cannot be requested in the CLI\n\n")
+	writeViolationDetails(&b, v)
+	b.WriteString("\nTo permit this action:\n")
+	b.WriteString("- run it from an IM channel, where approve mode can ask you, or\n")
+	b.WriteString(fmt.Sprintf("- set security.%s to \"off\" (or security.channel_modes.cli.%s to \"off\" for the CLI only)\n", v.Category, v.Category))
+	if v.Category == "exec_guard" {
+		b.WriteString("- if the matched rule comes from tools.exec.deny_patterns, narrow or remove it\n")
+	}
+	return b.String()
+}
+
+// writeViolationDetails writes the fields of v shown in approval and block
+// messages.
+func writeViolationDetails(b *strings.Builder, v Violation) {
 	b.WriteString(fmt.Sprintf("Category: %s\n", v.Category))
 	if v.Tool != "" {
 		b.WriteString(fmt.Sprintf("Tool: %s\n", v.Tool))
@@ -79,46 +301,185 @@ func formatApprovalMessage(v Violation, timeoutSec int) string {
 	if v.RuleName != "" {
 		b.WriteString(fmt.Sprintf("Rule: %s\n", v.RuleName))
 	}
-	b.WriteString(fmt.Sprintf("\nReply \"approve\" to allow or \"deny\" to block.\n"))
-	b.WriteString(fmt.Sprintf("回复 \"批准\" 允许执行，回复 \"拒绝\" 阻止执行。\n"))
-	if timeoutSec > 0 {
-		b.WriteString(fmt.Sprintf("Auto-deny in %d seconds.\n", timeoutSec))
+	if v.Details != "" {
+		b.WriteString(fmt.Sprintf("Explanation:\n%s\n", v.Details))
 	}
-	return b.String()
 }
 
-// isApproveKeyword checks lowercase ASCII approval keywords.
-func isApproveKeyword(lower string) bool {
+// parseApprovalReply interprets a reply to an approval prompt, with any
+// request ID already removed. ok is false when it is not an approval reply.
+func parseApprovalReply(content string, extra config.ApprovalKeywordsConfig) (result ApprovalResult, ok bool) {
+	lower := strings.ToLower(content)
+	if isAlwaysApproveKeyword(lower) || isAlwaysApproveKeywordCJK(content) {
+		return ApprovalResult{Approved: true, Always: true}, true
+	}
+	if isSessionApproveKeyword(lower) || isSessionApproveKeywordCJK(content) {
+		return ApprovalResult{Approved: true, Session: true}, true
+	}
+	if isApproveKeyword(lower, extra.Approve...) || isApproveKeywordCJK(content, extra.Approve...) {
+		return ApprovalResult{Approved: true}, true
+	}
+	if reason, ok := denyReason(content, extra.Deny); ok {
+		return ApprovalResult{Approved: false, Reason: reason}, true
+	}
+	return ApprovalResult{}, false
+}
+
+// formatAmbiguousReply asks the approver to say which of the pending requests
+// ids a bare reply was meant for.
+func formatAmbiguousReply(ids []uint64) string {
+	refs := make([]string, len(ids))
+	for i, id := range ids {
+		refs[i] = fmt.Sprintf("#%d", id)
+	}
+	return fmt.Sprintf("%d approval requests are pending here (%s). Add the number to your reply, e.g. \"approve %d\" or \"deny %d\"; send /approvals to list them.\n"+
+		"有多个待审批请求，请在回复中带上编号，例如 \"批准 %d\"。",
+		len(ids), strings.Join(refs, ", "), ids[0], ids[0], ids[0])
+}
+
+// isApproveKeyword checks lowercase ASCII approval keywords, including the
+// ASCII entries of extra.
+func isApproveKeyword(lower string, extra ...string) bool {
 	switch lower {
 	case "approve", "yes", "allow", "ok", "y":
 		return true
 	}
-	return false
+	return matchExtraKeyword(lower, extra, true)
 }
 
-// isApproveKeywordCJK checks CJK approval keywords (case-sensitive).
-func isApproveKeywordCJK(s string) bool {
+// isApproveKeywordCJK checks CJK approval keywords, including the non-ASCII
+// entries of extra (case-sensitive).
+func isApproveKeywordCJK(s string, extra ...string) bool {
 	switch s {
 	case "批准", "允许", "通过", "是", "承認", "許可", "はい":
 		return true
 	}
+	return matchExtraKeyword(s, extra, false)
+}
+
+// isAlwaysApproveKeyword checks lowercase ASCII keywords that approve the
+// action permanently.
+func isAlwaysApproveKeyword(lower string) bool {
+	switch lower {
+	case "always", "approve always", "always approve", "allow always", "always allow":
+		return true
+	}
 	return false
 }
 
-// isDenyKeyword checks lowercase ASCII denial keywords.
-func isDenyKeyword(lower string) bool {
+// isAlwaysApproveKeywordCJK checks CJK keywords that approve the action
+// permanently (case-sensitive).
+func isAlwaysApproveKeywordCJK(s string) bool {
+	switch s {
+	case "总是", "总是允许", "总是批准", "始终允许", "常に許可":
+		return true
+	}
+	return false
+}
+
+// isSessionApproveKeyword checks lowercase ASCII keywords that approve for the
+// rest of the session.
+func isSessionApproveKeyword(lower string) bool {
 	switch lower {
-	case "deny", "no", "reject", "block", "n":
+	case "session", "approve session", "allow session", "approve for session", "allow for session":
 		return true
 	}
 	return false
 }
 
-// isDenyKeywordCJK checks CJK denial keywords (case-sensitive).
-func isDenyKeywordCJK(s string) bool {
+// isSessionApproveKeywordCJK checks CJK keywords that approve for the rest of
+// the session (case-sensitive).
+func isSessionApproveKeywordCJK(s string) bool {
+	switch s {
+	case "本次会话", "本次会话允许", "会话内允许", "セッション中許可":
+		return true
+	}
+	return false
+}
+
+// isDenyKeyword checks lowercase ASCII denial keywords, including the ASCII
+// entries of extra.
+func isDenyKeyword(lower string, extra ...string) bool {
+	switch lower {
+	case "deny", "no", "reject", "block", "n":
+		return true
+	}
+	return matchExtraKeyword(lower, extra, true)
+}
+
+// isDenyKeywordCJK checks CJK denial keywords, including the non-ASCII
+// entries of extra (case-sensitive).
+func isDenyKeywordCJK(s string, extra ...string) bool {
 	switch s {
 	case "拒绝", "否决", "不", "拒否", "いいえ":
 		return true
 	}
+	return matchExtraKeyword(s, extra, false)
+}
+
+// denyReason reports whether content denies an approval and returns the
+// explanation given with it, e.g. "deny, that path is production". The deny
+// keyword may open the reply or stand as a clause of its own anywhere in it;
+// it is stripped and the rest trimmed. A bare keyword has no reason.
+func denyReason(content string, extra []string) (string, bool) {
+	isDeny := func(s string) bool {
+		return isDenyKeyword(strings.ToLower(s), extra...) || isDenyKeywordCJK(s, extra...)
+	}
+	if isDeny(content) {
+		return "", true
+	}
+	// Leading word: "deny that path is production".
+	if word, rest, ok := strings.Cut(content, " "); ok && isDeny(strings.TrimRightFunc(word, isClauseSeparator)) {
+		return trimReason(rest), true
+	}
+	// A clause of its own: "that path is production, deny" or "拒绝，这是生产环境".
+	for start := 0; start < len(content); {
+		end, next := len(content), len(content)
+		if i := strings.IndexFunc(content[start:], isClauseSeparator); i >= 0 {
+			_, size := utf8.DecodeRuneInString(content[start+i:])
+			end, next = start+i, start+i+size
+		}
+		if isDeny(strings.TrimSpace(content[start:end])) {
+			return trimReason(content[:start] + content[next:]), true
+		}
+		start = next
+	}
+	return "", false
+}
+
+func isClauseSeparator(r rune) bool {
+	return strings.ContainsRune(",;:.!?，。；：！？、", r)
+}
+
+// trimReason trims spaces, clause separators and dashes left around a reason
+// once its deny keyword is stripped.
+func trimReason(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || isClauseSeparator(r) || r == '-' || r == '—'
+	})
+}
+
+// matchExtraKeyword reports whether s is one of the configured keywords.
+// With ascii set only ASCII keywords are considered and compared ignoring
+// case; otherwise only non-ASCII keywords are, and they must match exactly.
+func matchExtraKeyword(s string, keywords []string, ascii bool) bool {
+	for _, k := range keywords {
+		k = strings.TrimSpace(k)
+		if k == "" || isASCII(k) != ascii {
+			continue
+		}
+		if ascii && strings.EqualFold(s, k) || !ascii && s == k {
+			return true
+		}
+	}
 	return false
 }
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
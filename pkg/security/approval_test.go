@@ -1,7 +1,16 @@
 package security
 
 import (
+	"context"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/clock"
+	"github.com/sipeed/picoclaw/pkg/config"
 )
 
 func TestIsApproveKeyword(t *testing.T) {
@@ -64,14 +73,117 @@ func TestIsDenyKeywordCJK(t *testing.T) {
 	}
 }
 
+func TestApprovalKeywords_Extra(t *testing.T) {
+	approve := []string{"Approved", "确认", " lgtm "}
+	deny := []string{"nope", "不行"}
+	if !isApproveKeyword("approved", approve...) || !isApproveKeyword("lgtm", approve...) {
+		t.Error("expected extra ASCII approve keywords to match case-insensitively")
+	}
+	if !isApproveKeywordCJK("确认", approve...) || isApproveKeyword("确认", approve...) {
+		t.Error("expected extra CJK approve keywords to be matched by the CJK check only")
+	}
+	if !isApproveKeyword("yes", approve...) || !isApproveKeywordCJK("批准", approve...) {
+		t.Error("expected built-in approve keywords to still match")
+	}
+	if !isDenyKeyword("nope", deny...) || !isDenyKeywordCJK("不行", deny...) || !isDenyKeyword("no", deny...) {
+		t.Error("expected extra and built-in deny keywords to match")
+	}
+	if isApproveKeyword("nope", approve...) || isDenyKeyword("approved", deny...) || isApproveKeywordCJK("确认 ", approve...) {
+		t.Error("unexpected keyword match")
+	}
+}
+
+func TestRequestApproval_ExtraKeywords(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ApprovalTimeout:  2,
+		ApprovalKeywords: config.ApprovalKeywordsConfig{Approve: []string{"确认"}, Deny: []string{"Nope"}},
+	}, msgBus)
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+
+	for _, tc := range []struct {
+		reply   string
+		approve bool
+	}{{"确认", true}, {"NOPE", false}} {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1")
+		}()
+		if _, ok := nextOutbound(t, msgBus, 2*time.Second); !ok {
+			t.Fatal("expected an approval prompt")
+		}
+		msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: tc.reply})
+		err := <-errCh
+		if tc.approve && err != nil {
+			t.Errorf("expected %q to approve, got: %v", tc.reply, err)
+		}
+		if !tc.approve && (err == nil || !strings.Contains(err.Error(), "denied")) {
+			t.Errorf("expected %q to deny, got: %v", tc.reply, err)
+		}
+	}
+}
+
+func TestDenyReason(t *testing.T) {
+	for _, tc := range []struct {
+		content, reason string
+		ok              bool
+	}{
+		{"deny", "", true},
+		{"NO", "", true},
+		{"deny, that path is production", "that path is production", true},
+		{"Deny: that path is production.", "that path is production", true},
+		{"no it runs against prod", "it runs against prod", true},
+		{"that path is production, deny", "that path is production", true},
+		{"hold on, deny, wrong server", "hold on, wrong server", true},
+		{"deny - wrong server", "wrong server", true},
+		{"拒绝，这是生产环境", "这是生产环境", true},
+		{"nope, later", "later", true},
+		{"I have no idea", "", false},
+		{"do not block the build", "", false},
+		{"不知道", "", false},
+		{"approve", "", false},
+	} {
+		reason, ok := denyReason(tc.content, []string{"nope"})
+		if ok != tc.ok || reason != tc.reason {
+			t.Errorf("denyReason(%q) = %q, %v; want %q, %v", tc.content, reason, ok, tc.reason, tc.ok)
+		}
+	}
+}
+
+func TestRequestApproval_DenialReason(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 2}, msgBus)
+	sink := &recordingSink{}
+	pe.SetAuditSink(sink)
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1")
+	}()
+	if _, ok := nextOutbound(t, msgBus, 2*time.Second); !ok {
+		t.Fatal("expected an approval prompt")
+	}
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "deny, that path is production"})
+	err := <-errCh
+	if err == nil || err.Error() != "denied by user: that path is production" {
+		t.Fatalf("expected the reason in the error, got: %v", err)
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.entries) != 1 || sink.entries[0].Reason != err.Error() {
+		t.Errorf("expected the reason to be audited, got %+v", sink.entries)
+	}
+}
+
 func TestFormatApprovalMessage(t *testing.T) {
-	msg := formatApprovalMessage(Violation{
+	msg := formatApprovalMessage(1, Violation{
 		Category: "exec_guard",
 		Tool:     "exec",
 		Action:   "rm -rf /tmp",
 		Reason:   "dangerous pattern detected",
 		RuleName: `\brm\s+-[rf]`,
-	}, 300)
+	}, 300, true, 0, "")
 
 	// Check essential fields are present
 	checks := []string{
@@ -105,3 +217,527 @@ func containsHelper(s, sub string) bool {
 	}
 	return false
 }
+
+func TestRequestApproval_MaxConcurrentSerializes(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 5, MaxConcurrentApprovals: 1}, msgBus)
+
+	errA := make(chan error, 1)
+	errB := make(chan error, 1)
+	go func() {
+		errA <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Action: "cmd-a", Reason: "a"}, "telegram", "chat1")
+	}()
+	go func() {
+		errB <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Action: "cmd-b", Reason: "b"}, "telegram", "chat1")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	first, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected first approval message")
+	}
+
+	// The second request must stay queued while the first is outstanding.
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer shortCancel()
+	if msg, ok := msgBus.SubscribeOutbound(shortCtx); ok {
+		t.Fatalf("second approval should be queued, got message: %s", msg.Content)
+	}
+
+	firstIsA := strings.Contains(first.Content, "cmd-a")
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve"})
+
+	second, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected second approval message after the first resolved")
+	}
+	if strings.Contains(second.Content, "cmd-a") == firstIsA {
+		t.Fatalf("second prompt should be for the other request, got: %s", second.Content)
+	}
+	time.Sleep(50 * time.Millisecond)
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "deny"})
+
+	approvedCh, deniedCh := errA, errB
+	if !firstIsA {
+		approvedCh, deniedCh = errB, errA
+	}
+	select {
+	case err := <-approvedCh:
+		if err != nil {
+			t.Errorf("first request should be approved, got: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("first request did not resolve")
+	}
+	select {
+	case err := <-deniedCh:
+		if err == nil || !strings.Contains(err.Error(), "denied") {
+			t.Errorf("second request should be denied, got: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("second request did not resolve")
+	}
+}
+
+func TestRequestApproval_QueueTimeout(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 60, MaxConcurrentApprovals: 1}, msgBus)
+	fake := clock.NewFake(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	pe.SetClock(fake)
+
+	// Hold the only slot so the request can never be presented.
+	release, err := pe.acquireApprovalSlot(context.Background(), "telegram:chat2", nil)
+	if err != nil {
+		t.Fatalf("acquireApprovalSlot failed: %v", err)
+	}
+	defer release()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Reason: "x"}, "telegram", "chat2")
+	}()
+
+	// The queue wait counts against the approval timeout.
+	fake.BlockUntil(1)
+	fake.Advance(60 * time.Second)
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Fatalf("expected queued request to time out, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued request did not time out when the clock advanced")
+	}
+}
+
+func TestRequestApproval_TimeoutWithFakeClock(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 300}, msgBus)
+	fake := clock.NewFake(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	pe.SetClock(fake)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Action: "rm -rf build", Reason: "x"}, "telegram", "chat3")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	prompt, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected approval prompt")
+	}
+	if !strings.Contains(prompt.Content, "300") {
+		t.Errorf("prompt should state the full timeout, got: %s", prompt.Content)
+	}
+
+	fake.Advance(299 * time.Second)
+	select {
+	case err := <-errCh:
+		t.Fatalf("request resolved before the timeout: %v", err)
+	default:
+	}
+
+	fake.Advance(time.Second)
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "timed out after 5m0s") {
+			t.Fatalf("expected timeout error, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request did not time out when the clock advanced")
+	}
+}
+
+func TestRequestApproval_CategoryTimeout(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 300, ApprovalTimeouts: map[string]int{"ssrf": 30}}, msgBus)
+	fake := clock.NewFake(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	pe.SetClock(fake)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "ssrf", Tool: "web_fetch", Action: "http://10.0.0.1", Reason: "x"}, "telegram", "chat1")
+	}()
+	prompt, ok := nextOutbound(t, msgBus, 2*time.Second)
+	if !ok {
+		t.Fatal("expected approval prompt")
+	}
+	if !strings.Contains(prompt.Content, "Auto-deny in 30 seconds.") {
+		t.Errorf("prompt should state the category timeout, got: %s", prompt.Content)
+	}
+
+	fake.Advance(30 * time.Second)
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "timed out after 30s") {
+			t.Fatalf("expected timeout error, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request did not time out after the category timeout")
+	}
+}
+
+func TestIsSessionApproveKeyword(t *testing.T) {
+	for _, w := range []string{"session", "approve session", "allow for session"} {
+		if !isSessionApproveKeyword(w) {
+			t.Errorf("expected %q to be a session approve keyword", w)
+		}
+	}
+	for _, w := range []string{"本次会话", "会话内允许"} {
+		if !isSessionApproveKeywordCJK(w) {
+			t.Errorf("expected %q to be a CJK session approve keyword", w)
+		}
+	}
+	for _, w := range []string{"always", "approve always", "always allow"} {
+		if !isAlwaysApproveKeyword(w) || isSessionApproveKeyword(w) {
+			t.Errorf("expected %q to be an always keyword only", w)
+		}
+	}
+	for _, w := range []string{"总是", "总是允许"} {
+		if !isAlwaysApproveKeywordCJK(w) || isSessionApproveKeywordCJK(w) {
+			t.Errorf("expected %q to be a CJK always keyword only", w)
+		}
+	}
+	if isSessionApproveKeyword("approve") || isSessionApproveKeywordCJK("批准") || isAlwaysApproveKeyword("approve") || isAlwaysApproveKeywordCJK("批准") {
+		t.Error("plain approval must stay one-shot")
+	}
+}
+
+// recordingSink collects audit entries for assertions.
+type recordingSink struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (s *recordingSink) Record(e AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *recordingSink) decisions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, e := range s.entries {
+		out = append(out, e.Decision)
+	}
+	return out
+}
+
+func TestRequestApproval_SessionAllowsForSession(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 2}, msgBus)
+	sink := &recordingSink{}
+	pe.SetAuditSink(sink)
+
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	prompt, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected an approval prompt")
+	}
+	if !strings.Contains(prompt.Content, `"session"`) {
+		t.Errorf("prompt should offer the session option:\n%s", prompt.Content)
+	}
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve session"})
+	if err := <-errCh; err != nil {
+		t.Fatalf("expected approval, got: %v", err)
+	}
+
+	// Same category and tool in the same session: no prompt.
+	v.Action = "make release"
+	if err := pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1"); err != nil {
+		t.Errorf("expected session auto-allow, got: %v", err)
+	}
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shortCancel()
+	if msg, ok := msgBus.SubscribeOutbound(shortCtx); ok {
+		t.Errorf("no prompt expected within the session, got: %s", msg.Content)
+	}
+
+	got := sink.decisions()
+	if len(got) != 2 || got[0] != DecisionApproved || got[1] != DecisionSessionAllowed {
+		t.Errorf("unexpected audit decisions: %v", got)
+	}
+
+	// A different session still prompts (and times out here).
+	start := time.Now()
+	err := pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat2")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected other session to require approval, got: %v", err)
+	}
+	if time.Since(start) < time.Second {
+		t.Error("other session should have waited for a reply")
+	}
+
+	// Forgetting the session forgets the approval.
+	pe.ForgetSession("telegram:chat1")
+	if pe.isSessionAllowed("telegram:chat1", v) {
+		t.Error("expected ForgetSession to clear session approvals")
+	}
+}
+
+func TestRequestApproval_RememberPerCategory(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ApprovalTimeout:   2,
+		RememberApprovals: map[string]bool{"ssrf": false},
+	}, msgBus)
+
+	// approveAlways answers the next prompt with "always" and returns its text.
+	approveAlways := func(v Violation) string {
+		t.Helper()
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1")
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		prompt, ok := msgBus.SubscribeOutbound(ctx)
+		if !ok {
+			t.Fatalf("expected an approval prompt for %s", v.Category)
+		}
+		msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "always"})
+		if err := <-errCh; err != nil {
+			t.Fatalf("expected approval for %s, got: %v", v.Category, err)
+		}
+		return prompt.Content
+	}
+
+	execV := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+	approveAlways(execV)
+	if !pe.isAllowlisted("telegram:chat1", execV) {
+		t.Error("expected exec approval to be remembered")
+	}
+
+	ssrfV := Violation{Category: "ssrf", Tool: "web_fetch", Action: "http://10.0.0.1/", Reason: "x"}
+	if prompt := approveAlways(ssrfV); strings.Contains(prompt, "always") {
+		t.Errorf("ssrf prompt should not offer the session option:\n%s", prompt)
+	}
+	if pe.isAllowlisted("telegram:chat1", ssrfV) {
+		t.Error("expected ssrf approval not to be remembered")
+	}
+	// The next ssrf violation prompts again.
+	approveAlways(ssrfV)
+}
+
+func TestRequestApproval_AlwaysIsScopedToCategory(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
+	pe.allowForSession("telegram:chat1", Violation{Category: "exec_guard", Tool: "exec"})
+
+	if pe.isSessionAllowed("telegram:chat1", Violation{Category: "ssrf", Tool: "web_fetch"}) {
+		t.Error("session approval must not leak to other categories")
+	}
+	if !pe.isSessionAllowed("telegram:chat1", Violation{Category: "exec_guard", Tool: "exec", Action: "other"}) {
+		t.Error("session approval should cover other actions of the same category and tool")
+	}
+}
+
+func TestRequestApproval_HoldsMessagesUntilResolved(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 5, HoldMessagesDuringApproval: true}, msgBus)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Reason: "x"}, "telegram", "chat1")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, ok := msgBus.SubscribeOutbound(ctx); !ok {
+		t.Fatal("expected an approval prompt")
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "first"})
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "second"})
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "other", Content: "unrelated"})
+
+	// Only the message from another chat reaches the agent while pending.
+	got, ok := msgBus.ConsumeInbound(ctx)
+	if !ok || got.Content != "unrelated" {
+		t.Fatalf("expected only the unrelated message, got %q", got.Content)
+	}
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shortCancel()
+	if msg, ok := msgBus.ConsumeInbound(shortCtx); ok {
+		t.Fatalf("message delivered while approval pending: %q", msg.Content)
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve"})
+	if err := <-errCh; err != nil {
+		t.Fatalf("expected approval, got: %v", err)
+	}
+
+	for _, want := range []string{"first", "second"} {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok || msg.Content != want {
+			t.Fatalf("expected held message %q after resolution, got %q", want, msg.Content)
+		}
+	}
+}
+
+func TestRequestApproval_PassesMessagesThroughByDefault(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 5}, msgBus)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Reason: "x"}, "telegram", "chat1")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, ok := msgBus.SubscribeOutbound(ctx); !ok {
+		t.Fatal("expected an approval prompt")
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "hello"})
+	if msg, ok := msgBus.ConsumeInbound(ctx); !ok || msg.Content != "hello" {
+		t.Fatalf("expected message to pass through, got %q", msg.Content)
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "deny"})
+	if err := <-errCh; err == nil {
+		t.Error("expected denial")
+	}
+}
+
+func TestFormatApprovalMessage_TruncatesLongAction(t *testing.T) {
+	action := "echo start " + strings.Repeat("x", 10000) + " end-marker"
+	msg := formatApprovalMessage(1, Violation{
+		Category: "exec_guard",
+		Tool:     "exec",
+		Action:   action,
+		Reason:   "dangerous pattern detected",
+	}, 300, true, 500, "")
+
+	if n := utf8.RuneCountInString(msg); n > 500 {
+		t.Errorf("message has %d characters, want at most 500", n)
+	}
+	for _, want := range []string{
+		"Action: echo start",
+		"end-marker",
+		"chars omitted",
+		"Reason: dangerous pattern detected",
+		`Reply "approve" to allow or "deny" to block.`,
+		`回复 "批准" 允许执行，回复 "拒绝" 阻止执行。`,
+		`Reply "always"`,
+		"Auto-deny in 300 seconds.",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("truncated message should contain %q:\n%s", want, msg)
+		}
+	}
+
+	// Short messages are left alone.
+	short := formatApprovalMessage(1, Violation{Category: "exec_guard", Action: "ls", Reason: "x"}, 0, false, 500, "")
+	if strings.Contains(short, "omitted") {
+		t.Errorf("short message should not be truncated:\n%s", short)
+	}
+}
+
+func TestFormatApprovalMessage_ShortensDetailsFirst(t *testing.T) {
+	msg := formatApprovalMessage(1, Violation{
+		Category: "exec_guard",
+		Tool:     "exec",
+		Action:   "rm -rf /tmp/x",
+		Reason:   "dangerous pattern detected",
+		Details:  "Command: rm -rf /tmp/x\n" + strings.Repeat("y", 10000) + "\nRisk: high",
+	}, 300, true, 600, "")
+
+	if n := utf8.RuneCountInString(msg); n > 600 {
+		t.Errorf("message has %d characters, want at most 600", n)
+	}
+	for _, want := range []string{"Action: rm -rf /tmp/x\n", "Explanation:\nCommand: rm", "chars omitted", "Risk: high"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message should contain %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestRequestApproval_DelegateDecides(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ApprovalTimeout:   5,
+		ApprovalDelegates: map[string]string{"exec_guard": "telegram:boss"},
+	}, msgBus)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Action: "make deploy", Reason: "x"}, "telegram", "dev")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	prompt, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected an approval prompt")
+	}
+	if prompt.ChatID != "boss" || !strings.Contains(prompt.Content, "Requested from: telegram:dev") {
+		t.Errorf("expected the prompt in the delegate chat naming the requester, got %s: %s", prompt.ChatID, prompt.Content)
+	}
+	notice, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || notice.ChatID != "dev" || !strings.Contains(notice.Content, "requested from telegram:boss") {
+		t.Errorf("expected the requesting chat to be told who was asked, got %s: %s", notice.ChatID, notice.Content)
+	}
+	if pending := pe.PendingApprovals("telegram", "boss"); len(pending) != 1 || pending[0].Approver != "telegram:boss" {
+		t.Errorf("expected the delegate to see the pending request, got %+v", pending)
+	}
+
+	// The requester cannot approve their own request.
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "dev", Content: "approve"})
+	select {
+	case err := <-errCh:
+		t.Fatalf("a reply from the requesting chat must be ignored, resolved with: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if msg, ok := msgBus.ConsumeInbound(ctx); !ok || msg.ChatID != "dev" {
+		t.Errorf("expected the requester's reply to pass through to the agent, got %+v", msg)
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "boss", Content: "approve"})
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("expected the delegate's approval to resolve the request, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request did not resolve after the delegate approved")
+	}
+}
+
+func TestRequestApproval_ViolationDelegateOverridesConfig(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ApprovalTimeout:   5,
+		ApprovalDelegates: map[string]string{"*": "telegram:boss"},
+	}, msgBus)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "ssrf", Action: "http://10.0.0.1", Reason: "x", Delegate: "slack:manager"}, "telegram", "dev")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if prompt, ok := msgBus.SubscribeOutbound(ctx); !ok || prompt.Channel != "slack" || prompt.ChatID != "manager" {
+		t.Fatalf("expected the prompt to go to the violation's delegate, got %+v", prompt)
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "boss", Content: "approve"})
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "slack", ChatID: "manager", Content: "deny"})
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "denied") {
+			t.Errorf("expected the delegate's denial, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request did not resolve after the delegate replied")
+	}
+}
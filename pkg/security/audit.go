@@ -0,0 +1,122 @@
+package security
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Audit decisions recorded for every non-off policy evaluation.
+const (
+	DecisionBlocked        = "blocked"         // rejected by block mode
+	DecisionApproved       = "approved"        // approved by the user
+	DecisionDenied         = "denied"          // denied by the user or cancelled
+	DecisionTimeout        = "timeout"         // no reply to the approval request in time
+	DecisionTrusted        = "trusted"         // auto-allowed for a trusted chat
+	DecisionSessionAllowed = "session_allowed" // auto-allowed by a "session" approval earlier in the session
+	DecisionAllowlisted    = "allowlisted"     // auto-allowed by an earlier "always" approval of the same action
+	DecisionWouldBlock     = "would_block"     // matched a shadow rule; recorded only, not enforced
+	DecisionWebhookAllowed = "webhook_allowed" // allowed by the external decision webhook
+	DecisionModeOverride   = "mode_override"   // a session mode override was set or cleared with /policy
+)
+
+// AuditEntry records a single security decision.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Category string    `json:"category"`
+	Mode     string    `json:"mode,omitempty"`
+	Tool     string    `json:"tool,omitempty"`
+	Action   string    `json:"action,omitempty"`
+	RuleName string    `json:"rule,omitempty"`
+	Channel  string    `json:"channel,omitempty"`
+	ChatID   string    `json:"chat_id,omitempty"`
+	Decision string    `json:"decision"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// AuditSink receives security decisions. Implementations must be safe for
+// concurrent use. An error means the entry was not recorded; see
+// SecurityConfig.AuditFailClosed for how that affects allowed actions.
+type AuditSink interface {
+	Record(entry AuditEntry) error
+}
+
+// SetAuditSink installs a sink that receives every decision in addition to
+// the log. Pass nil to log only.
+func (pe *PolicyEngine) SetAuditSink(sink AuditSink) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.auditSink = sink
+}
+
+// RecordShadow audits a match against a shadow rule without affecting the
+// outcome of the call. Safe to call on a nil engine.
+func (pe *PolicyEngine) RecordShadow(v Violation, channel, chatID string) {
+	if pe == nil {
+		return
+	}
+	pe.audit(v, "", channel, chatID, DecisionWouldBlock, v.Reason)
+}
+
+// auditAllow audits a decision that lets the action proceed. With
+// audit_fail_closed set, an action that cannot be recorded is blocked instead.
+func (pe *PolicyEngine) auditAllow(v Violation, mode PolicyMode, channel, chatID, decision, reason string) error {
+	err := pe.audit(v, mode, channel, chatID, decision, reason)
+	if err == nil || pe.config == nil || !pe.config.AuditFailClosed {
+		return nil
+	}
+	return fmt.Errorf("blocked by security policy [%s]: %s (audit log unavailable: %v)", v.Category, v.Reason, err)
+}
+
+// audit logs a decision taken under mode and forwards it to the configured
+// sink, returning the sink's error if it failed to record the entry.
+func (pe *PolicyEngine) audit(v Violation, mode PolicyMode, channel, chatID, decision, reason string) error {
+	entry := AuditEntry{
+		Time:     pe.clock.Now(),
+		Category: v.Category,
+		Mode:     string(mode),
+		Tool:     v.Tool,
+		Action:   v.Action,
+		RuleName: v.RuleName,
+		Channel:  channel,
+		ChatID:   chatID,
+		Decision: decision,
+		Reason:   reason,
+	}
+
+	logger.InfoCF("security", "Policy decision",
+		map[string]interface{}{
+			"category": entry.Category,
+			"mode":     entry.Mode,
+			"tool":     entry.Tool,
+			"action":   entry.Action,
+			"channel":  entry.Channel,
+			"chat_id":  entry.ChatID,
+			"decision": entry.Decision,
+			"reason":   entry.Reason,
+		})
+
+	pe.mu.Lock()
+	sink := pe.auditSink
+	for _, sub := range pe.auditSubs {
+		if sub.filter.Match(entry) {
+			sub.fn(entry)
+		}
+	}
+	pe.mu.Unlock()
+	if sink == nil {
+		return nil
+	}
+	if err := sink.Record(entry); err != nil {
+		logger.WarnCF("security", "Audit sink failed to record decision",
+			map[string]interface{}{
+				"category":    entry.Category,
+				"decision":    entry.Decision,
+				"error":       err.Error(),
+				"fail_closed": pe.config != nil && pe.config.AuditFailClosed,
+			})
+		return err
+	}
+	return nil
+}
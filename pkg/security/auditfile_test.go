@@ -0,0 +1,128 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/clock"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func readAuditLog(t *testing.T, path string) []AuditEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestFileAuditSink_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
+	pe.SetClock(clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)))
+	sink, err := NewFileAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe.SetAuditSink(sink)
+
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "rm -rf /", Reason: "dangerous", RuleName: "rm"}
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "42"); err == nil {
+		t.Fatal("expected block")
+	}
+	sink.Close()
+
+	// Reopening appends rather than truncating.
+	sink, err = NewFileAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe.SetAuditSink(sink)
+	pe.RecordShadow(v, "telegram", "42")
+	sink.Close()
+
+	entries := readAuditLog(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	want := AuditEntry{
+		Time: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Category: "exec_guard", Mode: "block", Tool: "exec",
+		Action: "rm -rf /", RuleName: "rm", Channel: "telegram", ChatID: "42", Decision: DecisionBlocked, Reason: "dangerous",
+	}
+	if entries[0] != want {
+		t.Errorf("got %+v, want %+v", entries[0], want)
+	}
+	if entries[1].Decision != DecisionWouldBlock || entries[1].Mode != "" {
+		t.Errorf("expected a shadow entry, got %+v", entries[1])
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestAudit_RecordsApprovalOutcomeAndTimeout(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 60}, msgBus)
+	fake := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	pe.SetClock(fake)
+	sink := &recordingSink{}
+	pe.SetAuditSink(sink)
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat1")
+	}()
+	if _, ok := nextOutbound(t, msgBus, 2*time.Second); !ok {
+		t.Fatal("expected an approval prompt")
+	}
+	sink.mu.Lock()
+	if len(sink.entries) != 0 {
+		t.Errorf("expected nothing recorded before the reply, got %+v", sink.entries)
+	}
+	sink.mu.Unlock()
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "yes"})
+	if err := <-errCh; err != nil {
+		t.Fatalf("expected approval, got %v", err)
+	}
+
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "chat2")
+	}()
+	if _, ok := nextOutbound(t, msgBus, 2*time.Second); !ok {
+		t.Fatal("expected an approval prompt")
+	}
+	fake.Advance(time.Minute)
+	if err := <-errCh; err == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", sink.entries)
+	}
+	if e := sink.entries[0]; e.Decision != DecisionApproved || e.Mode != "approve" || e.ChatID != "chat1" {
+		t.Errorf("unexpected approval entry %+v", e)
+	}
+	if e := sink.entries[1]; e.Decision != DecisionTimeout || e.Mode != "approve" || e.ChatID != "chat2" {
+		t.Errorf("unexpected timeout entry %+v", e)
+	}
+}
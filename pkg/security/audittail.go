@@ -0,0 +1,188 @@
+package security
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// auditTailBuffer is how many entries may wait to be forwarded to a chat
+// before newer ones are dropped.
+const auditTailBuffer = 64
+
+// auditDecisions lists every decision an AuditEntry may carry.
+var auditDecisions = []string{
+	DecisionBlocked, DecisionApproved, DecisionDenied, DecisionTimeout, DecisionTrusted,
+	DecisionSessionAllowed, DecisionAllowlisted, DecisionWouldBlock, DecisionWebhookAllowed,
+	DecisionModeOverride,
+}
+
+// AuditFilter selects audit entries by category and decision. An empty list
+// matches everything.
+type AuditFilter struct {
+	Categories []string
+	Decisions  []string
+}
+
+// Match reports whether entry passes the filter.
+func (f AuditFilter) Match(entry AuditEntry) bool {
+	return (len(f.Categories) == 0 || slices.Contains(f.Categories, entry.Category)) &&
+		(len(f.Decisions) == 0 || slices.Contains(f.Decisions, entry.Decision))
+}
+
+type auditSubscriber struct {
+	filter AuditFilter
+	fn     func(AuditEntry)
+}
+
+// SubscribeAudit calls fn with every later decision that matches filter until
+// the returned function is called. fn runs on the evaluating goroutine with
+// the engine locked, so it must not block or call back into the engine; no
+// call is in flight once unsubscribe returns.
+func (pe *PolicyEngine) SubscribeAudit(filter AuditFilter, fn func(AuditEntry)) (unsubscribe func()) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.nextSubID++
+	id := pe.nextSubID
+	pe.auditSubs[id] = auditSubscriber{filter: filter, fn: fn}
+	return func() {
+		pe.mu.Lock()
+		defer pe.mu.Unlock()
+		delete(pe.auditSubs, id)
+	}
+}
+
+// IsAuditOperator reports whether channel/chatID may tail the audit log.
+func (pe *PolicyEngine) IsAuditOperator(channel, chatID string) bool {
+	if pe.config == nil || channel == "" {
+		return false
+	}
+	for _, entry := range pe.config.AuditOperators {
+		if matchChatPattern(entry, channel, chatID) {
+			return true
+		}
+	}
+	return false
+}
+
+// InterceptAuditCommand handles "/audit tail [filters...]" and "/audit stop"
+// from operator chats, forwarding matching decisions to the chat as they are
+// made. Filters are category names and decisions, e.g.
+// "/audit tail exec_guard blocked".
+func (pe *PolicyEngine) InterceptAuditCommand(msg bus.InboundMessage) bool {
+	fields := strings.Fields(msg.Content)
+	if len(fields) == 0 || fields[0] != "/audit" || pe.bus == nil {
+		return false
+	}
+	pe.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: pe.handleAuditCommand(msg.Channel, msg.ChatID, fields[1:]),
+	})
+	return true
+}
+
+func (pe *PolicyEngine) handleAuditCommand(channel, chatID string, args []string) string {
+	const usage = "Usage: /audit tail [category...] [decision...] | /audit stop"
+	if !pe.IsAuditOperator(channel, chatID) {
+		return "This chat is not allowed to view the audit log (see security.audit_operators)."
+	}
+	if len(args) == 0 {
+		return usage
+	}
+
+	key := sessionKey(channel, chatID)
+	switch args[0] {
+	case "stop":
+		if !pe.stopAuditTail(key) {
+			return "No audit tail is running in this chat."
+		}
+		return "Stopped forwarding audit entries."
+
+	case "tail":
+		var filter AuditFilter
+		for _, arg := range args[1:] {
+			switch {
+			case slices.Contains(Categories, arg):
+				filter.Categories = append(filter.Categories, arg)
+			case slices.Contains(auditDecisions, arg):
+				filter.Decisions = append(filter.Decisions, arg)
+			default:
+				return fmt.Sprintf("Unknown filter %q. Categories: %s. Decisions: %s.",
+					arg, strings.Join(Categories, ", "), strings.Join(auditDecisions, ", "))
+			}
+		}
+		pe.stopAuditTail(key)
+		pe.startAuditTail(key, channel, chatID, filter)
+
+		desc := "all decisions"
+		if len(args) > 1 {
+			desc = strings.Join(args[1:], ", ")
+		}
+		return fmt.Sprintf("Forwarding audit entries (%s) to this chat. Send /audit stop to end.", desc)
+	}
+	return usage
+}
+
+// startAuditTail forwards matching entries to the chat through a buffered
+// queue, so a slow channel never holds up policy evaluation.
+func (pe *PolicyEngine) startAuditTail(key, channel, chatID string, filter AuditFilter) {
+	queue := make(chan AuditEntry, auditTailBuffer)
+	unsubscribe := pe.SubscribeAudit(filter, func(entry AuditEntry) {
+		select {
+		case queue <- entry:
+		default:
+			logger.WarnCF("security", "Audit tail queue full, dropping entry",
+				map[string]interface{}{"channel": channel, "chat_id": chatID})
+		}
+	})
+	go func() {
+		for entry := range queue {
+			pe.bus.PublishOutbound(bus.OutboundMessage{
+				Channel: channel,
+				ChatID:  chatID,
+				Content: FormatAuditEntry(entry),
+			})
+		}
+	}()
+
+	pe.mu.Lock()
+	pe.auditTails[key] = func() {
+		unsubscribe()
+		close(queue)
+	}
+	pe.mu.Unlock()
+}
+
+func (pe *PolicyEngine) stopAuditTail(key string) bool {
+	pe.mu.Lock()
+	stop, ok := pe.auditTails[key]
+	delete(pe.auditTails, key)
+	pe.mu.Unlock()
+	if ok {
+		stop()
+	}
+	return ok
+}
+
+// FormatAuditEntry renders an entry as a single chat line.
+func FormatAuditEntry(e AuditEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[audit %s] %s %s", e.Time.Format("15:04:05"), e.Decision, e.Category)
+	if e.Tool != "" {
+		fmt.Fprintf(&b, " (tool: %s)", e.Tool)
+	}
+	if e.Action != "" {
+		fmt.Fprintf(&b, ": %s", truncateMiddle(e.Action, 200))
+	}
+	if e.Channel != "" {
+		fmt.Fprintf(&b, " in %s:%s", e.Channel, e.ChatID)
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(&b, " - %s", e.Reason)
+	}
+	return b.String()
+}
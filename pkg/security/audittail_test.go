@@ -0,0 +1,99 @@
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// nextOutbound waits briefly for the next outbound message.
+func nextOutbound(t *testing.T, msgBus *bus.MessageBus, wait time.Duration) (bus.OutboundMessage, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	return msgBus.SubscribeOutbound(ctx)
+}
+
+func TestAuditTail_ForwardsBlockedDecision(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{AuditOperators: []string{"telegram:ops"}}, msgBus)
+	msgBus.AddInterceptor(pe.InterceptAuditCommand)
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "ops", Content: "/audit tail exec_guard blocked"})
+	if reply, ok := nextOutbound(t, msgBus, time.Second); !ok || !strings.Contains(reply.Content, "Forwarding audit entries (exec_guard, blocked)") {
+		t.Fatalf("expected tail confirmation, got %q", reply.Content)
+	}
+
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "rm -rf /", Reason: "dangerous"}
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "user1"); err == nil {
+		t.Fatal("expected block")
+	}
+	msg, ok := nextOutbound(t, msgBus, time.Second)
+	if !ok {
+		t.Fatal("expected the blocked decision to be forwarded")
+	}
+	if msg.ChatID != "ops" || !strings.Contains(msg.Content, "blocked exec_guard (tool: exec): rm -rf / in telegram:user1 - dangerous") {
+		t.Errorf("unexpected forwarded entry: %+v", msg)
+	}
+
+	// Filtered out: another category.
+	pe.Evaluate(context.Background(), ModeBlock, Violation{Category: "ssrf", Action: "http://10.0.0.1"}, "telegram", "user1")
+	if msg, ok := nextOutbound(t, msgBus, 100*time.Millisecond); ok {
+		t.Errorf("expected ssrf entry to be filtered, got %q", msg.Content)
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "ops", Content: "/audit stop"})
+	if reply, ok := nextOutbound(t, msgBus, time.Second); !ok || !strings.Contains(reply.Content, "Stopped") {
+		t.Fatalf("expected stop confirmation, got %q", reply.Content)
+	}
+	pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "user1")
+	if msg, ok := nextOutbound(t, msgBus, 100*time.Millisecond); ok {
+		t.Errorf("expected no forwarding after stop, got %q", msg.Content)
+	}
+}
+
+func TestAuditTail_RequiresOperator(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{AuditOperators: []string{"telegram:ops"}}, msgBus)
+	msgBus.AddInterceptor(pe.InterceptAuditCommand)
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "intruder", Content: "/audit tail"})
+	if reply, ok := nextOutbound(t, msgBus, time.Second); !ok || !strings.Contains(reply.Content, "not allowed") {
+		t.Fatalf("expected refusal, got %q", reply.Content)
+	}
+	pe.Evaluate(context.Background(), ModeBlock, Violation{Category: "exec_guard", Action: "x"}, "telegram", "user1")
+	if msg, ok := nextOutbound(t, msgBus, 100*time.Millisecond); ok {
+		t.Errorf("expected nothing forwarded to a non-operator, got %q", msg.Content)
+	}
+}
+
+func TestAuditTail_RejectsUnknownFilter(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{AuditOperators: []string{"slack:*"}}, bus.NewMessageBus())
+	if got := pe.handleAuditCommand("slack", "C1", []string{"tail", "everything"}); !strings.Contains(got, `Unknown filter "everything"`) {
+		t.Errorf("expected unknown filter error, got %q", got)
+	}
+	if got := pe.handleAuditCommand("slack", "C1", []string{"stop"}); !strings.Contains(got, "No audit tail") {
+		t.Errorf("expected no-tail message, got %q", got)
+	}
+}
+
+func TestSubscribeAudit_Unsubscribe(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
+	var got []string
+	unsubscribe := pe.SubscribeAudit(AuditFilter{Decisions: []string{DecisionBlocked}}, func(e AuditEntry) {
+		got = append(got, e.Action)
+	})
+
+	pe.Evaluate(context.Background(), ModeBlock, Violation{Category: "ssrf", Action: "a"}, "cli", "direct")
+	pe.RecordShadow(Violation{Category: "exec_guard", Action: "b"}, "cli", "direct")
+	unsubscribe()
+	pe.Evaluate(context.Background(), ModeBlock, Violation{Category: "ssrf", Action: "c"}, "cli", "direct")
+
+	if len(got) != 1 || got[0] != "a" {
+		t.Errorf("expected only the first blocked entry, got %v", got)
+	}
+}
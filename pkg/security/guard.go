@@ -0,0 +1,66 @@
+package security
+
+import (
+	"context"
+)
+
+// Guard is a custom security check run before every tool call. Inspect
+// returns a violation and true when the call should be subject to policy;
+// returning false lets the call through untouched.
+type Guard interface {
+	Inspect(ctx context.Context, toolName string, args map[string]interface{}) (*Violation, bool)
+}
+
+// GuardFunc adapts an ordinary function to the Guard interface.
+type GuardFunc func(ctx context.Context, toolName string, args map[string]interface{}) (*Violation, bool)
+
+// Inspect calls f(ctx, toolName, args).
+func (f GuardFunc) Inspect(ctx context.Context, toolName string, args map[string]interface{}) (*Violation, bool) {
+	return f(ctx, toolName, args)
+}
+
+// registeredGuard pairs a custom guard with the mode its violations are
+// evaluated under.
+type registeredGuard struct {
+	guard Guard
+	mode  PolicyMode
+}
+
+// RegisterGuard adds a custom guard. Its violations are evaluated under mode
+// unless a per-channel override exists for the violation's category.
+func (pe *PolicyEngine) RegisterGuard(g Guard, mode PolicyMode) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.guards = append(pe.guards, registeredGuard{guard: g, mode: mode})
+}
+
+// CheckGuards runs every registered guard against a pending tool call and
+// feeds any violation into Evaluate. It returns the first denial, or nil when
+// all guards pass or their violations are allowed. A nil engine allows all.
+func (pe *PolicyEngine) CheckGuards(ctx context.Context, toolName string, args map[string]interface{}, channel, chatID string) error {
+	if pe == nil {
+		return nil
+	}
+	pe.mu.Lock()
+	guards := make([]registeredGuard, len(pe.guards))
+	copy(guards, pe.guards)
+	pe.mu.Unlock()
+
+	for _, rg := range guards {
+		v, violated := rg.guard.Inspect(ctx, toolName, args)
+		if !violated || v == nil {
+			continue
+		}
+		if v.Category == "" {
+			v.Category = "custom_guard"
+		}
+		if v.Tool == "" {
+			v.Tool = toolName
+		}
+		mode := pe.EffectiveMode(v.Category, rg.mode, channel, chatID)
+		if err := pe.Evaluate(ctx, mode, *v, channel, chatID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
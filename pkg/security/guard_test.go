@@ -0,0 +1,83 @@
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// prodConfigGuard blocks write_file calls targeting the production config.
+var prodConfigGuard = GuardFunc(func(ctx context.Context, toolName string, args map[string]interface{}) (*Violation, bool) {
+	if toolName != "write_file" {
+		return nil, false
+	}
+	path, _ := args["path"].(string)
+	if path != "/etc/app/prod.yaml" {
+		return nil, false
+	}
+	return &Violation{
+		Category: "prod_config",
+		Action:   path,
+		Reason:   "writing to the production config is not allowed",
+		RuleName: "no_prod_config",
+	}, true
+})
+
+// TestCheckGuards_BlocksMatchingCall verifies a custom guard denies the path it targets
+func TestCheckGuards_BlocksMatchingCall(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
+	pe.RegisterGuard(prodConfigGuard, ModeBlock)
+
+	err := pe.CheckGuards(context.Background(), "write_file", map[string]interface{}{"path": "/etc/app/prod.yaml"}, "telegram", "chat1")
+	if err == nil {
+		t.Fatal("Expected custom guard to block write to prod config")
+	}
+	if !strings.Contains(err.Error(), "prod_config") {
+		t.Errorf("Expected error to name the guard category, got: %v", err)
+	}
+}
+
+// TestCheckGuards_PassesOtherCalls verifies unrelated calls are not affected
+func TestCheckGuards_PassesOtherCalls(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
+	pe.RegisterGuard(prodConfigGuard, ModeBlock)
+
+	calls := []struct {
+		tool string
+		path string
+	}{
+		{"write_file", "/etc/app/dev.yaml"},
+		{"read_file", "/etc/app/prod.yaml"},
+	}
+	for _, c := range calls {
+		if err := pe.CheckGuards(context.Background(), c.tool, map[string]interface{}{"path": c.path}, "telegram", "chat1"); err != nil {
+			t.Errorf("%s %s: expected pass, got %v", c.tool, c.path, err)
+		}
+	}
+}
+
+// TestCheckGuards_ChannelOverride verifies per-channel modes apply to custom categories
+func TestCheckGuards_ChannelOverride(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ChannelModes: map[string]map[string]string{"slack": {"prod_config": "off"}},
+	}, nil)
+	pe.RegisterGuard(prodConfigGuard, ModeBlock)
+
+	args := map[string]interface{}{"path": "/etc/app/prod.yaml"}
+	if err := pe.CheckGuards(context.Background(), "write_file", args, "slack", "c1"); err != nil {
+		t.Errorf("Expected slack override to allow, got %v", err)
+	}
+	if err := pe.CheckGuards(context.Background(), "write_file", args, "telegram", "c1"); err == nil {
+		t.Error("Expected telegram to stay blocked")
+	}
+}
+
+// TestCheckGuards_NilEngine verifies a nil engine allows everything
+func TestCheckGuards_NilEngine(t *testing.T) {
+	var pe *PolicyEngine
+	if err := pe.CheckGuards(context.Background(), "write_file", nil, "", ""); err != nil {
+		t.Errorf("Expected nil engine to allow, got %v", err)
+	}
+}
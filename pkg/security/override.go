@@ -0,0 +1,225 @@
+package security
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+const (
+	// MaxModeOverride bounds how long a session mode override may last.
+	MaxModeOverride = 24 * time.Hour
+	// modeOverrideConfirmWindow is how long a requested override waits for
+	// "/policy confirm" before it is dropped.
+	modeOverrideConfirmWindow = time.Minute
+)
+
+// modeOverride is a temporary mode for one category in one session.
+type modeOverride struct {
+	mode  PolicyMode
+	until time.Time
+}
+
+// pendingModeOverride is a "/policy set" awaiting confirmation.
+type pendingModeOverride struct {
+	category string
+	mode     PolicyMode
+	duration time.Duration
+	expires  time.Time
+}
+
+// IsModeOverrideOperator reports whether channel/chatID may change policy
+// modes for its own session with /policy.
+func (pe *PolicyEngine) IsModeOverrideOperator(channel, chatID string) bool {
+	if pe.config == nil || channel == "" {
+		return false
+	}
+	for _, entry := range pe.config.ModeOverrideOperators {
+		if matchChatPattern(entry, channel, chatID) {
+			return true
+		}
+	}
+	return false
+}
+
+// OverrideMode sets the mode of category for the channel/chatID session
+// until d has passed, after which the configured mode applies again. The
+// change is audited. It does not check who asked; callers handling chat
+// commands must check IsModeOverrideOperator first.
+func (pe *PolicyEngine) OverrideMode(channel, chatID, category string, mode PolicyMode, d time.Duration) error {
+	if !slices.Contains(Categories, category) {
+		return fmt.Errorf("unknown category %q (expected one of: %s)", category, strings.Join(Categories, ", "))
+	}
+	if _, err := parseOverrideMode(string(mode)); err != nil {
+		return err
+	}
+	if d <= 0 || d > MaxModeOverride {
+		return fmt.Errorf("duration must be between 1s and %s, got %s", MaxModeOverride, d)
+	}
+	mode = categoryMode(category, string(mode))
+
+	session := sessionKey(channel, chatID)
+	until := pe.clock.Now().Add(d)
+	pe.mu.Lock()
+	overrides, ok := pe.modeOverrides[session]
+	if !ok {
+		overrides = make(map[string]modeOverride)
+		pe.modeOverrides[session] = overrides
+	}
+	overrides[category] = modeOverride{mode: mode, until: until}
+	pe.mu.Unlock()
+
+	pe.audit(Violation{Category: category, Tool: "/policy", Action: fmt.Sprintf("set %s for %s", mode, d)}, mode,
+		channel, chatID, DecisionModeOverride, "session override until "+until.Format(time.RFC3339))
+	return nil
+}
+
+// ClearModeOverride drops the session override for category, or for every
+// category when category is empty, and reports whether one was active.
+func (pe *PolicyEngine) ClearModeOverride(channel, chatID, category string) bool {
+	session := sessionKey(channel, chatID)
+	now := pe.clock.Now()
+	var cleared []string
+	pe.mu.Lock()
+	for c, o := range pe.modeOverrides[session] {
+		if category != "" && c != category {
+			continue
+		}
+		if now.Before(o.until) {
+			cleared = append(cleared, c)
+		}
+		delete(pe.modeOverrides[session], c)
+	}
+	pe.mu.Unlock()
+
+	slices.Sort(cleared)
+	for _, c := range cleared {
+		pe.audit(Violation{Category: c, Tool: "/policy", Action: "clear"}, "",
+			channel, chatID, DecisionModeOverride, "session override cleared")
+	}
+	return len(cleared) > 0
+}
+
+// sessionMode returns the unexpired override for category in the session,
+// forgetting it once it has run out.
+func (pe *PolicyEngine) sessionMode(category, channel, chatID string) (PolicyMode, time.Time, bool) {
+	if pe == nil || channel == "" {
+		return "", time.Time{}, false
+	}
+	session := sessionKey(channel, chatID)
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	o, ok := pe.modeOverrides[session][category]
+	if !ok {
+		return "", time.Time{}, false
+	}
+	if !pe.clock.Now().Before(o.until) {
+		delete(pe.modeOverrides[session], category)
+		return "", time.Time{}, false
+	}
+	return o.mode, o.until, true
+}
+
+// parseOverrideMode is parseMode without the fallback to off, so a typo
+// cannot switch a check off.
+func parseOverrideMode(raw string) (PolicyMode, error) {
+	switch mode := PolicyMode(raw); mode {
+	case ModeOff, ModeBlock, ModeApprove, ModeFirstSeen:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown mode %q (expected off, block, approve or first_seen)", raw)
+}
+
+// InterceptPolicyCommand handles "/policy set <category> <mode> <duration>",
+// "/policy confirm" and "/policy clear [category]" from operator chats. A set
+// only takes effect once confirmed, and only for the chat it was sent from.
+func (pe *PolicyEngine) InterceptPolicyCommand(msg bus.InboundMessage) bool {
+	fields := strings.Fields(msg.Content)
+	if len(fields) == 0 || fields[0] != "/policy" || pe.bus == nil {
+		return false
+	}
+	pe.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: pe.handlePolicyCommand(msg.Channel, msg.ChatID, fields[1:]),
+	})
+	return true
+}
+
+func (pe *PolicyEngine) handlePolicyCommand(channel, chatID string, args []string) string {
+	const usage = "Usage: /policy set <category> <mode> <duration> | /policy confirm | /policy clear [category]"
+	if !pe.IsModeOverrideOperator(channel, chatID) {
+		return "This chat is not allowed to change policy modes (see security.mode_override_operators)."
+	}
+	if len(args) == 0 {
+		return usage
+	}
+
+	key := sessionKey(channel, chatID)
+	switch args[0] {
+	case "set":
+		if len(args) != 4 {
+			return usage
+		}
+		category := args[1]
+		if !slices.Contains(Categories, category) {
+			return fmt.Sprintf("Unknown category %q. Categories: %s.", category, strings.Join(Categories, ", "))
+		}
+		mode, err := parseOverrideMode(args[2])
+		if err != nil {
+			return err.Error()
+		}
+		d, err := time.ParseDuration(args[3])
+		if err != nil || d <= 0 || d > MaxModeOverride {
+			return fmt.Sprintf("Invalid duration %q: use e.g. 30m or 2h, at most %s.", args[3], MaxModeOverride)
+		}
+		pe.mu.Lock()
+		pe.pendingOverrides[key] = pendingModeOverride{
+			category: category,
+			mode:     mode,
+			duration: d,
+			expires:  pe.clock.Now().Add(modeOverrideConfirmWindow),
+		}
+		pe.mu.Unlock()
+		return fmt.Sprintf("Switch %s from %s to %s in this chat for %s? Send /policy confirm within %s to apply.",
+			category, pe.effectiveMode(category, channel, chatID), categoryMode(category, string(mode)), d, modeOverrideConfirmWindow)
+
+	case "confirm":
+		pe.mu.Lock()
+		p, ok := pe.pendingOverrides[key]
+		delete(pe.pendingOverrides, key)
+		pe.mu.Unlock()
+		if !ok || !pe.clock.Now().Before(p.expires) {
+			return "Nothing to confirm. Send /policy set first."
+		}
+		if err := pe.OverrideMode(channel, chatID, p.category, p.mode, p.duration); err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("%s is now %s in this chat for %s; it reverts automatically. Send /policy clear to revert sooner.",
+			p.category, categoryMode(p.category, string(p.mode)), p.duration)
+
+	case "clear":
+		category := ""
+		if len(args) > 1 {
+			category = args[1]
+		}
+		if !pe.ClearModeOverride(channel, chatID, category) {
+			return "No policy override is active in this chat."
+		}
+		return "Policy override cleared; the configured mode applies again."
+	}
+	return usage
+}
+
+// effectiveMode is the mode a violation of category in the chat would be
+// evaluated under right now, ignoring the decision webhook.
+func (pe *PolicyEngine) effectiveMode(category, channel, chatID string) PolicyMode {
+	mode := pe.EffectiveMode(category, pe.GetMode(category), channel, chatID)
+	if mode.IsOff() {
+		return ModeOff
+	}
+	return mode
+}
@@ -0,0 +1,131 @@
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/clock"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newOverrideEngine(t *testing.T) (*PolicyEngine, *bus.MessageBus, *clock.Fake) {
+	t.Helper()
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ExecGuard:             "block",
+		ModeOverrideOperators: []string{"telegram:ops"},
+	}, msgBus)
+	fake := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	pe.SetClock(fake)
+	msgBus.AddInterceptor(pe.InterceptPolicyCommand)
+	return pe, msgBus, fake
+}
+
+func sendPolicyCommand(t *testing.T, msgBus *bus.MessageBus, chatID, content string) string {
+	t.Helper()
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: chatID, Content: content})
+	reply, ok := nextOutbound(t, msgBus, time.Second)
+	if !ok {
+		t.Fatalf("expected a reply to %q", content)
+	}
+	return reply.Content
+}
+
+func TestPolicyCommand_OperatorOverridesUntilExpiry(t *testing.T) {
+	pe, msgBus, fake := newOverrideEngine(t)
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "matched deny rule"}
+
+	reply := sendPolicyCommand(t, msgBus, "ops", "/policy set exec_guard off 30m")
+	if !strings.Contains(reply, "Switch exec_guard from block to off in this chat for 30m0s?") {
+		t.Fatalf("expected a confirmation prompt, got %q", reply)
+	}
+	// Not applied until confirmed.
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "ops"); err == nil {
+		t.Fatal("expected block before confirmation")
+	}
+
+	reply = sendPolicyCommand(t, msgBus, "ops", "/policy confirm")
+	if !strings.Contains(reply, "exec_guard is now off in this chat for 30m0s") {
+		t.Fatalf("expected the override to be applied, got %q", reply)
+	}
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "ops"); err != nil {
+		t.Errorf("expected override to allow, got %v", err)
+	}
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "other"); err == nil {
+		t.Error("expected other chats to keep the configured mode")
+	}
+	if got := pe.Posture("telegram", "ops").Format(); !strings.Contains(got, "exec_guard: off (session override until 12:30)") {
+		t.Errorf("expected posture to show the override, got:\n%s", got)
+	}
+
+	fake.Advance(30 * time.Minute)
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "ops"); err == nil {
+		t.Error("expected the configured mode once the override expired")
+	}
+}
+
+func TestPolicyCommand_RejectsNonOperator(t *testing.T) {
+	pe, msgBus, _ := newOverrideEngine(t)
+
+	reply := sendPolicyCommand(t, msgBus, "user1", "/policy set exec_guard off 30m")
+	if !strings.Contains(reply, "not allowed") {
+		t.Fatalf("expected refusal, got %q", reply)
+	}
+	reply = sendPolicyCommand(t, msgBus, "user1", "/policy confirm")
+	if !strings.Contains(reply, "not allowed") {
+		t.Fatalf("expected refusal, got %q", reply)
+	}
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy"}
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "user1"); err == nil {
+		t.Error("expected block for a non-operator")
+	}
+}
+
+func TestPolicyCommand_ValidatesAndExpiresConfirmation(t *testing.T) {
+	pe, msgBus, fake := newOverrideEngine(t)
+
+	for _, cmd := range []string{
+		"/policy set exec_guard offf 30m",
+		"/policy set nope off 30m",
+		"/policy set exec_guard off 48h",
+		"/policy set exec_guard off",
+	} {
+		if reply := sendPolicyCommand(t, msgBus, "ops", cmd); strings.Contains(reply, "Send /policy confirm") {
+			t.Errorf("expected %q to be rejected, got %q", cmd, reply)
+		}
+	}
+
+	sendPolicyCommand(t, msgBus, "ops", "/policy set exec_guard off 30m")
+	fake.Advance(2 * time.Minute)
+	if reply := sendPolicyCommand(t, msgBus, "ops", "/policy confirm"); !strings.Contains(reply, "Nothing to confirm") {
+		t.Errorf("expected a stale confirmation to be refused, got %q", reply)
+	}
+	if _, _, ok := pe.sessionMode("exec_guard", "telegram", "ops"); ok {
+		t.Error("expected no override after a stale confirmation")
+	}
+}
+
+func TestPolicyCommand_ClearIsAudited(t *testing.T) {
+	pe, msgBus, _ := newOverrideEngine(t)
+	sink := &recordingSink{}
+	pe.SetAuditSink(sink)
+
+	if err := pe.OverrideMode("telegram", "ops", "exec_guard", ModeApprove, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if reply := sendPolicyCommand(t, msgBus, "ops", "/policy clear"); !strings.Contains(reply, "cleared") {
+		t.Fatalf("expected the override to be cleared, got %q", reply)
+	}
+	if _, _, ok := pe.sessionMode("exec_guard", "telegram", "ops"); ok {
+		t.Error("expected no override after clear")
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.entries) != 2 || sink.entries[0].Decision != DecisionModeOverride || sink.entries[0].Action != "set approve for 1h0m0s" || sink.entries[1].Action != "clear" {
+		t.Errorf("expected set and clear to be audited, got %+v", sink.entries)
+	}
+}
@@ -0,0 +1,166 @@
+package security
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// PendingApproval is an approval request that has not been resolved yet.
+type PendingApproval struct {
+	ID          uint64
+	Violation   Violation
+	Channel     string
+	ChatID      string
+	RequestedAt time.Time
+	Deadline    time.Time
+	Queued      bool // waiting for a slot; the prompt has not been sent yet
+	// Approver is the "channel:chatID" whose reply decides the request; it
+	// differs from Channel/ChatID when approval is delegated.
+	Approver string
+}
+
+// trackApproval records an outstanding approval and returns its ID and
+// functions that mark it as presented and remove it once resolved.
+func (pe *PolicyEngine) trackApproval(v Violation, channel, chatID, approver string, deadline time.Time, queued bool) (id uint64, presented, done func()) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.nextPendingID++
+	id = pe.nextPendingID
+	pe.pending[id] = &PendingApproval{
+		ID:          id,
+		Violation:   v,
+		Channel:     channel,
+		ChatID:      chatID,
+		RequestedAt: pe.clock.Now(),
+		Deadline:    deadline,
+		Queued:      queued,
+		Approver:    approver,
+	}
+
+	presented = func() {
+		pe.mu.Lock()
+		defer pe.mu.Unlock()
+		if p, ok := pe.pending[id]; ok {
+			p.Queued = false
+		}
+	}
+	done = func() {
+		pe.mu.Lock()
+		defer pe.mu.Unlock()
+		delete(pe.pending, id)
+	}
+	return id, presented, done
+}
+
+// presentedApprovals returns the IDs of the requests whose prompt has been
+// sent to approver and that are still unresolved, lowest first.
+func (pe *PolicyEngine) presentedApprovals(approver string) []uint64 {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	var ids []uint64
+	for id, p := range pe.pending {
+		if p.Approver == approver && !p.Queued {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// approvalTarget splits a reply such as "approve 3" or "deny #3, wrong host"
+// into the reply without the request ID and the ID it targets. The ID is the
+// second or the last word and only counts when it names a request presented
+// to approver; otherwise the reply is returned unchanged with ID 0.
+func (pe *PolicyEngine) approvalTarget(content, approver string) (string, uint64) {
+	words := strings.Fields(content)
+	if len(words) < 2 {
+		return content, 0
+	}
+	presented := pe.presentedApprovals(approver)
+	for _, i := range []int{1, len(words) - 1} {
+		word := strings.TrimRightFunc(words[i], isClauseSeparator)
+		id, err := strconv.ParseUint(strings.TrimPrefix(word, "#"), 10, 64)
+		if err != nil || !slices.Contains(presented, id) {
+			continue
+		}
+		rest := slices.Clone(words)
+		rest[i] = words[i][len(word):] // keep punctuation after the ID
+		return strings.TrimSpace(strings.Join(rest, " ")), id
+	}
+	return content, 0
+}
+
+// PendingApprovals lists the unresolved approval requests channel/chatID made
+// or was asked to decide, oldest first. An empty channel lists those of every
+// chat.
+func (pe *PolicyEngine) PendingApprovals(channel, chatID string) []PendingApproval {
+	if pe == nil {
+		return nil
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	var out []PendingApproval
+	for _, p := range pe.pending {
+		if channel == "" || (p.Channel == channel && p.ChatID == chatID) || p.Approver == sessionKey(channel, chatID) {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// InterceptApprovalsCommand answers "/approvals" with the chat's pending
+// approval requests. It runs as a bus interceptor, ahead of any approval
+// listener, so it responds even while the agent is waiting on an approval.
+func (pe *PolicyEngine) InterceptApprovalsCommand(msg bus.InboundMessage) bool {
+	if strings.TrimSpace(msg.Content) != "/approvals" || pe.bus == nil {
+		return false
+	}
+	pe.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: pe.FormatPendingApprovals(pe.PendingApprovals(msg.Channel, msg.ChatID)),
+	})
+	return true
+}
+
+// FormatPendingApprovals renders pending approvals for chat, with the time
+// left before each is auto-denied.
+func (pe *PolicyEngine) FormatPendingApprovals(pending []PendingApproval) string {
+	if len(pending) == 0 {
+		return "No pending approval requests."
+	}
+
+	now := time.Now()
+	if pe != nil {
+		now = pe.clock.Now()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Pending approval requests (%d):\n", len(pending))
+	for _, p := range pending {
+		remaining := p.Deadline.Sub(now).Round(time.Second)
+		if remaining < 0 {
+			remaining = 0
+		}
+		fmt.Fprintf(&b, "#%d [%s] %s", p.ID, p.Violation.Category, truncateMiddle(p.Violation.Action, 80))
+		if p.Violation.Tool != "" {
+			fmt.Fprintf(&b, " (tool: %s)", p.Violation.Tool)
+		}
+		status := "awaiting reply"
+		if p.Approver != "" && p.Approver != sessionKey(p.Channel, p.ChatID) {
+			status = "awaiting reply from " + p.Approver
+		}
+		if p.Queued {
+			status = "queued"
+		}
+		fmt.Fprintf(&b, " - %s, %v left\n", status, remaining)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
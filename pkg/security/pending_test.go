@@ -0,0 +1,170 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/clock"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPendingApprovals_ListedUntilResolved(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 300}, msgBus)
+	fake := clock.NewFake(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	pe.SetClock(fake)
+	msgBus.AddInterceptor(pe.InterceptApprovalsCommand)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}, "telegram", "chat1")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, ok := msgBus.SubscribeOutbound(ctx); !ok {
+		t.Fatal("expected approval prompt")
+	}
+
+	pending := pe.PendingApprovals("telegram", "chat1")
+	if len(pending) != 1 || pending[0].Violation.Action != "make deploy" || pending[0].Queued {
+		t.Fatalf("expected one presented approval, got %+v", pending)
+	}
+	if other := pe.PendingApprovals("telegram", "chat2"); len(other) != 0 {
+		t.Errorf("approval leaked into another chat: %+v", other)
+	}
+
+	fake.Advance(100 * time.Second)
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "/approvals"})
+	reply, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected /approvals reply")
+	}
+	for _, want := range []string{"Pending approval requests (1)", "[exec_guard] make deploy (tool: exec)", "awaiting reply, 3m20s left"} {
+		if !strings.Contains(reply.Content, want) {
+			t.Errorf("reply missing %q:\n%s", want, reply.Content)
+		}
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve"})
+	if err := <-errCh; err != nil {
+		t.Fatalf("expected approval, got: %v", err)
+	}
+	if pending := pe.PendingApprovals("", ""); len(pending) != 0 {
+		t.Errorf("expected no pending approvals after resolution, got %+v", pending)
+	}
+	if got := pe.FormatPendingApprovals(nil); got != "No pending approval requests." {
+		t.Errorf("unexpected empty listing: %q", got)
+	}
+}
+
+func TestPendingApprovals_QueuedRequestsMarked(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 60, MaxConcurrentApprovals: 1}, msgBus)
+	fake := clock.NewFake(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	pe.SetClock(fake)
+
+	release, err := pe.acquireApprovalSlot(context.Background(), "telegram:chat1", nil)
+	if err != nil {
+		t.Fatalf("acquireApprovalSlot failed: %v", err)
+	}
+	defer release()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "ssrf", Action: "http://10.0.0.1"}, "telegram", "chat1")
+	}()
+	fake.BlockUntil(1)
+
+	pending := pe.PendingApprovals("telegram", "chat1")
+	if len(pending) != 1 || !pending[0].Queued {
+		t.Fatalf("expected one queued approval, got %+v", pending)
+	}
+	if out := pe.FormatPendingApprovals(pending); !strings.Contains(out, "queued, 1m0s left") {
+		t.Errorf("expected queued status, got:\n%s", out)
+	}
+
+	fake.Advance(60 * time.Second)
+	<-errCh
+	if pending := pe.PendingApprovals("telegram", "chat1"); len(pending) != 0 {
+		t.Errorf("expected timed-out request to be removed, got %+v", pending)
+	}
+}
+
+func TestRequestApproval_RepliesTargetRequestByID(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 300}, msgBus)
+
+	errs := map[string]chan error{"make deploy": make(chan error, 1), "make release": make(chan error, 1)}
+	for action, ch := range errs {
+		go func() {
+			ch <- pe.Evaluate(context.Background(), ModeApprove, Violation{Category: "exec_guard", Tool: "exec", Action: action, Reason: "x"}, "telegram", "chat1")
+		}()
+	}
+	for range errs {
+		prompt, ok := nextOutbound(t, msgBus, 2*time.Second)
+		if !ok {
+			t.Fatal("expected two approval prompts")
+		}
+		if !strings.Contains(prompt.Content, "安全审批请求 #") {
+			t.Errorf("prompt should carry its request ID:\n%s", prompt.Content)
+		}
+	}
+	ids := map[string]uint64{}
+	for _, p := range pe.PendingApprovals("telegram", "chat1") {
+		ids[p.Violation.Action] = p.ID
+	}
+
+	// A bare reply is ambiguous and resolves nothing.
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve"})
+	reply, ok := nextOutbound(t, msgBus, 2*time.Second)
+	if !ok || !strings.Contains(reply.Content, "2 approval requests are pending here") {
+		t.Fatalf("expected an ambiguity notice, got %q", reply.Content)
+	}
+	if n := len(pe.PendingApprovals("telegram", "chat1")); n != 2 {
+		t.Fatalf("expected both requests to stay pending, got %d", n)
+	}
+
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: fmt.Sprintf("deny #%d, wrong branch", ids["make release"])})
+	if err := <-errs["make release"]; err == nil || err.Error() != "denied by user: wrong branch" {
+		t.Fatalf("expected the targeted request to be denied, got %v", err)
+	}
+
+	// With one request left, a bare reply is enough.
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve"})
+	if err := <-errs["make deploy"]; err != nil {
+		t.Fatalf("expected the remaining request to be approved, got %v", err)
+	}
+}
+
+func TestApprovalTarget(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
+	pe.trackApproval(Violation{}, "telegram", "chat1", "telegram:chat1", time.Time{}, false)
+	pe.trackApproval(Violation{}, "telegram", "chat1", "telegram:chat1", time.Time{}, true) // queued
+
+	for _, tc := range []struct {
+		content, rest string
+		id            uint64
+	}{
+		{"approve 1", "approve", 1},
+		{"approve #1", "approve", 1},
+		{"approve always 1", "approve always", 1},
+		{"deny 1, wrong host", "deny , wrong host", 1},
+		{"批准 1", "批准", 1},
+		{"approve 2", "approve 2", 0}, // not presented yet
+		{"deny, port 8080 is prod", "deny, port 8080 is prod", 0},
+		{"approve", "approve", 0},
+	} {
+		rest, id := pe.approvalTarget(tc.content, "telegram:chat1")
+		if rest != tc.rest || id != tc.id {
+			t.Errorf("approvalTarget(%q) = %q, %d; want %q, %d", tc.content, rest, id, tc.rest, tc.id)
+		}
+	}
+	if _, id := pe.approvalTarget("approve 1", "telegram:chat2"); id != 0 {
+		t.Error("expected IDs of other chats to be ignored")
+	}
+}
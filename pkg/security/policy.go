@@ -2,9 +2,14 @@ package security
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/clock"
 	"github.com/sipeed/picoclaw/pkg/config"
 )
 
@@ -15,6 +20,10 @@ const (
 	ModeOff     PolicyMode = "off"     // Security check disabled
 	ModeBlock   PolicyMode = "block"   // Reject on violation
 	ModeApprove PolicyMode = "approve" // Pause and request IM approval
+	// ModeFirstSeen requests approval the first time each distinct command
+	// runs in a session and auto-allows identical reruns. exec_guard only;
+	// other categories treat it as approve.
+	ModeFirstSeen PolicyMode = "first_seen"
 )
 
 // IsOff returns true when the mode means "no enforcement".
@@ -30,22 +39,59 @@ type Violation struct {
 	Action   string // the action that was attempted (command, URL, path, etc.)
 	Reason   string // human-readable explanation
 	RuleName string // name/pattern of the matched rule
+	Details  string // optional longer explanation shown in approval prompts
+	// Delegate is the "channel:chatID" that must approve instead of the
+	// requesting chat, overriding approval_delegates.
+	Delegate string
 }
 
 // PolicyEngine centralises security policy decisions.
 type PolicyEngine struct {
 	config *config.SecurityConfig
 	bus    *bus.MessageBus
+
+	mu            sync.Mutex
+	approvalSlots map[string]chan struct{} // per-chat semaphores, keyed by "channel:chatID"
+	guards        []registeredGuard        // custom guards, run by CheckGuards
+	auditSink     AuditSink
+	sessionAllows map[string]map[string]bool  // "session" approvals, kept until restart: session key -> category/tool
+	allowlist     *ApprovalAllowlist          // "always" approvals
+	seenActions   map[string]map[string]bool  // first_seen approvals, kept until restart: session key -> category/tool/action
+	clock         clock.Clock                 // time source for schedules, timeouts and audit entries
+	pending       map[uint64]*PendingApproval // unresolved approval requests by ID
+	nextPendingID uint64
+	auditSubs     map[uint64]auditSubscriber // live audit subscribers by ID
+	nextSubID     uint64
+	auditTails    map[string]func() // running /audit tails by "channel:chatID", value stops it
+	// modeOverrides are temporary /policy modes: session key -> category.
+	modeOverrides    map[string]map[string]modeOverride
+	pendingOverrides map[string]pendingModeOverride // unconfirmed /policy set by session key
 }
 
 // NewPolicyEngine creates a PolicyEngine from configuration and message bus.
 func NewPolicyEngine(cfg *config.SecurityConfig, msgBus *bus.MessageBus) *PolicyEngine {
 	return &PolicyEngine{
-		config: cfg,
-		bus:    msgBus,
+		config:           cfg,
+		bus:              msgBus,
+		approvalSlots:    make(map[string]chan struct{}),
+		sessionAllows:    make(map[string]map[string]bool),
+		allowlist:        NewApprovalAllowlist(""),
+		seenActions:      make(map[string]map[string]bool),
+		clock:            clock.Real(),
+		pending:          make(map[uint64]*PendingApproval),
+		auditSubs:        make(map[uint64]auditSubscriber),
+		auditTails:       make(map[string]func()),
+		modeOverrides:    make(map[string]map[string]modeOverride),
+		pendingOverrides: make(map[string]pendingModeOverride),
 	}
 }
 
+// SetClock replaces the engine's time source, e.g. with a clock.Fake so
+// approval timeouts and schedules can be tested without sleeping.
+func (pe *PolicyEngine) SetClock(c clock.Clock) {
+	pe.clock = c
+}
+
 // GetMode returns the configured PolicyMode for a given security category.
 func (pe *PolicyEngine) GetMode(category string) PolicyMode {
 	var raw string
@@ -61,11 +107,144 @@ func (pe *PolicyEngine) GetMode(category string) PolicyMode {
 	default:
 		return ModeOff
 	}
+	return categoryMode(category, raw)
+}
+
+// GetModeForChannel returns the mode for category on the given channel,
+// preferring a per-channel override and falling back to GetMode.
+func (pe *PolicyEngine) GetModeForChannel(category, channel string) PolicyMode {
+	if mode, ok := pe.ChannelMode(category, channel); ok {
+		return mode
+	}
+	return pe.GetMode(category)
+}
+
+// ChannelMode returns the per-channel override for category, if configured.
+// Tools constructed with an explicit mode use this so an override can tighten
+// or relax enforcement for the channel a call originates from.
+func (pe *PolicyEngine) ChannelMode(category, channel string) (PolicyMode, bool) {
+	if pe == nil || pe.config == nil || channel == "" {
+		return "", false
+	}
+	raw, ok := pe.config.ChannelModes[channel][category]
+	if !ok {
+		return "", false
+	}
+	return categoryMode(category, raw), true
+}
+
+// EffectiveMode returns the mode a violation of category from channel/chatID
+// is evaluated under right now, starting from the mode a tool was configured
+// with: a per-channel override replaces it, then the category's schedule,
+// then a /policy override for the session. Tools must check this, not their
+// configured mode, before skipping a check, or a schedule or override could
+// never switch an "off" category on. A nil engine returns mode unchanged.
+func (pe *PolicyEngine) EffectiveMode(category string, mode PolicyMode, channel, chatID string) PolicyMode {
+	mode, _ = pe.resolveMode(category, mode, channel, chatID)
+	return mode
+}
+
+// resolveMode is EffectiveMode, also naming the layer that decided the mode
+// as reported by Posture.
+func (pe *PolicyEngine) resolveMode(category string, mode PolicyMode, channel, chatID string) (PolicyMode, string) {
+	if pe == nil {
+		return mode, "global"
+	}
+	source := "global"
+	if m, ok := pe.ChannelMode(category, channel); ok {
+		mode, source = m, "channel override"
+	}
+	if m, timed := pe.timedMode(category, mode, channel, chatID); timed != "" {
+		mode, source = m, timed
+	}
+	return mode, source
+}
+
+// timedMode applies the layers that change over time, the schedule and then
+// a session override, to mode. source is empty when neither changed it.
+func (pe *PolicyEngine) timedMode(category string, mode PolicyMode, channel, chatID string) (PolicyMode, string) {
+	source := ""
+	if m := pe.scheduledMode(category, mode); m != mode {
+		mode, source = m, "schedule"
+	}
+	if m, until, ok := pe.sessionMode(category, channel, chatID); ok {
+		mode, source = m, "session override until "+until.Format("15:04")
+	}
+	return mode, source
+}
+
+// StrictSymlinks reports whether path validation must deny paths whose
+// symlinks cannot be resolved. A nil engine is lenient.
+func (pe *PolicyEngine) StrictSymlinks() bool {
+	return pe != nil && pe.config != nil && pe.config.StrictSymlinks
+}
+
+// DeniedPaths returns the .gitignore-style patterns of paths that must not be
+// read or written even inside the workspace. A nil engine denies none.
+func (pe *PolicyEngine) DeniedPaths() []string {
+	if pe == nil || pe.config == nil {
+		return nil
+	}
+	return pe.config.DeniedPaths
+}
+
+// SSRFAllowlist returns the destinations exempt from SSRF protection's
+// private-address checks. A nil engine exempts none.
+func (pe *PolicyEngine) SSRFAllowlist() []string {
+	if pe == nil || pe.config == nil {
+		return nil
+	}
+	return pe.config.SSRFAllowlist
+}
+
+// SSRFAllowedPorts returns the ports SSRF protection allows besides 80 and
+// 443. Empty, and for a nil engine, means any port.
+func (pe *PolicyEngine) SSRFAllowedPorts() []int {
+	if pe == nil || pe.config == nil {
+		return nil
+	}
+	return pe.config.SSRFAllowedPorts
+}
+
+// defaultApprovalTimeout applies when no approval timeout is configured.
+const defaultApprovalTimeout = 300 * time.Second
+
+// ApprovalTimeout returns how long an approval request for category waits for
+// a reply: its approval_timeouts entry, else approval_timeout, else 300s.
+func (pe *PolicyEngine) ApprovalTimeout(category string) time.Duration {
+	if pe == nil || pe.config == nil {
+		return defaultApprovalTimeout
+	}
+	seconds, ok := pe.config.ApprovalTimeouts[category]
+	if !ok || seconds <= 0 {
+		seconds = pe.config.ApprovalTimeout
+	}
+	if seconds <= 0 {
+		return defaultApprovalTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// categoryMode parses a mode configured for category. first_seen is only
+// meaningful for commands, so other categories get approve instead.
+func categoryMode(category, raw string) PolicyMode {
+	mode := parseMode(raw)
+	if mode == ModeFirstSeen && category != "exec_guard" {
+		return ModeApprove
+	}
+	return mode
+}
+
+// parseMode converts a configured mode string to a PolicyMode.
+// Unknown values are treated as off.
+func parseMode(raw string) PolicyMode {
 	switch PolicyMode(raw) {
 	case ModeBlock:
 		return ModeBlock
 	case ModeApprove:
 		return ModeApprove
+	case ModeFirstSeen:
+		return ModeFirstSeen
 	default:
 		return ModeOff
 	}
@@ -73,20 +252,211 @@ func (pe *PolicyEngine) GetMode(category string) PolicyMode {
 
 // Evaluate checks a violation against the given mode and returns nil to allow
 // or an error to deny. In "approve" mode it sends an IM approval request and
-// blocks until the user responds or the timeout expires.
+// blocks until the user responds or the timeout expires. A time-of-day
+// schedule configured for the category adjusts mode first, a /policy override
+// for the session takes precedence over both, then a configured decision
+// webhook may allow, deny or require approval instead.
 func (pe *PolicyEngine) Evaluate(ctx context.Context, mode PolicyMode, v Violation, channel, chatID string) error {
+	mode, _ = pe.timedMode(v.Category, mode, channel, chatID)
+	mode, v, allowed := pe.webhookMode(ctx, mode, v, channel, chatID)
+	if allowed {
+		return nil
+	}
+	if mode != ModeFirstSeen {
+		return pe.decide(ctx, mode, v, channel, chatID)
+	}
+
+	session := sessionKey(channel, chatID)
+	if pe.seenBefore(session, v) {
+		return pe.auditAllow(v, mode, channel, chatID, DecisionSessionAllowed, "approved earlier in the session")
+	}
+	if err := pe.decide(ctx, ModeApprove, v, channel, chatID); err != nil {
+		return err
+	}
+	pe.markSeen(session, v)
+	return nil
+}
+
+// decide applies mode to v once schedules and the webhook have had their say.
+func (pe *PolicyEngine) decide(ctx context.Context, mode PolicyMode, v Violation, channel, chatID string) error {
 	switch {
 	case mode.IsOff():
 		return nil
 	case mode == ModeBlock:
+		pe.audit(v, mode, channel, chatID, DecisionBlocked, v.Reason)
 		return fmt.Errorf("blocked by security policy [%s]: %s", v.Category, v.Reason)
 	case mode == ModeApprove:
+		if pe.IsTrustedChat(channel, chatID) {
+			return pe.auditAllow(v, mode, channel, chatID, DecisionTrusted, "")
+		}
+		if pe.isAllowlisted(sessionKey(channel, chatID), v) {
+			return pe.auditAllow(v, mode, channel, chatID, DecisionAllowlisted, "")
+		}
+		if pe.isSessionAllowed(sessionKey(channel, chatID), v) {
+			return pe.auditAllow(v, mode, channel, chatID, DecisionSessionAllowed, "")
+		}
 		// CLI channel has no async IM listener; fall back to block
 		if channel == "" || channel == "cli" {
+			pe.audit(v, mode, channel, chatID, DecisionBlocked, "approve mode unavailable in CLI")
+			if pe.config != nil && pe.config.VerboseCLIBlocks {
+				return fmt.Errorf("%s", formatCLIBlockMessage(v))
+			}
 			return fmt.Errorf("blocked by security policy [%s]: %s (approve mode unavailable in CLI)", v.Category, v.Reason)
 		}
-		return pe.requestApproval(ctx, v, channel, chatID)
+		if err := pe.requestApproval(ctx, v, channel, chatID); err != nil {
+			decision := DecisionDenied
+			if errors.Is(err, errApprovalTimedOut) {
+				decision = DecisionTimeout
+			}
+			pe.audit(v, mode, channel, chatID, decision, err.Error())
+			return err
+		}
+		return pe.auditAllow(v, mode, channel, chatID, DecisionApproved, "")
 	default:
 		return nil
 	}
 }
+
+// PendingAction is a held tool call that runs once its violation is allowed.
+// The returned text is published to the originating chat; an empty string
+// publishes nothing. Tools adapt their ToolResult into this text.
+type PendingAction func(ctx context.Context) string
+
+// EvaluateAndRun evaluates v like Evaluate and, only when allowed, runs action
+// and publishes its result to channel/chatID. The approval wait and the action
+// are detached from ctx cancellation so a fire-and-forget caller whose request
+// context has ended still gets the action executed once the user approves.
+func (pe *PolicyEngine) EvaluateAndRun(ctx context.Context, mode PolicyMode, v Violation, action PendingAction, channel, chatID string) error {
+	detached := context.WithoutCancel(ctx)
+	if err := pe.Evaluate(detached, mode, v, channel, chatID); err != nil {
+		return err
+	}
+	if action == nil {
+		return nil
+	}
+	if result := action(detached); result != "" && pe.bus != nil && channel != "" {
+		pe.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: result,
+		})
+	}
+	return nil
+}
+
+// sessionKey identifies a conversation the same way channels build
+// InboundMessage.SessionKey.
+func sessionKey(channel, chatID string) string {
+	return channel + ":" + chatID
+}
+
+// sessionRuleKey scopes a "session" approval to one category and tool.
+func sessionRuleKey(v Violation) string {
+	return v.Category + "\x00" + v.Tool
+}
+
+// seenActionKey identifies an action for first_seen mode. Runs of whitespace
+// are collapsed so trivially reformatted commands count as the same one.
+func seenActionKey(v Violation) string {
+	return sessionRuleKey(v) + "\x00" + strings.Join(strings.Fields(v.Action), " ")
+}
+
+// markSeen records that v's action was approved in the session.
+func (pe *PolicyEngine) markSeen(session string, v Violation) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	actions, ok := pe.seenActions[session]
+	if !ok {
+		actions = make(map[string]bool)
+		pe.seenActions[session] = actions
+	}
+	actions[seenActionKey(v)] = true
+}
+
+func (pe *PolicyEngine) seenBefore(session string, v Violation) bool {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	return pe.seenActions[session][seenActionKey(v)]
+}
+
+// allowForSession records a "session" approval so later violations with the
+// same category and tool in the session are auto-allowed.
+func (pe *PolicyEngine) allowForSession(session string, v Violation) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	rules, ok := pe.sessionAllows[session]
+	if !ok {
+		rules = make(map[string]bool)
+		pe.sessionAllows[session] = rules
+	}
+	rules[sessionRuleKey(v)] = true
+}
+
+func (pe *PolicyEngine) isSessionAllowed(session string, v Violation) bool {
+	if !pe.remembersApprovals(v.Category) {
+		return false
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	return pe.sessionAllows[session][sessionRuleKey(v)]
+}
+
+// remembersApprovals reports whether "always" and "session" approvals are
+// honoured for category. Categories not listed in RememberApprovals remember.
+func (pe *PolicyEngine) remembersApprovals(category string) bool {
+	if pe.config == nil {
+		return true
+	}
+	remember, ok := pe.config.RememberApprovals[category]
+	return !ok || remember
+}
+
+// ForgetSession forgets every "session" and first_seen approval granted in
+// the session, and any /policy override. "always" approvals are kept.
+// Nothing in picoclaw ends a session, so these otherwise last until the
+// process exits; embedders with their own notion of a session end call this.
+func (pe *PolicyEngine) ForgetSession(session string) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	delete(pe.sessionAllows, session)
+	delete(pe.seenActions, session)
+	delete(pe.modeOverrides, session)
+	delete(pe.pendingOverrides, session)
+}
+
+// IsTrustedChat reports whether channel/chatID matches an entry in the
+// configured trusted-chat allowlist.
+func (pe *PolicyEngine) IsTrustedChat(channel, chatID string) bool {
+	if pe.config == nil || channel == "" {
+		return false
+	}
+	for _, entry := range pe.config.TrustedChats {
+		if matchChatPattern(entry, channel, chatID) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchChatPattern matches a "channel:chatID" allowlist entry. The chat part
+// may be "*" (any chat on the channel) or end in "*" for a prefix match;
+// anything else must match exactly. The channel part never takes wildcards.
+func matchChatPattern(pattern, channel, chatID string) bool {
+	idx := strings.Index(pattern, ":")
+	if idx <= 0 {
+		return false
+	}
+	if pattern[:idx] != channel {
+		return false
+	}
+	chatPattern := pattern[idx+1:]
+	switch {
+	case chatPattern == "*":
+		return true
+	case strings.HasSuffix(chatPattern, "*"):
+		prefix := strings.TrimSuffix(chatPattern, "*")
+		return prefix != "" && strings.HasPrefix(chatID, prefix)
+	default:
+		return chatPattern != "" && chatPattern == chatID
+	}
+}
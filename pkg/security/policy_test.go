@@ -2,6 +2,7 @@ package security
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -55,6 +56,29 @@ func TestPolicyEngine_GetMode(t *testing.T) {
 	}
 }
 
+func TestPolicyEngine_ApprovalTimeout(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ApprovalTimeout:  120,
+		ApprovalTimeouts: map[string]int{"ssrf": 30, "path_validation": 0},
+	}, nil)
+	tests := []struct {
+		category string
+		want     time.Duration
+	}{
+		{"ssrf", 30 * time.Second},
+		{"exec_guard", 2 * time.Minute},
+		{"path_validation", 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := pe.ApprovalTimeout(tt.category); got != tt.want {
+			t.Errorf("ApprovalTimeout(%q) = %v, want %v", tt.category, got, tt.want)
+		}
+	}
+	if got := NewPolicyEngine(&config.SecurityConfig{}, nil).ApprovalTimeout("ssrf"); got != 300*time.Second {
+		t.Errorf("expected the 300s default, got %v", got)
+	}
+}
+
 func TestPolicyEngine_Evaluate_Off(t *testing.T) {
 	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
 	err := pe.Evaluate(context.Background(), ModeOff, Violation{Reason: "test"}, "telegram", "chat1")
@@ -91,6 +115,26 @@ func TestPolicyEngine_Evaluate_Approve_CLIFallback(t *testing.T) {
 	}
 }
 
+func TestPolicyEngine_Evaluate_Approve_CLIFallbackVerbose(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{VerboseCLIBlocks: true}, bus.NewMessageBus())
+	err := pe.Evaluate(context.Background(), ModeApprove, Violation{
+		Category: "exec_guard",
+		Tool:     "exec",
+		Action:   "sudo reboot",
+		Reason:   "dangerous pattern detected",
+		RuleName: `\bsudo\b`,
+	}, "cli", "direct")
+	if err == nil {
+		t.Fatal("CLI should fall back to block")
+	}
+	msg := err.Error()
+	for _, want := range []string{"cannot be requested in the CLI", "Action: sudo reboot", "Reason: dangerous pattern detected", `set security.exec_guard to "off"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error should contain %q, got: %s", want, msg)
+		}
+	}
+}
+
 func TestPolicyEngine_Evaluate_Approve_Approved(t *testing.T) {
 	msgBus := bus.NewMessageBus()
 	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 5}, msgBus)
@@ -239,3 +283,320 @@ func TestPolicyEngine_Evaluate_Approve_NonMatchingMessages(t *testing.T) {
 		t.Fatal("timed out")
 	}
 }
+
+func TestMatchChatPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		channel string
+		chatID  string
+		want    bool
+	}{
+		{"telegram:*", "telegram", "12345", true},
+		{"telegram:*", "telegram", "-100987", true},
+		{"telegram:*", "feishu", "12345", false},
+		{"feishu:123*", "feishu", "123", true},
+		{"feishu:123*", "feishu", "123456", true},
+		{"feishu:123*", "feishu", "456123", false},
+		{"feishu:123*", "telegram", "123456", false},
+		{"slack:C01", "slack", "C01", true},
+		{"slack:C01", "slack", "C012", false},
+		{"slack:*", "slack", "", true},
+		{"*:123", "telegram", "123", false},
+		{"discord:", "discord", "", false},
+		{"discord:*x", "discord", "x", false},
+		{"nocolon", "nocolon", "", false},
+	}
+	for _, tt := range tests {
+		if got := matchChatPattern(tt.pattern, tt.channel, tt.chatID); got != tt.want {
+			t.Errorf("matchChatPattern(%q, %q, %q) = %v, want %v", tt.pattern, tt.channel, tt.chatID, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyEngine_IsTrustedChat(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		TrustedChats: []string{"telegram:*", "feishu:123*"},
+	}, nil)
+
+	tests := []struct {
+		channel string
+		chatID  string
+		want    bool
+	}{
+		{"telegram", "any-chat", true},
+		{"telegram", "42", true},
+		{"feishu", "123abc", true},
+		{"feishu", "999", false},
+		{"discord", "123", false},
+	}
+	for _, tt := range tests {
+		if got := pe.IsTrustedChat(tt.channel, tt.chatID); got != tt.want {
+			t.Errorf("IsTrustedChat(%q, %q) = %v, want %v", tt.channel, tt.chatID, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyEngine_Evaluate_Approve_TrustedChat(t *testing.T) {
+	// No bus: a trusted chat must be approved without sending a request.
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ApprovalTimeout: 1,
+		TrustedChats:    []string{"telegram:*"},
+	}, nil)
+
+	err := pe.Evaluate(context.Background(), ModeApprove, Violation{
+		Category: "exec_guard",
+		Reason:   "test",
+	}, "telegram", "chat1")
+	if err != nil {
+		t.Errorf("trusted chat should be auto-approved, got: %v", err)
+	}
+
+	err = pe.Evaluate(context.Background(), ModeBlock, Violation{
+		Category: "exec_guard",
+		Reason:   "test",
+	}, "telegram", "chat1")
+	if err == nil {
+		t.Error("block mode should still reject trusted chats")
+	}
+}
+
+func TestPolicyEngine_EvaluateAndRun_Approved(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 5}, msgBus)
+
+	ran := make(chan struct{}, 1)
+	action := func(ctx context.Context) string {
+		ran <- struct{}{}
+		return "action result"
+	}
+
+	// The request context is already gone; the held action must still run.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.EvaluateAndRun(ctx, ModeApprove, Violation{
+			Category: "exec_guard",
+			Reason:   "test",
+		}, action, "telegram", "chat1")
+	}()
+
+	subCtx, subCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer subCancel()
+	if _, ok := msgBus.SubscribeOutbound(subCtx); !ok {
+		t.Fatal("expected outbound approval message")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve"})
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("expected approval, got: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("approval timed out")
+	}
+
+	select {
+	case <-ran:
+	default:
+		t.Fatal("action should run on approve")
+	}
+
+	outMsg, ok := msgBus.SubscribeOutbound(subCtx)
+	if !ok || outMsg.Content != "action result" {
+		t.Errorf("expected action result to be published, got %q (ok=%v)", outMsg.Content, ok)
+	}
+}
+
+func TestPolicyEngine_EvaluateAndRun_Denied(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 5}, msgBus)
+
+	ran := false
+	action := func(ctx context.Context) string {
+		ran = true
+		return "should not be published"
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pe.EvaluateAndRun(context.Background(), ModeApprove, Violation{
+			Category: "exec_guard",
+			Reason:   "test",
+		}, action, "telegram", "chat2")
+	}()
+
+	subCtx, subCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer subCancel()
+	msgBus.SubscribeOutbound(subCtx)
+
+	time.Sleep(50 * time.Millisecond)
+	msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat2", Content: "deny"})
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatal("expected denial error")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for denial")
+	}
+	if ran {
+		t.Error("action must not run on deny")
+	}
+}
+
+func TestPolicyEngine_EvaluateAndRun_Block(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{}, nil)
+	ran := false
+	err := pe.EvaluateAndRun(context.Background(), ModeBlock, Violation{Reason: "test"}, func(ctx context.Context) string {
+		ran = true
+		return ""
+	}, "telegram", "chat3")
+	if err == nil || ran {
+		t.Errorf("block mode should reject without running the action (err=%v, ran=%v)", err, ran)
+	}
+}
+
+func TestPolicyEngine_GetModeForChannel(t *testing.T) {
+	cfg := &config.SecurityConfig{
+		ExecGuard:      "approve",
+		SSRFProtection: "block",
+		ChannelModes: map[string]map[string]string{
+			"admin":    {"exec_guard": "off"},
+			"telegram": {"exec_guard": "block", "ssrf": "approve"},
+		},
+	}
+	pe := NewPolicyEngine(cfg, nil)
+
+	tests := []struct {
+		category string
+		channel  string
+		want     PolicyMode
+	}{
+		{"exec_guard", "admin", ModeOff},
+		{"exec_guard", "telegram", ModeBlock},
+		{"exec_guard", "discord", ModeApprove},
+		{"exec_guard", "", ModeApprove},
+		{"ssrf", "admin", ModeBlock},
+		{"ssrf", "telegram", ModeApprove},
+	}
+	for _, tt := range tests {
+		if got := pe.GetModeForChannel(tt.category, tt.channel); got != tt.want {
+			t.Errorf("GetModeForChannel(%q, %q) = %v, want %v", tt.category, tt.channel, got, tt.want)
+		}
+	}
+
+	if _, ok := pe.ChannelMode("exec_guard", "discord"); ok {
+		t.Error("ChannelMode should report no override for an unlisted channel")
+	}
+}
+
+// failingSink rejects every entry, like a sink whose disk is full.
+type failingSink struct{}
+
+func (failingSink) Record(AuditEntry) error {
+	return errors.New("no space left on device")
+}
+
+func TestPolicyEngine_AuditSinkFailure(t *testing.T) {
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "not in allowlist"}
+
+	t.Run("fail open", func(t *testing.T) {
+		pe := NewPolicyEngine(&config.SecurityConfig{TrustedChats: []string{"telegram:42"}}, nil)
+		pe.SetAuditSink(failingSink{})
+		if err := pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "42"); err != nil {
+			t.Errorf("expected the action to proceed, got: %v", err)
+		}
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		pe := NewPolicyEngine(&config.SecurityConfig{TrustedChats: []string{"telegram:42"}, AuditFailClosed: true}, nil)
+		pe.SetAuditSink(failingSink{})
+		err := pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "42")
+		if err == nil || !strings.Contains(err.Error(), "audit log unavailable: no space left on device") {
+			t.Errorf("expected an unauditable action to be blocked, got: %v", err)
+		}
+
+		// Blocked actions stay blocked either way.
+		if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "42"); err == nil {
+			t.Error("expected block mode to block")
+		}
+	})
+}
+
+func TestPolicyEngine_Evaluate_FirstSeen(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	pe := NewPolicyEngine(&config.SecurityConfig{ExecGuard: "first_seen", SSRFProtection: "first_seen", ApprovalTimeout: 5}, msgBus)
+	if pe.GetMode("exec_guard") != ModeFirstSeen || pe.GetMode("ssrf") != ModeApprove {
+		t.Fatalf("first_seen should apply to exec_guard only, got %v and %v", pe.GetMode("exec_guard"), pe.GetMode("ssrf"))
+	}
+
+	run := func(command, chatID string) error {
+		return pe.Evaluate(context.Background(), ModeFirstSeen, Violation{
+			Category: "exec_guard",
+			Tool:     "exec",
+			Action:   command,
+			Reason:   "first run of this command in this session",
+		}, "telegram", chatID)
+	}
+	// expectPrompt runs command and answers its approval prompt with reply.
+	expectPrompt := func(command, chatID, reply string) error {
+		errCh := make(chan error, 1)
+		go func() { errCh <- run(command, chatID) }()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if _, ok := msgBus.SubscribeOutbound(ctx); !ok {
+			t.Fatalf("expected an approval prompt for %q", command)
+		}
+		time.Sleep(50 * time.Millisecond)
+		msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: chatID, Content: reply})
+		select {
+		case err := <-errCh:
+			return err
+		case <-time.After(3 * time.Second):
+			t.Fatalf("approval for %q timed out", command)
+			return nil
+		}
+	}
+	// expectNoPrompt runs command and fails if it asks for approval.
+	expectNoPrompt := func(command, chatID string) error {
+		err := run(command, chatID)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if msg, ok := msgBus.SubscribeOutbound(ctx); ok {
+			t.Errorf("expected %q to run without a prompt, got: %s", command, msg.Content)
+		}
+		return err
+	}
+
+	if err := expectPrompt("make test", "chat1", "approve"); err != nil {
+		t.Fatalf("expected the first run to be approved, got: %v", err)
+	}
+	if err := expectNoPrompt("make test", "chat1"); err != nil {
+		t.Errorf("expected a rerun to be auto-allowed, got: %v", err)
+	}
+	if err := expectNoPrompt("make   test ", "chat1"); err != nil {
+		t.Errorf("expected a rerun differing only in whitespace to be auto-allowed, got: %v", err)
+	}
+
+	// A new command, or the same command in another session, asks again.
+	if err := expectPrompt("make deploy", "chat1", "deny"); err == nil {
+		t.Error("expected the denied command to fail")
+	}
+	if err := expectPrompt("make deploy", "chat1", "approve"); err != nil {
+		t.Errorf("expected a denied command to prompt again, got: %v", err)
+	}
+	if err := expectPrompt("make test", "chat2", "approve"); err != nil {
+		t.Errorf("expected another session to prompt, got: %v", err)
+	}
+
+	pe.ForgetSession("telegram:chat1")
+	if err := expectPrompt("make test", "chat1", "approve"); err != nil {
+		t.Errorf("expected a prompt after the session was forgotten, got: %v", err)
+	}
+}
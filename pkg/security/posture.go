@@ -0,0 +1,126 @@
+package security
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Categories lists the built-in policy categories in display order.
+var Categories = []string{"exec_guard", "ssrf", "path_validation", "skill_validation"}
+
+// CategoryPosture is the effective policy for one category in a chat.
+type CategoryPosture struct {
+	Category           string
+	Mode               PolicyMode // mode a violation would be evaluated under right now
+	Source             string     // "global", "channel override", "schedule" or "session override until HH:MM"
+	RemembersApprovals bool
+	SessionAllowed     []string // tools auto-allowed by a "session" approval in this chat
+	AlwaysAllowed      int      // actions approved permanently with "always" in this chat
+}
+
+// ListCount is the size of a configured allowlist or denylist.
+type ListCount struct {
+	Name  string
+	Count int
+}
+
+// Posture is a read-only snapshot of what the policy engine allows for a chat.
+type Posture struct {
+	Channel      string
+	ChatID       string
+	Categories   []CategoryPosture
+	Lists        []ListCount
+	ChatTrusted  bool
+	CustomGuards int
+}
+
+// Posture reports the effective mode of every category for channel/chatID,
+// together with trusted-chat and remembered-approval state. It does not
+// change any state and is safe to call on a nil engine.
+func (pe *PolicyEngine) Posture(channel, chatID string) Posture {
+	p := Posture{Channel: channel, ChatID: chatID}
+	if pe == nil {
+		for _, c := range Categories {
+			p.Categories = append(p.Categories, CategoryPosture{Category: c, Mode: ModeOff, Source: "global"})
+		}
+		return p
+	}
+
+	pe.mu.Lock()
+	allowed := make(map[string][]string)
+	for rule := range pe.sessionAllows[sessionKey(channel, chatID)] {
+		category, tool, _ := strings.Cut(rule, "\x00")
+		allowed[category] = append(allowed[category], tool)
+	}
+	p.CustomGuards = len(pe.guards)
+	pe.mu.Unlock()
+
+	always := make(map[string]int)
+	entries, _ := pe.approvalAllowlist().Entries(sessionKey(channel, chatID))
+	for _, e := range entries {
+		always[e.Category]++
+	}
+
+	for _, c := range Categories {
+		mode, source := pe.resolveMode(c, pe.GetMode(c), channel, chatID)
+		tools := allowed[c]
+		sort.Strings(tools)
+		p.Categories = append(p.Categories, CategoryPosture{
+			Category:           c,
+			Mode:               mode,
+			Source:             source,
+			RemembersApprovals: pe.remembersApprovals(c),
+			SessionAllowed:     tools,
+			AlwaysAllowed:      always[c],
+		})
+	}
+
+	if pe.config != nil {
+		p.Lists = append(p.Lists, ListCount{Name: "trusted chats", Count: len(pe.config.TrustedChats)})
+	}
+	p.ChatTrusted = pe.IsTrustedChat(channel, chatID)
+	return p
+}
+
+// Format renders the posture as a short plain-text report for chat.
+func (p Posture) Format() string {
+	var b strings.Builder
+	b.WriteString("Security posture")
+	if p.Channel != "" {
+		fmt.Fprintf(&b, " for %s:%s", p.Channel, p.ChatID)
+	}
+	b.WriteString("\n")
+
+	for _, c := range p.Categories {
+		mode := c.Mode
+		if mode.IsOff() {
+			mode = ModeOff
+		}
+		fmt.Fprintf(&b, "- %s: %s", c.Category, mode)
+		if c.Source != "global" {
+			fmt.Fprintf(&b, " (%s)", c.Source)
+		}
+		if mode == ModeApprove && !c.RemembersApprovals {
+			b.WriteString(", always asks")
+		}
+		if len(c.SessionAllowed) > 0 {
+			fmt.Fprintf(&b, ", allowed this session: %s", strings.Join(c.SessionAllowed, ", "))
+		}
+		if c.AlwaysAllowed > 0 {
+			fmt.Fprintf(&b, ", always allowed: %d action(s)", c.AlwaysAllowed)
+		}
+		b.WriteString("\n")
+	}
+
+	for _, l := range p.Lists {
+		fmt.Fprintf(&b, "- %s: %d\n", l.Name, l.Count)
+	}
+	if p.CustomGuards > 0 {
+		fmt.Fprintf(&b, "- custom guards: %d\n", p.CustomGuards)
+	}
+	if p.ChatTrusted {
+		b.WriteString("This chat is trusted: approve-mode actions run without asking.\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
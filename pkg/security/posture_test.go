@@ -0,0 +1,107 @@
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPosture_ReflectsConfiguredModes(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ExecGuard:      "approve",
+		SSRFProtection: "block",
+		ChannelModes: map[string]map[string]string{
+			"telegram": {"path_validation": "block"},
+		},
+		Schedules: map[string]config.ModeSchedule{
+			"skill_validation": {Window: "09:00-18:00", Inside: "approve", Timezone: "UTC"},
+		},
+		TrustedChats:      []string{"slack:*", "discord:1"},
+		RememberApprovals: map[string]bool{"exec_guard": false},
+	}, nil)
+	pe.SetClock(fixedClock(10, 0))
+
+	p := pe.Posture("telegram", "42")
+	want := map[string]struct {
+		mode   PolicyMode
+		source string
+	}{
+		"exec_guard":       {ModeApprove, "global"},
+		"ssrf":             {ModeBlock, "global"},
+		"path_validation":  {ModeBlock, "channel override"},
+		"skill_validation": {ModeApprove, "schedule"},
+	}
+	for _, c := range p.Categories {
+		w := want[c.Category]
+		if c.Mode != w.mode || c.Source != w.source {
+			t.Errorf("%s: got %s (%s), want %s (%s)", c.Category, c.Mode, c.Source, w.mode, w.source)
+		}
+	}
+	if p.ChatTrusted {
+		t.Error("telegram:42 should not be trusted")
+	}
+
+	out := p.Format()
+	for _, s := range []string{
+		"Security posture for telegram:42",
+		"- exec_guard: approve, always asks",
+		"- path_validation: block (channel override)",
+		"- skill_validation: approve (schedule)",
+		"- trusted chats: 2",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("report missing %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestPosture_ShowsSessionApprovals(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{ExecGuard: "approve", TrustedChats: []string{"slack:*"}}, nil)
+	pe.allowForSession(sessionKey("telegram", "42"), Violation{Category: "exec_guard", Tool: "exec"})
+
+	out := pe.Posture("telegram", "42").Format()
+	if !strings.Contains(out, "- exec_guard: approve, allowed this session: exec") {
+		t.Errorf("expected remembered approval in report:\n%s", out)
+	}
+	if other := pe.Posture("telegram", "7").Format(); strings.Contains(other, "allowed this session") {
+		t.Errorf("approval leaked into another chat:\n%s", other)
+	}
+
+	pe.ForgetSession(sessionKey("telegram", "42"))
+	if out := pe.Posture("telegram", "42").Format(); strings.Contains(out, "allowed this session") {
+		t.Errorf("expected approvals cleared after ForgetSession:\n%s", out)
+	}
+	if out := pe.Posture("slack", "C1").Format(); !strings.Contains(out, "This chat is trusted") {
+		t.Errorf("expected trusted chat note:\n%s", out)
+	}
+}
+
+func TestPosture_NilEngine(t *testing.T) {
+	var pe *PolicyEngine
+	out := pe.Posture("", "").Format()
+	if !strings.Contains(out, "- exec_guard: off") {
+		t.Errorf("expected all categories off:\n%s", out)
+	}
+}
+
+func TestPosture_OverrideOfOffCategoryMatchesEnforcement(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{ExecGuard: "off"}, nil)
+	pe.SetClock(fixedClock(10, 0))
+	if err := pe.OverrideMode("telegram", "42", "exec_guard", ModeBlock, time.Hour); err != nil {
+		t.Fatalf("OverrideMode failed: %v", err)
+	}
+
+	for _, c := range pe.Posture("telegram", "42").Categories {
+		if c.Category != "exec_guard" {
+			continue
+		}
+		if c.Mode != ModeBlock || c.Source != "session override until 11:00" {
+			t.Errorf("got %s (%s), want block (session override until 11:00)", c.Mode, c.Source)
+		}
+		if enforced := pe.EffectiveMode("exec_guard", ModeOff, "telegram", "42"); enforced != c.Mode {
+			t.Errorf("posture reports %s but tools enforce %s", c.Mode, enforced)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package security
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// scheduledMode applies the time-of-day schedule configured for category, if
+// any, to mode. A schedule that cannot be parsed leaves mode unchanged. Tools
+// reach it through EffectiveMode, so a schedule can also switch on a category
+// that is configured off.
+func (pe *PolicyEngine) scheduledMode(category string, mode PolicyMode) PolicyMode {
+	if pe.config == nil {
+		return mode
+	}
+	sched, ok := pe.config.Schedules[category]
+	if !ok {
+		return mode
+	}
+
+	inside, err := inWindow(sched, pe.clock.Now())
+	if err != nil {
+		logger.WarnCF("security", "Ignoring invalid policy schedule",
+			map[string]interface{}{
+				"category": category,
+				"error":    err.Error(),
+			})
+		return mode
+	}
+
+	raw := sched.Outside
+	if inside {
+		raw = sched.Inside
+	}
+	if raw == "" {
+		return mode
+	}
+	return categoryMode(category, raw)
+}
+
+// inWindow reports whether now, in the schedule's timezone, falls within its
+// window. The start is inclusive and the end exclusive.
+func inWindow(sched config.ModeSchedule, now time.Time) (bool, error) {
+	startStr, endStr, ok := strings.Cut(sched.Window, "-")
+	if !ok {
+		return false, fmt.Errorf("window %q must be HH:MM-HH:MM", sched.Window)
+	}
+	start, err := parseClock(startStr)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return false, err
+	}
+
+	if sched.Timezone != "" {
+		loc, err := time.LoadLocation(sched.Timezone)
+		if err != nil {
+			return false, fmt.Errorf("unknown timezone %q", sched.Timezone)
+		}
+		now = now.In(loc)
+	}
+	minute := now.Hour()*60 + now.Minute()
+
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	// The window wraps past midnight, e.g. 22:00-06:00.
+	return minute >= start || minute < end, nil
+}
+
+// parseClock converts "HH:MM" to minutes since midnight.
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid hour in %q", s)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid minute in %q", s)
+	}
+	return h*60 + m, nil
+}
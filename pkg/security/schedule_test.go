@@ -0,0 +1,117 @@
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/clock"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func fixedClock(hour, minute int) clock.Clock {
+	return clock.NewFake(time.Date(2025, 3, 10, hour, minute, 0, 0, time.UTC))
+}
+
+func TestScheduledMode_InsideAndOutsideWindow(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		Schedules: map[string]config.ModeSchedule{
+			"exec_guard": {Window: "09:00-18:00", Inside: "approve", Outside: "block", Timezone: "UTC"},
+		},
+	}, nil)
+
+	tests := []struct {
+		hour, minute int
+		want         PolicyMode
+	}{
+		{9, 0, ModeApprove},
+		{12, 30, ModeApprove},
+		{17, 59, ModeApprove},
+		{18, 0, ModeBlock},
+		{3, 0, ModeBlock},
+	}
+	for _, tc := range tests {
+		pe.SetClock(fixedClock(tc.hour, tc.minute))
+		if got := pe.scheduledMode("exec_guard", ModeBlock); got != tc.want {
+			t.Errorf("%02d:%02d: got %s, want %s", tc.hour, tc.minute, got, tc.want)
+		}
+	}
+
+	// Categories without a schedule keep their mode.
+	if got := pe.scheduledMode("ssrf", ModeApprove); got != ModeApprove {
+		t.Errorf("unscheduled category changed mode to %s", got)
+	}
+}
+
+func TestScheduledMode_WrapsMidnightAndTimezone(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		Schedules: map[string]config.ModeSchedule{
+			"ssrf": {Window: "22:00-06:00", Inside: "block", Timezone: "Asia/Shanghai"},
+		},
+	}, nil)
+
+	// 15:00 UTC is 23:00 in Shanghai: inside the overnight window.
+	pe.SetClock(fixedClock(15, 0))
+	if got := pe.scheduledMode("ssrf", ModeApprove); got != ModeBlock {
+		t.Errorf("expected block inside overnight window, got %s", got)
+	}
+	// 04:00 UTC is 12:00 in Shanghai: outside, and Outside is unset.
+	pe.SetClock(fixedClock(4, 0))
+	if got := pe.scheduledMode("ssrf", ModeApprove); got != ModeApprove {
+		t.Errorf("expected configured mode outside window, got %s", got)
+	}
+}
+
+func TestEvaluate_UsesSchedule(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		Schedules: map[string]config.ModeSchedule{
+			"exec_guard": {Window: "09:00-18:00", Inside: "off", Outside: "block", Timezone: "UTC"},
+		},
+	}, nil)
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "make deploy", Reason: "x"}
+
+	pe.SetClock(fixedClock(10, 0))
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "chat1"); err != nil {
+		t.Errorf("expected schedule to allow during the window, got: %v", err)
+	}
+	pe.SetClock(fixedClock(20, 0))
+	err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "chat1")
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected block outside the window, got: %v", err)
+	}
+}
+
+func TestEffectiveMode_ScheduleTightensOffMode(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		ExecGuard: "off",
+		Schedules: map[string]config.ModeSchedule{
+			"exec_guard": {Window: "22:00-06:00", Inside: "approve", Timezone: "UTC"},
+		},
+	}, nil)
+
+	pe.SetClock(fixedClock(23, 0))
+	if got := pe.EffectiveMode("exec_guard", ModeOff, "telegram", "chat1"); got != ModeApprove {
+		t.Errorf("expected the schedule to switch off to approve, got %s", got)
+	}
+	pe.SetClock(fixedClock(12, 0))
+	if got := pe.EffectiveMode("exec_guard", ModeOff, "telegram", "chat1"); !got.IsOff() {
+		t.Errorf("expected the configured off mode outside the window, got %s", got)
+	}
+
+	var nilEngine *PolicyEngine
+	if got := nilEngine.EffectiveMode("exec_guard", ModeBlock, "", ""); got != ModeBlock {
+		t.Errorf("nil engine changed the mode to %s", got)
+	}
+}
+
+func TestScheduledMode_InvalidScheduleIgnored(t *testing.T) {
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		Schedules: map[string]config.ModeSchedule{
+			"exec_guard": {Window: "9am-6pm", Inside: "off"},
+		},
+	}, nil)
+	if got := pe.scheduledMode("exec_guard", ModeBlock); got != ModeBlock {
+		t.Errorf("invalid schedule should leave mode unchanged, got %s", got)
+	}
+}
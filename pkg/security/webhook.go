@@ -0,0 +1,123 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// webhookRequest is the JSON body POSTed to the decision webhook.
+type webhookRequest struct {
+	Category string `json:"category"`
+	Tool     string `json:"tool,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	RuleName string `json:"rule,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+	Mode     string `json:"mode"` // the locally configured mode
+}
+
+// webhookResponse is the decision webhook's answer.
+type webhookResponse struct {
+	Decision string `json:"decision"` // "allow", "deny" or "approve"
+	Reason   string `json:"reason,omitempty"`
+}
+
+// webhookMode consults the decision webhook, when configured, and returns the
+// mode to enforce and the violation to enforce it with. allowed is true when
+// the webhook allowed the action outright. Off modes are never sent.
+func (pe *PolicyEngine) webhookMode(ctx context.Context, mode PolicyMode, v Violation, channel, chatID string) (PolicyMode, Violation, bool) {
+	if pe.config == nil || pe.config.DecisionWebhook.URL == "" || mode.IsOff() {
+		return mode, v, false
+	}
+
+	resp, err := pe.askWebhook(ctx, mode, v, channel, chatID)
+	if err != nil {
+		logger.WarnCF("security", "Decision webhook failed",
+			map[string]interface{}{
+				"category":    v.Category,
+				"error":       err.Error(),
+				"fail_closed": pe.config.DecisionWebhook.FailClosed,
+			})
+		if pe.config.DecisionWebhook.FailClosed {
+			v.Reason = fmt.Sprintf("%s (decision webhook unavailable: %v)", v.Reason, err)
+			return ModeBlock, v, false
+		}
+		return mode, v, false
+	}
+
+	switch resp.Decision {
+	case "allow":
+		if err := pe.auditAllow(v, mode, channel, chatID, DecisionWebhookAllowed, resp.Reason); err != nil {
+			v.Reason = fmt.Sprintf("%s (audit log unavailable)", v.Reason)
+			return ModeBlock, v, false
+		}
+		return mode, v, true
+	case "deny":
+		if resp.Reason != "" {
+			v.Reason = resp.Reason
+		}
+		return ModeBlock, v, false
+	default: // "approve", checked by askWebhook
+		return ModeApprove, v, false
+	}
+}
+
+// askWebhook POSTs the violation to the decision webhook and validates its
+// answer.
+func (pe *PolicyEngine) askWebhook(ctx context.Context, mode PolicyMode, v Violation, channel, chatID string) (webhookResponse, error) {
+	cfg := pe.config.DecisionWebhook
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	body, err := json.Marshal(webhookRequest{
+		Category: v.Category,
+		Tool:     v.Tool,
+		Action:   v.Action,
+		Reason:   v.Reason,
+		RuleName: v.RuleName,
+		Channel:  channel,
+		ChatID:   chatID,
+		Mode:     string(mode),
+	})
+	if err != nil {
+		return webhookResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return webhookResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return webhookResponse{}, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return webhookResponse{}, fmt.Errorf("unexpected status %s", httpResp.Status)
+	}
+
+	var resp webhookResponse
+	if err := json.NewDecoder(io.LimitReader(httpResp.Body, 64*1024)).Decode(&resp); err != nil {
+		return webhookResponse{}, fmt.Errorf("invalid response: %w", err)
+	}
+	switch resp.Decision {
+	case "allow", "deny", "approve":
+		return resp, nil
+	default:
+		return webhookResponse{}, fmt.Errorf("unknown decision %q", resp.Decision)
+	}
+}
@@ -0,0 +1,123 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// decisionServer answers every request with decision and records the last
+// request body it received.
+func decisionServer(t *testing.T, decision string, last *webhookRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if last != nil {
+			json.NewDecoder(r.Body).Decode(last)
+		}
+		json.NewEncoder(w).Encode(webhookResponse{Decision: decision, Reason: "policy says " + decision})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDecisionWebhook_Decisions(t *testing.T) {
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "rm -rf /tmp/x", Reason: "dangerous"}
+
+	t.Run("allow", func(t *testing.T) {
+		var got webhookRequest
+		srv := decisionServer(t, "allow", &got)
+		pe := NewPolicyEngine(&config.SecurityConfig{DecisionWebhook: config.DecisionWebhookConfig{URL: srv.URL}}, nil)
+		rec := &recordingSink{}
+		pe.SetAuditSink(rec)
+
+		if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "42"); err != nil {
+			t.Fatalf("expected webhook allow to override block, got: %v", err)
+		}
+		if got.Action != v.Action || got.Mode != "block" || got.ChatID != "42" {
+			t.Errorf("webhook received unexpected request: %+v", got)
+		}
+		if got := rec.decisions(); len(got) != 1 || got[0] != DecisionWebhookAllowed {
+			t.Errorf("expected one webhook_allowed audit entry, got %v", got)
+		}
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		srv := decisionServer(t, "deny", nil)
+		pe := NewPolicyEngine(&config.SecurityConfig{DecisionWebhook: config.DecisionWebhookConfig{URL: srv.URL}}, nil)
+
+		err := pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "42")
+		if err == nil || !strings.Contains(err.Error(), "policy says deny") {
+			t.Fatalf("expected webhook denial, got: %v", err)
+		}
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		srv := decisionServer(t, "approve", nil)
+		pe := NewPolicyEngine(&config.SecurityConfig{
+			DecisionWebhook: config.DecisionWebhookConfig{URL: srv.URL},
+			TrustedChats:    []string{"telegram:42"},
+		}, nil)
+		rec := &recordingSink{}
+		pe.SetAuditSink(rec)
+
+		// Block locally, but the webhook asks for approval, which a trusted
+		// chat grants without prompting.
+		if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "42"); err != nil {
+			t.Fatalf("expected approval path, got: %v", err)
+		}
+		if got := rec.decisions(); len(got) != 1 || got[0] != DecisionTrusted {
+			t.Errorf("expected trusted audit entry, got %v", got)
+		}
+	})
+
+	t.Run("off modes skip the webhook", func(t *testing.T) {
+		called := false
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer srv.Close()
+		pe := NewPolicyEngine(&config.SecurityConfig{DecisionWebhook: config.DecisionWebhookConfig{URL: srv.URL}}, nil)
+		if err := pe.Evaluate(context.Background(), ModeOff, v, "telegram", "42"); err != nil || called {
+			t.Errorf("expected off mode to allow without calling the webhook, err=%v called=%v", err, called)
+		}
+	})
+}
+
+func TestDecisionWebhook_Fallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	v := Violation{Category: "exec_guard", Tool: "exec", Action: "ls", Reason: "dangerous"}
+
+	// Fail open: the local mode decides, here approve in a trusted chat.
+	pe := NewPolicyEngine(&config.SecurityConfig{
+		DecisionWebhook: config.DecisionWebhookConfig{URL: srv.URL},
+		TrustedChats:    []string{"telegram:42"},
+	}, nil)
+	if err := pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "42"); err != nil {
+		t.Errorf("expected local mode to apply when the webhook fails, got: %v", err)
+	}
+
+	// Fail closed: the same call is denied.
+	pe = NewPolicyEngine(&config.SecurityConfig{
+		DecisionWebhook: config.DecisionWebhookConfig{URL: srv.URL, FailClosed: true},
+		TrustedChats:    []string{"telegram:42"},
+	}, nil)
+	err := pe.Evaluate(context.Background(), ModeApprove, v, "telegram", "42")
+	if err == nil || !strings.Contains(err.Error(), "decision webhook unavailable") {
+		t.Errorf("expected fail-closed denial, got: %v", err)
+	}
+
+	// An unknown decision counts as a failure too.
+	bad := decisionServer(t, "maybe", nil)
+	pe = NewPolicyEngine(&config.SecurityConfig{DecisionWebhook: config.DecisionWebhookConfig{URL: bad.URL, FailClosed: true}}, nil)
+	if err := pe.Evaluate(context.Background(), ModeBlock, v, "telegram", "42"); err == nil || !strings.Contains(err.Error(), `unknown decision "maybe"`) {
+		t.Errorf("expected invalid decision to fail closed, got: %v", err)
+	}
+}
@@ -55,11 +55,12 @@ func NewSkillInstallerWithPolicy(workspace string, pe *security.PolicyEngine, mo
 
 func (si *SkillInstaller) InstallFromGitHub(ctx context.Context, repo string) error {
 	// Validate repo format to prevent URL injection (mode-aware)
-	if !si.skillMode.IsOff() {
+	mode := si.policyEngine.EffectiveMode("skill_validation", si.skillMode, "", "")
+	if !mode.IsOff() {
 		if !repoNamePattern.MatchString(repo) {
 			reason := fmt.Sprintf("invalid repository format: must be 'owner/repo' (got %q)", repo)
 			if si.policyEngine != nil {
-				err := si.policyEngine.Evaluate(ctx, si.skillMode, security.Violation{
+				err := si.policyEngine.Evaluate(ctx, mode, security.Violation{
 					Category: "skill_validation",
 					Tool:     "skill_install",
 					Action:   repo,
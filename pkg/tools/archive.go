@@ -0,0 +1,339 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+const (
+	defaultArchiveEntries = 200
+	maxArchiveEntries     = 2000
+	// maxArchiveScan caps how many headers are read, so a crafted archive
+	// with millions of tiny members cannot keep the tool busy.
+	maxArchiveScan = 100000
+
+	// DefaultMaxArchiveBytes is the uncompressed size above which an archive
+	// is reported as a possible zip bomb.
+	DefaultMaxArchiveBytes = 1 << 30
+	// DefaultMaxArchiveRatio is the compression ratio above which a zip
+	// member, or a whole tar.gz, is reported as a possible zip bomb.
+	DefaultMaxArchiveRatio = 100
+	// minRatioCheckSize exempts small members from the ratio check; a few
+	// KiB of repeated text compresses far better than 100:1 harmlessly.
+	minRatioCheckSize = 1 << 20
+)
+
+// archiveLimits are the thresholds past which list_archive warns that
+// extracting an archive could fill the disk. Zero disables a check.
+type archiveLimits struct {
+	maxBytes int64 // cumulative uncompressed size
+	maxRatio int64 // uncompressed:compressed
+}
+
+// archiveEntry is one member of an archive.
+type archiveEntry struct {
+	name       string
+	size       int64
+	compressed int64 // compressed size of a zip member; 0 when unknown
+	mode       fs.FileMode
+	link       string // symlink or hard link target, if any
+	unsafe     string // why extracting this entry would be unsafe, if it would
+}
+
+// ListArchiveTool lists the members of a zip or tar(.gz) archive without
+// extracting anything, flagging entries that would escape the extraction
+// directory (zip-slip) and archives that would expand far beyond their size
+// (zip bombs).
+type ListArchiveTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	limits       archiveLimits
+	channel      string
+	chatID       string
+}
+
+func NewListArchiveTool(workspace string, restrict bool) *ListArchiveTool {
+	return NewListArchiveToolWithPolicy(workspace, restrict, PathPolicyOpts{})
+}
+
+func NewListArchiveToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *ListArchiveTool {
+	limits := archiveLimits{maxBytes: opts.MaxArchiveBytes, maxRatio: int64(opts.MaxArchiveRatio)}
+	if limits.maxBytes == 0 {
+		limits.maxBytes = DefaultMaxArchiveBytes
+	}
+	if limits.maxRatio == 0 {
+		limits.maxRatio = DefaultMaxArchiveRatio
+	}
+	return &ListArchiveTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, limits: limits}
+}
+
+func (t *ListArchiveTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *ListArchiveTool) Name() string {
+	return "list_archive"
+}
+
+func (t *ListArchiveTool) Description() string {
+	return "List the contents of a .zip, .tar or .tar.gz archive (names, sizes, modes) without extracting it. Flags entries with '..' or absolute paths that would escape the extraction directory, archives that would expand to an extreme size or compression ratio (zip bombs), and nested archives. Use before extracting an archive."
+}
+
+func (t *ListArchiveTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the archive",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of entries to list (default %d, max %d); unsafe entries are always reported", defaultArchiveEntries, maxArchiveEntries),
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ListArchiveTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	p, ok := args["path"].(string)
+	if !ok || p == "" {
+		return ErrorResult("path is required")
+	}
+
+	limit := defaultArchiveEntries
+	if l, ok := args["limit"].(float64); ok {
+		if l < 1 {
+			return ErrorResult("limit must be at least 1")
+		}
+		limit = min(int(l), maxArchiveEntries)
+	}
+
+	resolvedPath, err := validatePathWithMode(p, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	f, err := os.Open(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open archive: %v", err))
+	}
+	defer f.Close()
+
+	format, entries, truncated, err := readArchive(f, p)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read archive: %v", err))
+	}
+	var archiveSize int64
+	if info, err := f.Stat(); err == nil {
+		archiveSize = info.Size()
+	}
+	listing := formatArchiveListing(p, format, entries, truncated, limit)
+	return NewToolResult(listing + formatBombWarnings(format, entries, archiveSize, t.limits))
+}
+
+// readArchive detects the archive format from its leading bytes (falling back
+// to the file extension for plain tar) and reads every member header.
+func readArchive(f *os.File, name string) (format string, entries []archiveEntry, truncated bool, err error) {
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(f, magic)
+	magic = magic[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", nil, false, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		entries, truncated, err = readZipEntries(f)
+		return "zip", entries, truncated, err
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return "", nil, false, err
+		}
+		defer gz.Close()
+		entries, truncated, err = readTarEntries(gz)
+		return "tar.gz", entries, truncated, err
+	case strings.HasSuffix(strings.ToLower(name), ".tar"):
+		entries, truncated, err = readTarEntries(bufio.NewReader(f))
+		return "tar", entries, truncated, err
+	}
+	return "", nil, false, fmt.Errorf("unsupported archive format (expected .zip, .tar or .tar.gz)")
+}
+
+func readZipEntries(f *os.File) ([]archiveEntry, bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return nil, false, err
+	}
+	var entries []archiveEntry
+	for i, zf := range zr.File {
+		if i == maxArchiveScan {
+			return entries, true, nil
+		}
+		e := archiveEntry{name: zf.Name, size: int64(zf.UncompressedSize64), compressed: int64(zf.CompressedSize64), mode: zf.Mode()}
+		e.unsafe = unsafeArchivePath(zf.Name)
+		entries = append(entries, e)
+	}
+	return entries, false, nil
+}
+
+func readTarEntries(r io.Reader) ([]archiveEntry, bool, error) {
+	tr := tar.NewReader(r)
+	var entries []archiveEntry
+	for len(entries) < maxArchiveScan {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, false, nil
+		}
+		if err != nil {
+			return entries, false, err
+		}
+		e := archiveEntry{name: hdr.Name, size: hdr.Size, mode: hdr.FileInfo().Mode()}
+		e.unsafe = unsafeArchivePath(hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			e.link = hdr.Linkname
+			if e.unsafe == "" {
+				// A relative target is resolved from the link's directory.
+				target := hdr.Linkname
+				if !strings.HasPrefix(target, "/") {
+					target = path.Join(path.Dir(hdr.Name), target)
+				}
+				if reason := unsafeArchivePath(target); reason != "" {
+					e.unsafe = "symlink target " + reason
+				}
+			}
+		case tar.TypeLink:
+			e.link = hdr.Linkname
+			if e.unsafe == "" {
+				if reason := unsafeArchivePath(hdr.Linkname); reason != "" {
+					e.unsafe = "hard link target " + reason
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, true, nil
+}
+
+// unsafeArchivePath reports why extracting a member named name could write
+// outside the extraction directory, or "" if it cannot.
+func unsafeArchivePath(name string) string {
+	n := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(n, "/") || (len(n) >= 2 && n[1] == ':') {
+		return "is an absolute path"
+	}
+	// "a/../b" stays inside; only a path that climbs above the root escapes.
+	if clean := path.Clean(n); clean == ".." || strings.HasPrefix(clean, "../") {
+		return "escapes the extraction directory with '..'"
+	}
+	return ""
+}
+
+func formatArchiveListing(name, format string, entries []archiveEntry, truncated bool, limit int) string {
+	var total int64
+	var unsafe []archiveEntry
+	for _, e := range entries {
+		total += e.size
+		if e.unsafe != "" {
+			unsafe = append(unsafe, e)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%s): %d entries, %d bytes uncompressed", name, format, len(entries), total)
+	if truncated {
+		fmt.Fprintf(&sb, " (stopped after %d entries)", maxArchiveScan)
+	}
+	sb.WriteString("\n")
+	for i, e := range entries {
+		if i == limit {
+			fmt.Fprintf(&sb, "... %d more entries (raise limit to see them)\n", len(entries)-limit)
+			break
+		}
+		fmt.Fprintf(&sb, "%s %10d  %s", e.mode, e.size, e.name)
+		if e.link != "" {
+			fmt.Fprintf(&sb, " -> %s", e.link)
+		}
+		if e.unsafe != "" {
+			sb.WriteString("  [UNSAFE]")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(unsafe) > 0 {
+		fmt.Fprintf(&sb, "\nWARNING: %d unsafe entries (zip-slip); do not extract this archive as is:\n", len(unsafe))
+		for _, e := range unsafe {
+			fmt.Fprintf(&sb, "- %s: %s\n", e.name, e.unsafe)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// nestedArchiveExts are member name suffixes that mark an archive inside an
+// archive, which would need its own check before being extracted in turn.
+var nestedArchiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz", ".gz", ".jar"}
+
+// formatBombWarnings reports why extracting the archive could exhaust the
+// disk: a cumulative uncompressed size over limits.maxBytes, or a compression
+// ratio over limits.maxRatio for a zip member or, since gzip compresses the
+// stream as a whole, for the entire tar.gz. Nested archives are noted too.
+// Sizes come from the headers; extraction must still enforce them.
+func formatBombWarnings(format string, entries []archiveEntry, archiveSize int64, limits archiveLimits) string {
+	var total int64
+	var reasons, nested []string
+	for _, e := range entries {
+		total += e.size
+		if limits.maxRatio > 0 && e.compressed > 0 && e.size >= minRatioCheckSize && e.size/e.compressed > limits.maxRatio {
+			reasons = append(reasons, fmt.Sprintf("%s expands %d:1 (%d bytes from %d), over the %d:1 limit", e.name, e.size/e.compressed, e.size, e.compressed, limits.maxRatio))
+		}
+		lower := strings.ToLower(e.name)
+		for _, ext := range nestedArchiveExts {
+			if strings.HasSuffix(lower, ext) {
+				nested = append(nested, e.name)
+				break
+			}
+		}
+	}
+	if limits.maxBytes > 0 && total > limits.maxBytes {
+		reasons = append([]string{fmt.Sprintf("expands to %d bytes in total, over the %d byte limit", total, limits.maxBytes)}, reasons...)
+	}
+	if format == "tar.gz" && limits.maxRatio > 0 && archiveSize > 0 && total >= minRatioCheckSize && total/archiveSize > limits.maxRatio {
+		reasons = append(reasons, fmt.Sprintf("archive expands %d:1 (%d bytes from %d), over the %d:1 limit", total/archiveSize, total, archiveSize, limits.maxRatio))
+	}
+
+	var sb strings.Builder
+	if len(reasons) > 0 {
+		sb.WriteString("\n\nWARNING: possible zip bomb; do not extract this archive:\n")
+		sb.WriteString("- " + strings.Join(reasons, "\n- "))
+	}
+	if len(nested) > 0 {
+		fmt.Fprintf(&sb, "\n\nNOTE: %d nested archive(s); check each with list_archive before extracting it: %s", len(nested), strings.Join(nested[:min(len(nested), 10)], ", "))
+		if len(nested) > 10 {
+			fmt.Fprintf(&sb, ", ... %d more", len(nested)-10)
+		}
+	}
+	return sb.String()
+}
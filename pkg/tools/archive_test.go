@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestListArchiveTool_Zip verifies a normal zip is listed with names, sizes
+// and modes and no warnings
+func TestListArchiveTool_Zip(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestZip(t, filepath.Join(tmpDir, "site.zip"), map[string]string{
+		"index.html":    "<html></html>",
+		"css/style.css": "body{}",
+		"a/../b/ok.txt": "ok",
+	})
+
+	result := NewListArchiveTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "site.zip"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{"site.zip (zip): 3 entries, 21 bytes uncompressed", "13  index.html", "6  css/style.css", "-rw-rw-rw-"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in:\n%s", want, result.ForLLM)
+		}
+	}
+	if strings.Contains(result.ForLLM, "UNSAFE") || strings.Contains(result.ForLLM, "WARNING") {
+		t.Errorf("Expected no warnings, got:\n%s", result.ForLLM)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+		t.Error("list_archive must not extract anything")
+	}
+}
+
+// TestListArchiveTool_FlagsZipSlip verifies crafted '..' and absolute members
+// are flagged
+func TestListArchiveTool_FlagsZipSlip(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestZip(t, filepath.Join(tmpDir, "evil.zip"), map[string]string{
+		"readme.txt":           "hi",
+		"../../etc/cron.d/x":   "* * * * * root sh",
+		"/abs/path.txt":        "x",
+		"dir\\..\\..\\win.txt": "x",
+	})
+
+	result := NewListArchiveTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "evil.zip", "limit": float64(1)})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{
+		"WARNING: 3 unsafe entries (zip-slip)",
+		"- ../../etc/cron.d/x: escapes the extraction directory with '..'",
+		"- /abs/path.txt: is an absolute path",
+		`- dir\..\..\win.txt: escapes the extraction directory with '..'`,
+		"... 3 more entries",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in:\n%s", want, result.ForLLM)
+		}
+	}
+}
+
+// TestListArchiveTool_TarGz verifies tar.gz members, including an escaping
+// symlink, are listed
+func TestListArchiveTool_TarGz(t *testing.T) {
+	tmpDir := t.TempDir()
+	f, err := os.Create(filepath.Join(tmpDir, "pkg.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "pkg/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "pkg/run.sh", Typeflag: tar.TypeReg, Mode: 0755, Size: 5})
+	tw.Write([]byte("exit\n"))
+	tw.WriteHeader(&tar.Header{Name: "pkg/link", Typeflag: tar.TypeSymlink, Linkname: "../../home", Mode: 0777})
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	result := NewListArchiveTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "pkg.tar.gz"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{
+		"pkg.tar.gz (tar.gz): 3 entries, 5 bytes uncompressed",
+		"-rwxr-xr-x          5  pkg/run.sh",
+		"pkg/link -> ../../home  [UNSAFE]",
+		"- pkg/link: symlink target escapes the extraction directory with '..'",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in:\n%s", want, result.ForLLM)
+		}
+	}
+}
+
+// TestListArchiveTool_Unsupported verifies non-archives are rejected
+func TestListArchiveTool_Unsupported(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "notes.txt"), []byte("plain text"), 0644)
+
+	result := NewListArchiveTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "notes.txt"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "unsupported archive format") {
+		t.Errorf("Expected unsupported format error, got: %s", result.ForLLM)
+	}
+}
+
+// TestListArchiveTool_FlagsZipBomb verifies a highly compressed member and an
+// oversized total are reported, along with nested archives
+func TestListArchiveTool_FlagsZipBomb(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestZip(t, filepath.Join(tmpDir, "bomb.zip"), map[string]string{
+		"zeros.bin": strings.Repeat("\x00", 8<<20),
+		"inner.zip": "PK",
+	})
+
+	result := NewListArchiveTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "bomb.zip"})
+	if result.IsError {
+		t.Fatalf("Expected a listing, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{"WARNING: possible zip bomb", "zeros.bin expands", "over the 100:1 limit", "NOTE: 1 nested archive(s)", "inner.zip"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in:\n%s", want, result.ForLLM)
+		}
+	}
+
+	tool := NewListArchiveToolWithPolicy(tmpDir, true, PathPolicyOpts{MaxArchiveBytes: 1 << 20, MaxArchiveRatio: 1 << 20})
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "bomb.zip"})
+	if !strings.Contains(result.ForLLM, "over the 1048576 byte limit") || strings.Contains(result.ForLLM, "zeros.bin expands") {
+		t.Errorf("Expected only the total size limit to trip, got:\n%s", result.ForLLM)
+	}
+}
+
+// TestListArchiveTool_FlagsTarGzBomb verifies the whole-archive ratio is
+// checked for tar.gz, where members have no compressed size of their own
+func TestListArchiveTool_FlagsTarGzBomb(t *testing.T) {
+	tmpDir := t.TempDir()
+	f, _ := os.Create(filepath.Join(tmpDir, "bomb.tar.gz"))
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	data := make([]byte, 8<<20)
+	tw.WriteHeader(&tar.Header{Name: "zeros.bin", Mode: 0644, Size: int64(len(data))})
+	tw.Write(data)
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	result := NewListArchiveTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "bomb.tar.gz"})
+	if !strings.Contains(result.ForLLM, "WARNING: possible zip bomb") || !strings.Contains(result.ForLLM, "archive expands") {
+		t.Errorf("Expected a ratio warning, got:\n%s", result.ForLLM)
+	}
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Artifact is a file a tool produced, described for the next tool call or a
+// channel adapter so they need not parse the result text.
+type Artifact struct {
+	// Path is the absolute path of the file.
+	Path string `json:"path"`
+	// MIME is the media type, from the extension or else sniffed from the
+	// content. Empty if neither gives an answer.
+	MIME string `json:"mime,omitempty"`
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
+}
+
+// NewArtifact describes the file at path as it is now on disk.
+func NewArtifact(path string) (Artifact, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return Artifact{}, err
+	}
+	return Artifact{Path: abs, MIME: detectMIME(abs), Size: info.Size()}, nil
+}
+
+// detectMIME guesses the media type of path, preferring the extension and
+// falling back to sniffing the first 512 bytes.
+func detectMIME(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	if n == 0 {
+		return ""
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// WithArtifact records the file at path as an artifact of the result and
+// returns the result for chaining. A file that cannot be described is left
+// out; the text of the result still names it.
+func (tr *ToolResult) WithArtifact(path string) *ToolResult {
+	if a, err := NewArtifact(path); err == nil {
+		tr.Artifacts = append(tr.Artifacts, a)
+	}
+	return tr
+}
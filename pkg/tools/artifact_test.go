@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteFileTool_Artifact verifies a write reports the file it produced
+// with its absolute path, media type and size
+func TestWriteFileTool_Artifact(t *testing.T) {
+	tmpDir, _ := filepath.EvalSymlinks(t.TempDir())
+	result := NewWriteFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path":    "out/report.json",
+		"content": `{"ok":true}`,
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if len(result.Artifacts) != 1 {
+		t.Fatalf("Expected one artifact, got %+v", result.Artifacts)
+	}
+	a := result.Artifacts[0]
+	if a.Path != filepath.Join(tmpDir, "out", "report.json") || a.MIME != "application/json" || a.Size != 11 {
+		t.Errorf("Unexpected artifact: %+v", a)
+	}
+
+	// Failed writes report nothing.
+	result = NewWriteFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path":    "../escape.txt",
+		"content": "x",
+	})
+	if !result.IsError || len(result.Artifacts) != 0 {
+		t.Errorf("Expected an error without artifacts, got: %+v", result)
+	}
+}
+
+// TestDownloadTool_Artifact verifies a download reports the saved file,
+// sniffing the media type when the name has no known extension
+func TestDownloadTool_Artifact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("%PDF-1.7\n"))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	result := NewDownloadTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"url":  server.URL + "/paper",
+		"path": "paper",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := Artifact{Path: filepath.Join(tmpDir, "paper"), MIME: "application/pdf", Size: 9}
+	if len(result.Artifacts) != 1 || result.Artifacts[0] != want {
+		t.Errorf("Expected artifact %+v, got %+v", want, result.Artifacts)
+	}
+}
+
+func TestToolResult_ArtifactsJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("hi"), 0644)
+
+	data, _ := json.Marshal(SilentResult("saved").WithArtifact(path).WithArtifact(path + ".missing"))
+	var parsed struct {
+		Artifacts []Artifact `json:"artifacts"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Artifacts) != 1 || parsed.Artifacts[0].Path != path || parsed.Artifacts[0].Size != 2 {
+		t.Errorf("Unexpected artifacts in %s", data)
+	}
+
+	data, _ = json.Marshal(SilentResult("nothing produced"))
+	if string(data) != `{"for_llm":"nothing produced","silent":true,"is_error":false,"async":false}` {
+		t.Errorf("Expected no artifacts key, got %s", data)
+	}
+}
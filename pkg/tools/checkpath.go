@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// CheckPathTool is a dry run of the path guard: it reports where a path
+// resolves and whether file tools would be allowed to use it, without
+// touching the file or prompting for approval.
+type CheckPathTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewCheckPathTool(workspace string, restrict bool) *CheckPathTool {
+	return &CheckPathTool{workspace: workspace, restrict: restrict}
+}
+
+func NewCheckPathToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *CheckPathTool {
+	return &CheckPathTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *CheckPathTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *CheckPathTool) Name() string {
+	return "check_path"
+}
+
+func (t *CheckPathTool) Description() string {
+	return "Check a path against the workspace restriction without accessing it: shows the resolved absolute path and whether file tools may use it, and why not if denied. Use before proposing paths outside the usual workspace layout."
+}
+
+func (t *CheckPathTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to check, absolute or relative to the workspace",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *CheckPathTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+
+	mode := t.policyEngine.EffectiveMode("path_validation", t.pathMode, t.channel, t.chatID)
+	// Resolve exactly as the guard would, but never reach the approval prompt.
+	dryMode := mode
+	if dryMode == security.ModeApprove {
+		dryMode = security.ModeBlock
+	}
+	resolved, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, dryMode, t.policyEngine, "", "")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Path: %s\n", path)
+	if err == nil {
+		fmt.Fprintf(&sb, "Resolved: %s\n", resolved)
+		if !t.restrict || t.workspace == "" {
+			sb.WriteString("Allowed: yes (workspace restriction is off)")
+		} else {
+			sb.WriteString("Allowed: yes (inside the workspace)")
+		}
+		return NewToolResult(sb.String())
+	}
+
+	if abs, absErr := filepath.Abs(filepath.Join(t.workspace, path)); absErr == nil {
+		if filepath.IsAbs(path) {
+			abs = filepath.Clean(path)
+		}
+		fmt.Fprintf(&sb, "Resolved: %s\n", abs)
+	}
+	// Unresolvable symlinks are refused outright in strict mode; other
+	// violations can still be approved in approve mode.
+	if mode == security.ModeApprove && t.policyEngine != nil && !strings.Contains(err.Error(), "cannot resolve symlinks") {
+		sb.WriteString("Allowed: only with approval (path_validation is in approve mode)\n")
+	} else {
+		sb.WriteString("Allowed: no\n")
+	}
+	fmt.Fprintf(&sb, "Reason: %s", err.Error())
+	return NewToolResult(sb.String())
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// TestCheckPathTool_InsideWorkspace verifies an in-workspace path is reported
+// as allowed with its absolute path, even if it does not exist yet
+func TestCheckPathTool_InsideWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewCheckPathTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "sub/new.txt"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Resolved: "+filepath.Join(tmpDir, "sub", "new.txt")) || !strings.Contains(result.ForLLM, "Allowed: yes") {
+		t.Errorf("Expected allowed with resolved path, got:\n%s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "sub")); !os.IsNotExist(err) {
+		t.Error("check_path must not create anything")
+	}
+}
+
+// TestCheckPathTool_EscapeDenied verifies a ../ escape is reported as denied
+// with the guard's reason
+func TestCheckPathTool_EscapeDenied(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewCheckPathTool(filepath.Join(tmpDir, "ws"), true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "../escape"})
+	if result.IsError {
+		t.Fatalf("Expected a report, not an error: %s", result.ForLLM)
+	}
+	for _, want := range []string{"Resolved: " + filepath.Join(tmpDir, "escape"), "Allowed: no", "Reason: access denied: path is outside the workspace"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in:\n%s", want, result.ForLLM)
+		}
+	}
+
+	unrestricted := NewCheckPathTool(filepath.Join(tmpDir, "ws"), false)
+	result = unrestricted.Execute(context.Background(), map[string]interface{}{"path": "../escape"})
+	if !strings.Contains(result.ForLLM, "Allowed: yes (workspace restriction is off)") {
+		t.Errorf("Expected allowed without restriction, got:\n%s", result.ForLLM)
+	}
+}
+
+// TestCheckPathTool_ApproveModeDoesNotPrompt verifies approve mode reports
+// that approval is needed without sending a prompt
+func TestCheckPathTool_ApproveModeDoesNotPrompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(tmpDir, "link"))
+
+	pe := security.NewPolicyEngine(&config.SecurityConfig{PathValidation: "approve"}, nil)
+	tool := NewCheckPathToolWithPolicy(tmpDir, true, PathPolicyOpts{PathMode: security.ModeApprove, PolicyEngine: pe})
+	tool.SetContext("telegram", "chat1")
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "link/secret.txt"})
+	if !strings.Contains(result.ForLLM, "Allowed: only with approval") || !strings.Contains(result.ForLLM, "symlink resolves outside workspace") {
+		t.Errorf("Expected approval-required report, got:\n%s", result.ForLLM)
+	}
+}
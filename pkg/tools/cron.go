@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/adhocore/gronx"
+
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/utils"
@@ -65,8 +67,8 @@ func (t *CronTool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"add", "list", "remove", "enable", "disable"},
-				"description": "Action to perform. Use 'add' when user wants to schedule a reminder or task.",
+				"enum":        []string{"add", "list", "remove", "enable", "disable", "validate"},
+				"description": "Action to perform. Use 'add' when user wants to schedule a reminder or task. Use 'validate' to check a cron_expr and explain it without scheduling anything.",
 			},
 			"message": map[string]interface{}{
 				"type":        "string",
@@ -127,11 +129,31 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		return t.enableJob(args, true)
 	case "disable":
 		return t.enableJob(args, false)
+	case "validate":
+		return t.validateExpr(args)
 	default:
 		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
 	}
 }
 
+// validateExpr checks a cron expression and describes it without creating a job.
+func (t *CronTool) validateExpr(args map[string]interface{}) *ToolResult {
+	expr, ok := args["cron_expr"].(string)
+	if !ok || expr == "" {
+		return ErrorResult("cron_expr is required for validate")
+	}
+
+	if err := cron.ValidateExpr(expr); err != nil {
+		return NewToolResult(fmt.Sprintf("Invalid cron expression %q: %v", expr, err))
+	}
+
+	result := fmt.Sprintf("Valid cron expression %q\nMeaning: %s", expr, cron.DescribeExpr(expr))
+	if next, err := gronx.NextTickAfter(expr, time.Now(), false); err == nil {
+		result += fmt.Sprintf("\nNext run: %s", next.Format(time.RFC3339))
+	}
+	return NewToolResult(result)
+}
+
 func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 	t.mu.RLock()
 	channel := t.channel
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/sipeed/picoclaw/pkg/cron"
@@ -122,3 +123,32 @@ func TestCronTool_ExecuteJob_UnrestrictedCommand(t *testing.T) {
 		})
 	}
 }
+
+// TestCronTool_Validate verifies validate explains an expression without scheduling a job
+func TestCronTool_Validate(t *testing.T) {
+	cronService := cron.NewCronService("", nil)
+	cronTool := NewCronTool(cronService, nil, nil, t.TempDir(), true)
+	ctx := context.Background()
+
+	result := cronTool.Execute(ctx, map[string]interface{}{
+		"action":    "validate",
+		"cron_expr": "0 9 * * 1-5",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Valid") || !strings.Contains(result.ForLLM, "At 09:00, Monday through Friday") {
+		t.Errorf("Expected description, got: %s", result.ForLLM)
+	}
+	if jobs := cronService.ListJobs(true); len(jobs) != 0 {
+		t.Errorf("validate must not schedule jobs, found %d", len(jobs))
+	}
+
+	result = cronTool.Execute(ctx, map[string]interface{}{
+		"action":    "validate",
+		"cron_expr": "61 * * * *",
+	})
+	if !strings.Contains(result.ForLLM, "Invalid") || !strings.Contains(result.ForLLM, `invalid minute field "61"`) {
+		t.Errorf("Expected precise parse error, got: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// deniedPathRule returns the security.denied_paths pattern that denies
+// absPath, if any. Patterns use .gitignore syntax relative to workspace: one
+// without an inner slash matches at any depth, "dir/" denies everything
+// beneath dir, and "!pattern" re-allows a match. Paths outside the workspace
+// are matched on their absolute form, so only unanchored patterns apply.
+func deniedPathRule(patterns []string, absPath, workspace string) (string, bool) {
+	if len(patterns) == 0 {
+		return "", false
+	}
+	rel := strings.TrimPrefix(filepath.ToSlash(absPath), "/")
+	if isWithinWorkspace(absPath, workspace) {
+		r, err := filepath.Rel(workspace, absPath)
+		if err != nil || r == "." {
+			return "", false
+		}
+		rel = filepath.ToSlash(r)
+	}
+
+	isDir := false
+	if info, err := os.Stat(absPath); err == nil {
+		isDir = info.IsDir()
+	}
+	return deniedRelRule(patterns, rel, isDir)
+}
+
+// deniedRelRule matches rel, a slash-separated path relative to the
+// workspace, against patterns. A denied directory covers everything beneath
+// it, so each ancestor is checked as a directory and rel itself as a
+// directory only when isDir is set.
+func deniedRelRule(patterns []string, rel string, isDir bool) (string, bool) {
+	segs := strings.Split(rel, "/")
+	for i := 1; i <= len(segs); i++ {
+		var denied string
+		for _, p := range patterns {
+			rule, ok := parseIgnoreLine(p)
+			if !ok || (rule.dirOnly && i == len(segs) && !isDir) {
+				continue
+			}
+			if matchSegments(rule.segs, segs[:i]) {
+				denied = p
+				if rule.negate {
+					denied = ""
+				}
+			}
+		}
+		if denied != "" {
+			return denied, true
+		}
+	}
+	return "", false
+}
+
+// checkDeniedPaths enforces security.denied_paths on absPath, a form of the
+// requested path, relative to workspace. A match prompts when pathMode is
+// approve and is blocked otherwise, whatever the path_validation mode.
+func checkDeniedPaths(path, absPath, workspace string, pathMode security.PolicyMode, pe *security.PolicyEngine, channel, chatID string) error {
+	pattern, ok := deniedPathRule(pe.DeniedPaths(), absPath, workspace)
+	if !ok {
+		return nil
+	}
+	return evaluateDeniedPath(path, pattern, pathMode, pe, channel, chatID)
+}
+
+// evaluateDeniedPath applies the policy to path matching denied_paths pattern.
+func evaluateDeniedPath(path, pattern string, pathMode security.PolicyMode, pe *security.PolicyEngine, channel, chatID string) error {
+	mode := pathMode
+	if mode != security.ModeApprove {
+		mode = security.ModeBlock
+	}
+	return pe.Evaluate(context.Background(), mode, security.Violation{
+		Category: "path_validation",
+		Tool:     "filesystem",
+		Action:   path,
+		Reason:   fmt.Sprintf("access denied: %s matches denied_paths pattern %q", path, pattern),
+		RuleName: pattern,
+	}, channel, chatID)
+}
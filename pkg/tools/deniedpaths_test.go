@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// TestReadFileTool_DeniedPaths verifies a .env read is denied inside the
+// workspace while a normal file passes
+func TestReadFileTool_DeniedPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("SECRET=1"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main"), 0644)
+	pe := security.NewPolicyEngine(&config.SecurityConfig{DeniedPaths: []string{".env"}}, nil)
+	tool := NewReadFileToolWithPolicy(tmpDir, true, PathPolicyOpts{PolicyEngine: pe})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": ".env"})
+	if !result.IsError || !strings.Contains(result.ForLLM, `denied_paths pattern ".env"`) || strings.Contains(result.ForLLM, "SECRET") {
+		t.Errorf("Expected .env to be denied, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "main.go"})
+	if result.IsError || result.ForLLM != "package main" {
+		t.Errorf("Expected main.go to be readable, got: %s", result.ForLLM)
+	}
+}
+
+func TestValidatePath_DeniedPaths(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, ".git"), 0755)
+	os.MkdirAll(filepath.Join(workspace, "secrets"), 0755)
+	pe := security.NewPolicyEngine(&config.SecurityConfig{
+		DeniedPaths: []string{".env*", "!.env.example", "/.git/config", "secrets/", "*.pem"},
+	}, nil)
+
+	tests := []struct {
+		path   string
+		denied bool
+	}{
+		{".env", true},
+		{"services/api/.env.local", true},
+		{".env.example", false},
+		{".git/config", true},
+		{".git/HEAD", false},
+		{"vendor/.git/config", false},
+		{"secrets", true},
+		{"secrets/db/password.txt", true},
+		{"certs/server.pem", true},
+		{"README.md", false},
+	}
+	for _, tt := range tests {
+		// Mode off still enforces the list; the caller configured it.
+		_, err := validatePathWithMode(tt.path, workspace, nil, true, security.ModeOff, pe, "", "")
+		if denied := err != nil; denied != tt.denied {
+			t.Errorf("%s: denied=%v, want %v (err: %v)", tt.path, denied, tt.denied, err)
+		}
+	}
+}
+
+func TestValidatePath_DeniedPathsThroughSymlink(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, ".env"), []byte("SECRET=1"), 0644)
+	if err := os.Symlink(".env", filepath.Join(workspace, "config.txt")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	pe := security.NewPolicyEngine(&config.SecurityConfig{DeniedPaths: []string{".env"}}, nil)
+
+	_, err := validatePathWithMode("config.txt", workspace, nil, true, security.ModeBlock, pe, "", "")
+	if err == nil || !strings.Contains(err.Error(), "blocked by security policy") {
+		t.Errorf("Expected a symlink to .env to be blocked, got: %v", err)
+	}
+}
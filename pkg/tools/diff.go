@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// Limits for UnifiedDiff. The edit trace grows with the square of the
+// number of changed lines, so very different inputs are refused.
+const (
+	maxDiffLines = 20000
+	maxDiffEdits = 4000
+)
+
+// diffOp is one line of an edit script: ' ' kept, '-' deleted, '+' inserted.
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// splitDiffLines splits s into lines that keep their "\n", so a missing
+// newline at the end of the file shows up as a difference.
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script from a to b (Myers' algorithm).
+// ok is false when more than maxDiffEdits lines differ.
+func diffLines(a, b []string) (ops []diffOp, ok bool) {
+	n, m := len(a), len(b)
+	off := n + m + 1
+	v := make([]int, 2*off+1)
+	// trace[d] holds v[-d-1..d+1] as it was before round d.
+	var trace [][]int
+
+search:
+	for d := 0; ; d++ {
+		if d > maxDiffEdits {
+			return nil, false
+		}
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards from the end to recover the edits.
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v, off := trace[d], d+1
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[off+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[y-1]})
+			} else {
+				ops = append(ops, diffOp{'-', a[x-1]})
+			}
+			x, y = prevX, prevY
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops, true
+}
+
+// UnifiedDiff renders the changes from oldText to newText as a unified diff
+// labelled with name. An empty oldText with isNew set is shown as a new file.
+// It returns "" when the texts are equal.
+func UnifiedDiff(name, oldText, newText string, isNew bool) (string, error) {
+	if oldText == newText {
+		return "", nil
+	}
+	a, b := splitDiffLines(oldText), splitDiffLines(newText)
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		return "", fmt.Errorf("too large to diff (more than %d lines)", maxDiffLines)
+	}
+	ops, ok := diffLines(a, b)
+	if !ok {
+		return "", fmt.Errorf("too many changes to diff (more than %d lines differ)", maxDiffEdits)
+	}
+
+	var sb strings.Builder
+	if isNew {
+		sb.WriteString("--- /dev/null\n")
+	} else {
+		fmt.Fprintf(&sb, "--- a/%s\n", name)
+	}
+	fmt.Fprintf(&sb, "+++ b/%s\n", name)
+
+	// Old and new line numbers before each op.
+	oldLine := make([]int, len(ops)+1)
+	newLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk while the next change is close enough that the
+		// context of both would touch.
+		last := i
+		for j := i + 1; j < len(ops) && j-last <= 2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		start := max(0, i-diffContext)
+		end := min(len(ops), last+diffContext+1)
+
+		oldCount := oldLine[end] - oldLine[start]
+		newCount := newLine[end] - newLine[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return sb.String(), nil
+}
+
+// hunkRange formats a hunk's line range; an empty range names the line
+// before it, as diff(1) does.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
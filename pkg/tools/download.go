@@ -0,0 +1,315 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// downloadCacheDir is the workspace-relative directory used for downloads
+// cached by URL hash.
+const downloadCacheDir = ".cache/downloads"
+
+// DownloadToolOptions configures DownloadTool.
+type DownloadToolOptions struct {
+	Workspace    string
+	Restrict     bool
+	Roots        map[string]string // named roots; see PathPolicyOpts.Roots
+	MaxBytes     int64             // Size cap per download, default 50 MiB
+	PathMode     security.PolicyMode
+	PolicyEngine *security.PolicyEngine
+	SSRFMode     security.PolicyMode
+}
+
+// DownloadTool fetches a URL and streams it into a file in the workspace.
+type DownloadTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	maxBytes     int64
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	ssrfMode     security.PolicyMode
+	channel      string
+	chatID       string
+}
+
+func NewDownloadTool(workspace string, restrict bool) *DownloadTool {
+	return NewDownloadToolWithPolicy(DownloadToolOptions{Workspace: workspace, Restrict: restrict})
+}
+
+func NewDownloadToolWithPolicy(opts DownloadToolOptions) *DownloadTool {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 50 << 20
+	}
+	return &DownloadTool{
+		workspace:    opts.Workspace,
+		restrict:     opts.Restrict,
+		roots:        opts.Roots,
+		maxBytes:     opts.MaxBytes,
+		pathMode:     opts.PathMode,
+		policyEngine: opts.PolicyEngine,
+		ssrfMode:     opts.SSRFMode,
+	}
+}
+
+// SetContext implements ContextualTool for IM-based approval.
+func (t *DownloadTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *DownloadTool) Name() string {
+	return "download"
+}
+
+func (t *DownloadTool) Description() string {
+	return "Download a URL into a file in the workspace and return the saved path. Use cache to reuse a previous download of the same URL."
+}
+
+func (t *DownloadTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "http(s) URL to download",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Destination file. Default: " + downloadCacheDir + "/<url-hash>-<name>",
+			},
+			"overwrite": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace an existing destination file (default false)",
+			},
+			"resume": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Continue a partial download with an HTTP Range request (default false)",
+			},
+			"cache": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return the existing file without fetching if this URL was already downloaded to the default path (default true)",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *DownloadTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	urlStr, ok := args["url"].(string)
+	if !ok || urlStr == "" {
+		return ErrorResult("url is required")
+	}
+	overwrite, _ := args["overwrite"].(bool)
+	resume, _ := args["resume"].(bool)
+	useCache := true
+	if c, ok := args["cache"].(bool); ok {
+		useCache = c
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid URL: %v", err))
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return ErrorResult(fmt.Sprintf("only http/https URLs are supported, got: %s", parsedURL.Scheme))
+	}
+	if parsedURL.Host == "" {
+		return ErrorResult("missing domain in URL")
+	}
+
+	ssrfMode := t.policyEngine.EffectiveMode("ssrf", t.ssrfMode, t.channel, t.chatID)
+
+	// SSRF protection (mode-aware). An approved URL is not re-checked at dial
+	// time; otherwise every connection is checked against the address it
+	// actually reaches so a DNS answer cannot change between check and use.
+	checkDial := !ssrfMode.IsOff()
+	if !ssrfMode.IsOff() {
+		if err := utils.ValidateURLWithPolicy(urlStr, ssrfPolicy(t.policyEngine)); err != nil {
+			if t.policyEngine == nil {
+				return ErrorResult(fmt.Sprintf("URL blocked: %v", err))
+			}
+			if pErr := t.policyEngine.Evaluate(ctx, ssrfMode, security.Violation{
+				Category: "ssrf",
+				Tool:     "download",
+				Action:   urlStr,
+				Reason:   err.Error(),
+			}, t.channel, t.chatID); pErr != nil {
+				return ErrorResult(fmt.Sprintf("URL blocked: %v", pErr))
+			}
+			checkDial = false
+		}
+	}
+
+	dest, _ := args["path"].(string)
+	cached := dest == ""
+	if cached {
+		dest = path.Join(downloadCacheDir, downloadCacheName(parsedURL))
+	}
+	resolvedPath, err := validatePathWithMode(dest, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var offset int64
+	if info, err := os.Stat(resolvedPath); err == nil {
+		switch {
+		case info.IsDir():
+			return ErrorResult(fmt.Sprintf("destination is a directory: %s", dest))
+		case cached && useCache && !overwrite && !resume:
+			return SilentResult(fmt.Sprintf("Using cached download: %s (%d bytes)", dest, info.Size())).WithArtifact(resolvedPath)
+		case resume:
+			offset = info.Size()
+		case !overwrite:
+			return ErrorResult(fmt.Sprintf("destination already exists: %s (set overwrite or resume)", dest))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedPath), 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
+	}
+
+	written, err := t.fetch(ctx, urlStr, resolvedPath, offset, checkDial, ssrfMode)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	return SilentResult(fmt.Sprintf("Downloaded %s to %s (%d bytes)", urlStr, dest, written)).WithArtifact(resolvedPath)
+}
+
+// fetch streams urlStr into dest. With offset > 0 it asks the server for the
+// remaining bytes and appends; a server that ignores the range restarts the
+// file. It returns the final file size.
+func (t *DownloadTool) fetch(ctx context.Context, urlStr, dest string, offset int64, checkDial bool, ssrfMode security.PolicyMode) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := newDownloadClient(checkDial, ssrfMode, ssrfPolicy(t.policyEngine)).Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is already complete.
+		return offset, nil
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	default:
+		return 0, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+
+	if resp.ContentLength > 0 && offset+resp.ContentLength > t.maxBytes {
+		return 0, fmt.Errorf("download too large: %d bytes exceeds limit of %d", offset+resp.ContentLength, t.maxBytes)
+	}
+
+	// Fresh downloads go to a temp file renamed into place on success, so a
+	// failed download never leaves a truncated file at dest.
+	var f *os.File
+	target := dest
+	if offset > 0 {
+		f, err = os.OpenFile(dest, os.O_WRONLY|os.O_APPEND, 0)
+	} else {
+		f, err = os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".part-*")
+		if err == nil {
+			target = f.Name()
+			defer os.Remove(target)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open destination: %v", err)
+	}
+
+	limit := t.maxBytes - offset
+	n, err := io.Copy(f, io.LimitReader(resp.Body, limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write file: %v", err)
+	}
+	if n > limit {
+		if offset > 0 {
+			os.Truncate(dest, offset)
+		}
+		return 0, fmt.Errorf("download too large: exceeds limit of %d bytes", t.maxBytes)
+	}
+
+	if offset == 0 {
+		if err := os.Rename(target, dest); err != nil {
+			return 0, fmt.Errorf("failed to save file: %v", err)
+		}
+	}
+	return offset + n, nil
+}
+
+// newDownloadClient builds an HTTP client whose redirects are validated like
+// the initial URL and, when checkDial is set, whose connections are pinned to
+// the addresses validated at dial time, refusing any that is private,
+// loopback or link-local and not allowed by policy.
+func newDownloadClient(checkDial bool, ssrfMode security.PolicyMode, policy utils.URLPolicy) *http.Client {
+	transport := &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout: 15 * time.Second,
+	}
+	if checkDial {
+		transport = utils.NewPinnedTransport(policy.Allow)
+	}
+
+	return &http.Client{
+		Timeout:       10 * time.Minute,
+		Transport:     transport,
+		CheckRedirect: redirectPolicy(ssrfMode, policy),
+	}
+}
+
+// redirectPolicy validates every redirect like the initial URL while SSRF
+// protection is on, and only caps the number of redirects otherwise.
+func redirectPolicy(ssrfMode security.PolicyMode, policy utils.URLPolicy) func(req *http.Request, via []*http.Request) error {
+	if !ssrfMode.IsOff() {
+		return utils.CheckRedirect(policy, utils.MaxRedirects)
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= utils.MaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", utils.MaxRedirects)
+		}
+		return nil
+	}
+}
+
+// ssrfPolicy builds the URL policy for SSRF checks from the security config.
+func ssrfPolicy(pe *security.PolicyEngine) utils.URLPolicy {
+	return utils.URLPolicy{Allow: pe.SSRFAllowlist(), Ports: pe.SSRFAllowedPorts()}
+}
+
+// downloadCacheName derives a stable file name from the URL hash, keeping the
+// last path segment for readability.
+func downloadCacheName(u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.String()))
+	name := utils.SanitizeFilename(path.Base(u.Path))
+	if name == "" || name == "." || name == "_" {
+		name = "download"
+	}
+	return hex.EncodeToString(sum[:8]) + "-" + name
+}
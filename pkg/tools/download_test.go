@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// TestDownloadTool_SavesToWorkspace verifies a download from a test server lands in the workspace
+func TestDownloadTool_SavesToWorkspace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "payload data")
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	tool := NewDownloadTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":  server.URL + "/file.bin",
+		"path": "data/file.bin",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "data/file.bin") {
+		t.Errorf("Expected saved path in result, got: %s", result.ForLLM)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, "data", "file.bin"))
+	if err != nil || string(content) != "payload data" {
+		t.Errorf("Unexpected file content %q, err=%v", content, err)
+	}
+
+	// Existing file is not replaced without overwrite
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"url":  server.URL + "/file.bin",
+		"path": "data/file.bin",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "already exists") {
+		t.Errorf("Expected existing-file error, got: %s", result.ForLLM)
+	}
+}
+
+// TestDownloadTool_CacheByURLHash verifies a second default-path download reuses the cached file
+func TestDownloadTool_CacheByURLHash(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		fmt.Fprint(w, "cached")
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	tool := NewDownloadTool(tmpDir, true)
+	args := map[string]interface{}{"url": server.URL + "/asset.txt"}
+
+	first := tool.Execute(context.Background(), args)
+	second := tool.Execute(context.Background(), args)
+	if first.IsError || second.IsError {
+		t.Fatalf("Expected success, got: %s / %s", first.ForLLM, second.ForLLM)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("Expected one fetch, got %d", hits.Load())
+	}
+	if !strings.Contains(second.ForLLM, "cached") || !strings.Contains(second.ForLLM, "asset.txt") {
+		t.Errorf("Expected cached result naming the file, got: %s", second.ForLLM)
+	}
+}
+
+// TestDownloadTool_Resume verifies a partial file is completed with a Range request
+func TestDownloadTool_Resume(t *testing.T) {
+	const full = "0123456789"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "f.txt", time.Time{}, strings.NewReader(full))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "f.txt"), []byte(full[:4]), 0644)
+
+	tool := NewDownloadTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":    server.URL + "/f.txt",
+		"path":   "f.txt",
+		"resume": true,
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "f.txt"))
+	if string(content) != full {
+		t.Errorf("Expected resumed content %q, got %q", full, content)
+	}
+}
+
+// TestDownloadTool_SizeCap verifies oversized downloads are refused and leave no file
+func TestDownloadTool_SizeCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// No Content-Length: the cap must be enforced while streaming.
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, strings.Repeat("x", 100))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	tool := NewDownloadToolWithPolicy(DownloadToolOptions{Workspace: tmpDir, Restrict: true, MaxBytes: 10})
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":  server.URL,
+		"path": "big.bin",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "too large") {
+		t.Errorf("Expected size cap error, got: %s", result.ForLLM)
+	}
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("Expected no leftover files, found %d", len(entries))
+	}
+}
+
+// TestDownloadTool_BlocksPrivateIP verifies SSRF protection rejects private addresses
+func TestDownloadTool_BlocksPrivateIP(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewDownloadToolWithPolicy(DownloadToolOptions{
+		Workspace: tmpDir,
+		Restrict:  true,
+		SSRFMode:  security.ModeBlock,
+	})
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":  "http://10.0.0.1/secret",
+		"path": "secret.txt",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "blocked") {
+		t.Errorf("Expected private IP to be blocked, got: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "secret.txt")); !os.IsNotExist(err) {
+		t.Error("Blocked download must not create a file")
+	}
+}
+
+// TestDownloadTool_SSRFAllowlist verifies an allowlisted internal address is
+// reachable with SSRF protection on, including at dial time
+func TestDownloadTool_SSRFAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "internal data")
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	pe := security.NewPolicyEngine(&config.SecurityConfig{SSRFAllowlist: []string{"127.0.0.1"}}, nil)
+	tool := NewDownloadToolWithPolicy(DownloadToolOptions{
+		Workspace:    tmpDir,
+		Restrict:     true,
+		PolicyEngine: pe,
+		SSRFMode:     security.ModeBlock,
+	})
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":  server.URL + "/data.txt",
+		"path": "data.txt",
+	})
+	if result.IsError {
+		t.Fatalf("Expected the allowlisted address to be reachable, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"url":  "http://10.0.0.1/secret",
+		"path": "secret.txt",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "blocked") {
+		t.Errorf("Expected unlisted private IP to be blocked, got: %s", result.ForLLM)
+	}
+}
+
+// TestDownloadTool_OutsideWorkspace verifies the destination goes through path validation
+func TestDownloadTool_OutsideWorkspace(t *testing.T) {
+	tool := NewDownloadTool(t.TempDir(), true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":  "http://example.com/x",
+		"path": "../escape.txt",
+	})
+	if !result.IsError {
+		t.Error("Expected error for destination outside workspace")
+	}
+}
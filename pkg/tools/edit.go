@@ -4,16 +4,19 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/sipeed/picoclaw/pkg/security"
 )
 
-// EditFileTool edits a file by replacing old_text with new_text.
-// The old_text must exist exactly in the file.
+// EditFileTool edits a file by replacing old_string with new_string.
+// The old_string must occur exactly once in the file unless replace_all is
+// set. old_text and new_text are accepted as older names for the two.
 type EditFileTool struct {
 	allowedDir   string
 	restrict     bool
+	roots        map[string]string
 	pathMode     security.PolicyMode
 	policyEngine *security.PolicyEngine
 	channel      string
@@ -29,7 +32,7 @@ func NewEditFileTool(allowedDir string, restrict bool) *EditFileTool {
 }
 
 func NewEditFileToolWithPolicy(allowedDir string, restrict bool, opts PathPolicyOpts) *EditFileTool {
-	return &EditFileTool{allowedDir: allowedDir, restrict: restrict, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+	return &EditFileTool{allowedDir: allowedDir, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
 }
 
 func (t *EditFileTool) SetContext(channel, chatID string) {
@@ -42,7 +45,7 @@ func (t *EditFileTool) Name() string {
 }
 
 func (t *EditFileTool) Description() string {
-	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file."
+	return "Edit a file by replacing old_string with new_string instead of rewriting it. old_string must match exactly once, so include enough surrounding lines to make it unique, or set replace_all to change every occurrence."
 }
 
 func (t *EditFileTool) Parameters() map[string]interface{} {
@@ -53,16 +56,24 @@ func (t *EditFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The file path to edit",
 			},
-			"old_text": map[string]interface{}{
+			"old_string": map[string]interface{}{
 				"type":        "string",
 				"description": "The exact text to find and replace",
 			},
-			"new_text": map[string]interface{}{
+			"new_string": map[string]interface{}{
 				"type":        "string",
 				"description": "The text to replace with",
 			},
+			"replace_all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace every occurrence of old_string instead of requiring exactly one (default false)",
+			},
+			"if_match": map[string]interface{}{
+				"type":        "string",
+				"description": "Only edit if the file's SHA-256 equals this hash (from read_file include_hash); otherwise fail with a conflict",
+			},
 		},
-		"required": []string{"path", "old_text", "new_text"},
+		"required": []string{"path", "old_string", "new_string"},
 	}
 }
 
@@ -72,22 +83,29 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("path is required")
 	}
 
-	oldText, ok := args["old_text"].(string)
-	if !ok {
-		return ErrorResult("old_text is required")
+	oldText, ok := stringArg(args, "old_string", "old_text")
+	if !ok || oldText == "" {
+		return ErrorResult("old_string is required")
 	}
 
-	newText, ok := args["new_text"].(string)
+	newText, ok := stringArg(args, "new_string", "new_text")
 	if !ok {
-		return ErrorResult("new_text is required")
+		return ErrorResult("new_string is required")
 	}
+	replaceAll, _ := args["replace_all"].(bool)
 
-	resolvedPath, err := validatePathWithMode(path, t.allowedDir, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	resolvedPath, err := validatePathWithMode(path, t.allowedDir, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
-	info, err := os.Stat(resolvedPath)
+	// Write through symlinks, as write_file does.
+	target := resolvedPath
+	if real, err := filepath.EvalSymlinks(resolvedPath); err == nil {
+		target = real
+	}
+
+	info, err := os.Stat(target)
 	if os.IsNotExist(err) {
 		return ErrorResult(fmt.Sprintf("file not found: %s", path))
 	}
@@ -98,34 +116,57 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 	// Preserve original file permissions
 	perm := info.Mode().Perm()
 
-	content, err := os.ReadFile(resolvedPath)
+	ifMatch, _ := args["if_match"].(string)
+	if err := checkIfMatch(resolvedPath, path, ifMatch); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	content, err := os.ReadFile(target)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
 
 	contentStr := string(content)
 
-	if !strings.Contains(contentStr, oldText) {
-		return ErrorResult("old_text not found in file. Make sure it matches exactly")
-	}
-
 	count := strings.Count(contentStr, oldText)
-	if count > 1 {
-		return ErrorResult(fmt.Sprintf("old_text appears %d times. Please provide more context to make it unique", count))
+	if count == 0 {
+		return ErrorResult(fmt.Sprintf("old_string not found in %s. Make sure it matches exactly, including whitespace and indentation", path))
+	}
+	if count > 1 && !replaceAll {
+		return ErrorResult(fmt.Sprintf("old_string appears %d times in %s. Please provide more context to make it unique, or set replace_all", count, path))
 	}
 
-	newContent := strings.Replace(contentStr, oldText, newText, 1)
+	newContent := strings.ReplaceAll(contentStr, oldText, newText)
 
-	if err := os.WriteFile(resolvedPath, []byte(newContent), perm); err != nil {
-		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
+	if err := writeFileAtomic(target, []byte(newContent), perm); err != nil {
+		return writeErrorResult(err, path)
 	}
 
+	if count > 1 {
+		return SilentResult(fmt.Sprintf("File edited: %s (%d replacements)", path, count))
+	}
 	return SilentResult(fmt.Sprintf("File edited: %s", path))
 }
 
+func (t *EditFileTool) argAliases() map[string][]string {
+	return map[string][]string{"old_string": {"old_text"}, "new_string": {"new_text"}}
+}
+
+// stringArg returns the first of keys present in args as a string, so a
+// renamed parameter can still be given under its old name.
+func stringArg(args map[string]interface{}, keys ...string) (string, bool) {
+	for _, key := range keys {
+		if v, ok := args[key].(string); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
 type AppendFileTool struct {
 	workspace    string
 	restrict     bool
+	roots        map[string]string
 	pathMode     security.PolicyMode
 	policyEngine *security.PolicyEngine
 	channel      string
@@ -137,7 +178,7 @@ func NewAppendFileTool(workspace string, restrict bool) *AppendFileTool {
 }
 
 func NewAppendFileToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *AppendFileTool {
-	return &AppendFileTool{workspace: workspace, restrict: restrict, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+	return &AppendFileTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
 }
 
 func (t *AppendFileTool) SetContext(channel, chatID string) {
@@ -181,7 +222,7 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePathWithMode(path, t.workspace, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -321,3 +321,92 @@ func TestNewAppendFileToolWithPolicy(t *testing.T) {
 		t.Error("WithPolicy constructor did not set fields correctly")
 	}
 }
+
+// TestEditTool_EditFile_IfMatchConflict verifies an edit with a stale hash is rejected
+func TestEditTool_EditFile_IfMatchConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	os.WriteFile(testFile, []byte("Hello World"), 0644)
+	hash, _ := hashFile(testFile)
+
+	// Simulate an external process changing the file after it was read.
+	os.WriteFile(testFile, []byte("Hello World!"), 0644)
+
+	tool := NewEditFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":     testFile,
+		"old_text": "World",
+		"new_text": "Universe",
+		"if_match": hash,
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "conflict") {
+		t.Errorf("Expected conflict error, got: %s", result.ForLLM)
+	}
+
+	current, _ := hashFile(testFile)
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path":     testFile,
+		"old_text": "World",
+		"new_text": "Universe",
+		"if_match": "sha256:" + current,
+	})
+	if result.IsError {
+		t.Errorf("Expected matching hash to succeed, got: %s", result.ForLLM)
+	}
+}
+
+// TestEditTool_EditFile_OldStringNewString verifies the old_string/new_string
+// parameters and that the file keeps its mode
+func TestEditTool_EditFile_OldStringNewString(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.conf")
+	os.WriteFile(testFile, []byte("port=80\nhost=a\n"), 0600)
+
+	result := NewEditFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path":       "app.conf",
+		"old_string": "port=80",
+		"new_string": "port=8080",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "port=8080\nhost=a\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+	if info, _ := os.Stat(testFile); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+// TestEditTool_EditFile_ReplaceAll verifies replace_all replaces every
+// occurrence, and that without it the error reports the match count
+func TestEditTool_EditFile_ReplaceAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.go")
+	os.WriteFile(testFile, []byte("foo()\nbar()\nfoo()\n"), 0644)
+	tool := NewEditFileTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path": "main.go", "old_string": "foo()", "new_string": "baz()",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "appears 2 times") {
+		t.Errorf("Expected an ambiguity error with the count, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path": "main.go", "old_string": "foo()", "new_string": "baz()", "replace_all": true,
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "2 replacements") {
+		t.Errorf("Expected success with 2 replacements, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "baz()\nbar()\nbaz()\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path": "main.go", "old_string": "foo()", "new_string": "baz()", "replace_all": true,
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "old_string not found") {
+		t.Errorf("Expected a not-found error, got: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Error codes classify why a tool call, or one item of a multi-target call,
+// failed, so the LLM can tell a retry-worthy failure from a permanent one
+// without parsing the message.
+const (
+	CodeNotFound         = "not_found"
+	CodePermissionDenied = "permission_denied"
+	CodePolicyDenied     = "policy_denied"
+	CodeInvalidArgument  = "invalid_argument"
+	CodeUnsupported      = "unsupported"
+	CodeTooLarge         = "too_large"
+	CodeDiskFull         = "disk_full"
+	CodeIO               = "io_error"
+)
+
+// errorCode classifies a filesystem error.
+func errorCode(err error) string {
+	var dfe *DiskFullError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return CodePermissionDenied
+	case errors.As(err, &dfe):
+		return CodeDiskFull
+	default:
+		return CodeIO
+	}
+}
+
+// WithCode sets the error code and returns the result for chaining.
+func (tr *ToolResult) WithCode(code string) *ToolResult {
+	tr.Code = code
+	return tr
+}
@@ -0,0 +1,345 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// commandInfo describes a well-known binary for command explanations.
+// Modifier flags read as adjectives before the verb ("recursive force
+// delete"); note flags are listed after it.
+type commandInfo struct {
+	verb      string
+	risk      int // 0 low, 1 medium, 2 high
+	modifiers map[string]string
+	notes     map[string]string
+}
+
+const (
+	riskLow = iota
+	riskMedium
+	riskHigh
+)
+
+var riskNames = []string{"low", "medium", "high"}
+
+var knownCommands = map[string]commandInfo{
+	"rm": {verb: "delete", risk: riskHigh,
+		modifiers: map[string]string{"r": "recursive", "R": "recursive", "recursive": "recursive", "f": "force", "force": "force"},
+		notes:     map[string]string{"i": "asks before each removal", "v": "lists removed files"}},
+	"rmdir": {verb: "remove directories", risk: riskMedium},
+	"mv":    {verb: "move/rename", risk: riskMedium, modifiers: map[string]string{"f": "force"}, notes: map[string]string{"n": "never overwrites"}},
+	"cp":    {verb: "copy", risk: riskLow, modifiers: map[string]string{"r": "recursive", "R": "recursive", "a": "archive", "f": "force"}},
+	"dd":    {verb: "raw block copy", risk: riskHigh},
+	"mkfs":  {verb: "create a filesystem (erases the device)", risk: riskHigh},
+	"chmod": {verb: "change permissions", risk: riskMedium, modifiers: map[string]string{"R": "recursive"}},
+	"chown": {verb: "change ownership", risk: riskMedium, modifiers: map[string]string{"R": "recursive"}},
+	"kill":  {verb: "signal processes", risk: riskMedium, notes: map[string]string{"9": "SIGKILL, cannot be caught"}},
+	"pkill": {verb: "signal processes by name", risk: riskMedium},
+	"curl": {verb: "transfer data over the network", risk: riskMedium,
+		modifiers: map[string]string{"s": "silent", "L": "redirect-following", "k": "insecure (no TLS verification)"},
+		notes: map[string]string{"d": "sends data", "data": "sends data", "F": "uploads a form", "T": "uploads a file",
+			"X": "custom request method", "o": "writes to a file", "O": "writes to a file"}},
+	"wget": {verb: "download over the network", risk: riskMedium,
+		notes: map[string]string{"post-data": "sends data", "post-file": "uploads a file", "O": "writes to a file"}},
+	"ssh":      {verb: "open a remote shell", risk: riskMedium},
+	"scp":      {verb: "copy files to/from a remote host", risk: riskMedium},
+	"nc":       {verb: "open a raw network connection", risk: riskHigh},
+	"git":      {verb: "run git", risk: riskLow, modifiers: map[string]string{"f": "force", "force": "force"}},
+	"sudo":     {verb: "run as root", risk: riskHigh},
+	"shutdown": {verb: "shut down the machine", risk: riskHigh},
+	"reboot":   {verb: "reboot the machine", risk: riskHigh},
+	"ls":       {verb: "list files", risk: riskLow, modifiers: map[string]string{"l": "long", "a": "all-files", "R": "recursive"}},
+	"cat":      {verb: "print files", risk: riskLow},
+	"grep":     {verb: "search text", risk: riskLow, modifiers: map[string]string{"r": "recursive", "R": "recursive", "i": "case-insensitive"}},
+	"find":     {verb: "search for files", risk: riskLow, notes: map[string]string{"delete": "deletes matches", "exec": "runs a command on matches"}},
+	"echo":     {verb: "print text", risk: riskLow},
+	"sh":       {verb: "run a shell", risk: riskMedium, notes: map[string]string{"c": "runs the given script"}},
+	"bash":     {verb: "run a shell", risk: riskMedium, notes: map[string]string{"c": "runs the given script"}},
+	"python":   {verb: "run Python", risk: riskMedium},
+	"python3":  {verb: "run Python", risk: riskMedium},
+	"node":     {verb: "run Node.js", risk: riskMedium},
+	"make":     {verb: "run build targets", risk: riskLow},
+	"go":       {verb: "run the Go toolchain", risk: riskLow},
+	"npm":      {verb: "run npm", risk: riskMedium, notes: map[string]string{"g": "installs globally"}},
+	"pip":      {verb: "manage Python packages", risk: riskMedium},
+	"docker":   {verb: "run docker", risk: riskMedium},
+}
+
+// gitSubcommandRisk raises the risk of git subcommands that publish or
+// discard work.
+var gitSubcommandRisk = map[string]int{"push": riskMedium, "reset": riskMedium, "clean": riskHigh}
+
+// commandStep is one simple command of a pipeline or command list.
+type commandStep struct {
+	Binary    string
+	Args      []string
+	Modifiers []string
+	Notes     []string
+	Summary   string
+	Risk      int
+}
+
+// CommandExplanation is a plain-language breakdown of a shell command.
+type CommandExplanation struct {
+	Command    string
+	Steps      []commandStep
+	Constructs []string // notable shell features, e.g. command substitution
+	Rules      []string // deny patterns the command matches
+	NotAllowed bool     // an allowlist is configured and the command is not on it
+	Risk       int
+}
+
+// splitShellWords splits a command line into words and operators ("|", "&&",
+// "||", ";", "&"), honouring single and double quotes. It is deliberately
+// simple: it explains commands, it does not execute them.
+func splitShellWords(command string) []string {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	flush := func() {
+		if inWord {
+			words = append(words, cur.String())
+			cur.Reset()
+			inWord = false
+		}
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case r == '|' || r == '&' || r == ';':
+			flush()
+			op := string(r)
+			if (r == '|' || r == '&') && i+1 < len(runes) && runes[i+1] == r {
+				op += string(r)
+				i++
+			}
+			words = append(words, op)
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	flush()
+	return words
+}
+
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// explainStep describes one simple command.
+func explainStep(words []string) commandStep {
+	for len(words) > 0 && envAssignment.MatchString(words[0]) {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return commandStep{}
+	}
+
+	step := commandStep{Binary: filepath.Base(words[0]), Risk: riskLow}
+	info, known := knownCommands[step.Binary]
+	if known {
+		step.Risk = info.risk
+	}
+
+	seen := map[string]bool{}
+	add := func(list *[]string, desc string) {
+		if !seen[desc] {
+			seen[desc] = true
+			*list = append(*list, desc)
+		}
+	}
+	lookup := func(flag string) {
+		if d, ok := info.modifiers[flag]; ok {
+			add(&step.Modifiers, d)
+		} else if d, ok := info.notes[flag]; ok {
+			add(&step.Notes, d)
+		}
+	}
+	for _, w := range words[1:] {
+		switch {
+		case strings.HasPrefix(w, "--") && len(w) > 2:
+			name, _, _ := strings.Cut(w[2:], "=")
+			lookup(name)
+		case strings.HasPrefix(w, "-") && len(w) > 1:
+			// Clustered short flags: -rf is -r -f. "-9" style flags are
+			// looked up whole as well.
+			lookup(w[1:])
+			for _, c := range w[1:] {
+				lookup(string(c))
+			}
+		default:
+			step.Args = append(step.Args, w)
+		}
+	}
+
+	if step.Binary == "git" && len(step.Args) > 0 {
+		if r, ok := gitSubcommandRisk[step.Args[0]]; ok {
+			step.Risk = max(step.Risk, r)
+		}
+	}
+	if step.Binary == "sudo" && len(step.Args) > 0 {
+		inner := explainStep(step.Args)
+		inner.Summary = "as root: " + inner.Summary
+		inner.Risk = riskHigh
+		inner.Binary = "sudo " + inner.Binary
+		return inner
+	}
+
+	verb := "run " + step.Binary
+	if known {
+		verb = info.verb
+	}
+	step.Summary = verb
+	if len(step.Modifiers) > 0 {
+		step.Summary = strings.Join(step.Modifiers, " ") + " " + verb
+	}
+	if len(step.Args) > 0 {
+		step.Summary += " of " + strings.Join(step.Args, " ")
+	}
+	if len(step.Notes) > 0 {
+		step.Summary += " (" + strings.Join(step.Notes, ", ") + ")"
+	}
+	return step
+}
+
+// shellConstructs are shell features worth pointing out in an explanation.
+var shellConstructs = []struct {
+	pattern *regexp.Regexp
+	desc    string
+}{
+	{regexp.MustCompile(`\$\(|` + "`"), "command substitution (runs a nested command)"},
+	{regexp.MustCompile(`\|\s*(sh|bash|zsh)\b`), "pipes data into a shell"},
+	{regexp.MustCompile(`(^|[^>])>\s*[^>&\s]`), "overwrites a file with output"},
+	{regexp.MustCompile(`>>`), "appends output to a file"},
+	{regexp.MustCompile(`(^|[^&>])&\s*($|[^&>])`), "runs something in the background"},
+}
+
+// ExplainCommand breaks command into steps and reports which deny patterns it
+// matches and whether an allowlist admits it.
+func ExplainCommand(command string, denyPatterns, allowPatterns []*regexp.Regexp) CommandExplanation {
+	exp := CommandExplanation{Command: strings.TrimSpace(command)}
+
+	var cur []string
+	flushStep := func() {
+		if step := explainStep(cur); step.Binary != "" {
+			exp.Steps = append(exp.Steps, step)
+			exp.Risk = max(exp.Risk, step.Risk)
+		}
+		cur = nil
+	}
+	for _, w := range splitShellWords(exp.Command) {
+		switch w {
+		case "|", "||", "&&", ";", "&":
+			flushStep()
+		default:
+			cur = append(cur, w)
+		}
+	}
+	flushStep()
+
+	for _, c := range shellConstructs {
+		if c.pattern.MatchString(exp.Command) {
+			exp.Constructs = append(exp.Constructs, c.desc)
+		}
+	}
+
+	lower := strings.ToLower(exp.Command)
+	for _, p := range denyPatterns {
+		if p.MatchString(lower) {
+			exp.Rules = append(exp.Rules, p.String())
+		}
+	}
+	if len(exp.Rules) > 0 {
+		exp.Risk = riskHigh
+	}
+	if len(allowPatterns) > 0 {
+		exp.NotAllowed = true
+		for _, p := range allowPatterns {
+			if p.MatchString(lower) {
+				exp.NotAllowed = false
+				break
+			}
+		}
+	}
+	return exp
+}
+
+// Format renders the explanation as plain text.
+func (e CommandExplanation) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Command: %s\n", e.Command)
+	if len(e.Steps) == 1 {
+		fmt.Fprintf(&b, "What it does: %s: %s\n", e.Steps[0].Binary, e.Steps[0].Summary)
+	} else {
+		b.WriteString("What it does:\n")
+		for i, s := range e.Steps {
+			fmt.Fprintf(&b, "%d. %s: %s\n", i+1, s.Binary, s.Summary)
+		}
+	}
+	for _, c := range e.Constructs {
+		fmt.Fprintf(&b, "Note: %s\n", c)
+	}
+	if len(e.Rules) > 0 {
+		fmt.Fprintf(&b, "Matches deny rule(s): %s\n", strings.Join(e.Rules, ", "))
+	}
+	if e.NotAllowed {
+		b.WriteString("Not on the exec allowlist\n")
+	}
+	fmt.Fprintf(&b, "Risk: %s", riskNames[e.Risk])
+	return b.String()
+}
+
+// ExplainCommandTool explains a shell command against the exec tool's guard
+// rules without running it.
+type ExplainCommandTool struct {
+	exec *ExecTool
+}
+
+func NewExplainCommandTool(exec *ExecTool) *ExplainCommandTool {
+	return &ExplainCommandTool{exec: exec}
+}
+
+func (t *ExplainCommandTool) Name() string {
+	return "explain_command"
+}
+
+func (t *ExplainCommandTool) Description() string {
+	return "Explain what a shell command would do without running it: the programs it runs and their notable flags, shell features such as pipes into a shell, the exec guard rules it matches and an overall risk level."
+}
+
+func (t *ExplainCommandTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "The shell command to explain",
+			},
+		},
+		"required": []string{"command"},
+	}
+}
+
+func (t *ExplainCommandTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	command, ok := args["command"].(string)
+	if !ok || strings.TrimSpace(command) == "" {
+		return ErrorResult("command is required")
+	}
+	return NewToolResult(t.exec.Explain(command).Format())
+}
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// maxFileInfoBytes bounds how much of a file is inspected.
+const maxFileInfoBytes = 16 * 1024 * 1024
+
+// FileInfoTool reports the text conventions of a file (encoding, BOM, line
+// endings, trailing newline) so edits can preserve them.
+type FileInfoTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewFileInfoTool(workspace string, restrict bool) *FileInfoTool {
+	return &FileInfoTool{workspace: workspace, restrict: restrict}
+}
+
+func NewFileInfoToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *FileInfoTool {
+	return &FileInfoTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *FileInfoTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *FileInfoTool) Name() string {
+	return "file_info"
+}
+
+func (t *FileInfoTool) Description() string {
+	return "Report a text file's encoding, byte order mark, dominant line ending (LF/CRLF/CR), line count and whether it ends with a newline. Check this before editing so the file's conventions can be kept (see write_file's ensure_final_newline)."
+}
+
+func (t *FileInfoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// textStats describes the conventions of a text file.
+type textStats struct {
+	Encoding        string
+	BOM             string // "", "UTF-8", "UTF-16LE" or "UTF-16BE"
+	Binary          bool
+	LF, CRLF, CR    int
+	Lines           int
+	TrailingNewline bool
+}
+
+// LineEnding names the dominant line ending, "mixed" when no style accounts
+// for all line breaks, or "none" for a single line.
+func (s textStats) LineEnding() string {
+	total := s.LF + s.CRLF + s.CR
+	switch {
+	case total == 0:
+		return "none"
+	case s.CRLF == total:
+		return "CRLF"
+	case s.LF == total:
+		return "LF"
+	case s.CR == total:
+		return "CR"
+	}
+	dominant, n := "LF", s.LF
+	if s.CRLF > n {
+		dominant, n = "CRLF", s.CRLF
+	}
+	if s.CR > n {
+		dominant = "CR"
+	}
+	return "mixed, mostly " + dominant
+}
+
+// analyzeText inspects raw file bytes.
+func analyzeText(data []byte) textStats {
+	var s textStats
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		s.BOM = "UTF-8"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		s.BOM = "UTF-16LE"
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		s.BOM = "UTF-16BE"
+	}
+
+	var text string
+	switch {
+	case s.BOM == "UTF-8":
+		s.Encoding = "utf-8"
+		text = string(data[3:])
+	case s.BOM != "":
+		s.Encoding = strings.ToLower(s.BOM)
+		text, _, _, _ = decodeText(data, "")
+	case utf8.Valid(data):
+		s.Encoding = "utf-8"
+		if isASCII(data) {
+			s.Encoding = "ascii"
+		}
+		text = string(data)
+	case bytes.IndexByte(data, 0) >= 0:
+		s.Encoding = "binary"
+		s.Binary = true
+		return s
+	default:
+		decoded, used, _, _ := decodeText(data, "")
+		s.Encoding = used + " (guessed)"
+		text = decoded
+	}
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			s.LF++
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				s.CRLF++
+				i++
+			} else {
+				s.CR++
+			}
+		}
+	}
+	s.TrailingNewline = strings.HasSuffix(text, "\n") || strings.HasSuffix(text, "\r")
+	s.Lines = s.LF + s.CRLF + s.CR
+	if text != "" && !s.TrailingNewline {
+		s.Lines++
+	}
+	return s
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *FileInfoTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok {
+		return ErrorResult("path is required")
+	}
+
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if kind, err := specialFileKind(resolvedPath); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat file: %v", err))
+	} else if kind != "" {
+		return ErrorResult(fmt.Sprintf("%s is a %s, not a regular file", path, kind))
+	}
+
+	f, err := os.Open(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open file: %v", err))
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat file: %v", err))
+	}
+	if info.IsDir() {
+		return ErrorResult(fmt.Sprintf("%s is a directory", path))
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxFileInfoBytes))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+
+	s := analyzeText(data)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %d bytes\n", path, info.Size())
+	if info.Size() > maxFileInfoBytes {
+		fmt.Fprintf(&sb, "(only the first %d bytes were inspected)\n", maxFileInfoBytes)
+	}
+	fmt.Fprintf(&sb, "Encoding: %s\n", s.Encoding)
+	if s.Binary {
+		sb.WriteString("File contains NUL bytes and looks binary; line statistics skipped")
+		return NewToolResult(sb.String())
+	}
+	bom := "none"
+	if s.BOM != "" {
+		bom = s.BOM
+	}
+	fmt.Fprintf(&sb, "BOM: %s\n", bom)
+	fmt.Fprintf(&sb, "Line endings: %s (LF %d, CRLF %d, CR %d)\n", s.LineEnding(), s.LF, s.CRLF, s.CR)
+	fmt.Fprintf(&sb, "Lines: %d\n", s.Lines)
+	trailing := "no"
+	if s.TrailingNewline {
+		trailing = "yes"
+	}
+	fmt.Fprintf(&sb, "Trailing newline: %s", trailing)
+	return NewToolResult(sb.String())
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFileInfoTool_CRLFWithBOM verifies a UTF-8 BOM and CRLF endings are
+// detected
+func TestFileInfoTool_CRLFWithBOM(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "win.txt"), []byte("\xEF\xBB\xBFfirst\r\nsecond\r\n"), 0644)
+
+	tool := NewFileInfoTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "win.txt"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{"Encoding: utf-8\n", "BOM: UTF-8\n", "Line endings: CRLF (LF 0, CRLF 2, CR 0)", "Lines: 2\n", "Trailing newline: yes"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, result.ForLLM)
+		}
+	}
+}
+
+// TestFileInfoTool_LFNoTrailingNewline verifies plain LF files without a final
+// newline are reported as such
+func TestFileInfoTool_LFNoTrailingNewline(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "unix.txt"), []byte("a\nb\nc"), 0644)
+
+	tool := NewFileInfoTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "unix.txt"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{"Encoding: ascii\n", "BOM: none\n", "Line endings: LF (LF 2, CRLF 0, CR 0)", "Lines: 3\n", "Trailing newline: no"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, result.ForLLM)
+		}
+	}
+}
+
+// TestAnalyzeText_Variants verifies mixed endings, UTF-16 and binary input
+func TestAnalyzeText_Variants(t *testing.T) {
+	if got := analyzeText([]byte("a\r\nb\nc\r\n")).LineEnding(); got != "mixed, mostly CRLF" {
+		t.Errorf("mixed endings: got %q", got)
+	}
+
+	utf16 := []byte{0xFF, 0xFE, 'h', 0, 'i', 0, '\r', 0, '\n', 0}
+	s := analyzeText(utf16)
+	if s.Encoding != "utf-16le" || s.BOM != "UTF-16LE" || s.CRLF != 1 || !s.TrailingNewline {
+		t.Errorf("utf-16: got %+v", s)
+	}
+
+	if s := analyzeText([]byte{0x89, 'P', 'N', 'G', 0, 0}); !s.Binary {
+		t.Errorf("expected binary, got %+v", s)
+	}
+}
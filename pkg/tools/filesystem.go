@@ -2,10 +2,20 @@ package tools
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"github.com/sipeed/picoclaw/pkg/security"
 )
@@ -14,15 +24,56 @@ import (
 // When pathMode is "off", only basic prefix check is performed (no symlink resolution).
 // When pathMode is "block" or "approve", enhanced symlink resolution is used.
 func validatePath(path, workspace string, restrict bool) (string, error) {
-	return validatePathWithMode(path, workspace, restrict, security.ModeOff, nil, "", "")
+	return validatePathWithMode(path, workspace, nil, restrict, security.ModeOff, nil, "", "")
+}
+
+// normalizePath tidies a path as written by the model before it is checked:
+// NUL bytes and other control characters are rejected, backslashes are taken
+// as separators, and duplicate separators, "." elements and trailing slashes
+// are cleaned away. An empty path stays empty.
+func normalizePath(path string) (string, error) {
+	for _, r := range path {
+		if r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf("invalid path %q: contains a control character", path)
+		}
+	}
+	if path == "" {
+		return "", nil
+	}
+	return filepath.Clean(filepath.FromSlash(strings.ReplaceAll(path, `\`, "/"))), nil
 }
 
 // validatePathWithMode is the full-featured path validator with policy support.
-func validatePathWithMode(path, workspace string, restrict bool, pathMode security.PolicyMode, pe *security.PolicyEngine, channel, chatID string) (string, error) {
+// The path is normalized first, so every filesystem tool accepts the same
+// spellings and rejects the same malformed input. roots are the named roots
+// from PathPolicyOpts.Roots.
+func validatePathWithMode(path, workspace string, roots map[string]string, restrict bool, pathMode security.PolicyMode, pe *security.PolicyEngine, channel, chatID string) (string, error) {
+	// "name:path" targets a named root, which is always enforced as the
+	// boundary for that path. The prefix is split off before cleaning, so
+	// ".." in the rest can never cancel it.
+	root, rest, err := resolveRoot(path, roots)
+	if err != nil {
+		return "", err
+	}
+	if root != "" {
+		path = rest
+	}
+	path, err = normalizePath(path)
+	if err != nil {
+		return "", err
+	}
+	if root != "" {
+		if filepath.IsAbs(path) || !isWithinWorkspace(filepath.Join(root, path), root) {
+			return "", fmt.Errorf("access denied: %s is outside the workspace root", path)
+		}
+		workspace, restrict = root, true
+	}
 	if workspace == "" {
 		return path, nil
 	}
 
+	pathMode = pe.EffectiveMode("path_validation", pathMode, channel, chatID)
+
 	absWorkspace, err := filepath.Abs(workspace)
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve workspace path: %w", err)
@@ -38,6 +89,12 @@ func validatePathWithMode(path, workspace string, restrict bool, pathMode securi
 		}
 	}
 
+	// Denied paths are checked as requested and, below, as resolved, so a
+	// symlink cannot be used to reach one.
+	if err := checkDeniedPaths(path, absPath, absWorkspace, pathMode, pe, channel, chatID); err != nil {
+		return "", err
+	}
+
 	if restrict {
 		useSymlinkResolution := !pathMode.IsOff()
 
@@ -50,14 +107,24 @@ func validatePathWithMode(path, workspace string, restrict bool, pathMode securi
 
 		realPath := absPath
 		if useSymlinkResolution {
+			var resolveErr error
 			if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
 				realPath = resolved
 			} else if os.IsNotExist(err) {
 				if parentResolved, e2 := resolveExistingAncestor(filepath.Dir(absPath)); e2 == nil {
 					realPath = filepath.Join(parentResolved, filepath.Base(absPath))
+				} else {
+					resolveErr = e2
 				}
-			} else if resolved, err := filepath.EvalSymlinks(filepath.Dir(absPath)); err == nil {
+			} else if resolved, e2 := filepath.EvalSymlinks(filepath.Dir(absPath)); e2 == nil {
 				realPath = filepath.Join(resolved, filepath.Base(absPath))
+			} else {
+				resolveErr = e2
+			}
+			// Lenient mode falls back to the unresolved path; strict mode refuses
+			// to guess where an unresolvable symlink might point.
+			if resolveErr != nil && pe.StrictSymlinks() {
+				return "", fmt.Errorf("access denied: cannot resolve symlinks for %s: %v", path, resolveErr)
 			}
 		}
 
@@ -82,6 +149,11 @@ func validatePathWithMode(path, workspace string, restrict bool, pathMode securi
 			}
 		}
 
+		if realPath != absPath {
+			if err := checkDeniedPaths(path, realPath, realWorkspace, pathMode, pe, channel, chatID); err != nil {
+				return "", err
+			}
+		}
 		absPath = realPath
 	}
 
@@ -106,27 +178,212 @@ func isWithinWorkspace(candidate, workspace string) bool {
 	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
 }
 
+// writeTemp writes the payload of an atomic write. Tests replace it to
+// simulate a full disk.
+var writeTemp = func(f *os.File, data []byte) (int, error) {
+	return f.Write(data)
+}
+
+// DiskFullError reports a write that failed because the device is out of
+// space (ENOSPC) or the user's quota is exhausted (EDQUOT).
+type DiskFullError struct {
+	Path string
+	Code string // "ENOSPC" or "EDQUOT"
+	Err  error
+}
+
+func (e *DiskFullError) Error() string {
+	reason := "no space left on device"
+	if e.Code == "EDQUOT" {
+		reason = "disk quota exceeded"
+	}
+	return fmt.Sprintf("failed to write file %s: %s (%s); free up space and retry", e.Path, reason, e.Code)
+}
+
+func (e *DiskFullError) Unwrap() error { return e.Err }
+
+// classifyWriteError wraps ENOSPC and EDQUOT failures in a DiskFullError and
+// returns any other error unchanged.
+func classifyWriteError(err error, path string) error {
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		return &DiskFullError{Path: path, Code: "ENOSPC", Err: err}
+	case errors.Is(err, syscall.EDQUOT):
+		return &DiskFullError{Path: path, Code: "EDQUOT", Err: err}
+	}
+	return err
+}
+
+// writeErrorResult turns a failed write into a tool result, giving disk-full
+// errors a message the user sees directly.
+func writeErrorResult(err error, path string) *ToolResult {
+	err = classifyWriteError(err, path)
+	var dfe *DiskFullError
+	if errors.As(err, &dfe) {
+		result := ErrorResult(dfe.Error()).WithError(dfe)
+		result.ForUser = fmt.Sprintf("Could not write %s: the disk is full (%s).", path, dfe.Code)
+		return result
+	}
+	return ErrorResult(fmt.Sprintf("failed to write file: %v", err)).WithError(err)
+}
+
+// writeFileAtomic writes data to a temporary file in the target directory and
+// renames it into place, so readers never observe a partially written file.
+// The data is synced before the rename so a crash cannot leave the new name
+// pointing at an empty file; the temp file is removed if anything fails.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := writeTemp(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// checkIfMatch implements optimistic concurrency for writes: when ifMatch is
+// set, the file at path must exist and its SHA-256 must equal ifMatch.
+func checkIfMatch(path, displayPath, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+	want := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ifMatch), "sha256:"))
+	got, err := hashFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("conflict: %s no longer exists; re-read it before writing", displayPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hash file: %v", err)
+	}
+	if got != want {
+		return fmt.Errorf("conflict: %s was modified since it was read (expected sha256 %s, found %s); re-read it before writing", displayPath, want, got)
+	}
+	return nil
+}
+
 // PathPolicyOpts holds optional security policy settings for filesystem tools.
 type PathPolicyOpts struct {
 	PathMode     security.PolicyMode
 	PolicyEngine *security.PolicyEngine
+	// MaxAffectedFiles caps the entries a bulk tool may touch in one call.
+	// 0 means unlimited.
+	MaxAffectedFiles int
+	// FSRetries is how many times read_file, write_file and list_dir retry a
+	// transient error such as ESTALE. 0 disables retries.
+	FSRetries int
+	// MaxLineLength is the longest line line-based reads return, in bytes.
+	// 0 means DefaultMaxLineLength.
+	MaxLineLength int
+	// MaxReadBytes is how much of a file read_file returns; longer files are
+	// truncated with a notice. 0 means DefaultMaxReadBytes.
+	MaxReadBytes int
+	// MaxArchiveBytes and MaxArchiveRatio are the uncompressed size and
+	// compression ratio past which list_archive warns of a zip bomb. 0 means
+	// DefaultMaxArchiveBytes and DefaultMaxArchiveRatio.
+	MaxArchiveBytes int64
+	MaxArchiveRatio int
+	// Roots are named directories besides the workspace, from
+	// ResolveWorkspaceRoots. A "name:path" path resolves inside that root.
+	Roots map[string]string
+	// ShowHidden makes list_dir, glob and repo_summary include dot-prefixed
+	// entries unless a call passes show_hidden itself.
+	ShowHidden bool
+}
+
+// isHidden reports whether name is a dotfile or dot directory.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// showHiddenArg returns the show_hidden argument of a listing call, or def
+// when the call does not set it.
+func showHiddenArg(args map[string]interface{}, def bool) bool {
+	if v, ok := args["show_hidden"].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// showHiddenParam is the show_hidden parameter shared by the listing tools.
+func showHiddenParam(def bool) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "boolean",
+		"description": fmt.Sprintf("Include entries whose names start with '.' (default %t)", def),
+	}
 }
 
 type ReadFileTool struct {
-	workspace    string
-	restrict     bool
-	pathMode     security.PolicyMode
-	policyEngine *security.PolicyEngine
-	channel      string
-	chatID       string
+	workspace     string
+	restrict      bool
+	roots         map[string]string
+	fsys          fs.FS // nil reads the OS filesystem
+	pathMode      security.PolicyMode
+	policyEngine  *security.PolicyEngine
+	fsRetries     int
+	maxLineLength int
+	maxReadBytes  int
+	channel       string
+	chatID        string
 }
 
+// DefaultMaxReadBytes is how much of a file read_file returns when
+// tools.max_read_bytes is not set.
+const DefaultMaxReadBytes = 1024 * 1024
+
 func NewReadFileTool(workspace string, restrict bool) *ReadFileTool {
 	return &ReadFileTool{workspace: workspace, restrict: restrict}
 }
 
 func NewReadFileToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *ReadFileTool {
-	return &ReadFileTool{workspace: workspace, restrict: restrict, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+	return &ReadFileTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, fsRetries: opts.FSRetries, maxLineLength: opts.MaxLineLength, maxReadBytes: opts.MaxReadBytes}
+}
+
+// NewReadFileToolWithFS reads from fsys instead of the OS filesystem, e.g. an
+// embedded, archive-backed or in-memory tree. Paths are names relative to the
+// root of fsys and cannot leave it; denied_paths still apply.
+func NewReadFileToolWithFS(fsys fs.FS, opts PathPolicyOpts) *ReadFileTool {
+	t := NewReadFileToolWithPolicy("", true, opts)
+	t.fsys = fsys
+	return t
+}
+
+// files returns the filesystem the tool reads from.
+func (t *ReadFileTool) files() fs.FS {
+	if t.fsys == nil {
+		return osFS{}
+	}
+	return t.fsys
+}
+
+// resolve validates path and returns its name in t.files().
+func (t *ReadFileTool) resolve(path string) (string, error) {
+	if t.fsys == nil {
+		return validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	}
+	name, err := fsName(path)
+	if err != nil {
+		return "", err
+	}
+	if err := checkDeniedName(t.fsys, path, name, t.pathMode, t.policyEngine, t.channel, t.chatID); err != nil {
+		return "", err
+	}
+	return name, nil
 }
 
 func (t *ReadFileTool) SetContext(channel, chatID string) {
@@ -150,35 +407,369 @@ func (t *ReadFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": fmt.Sprintf("Read several files in one call instead of path (at most %d). Each file succeeds or fails on its own, with an error code per file, and the other options apply to every file", maxMultiRead),
+			},
+			"encoding": map[string]interface{}{
+				"type":        "string",
+				"description": "Source charset (e.g. 'gbk', 'latin1', 'utf-16le'). Default: auto-detect; content is always returned as UTF-8",
+			},
+			"include_hash": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Prefix the content with the file's SHA-256, for use as if_match in write_file or edit_file. Default: false",
+			},
+			"force": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Read a FIFO, device or socket anyway, up to 64 KiB. May block until data arrives. Default: false",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"text", "jsonl"},
+				"description": "'jsonl' treats the file as newline-delimited JSON and returns a window of valid records, one per line, reporting malformed lines. Use for large data files. Default: text",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "jsonl only: index of the first record to return (0-based, malformed lines are not counted). Default: 0",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "jsonl only: maximum number of records to return. Default: 50, max: 1000",
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "text only: first line to return (1-based). Reads just the requested lines of a large UTF-8 file",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "text only: last line to return (inclusive). Default: start_line + 199",
+			},
 		},
-		"required": []string{"path"},
 	}
 }
 
+// maxMultiRead caps the files a single read_file call may read with paths.
+const maxMultiRead = 20
+
+// readMany reads each of paths with the other arguments of the call. Files
+// fail independently; the result lists every file's outcome and code so the
+// failed ones can be retried alone.
+func (t *ReadFileTool) readMany(ctx context.Context, paths []interface{}, args map[string]interface{}) *ToolResult {
+	if len(paths) == 0 {
+		return ErrorResult("paths must list at least one file").WithCode(CodeInvalidArgument)
+	}
+	if len(paths) > maxMultiRead {
+		return ErrorResult(fmt.Sprintf("paths lists %d files, above the limit of %d per call; split it into smaller calls", len(paths), maxMultiRead)).WithCode(CodeInvalidArgument)
+	}
+
+	items := make([]ItemResult, 0, len(paths))
+	var body strings.Builder
+	var failed []string
+	for i, raw := range paths {
+		path, ok := raw.(string)
+		if !ok || path == "" {
+			path = fmt.Sprintf("paths[%d]", i)
+			items = append(items, ItemResult{Target: path, Code: CodeInvalidArgument, Error: "not a file path"})
+			failed = append(failed, path)
+			fmt.Fprintf(&body, "\n=== %s [%s] ===\nnot a file path\n", path, CodeInvalidArgument)
+			continue
+		}
+
+		single := make(map[string]interface{}, len(args))
+		for k, v := range args {
+			single[k] = v
+		}
+		delete(single, "paths")
+		single["path"] = path
+
+		result := t.Execute(ctx, single)
+		if result.IsError {
+			code := result.Code
+			if code == "" {
+				code = CodeIO
+			}
+			items = append(items, ItemResult{Target: path, Code: code, Error: result.ForLLM})
+			failed = append(failed, path)
+			fmt.Fprintf(&body, "\n=== %s [%s] ===\n%s\n", path, code, result.ForLLM)
+			continue
+		}
+		items = append(items, ItemResult{Target: path, OK: true})
+		fmt.Fprintf(&body, "\n=== %s ===\n%s\n", path, result.ForLLM)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Read %d of %d file(s)", len(paths)-len(failed), len(paths))
+	if len(failed) > 0 {
+		fmt.Fprintf(&sb, "; %d failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(body.String())
+	return BatchResult(sb.String(), items)
+}
+
+// maxSpecialFileRead bounds a forced read of a non-regular file, which may
+// otherwise produce data forever (e.g. /dev/zero).
+const maxSpecialFileRead = 64 * 1024
+
+// specialFileKind describes path if it is not a regular file or directory,
+// and returns "" otherwise. Symlinks are judged by their target.
+func specialFileKind(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if info, err = os.Stat(path); err != nil {
+			return "", err
+		}
+	}
+
+	return modeKind(info.Mode()), nil
+}
+
+// modeKind describes mode if it is not a regular file or directory, and
+// returns "" otherwise.
+func modeKind(mode fs.FileMode) string {
+	switch {
+	case mode.IsRegular(), mode.IsDir():
+		return ""
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe (FIFO)"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "block device"
+	default:
+		return "special file"
+	}
+}
+
+// readSpecialFile reads at most maxSpecialFileRead bytes from name in fsys.
+func readSpecialFile(fsys fs.FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxSpecialFileRead))
+}
+
+// readFileHead reads at most max bytes of name in fsys and reports the
+// file's full size. Files within the limit are read whole, through readFile
+// for the OS filesystem like other reads; longer ones are never loaded whole.
+func readFileHead(fsys fs.FS, name string, max int64) ([]byte, int64, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() <= max {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, 0, err
+		}
+		// The file may have grown since the stat.
+		return content[:min(int64(len(content)), max)], int64(len(content)), nil
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, max))
+	return content, info.Size(), err
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence that truncation left at
+// the end of b, so the text is not mistaken for another encoding.
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
 func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if paths, ok := args["paths"].([]interface{}); ok {
+		return t.readMany(ctx, paths, args)
+	}
+
 	path, ok := args["path"].(string)
 	if !ok {
-		return ErrorResult("path is required")
+		return ErrorResult("path is required").WithCode(CodeInvalidArgument)
 	}
 
-	resolvedPath, err := validatePathWithMode(path, t.workspace, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	resolvedPath, err := t.resolve(path)
 	if err != nil {
-		return ErrorResult(err.Error())
+		return ErrorResult(err.Error()).WithCode(CodePolicyDenied)
+	}
+
+	kind, err := fsSpecialKind(t.files(), resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err)).WithCode(errorCode(err))
+	}
+
+	if kind != "" {
+		if force, _ := args["force"].(bool); !force {
+			return ErrorResult(fmt.Sprintf("refusing to read %s: it is a %s, not a regular file, and reading it may block or never end (set force to read up to %d bytes)", path, kind, maxSpecialFileRead)).WithCode(CodeUnsupported)
+		}
+	}
+
+	switch format, _ := args["format"].(string); format {
+	case "", "text":
+	case "jsonl":
+		return t.readJSONLFile(resolvedPath, kind != "", args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown format %q (expected text or jsonl)", format)).WithCode(CodeInvalidArgument)
+	}
+
+	_, hasStart := args["start_line"]
+	_, hasEnd := args["end_line"]
+	if hasStart || hasEnd {
+		return t.readLineRange(resolvedPath, kind != "", args)
+	}
+
+	maxBytes := int64(t.maxReadBytes)
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxReadBytes
+	}
+	var content []byte
+	var size int64
+	if kind != "" {
+		content, err = readSpecialFile(t.files(), resolvedPath)
+		size = int64(len(content))
+	} else {
+		err = retryFS(t.fsRetries, func() error {
+			content, size, err = readFileHead(t.files(), resolvedPath, maxBytes)
+			return err
+		})
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err)).WithCode(errorCode(err))
+	}
+	truncated := size > int64(len(content))
+
+	if truncated {
+		content = trimPartialRune(content)
+	}
+	shown := len(content)
+
+	encodingName, _ := args["encoding"].(string)
+	text, used, guessed, err := decodeText(content, encodingName)
+	if err != nil {
+		return ErrorResult(err.Error()).WithCode(CodeInvalidArgument)
+	}
+	if guessed {
+		text = fmt.Sprintf("[file is not UTF-8; decoded as %s (guessed)]\n", used) + text
+	}
+	if includeHash, _ := args["include_hash"].(bool); includeHash {
+		// The hash always covers the whole file, for use as if_match.
+		var digest string
+		if truncated {
+			if digest, err = hashFSFile(t.files(), resolvedPath); err != nil {
+				return ErrorResult(fmt.Sprintf("failed to hash file: %v", err)).WithCode(errorCode(err))
+			}
+		} else {
+			sum := sha256.Sum256(content)
+			digest = hex.EncodeToString(sum[:])
+		}
+		text = fmt.Sprintf("[sha256: %s]\n", digest) + text
+	}
+	if truncated {
+		text += fmt.Sprintf("\n[truncated: showing %d of %d bytes; use start_line/end_line to read further]", shown, size)
+	}
+
+	return NewToolResult(text)
+}
+
+// readJSONLFile serves format=jsonl, streaming the file instead of reading
+// it whole.
+func (t *ReadFileTool) readJSONLFile(resolvedPath string, special bool, args map[string]interface{}) *ToolResult {
+	offset := 0
+	if o, ok := args["offset"].(float64); ok {
+		offset = max(int(o), 0)
+	}
+	limit := defaultJSONLLimit
+	if l, ok := args["limit"].(float64); ok {
+		limit = min(max(int(l), 1), maxJSONLLimit)
+	}
+
+	f, err := t.files().Open(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err)).WithCode(errorCode(err))
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if special {
+		r = io.LimitReader(f, maxSpecialFileRead)
+	}
+
+	page, err := readJSONL(r, offset, limit)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read jsonl: %v", err)).WithCode(errorCode(err))
+	}
+	return NewToolResult(page.Format())
+}
+
+// defaultLineRange is how many lines a line-range read returns when only
+// start_line is given.
+const defaultLineRange = 200
+
+// readLineRange serves start_line/end_line, streaming the file so only the
+// requested lines are held in memory.
+func (t *ReadFileTool) readLineRange(resolvedPath string, special bool, args map[string]interface{}) *ToolResult {
+	if enc, _ := args["encoding"].(string); enc != "" {
+		return ErrorResult("start_line/end_line read UTF-8 text and cannot be combined with encoding").WithCode(CodeInvalidArgument)
+	}
+	start := 1
+	if s, ok := args["start_line"].(float64); ok {
+		start = int(s)
+	}
+	end := start + defaultLineRange - 1
+	if e, ok := args["end_line"].(float64); ok {
+		end = int(e)
+	}
+	if start < 1 || end < start {
+		return ErrorResult(fmt.Sprintf("invalid line range %d-%d: start_line must be at least 1 and end_line not before it", start, end)).WithCode(CodeInvalidArgument)
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	f, err := t.files().Open(resolvedPath)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err)).WithCode(errorCode(err))
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if special {
+		r = io.LimitReader(f, maxSpecialFileRead)
 	}
 
-	return NewToolResult(string(content))
+	page, err := readLineRange(r, start, end, t.maxLineLength)
+	if err != nil {
+		code := errorCode(err)
+		var tooLong *lineTooLongError
+		if errors.As(err, &tooLong) {
+			code = CodeTooLarge
+		}
+		return ErrorResult(fmt.Sprintf("failed to read lines: %v", err)).WithCode(code)
+	}
+	return NewToolResult(page.Format())
 }
 
 type WriteFileTool struct {
 	workspace    string
 	restrict     bool
+	roots        map[string]string
 	pathMode     security.PolicyMode
 	policyEngine *security.PolicyEngine
+	fsRetries    int
 	channel      string
 	chatID       string
 }
@@ -188,7 +779,7 @@ func NewWriteFileTool(workspace string, restrict bool) *WriteFileTool {
 }
 
 func NewWriteFileToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *WriteFileTool {
-	return &WriteFileTool{workspace: workspace, restrict: restrict, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+	return &WriteFileTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, fsRetries: opts.FSRetries}
 }
 
 func (t *WriteFileTool) SetContext(channel, chatID string) {
@@ -216,8 +807,37 @@ func (t *WriteFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Content to write to the file",
 			},
+			"content_ref": map[string]interface{}{
+				"type":        "string",
+				"description": "Path of a staging file whose contents are written instead of content. Use for large payloads built up with append_file or other tools",
+			},
+			"ensure_final_newline": map[string]interface{}{
+				"type":        "boolean",
+				"description": "End the file with exactly one newline. Default: false (write bytes as given)",
+			},
+			"strip_trailing_whitespace": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Remove trailing spaces and tabs from every line. Default: false",
+			},
+			"if_match": map[string]interface{}{
+				"type":        "string",
+				"description": "Only write if the existing file's SHA-256 equals this hash (from read_file include_hash); otherwise fail with a conflict",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Write content at this byte offset in place, keeping the rest of the file (like pwrite). Writing past the end extends the file, zero-filling any gap. Default: replace the whole file",
+			},
+			"content_encoding": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"text", "hex", "base64"},
+				"description": "How content is encoded; use hex or base64 for binary data. Default: text",
+			},
+			"backup": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Copy an existing file to <path>.bak (or a timestamped .bak if that exists) before writing; the result names the backup. Default: false",
+			},
 		},
-		"required": []string{"path", "content"},
+		"required": []string{"path"},
 	}
 }
 
@@ -227,33 +847,222 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		return ErrorResult("path is required")
 	}
 
-	content, ok := args["content"].(string)
-	if !ok {
+	content, hasContent := args["content"].(string)
+	contentRef, _ := args["content_ref"].(string)
+	switch {
+	case hasContent && contentRef != "":
+		return ErrorResult("provide either content or content_ref, not both")
+	case !hasContent && contentRef == "":
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePathWithMode(path, t.workspace, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
+	if contentRef != "" {
+		refPath, err := validatePathWithMode(contentRef, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid content_ref: %v", err))
+		}
+		data, err := os.ReadFile(refPath)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read content_ref: %v", err))
+		}
+		content = string(data)
+	}
+
+	contentEncoding, _ := args["content_encoding"].(string)
+	data, err := decodeContent(content, contentEncoding)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	stripTrailing, _ := args["strip_trailing_whitespace"].(bool)
+	ensureNewline, _ := args["ensure_final_newline"].(bool)
+	offsetArg, hasOffset := args["offset"].(float64)
+	if (stripTrailing || ensureNewline) && (hasOffset || contentEncoding == "hex" || contentEncoding == "base64") {
+		return ErrorResult("strip_trailing_whitespace and ensure_final_newline only apply to whole-file text writes")
+	}
+	if !hasOffset {
+		data = []byte(normalizeContent(string(data), stripTrailing, ensureNewline))
+	}
+	// An offset write is checked before anything touches the disk, so a
+	// rejected call leaves no backup or directory behind.
+	offset := int64(offsetArg)
+	if hasOffset {
+		if offsetArg < 0 || float64(offset) != offsetArg {
+			return ErrorResult("offset must be a non-negative integer")
+		}
+		if offset+int64(len(data)) > maxOffsetWriteSize {
+			return ErrorResult(fmt.Sprintf("writing %d bytes at offset %d would grow %s beyond %d bytes", len(data), offset, path, int64(maxOffsetWriteSize)))
+		}
+	}
+
+	ifMatch, _ := args["if_match"].(string)
+	if err := checkIfMatch(resolvedPath, path, ifMatch); err != nil {
+		return ErrorResult(err.Error())
+	}
+
 	dir := filepath.Dir(resolvedPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
 	}
 
-	if err := os.WriteFile(resolvedPath, []byte(content), 0600); err != nil {
-		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
+	// Write through symlinks and keep the mode of an existing file, as a
+	// plain in-place write would; new files are created private.
+	target := resolvedPath
+	if real, err := filepath.EvalSymlinks(resolvedPath); err == nil {
+		target = real
+	}
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	var backupNote string
+	if backup, _ := args["backup"].(bool); backup {
+		backupPath, err := t.backupFile(path, target, perm)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to back up %s: %v", path, err))
+		}
+		if backupPath != "" {
+			backupNote = fmt.Sprintf(" (backup of previous content: %s)", backupPath)
+		}
+	}
+
+	if hasOffset {
+		err = retryFS(t.fsRetries, func() error {
+			return writeFileAt(target, data, offset, perm)
+		})
+		if err != nil {
+			return writeErrorResult(err, path)
+		}
+		return SilentResult(fmt.Sprintf("Wrote %d bytes at offset %d: %s%s", len(data), offset, path, backupNote)).WithArtifact(target)
+	}
+
+	// The atomic write leaves the previous content intact if the disk fills
+	// up part way through.
+	err = retryFS(t.fsRetries, func() error {
+		return writeFileAtomic(target, data, perm)
+	})
+	if err != nil {
+		return writeErrorResult(err, path)
 	}
 
-	return SilentResult(fmt.Sprintf("File written: %s", path))
+	return SilentResult(fmt.Sprintf("File written: %s%s", path, backupNote)).WithArtifact(target)
+}
+
+// backupFile copies the file at target to path.bak, or to a timestamped
+// .bak when that name is taken, and returns the backup path as the caller
+// wrote it. The backup path is validated like any other write, so a
+// symlink or denied path cannot redirect it. A missing file needs no backup.
+func (t *WriteFileTool) backupFile(path, target string, perm os.FileMode) (string, error) {
+	data, err := readFile(target)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	name := path + ".bak"
+	resolved, err := validatePathWithMode(name, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(resolved); err == nil {
+		name = path + "." + time.Now().UTC().Format("20060102T150405.000Z") + ".bak"
+		if resolved, err = validatePathWithMode(name, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFileAtomic(resolved, data, perm); err != nil {
+		return "", classifyWriteError(err, name)
+	}
+	return name, nil
+}
+
+// maxOffsetWriteSize bounds the size a file may reach through an offset write.
+const maxOffsetWriteSize = 1 << 30
+
+// writeFileAt writes data at offset in place, creating the file if needed.
+// Bytes outside the written range are kept; a gap past the old end of the
+// file reads back as zeros.
+func writeFileAt(path string, data []byte, offset int64, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, offset); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// decodeContent turns write_file's content into bytes according to encoding.
+func decodeContent(content, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "text":
+		return []byte(content), nil
+	case "hex":
+		data, err := hex.DecodeString(strings.Join(strings.Fields(content), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex content: %v", err)
+		}
+		return data, nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %v", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown content_encoding %q (expected text, hex or base64)", encoding)
+	}
+}
+
+// normalizeContent optionally strips trailing spaces/tabs from each line and
+// collapses any trailing newlines into exactly one. CRLF line endings are kept.
+// Empty content is returned unchanged.
+func normalizeContent(content string, stripTrailing, ensureNewline bool) string {
+	if content == "" {
+		return content
+	}
+
+	if stripTrailing {
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			if strings.HasSuffix(line, "\r") {
+				lines[i] = strings.TrimRight(line[:len(line)-1], " \t") + "\r"
+			} else {
+				lines[i] = strings.TrimRight(line, " \t")
+			}
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	if ensureNewline {
+		eol := "\n"
+		if strings.Contains(content, "\r\n") {
+			eol = "\r\n"
+		}
+		content = strings.TrimRight(content, "\r\n") + eol
+	}
+
+	return content
 }
 
 type ListDirTool struct {
 	workspace    string
 	restrict     bool
+	roots        map[string]string
+	fsys         fs.FS // nil lists the OS filesystem
 	pathMode     security.PolicyMode
 	policyEngine *security.PolicyEngine
+	fsRetries    int
+	showHidden   bool
 	channel      string
 	chatID       string
 }
@@ -263,7 +1072,38 @@ func NewListDirTool(workspace string, restrict bool) *ListDirTool {
 }
 
 func NewListDirToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *ListDirTool {
-	return &ListDirTool{workspace: workspace, restrict: restrict, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+	return &ListDirTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, fsRetries: opts.FSRetries, showHidden: opts.ShowHidden}
+}
+
+// NewListDirToolWithFS lists fsys instead of the OS filesystem, with the same
+// path rules as NewReadFileToolWithFS.
+func NewListDirToolWithFS(fsys fs.FS, opts PathPolicyOpts) *ListDirTool {
+	t := NewListDirToolWithPolicy("", true, opts)
+	t.fsys = fsys
+	return t
+}
+
+// files returns the filesystem the tool lists.
+func (t *ListDirTool) files() fs.FS {
+	if t.fsys == nil {
+		return osFS{}
+	}
+	return t.fsys
+}
+
+// resolve validates path and returns its name in t.files().
+func (t *ListDirTool) resolve(path string) (string, error) {
+	if t.fsys == nil {
+		return validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	}
+	name, err := fsName(path)
+	if err != nil {
+		return "", err
+	}
+	if err := checkDeniedName(t.fsys, path, name, t.pathMode, t.policyEngine, t.channel, t.chatID); err != nil {
+		return "", err
+	}
+	return name, nil
 }
 
 func (t *ListDirTool) SetContext(channel, chatID string) {
@@ -276,7 +1116,7 @@ func (t *ListDirTool) Name() string {
 }
 
 func (t *ListDirTool) Description() string {
-	return "List files and directories in a path"
+	return fmt.Sprintf("List files and directories in a path, sorted by name. Use offset and limit to page through large directories, or recursive with max_depth for an indented tree (at most %d entries).", maxListDirEntries)
 }
 
 func (t *ListDirTool) Parameters() map[string]interface{} {
@@ -287,6 +1127,27 @@ func (t *ListDirTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to list",
 			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of entries to skip (default 0)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum entries to return. Default: all",
+			},
+			"recursive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "List subdirectories too, indenting nested entries (default false). Cannot be combined with offset/limit",
+			},
+			"max_depth": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("With recursive, how many levels to list; 1 is just path itself (default %d)", defaultListDirDepth),
+			},
+			"details": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Append each entry's size in bytes and modification time (RFC3339, UTC) (default false)",
+			},
+			"show_hidden": showHiddenParam(t.showHidden),
 		},
 		"required": []string{"path"},
 	}
@@ -298,24 +1159,215 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 		path = "."
 	}
 
-	resolvedPath, err := validatePathWithMode(path, t.workspace, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	resolvedPath, err := t.resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
-	entries, err := os.ReadDir(resolvedPath)
+	offset, limit := 0, 0
+	if o, ok := args["offset"].(float64); ok {
+		if o < 0 {
+			return ErrorResult("offset must not be negative")
+		}
+		offset = int(o)
+	}
+	if l, ok := args["limit"].(float64); ok {
+		if l < 1 {
+			return ErrorResult("limit must be at least 1")
+		}
+		limit = int(l)
+	}
+	_, hasOffset := args["offset"]
+	paged := hasOffset || limit > 0
+	details, _ := args["details"].(bool)
+
+	if recursive, _ := args["recursive"].(bool); recursive {
+		if paged {
+			return ErrorResult("offset and limit cannot be combined with recursive")
+		}
+		maxDepth := defaultListDirDepth
+		if d, ok := args["max_depth"].(float64); ok {
+			if d < 1 {
+				return ErrorResult("max_depth must be at least 1")
+			}
+			maxDepth = int(d)
+		}
+		return t.listTree(resolvedPath, maxDepth, showHiddenArg(args, t.showHidden), details)
+	}
+
+	// fs.ReadDir sorts by file name, which keeps pages stable between calls.
+	var entries []os.DirEntry
+	err = retryFS(t.fsRetries, func() error {
+		entries, err = fs.ReadDir(t.files(), resolvedPath)
+		return err
+	})
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read directory: %v", err))
 	}
+	if !showHiddenArg(args, t.showHidden) {
+		visible := entries[:0]
+		for _, entry := range entries {
+			if !isHidden(entry.Name()) {
+				visible = append(visible, entry)
+			}
+		}
+		entries = visible
+	}
 
-	result := ""
-	for _, entry := range entries {
-		if entry.IsDir() {
-			result += "DIR:  " + entry.Name() + "\n"
+	total := len(entries)
+	start := min(offset, total)
+	end := total
+	if limit > 0 {
+		end = min(start+limit, total)
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries[start:end] {
+		sb.WriteString(dirEntryLine(entry, entry.IsDir(), details))
+	}
+
+	if paged {
+		switch {
+		case start == end:
+			fmt.Fprintf(&sb, "[no entries at offset %d; directory has %d entries]\n", offset, total)
+		case end < total:
+			fmt.Fprintf(&sb, "[entries %d-%d of %d; more remain, next offset=%d]\n", start+1, end, total, end)
+		default:
+			fmt.Fprintf(&sb, "[entries %d-%d of %d; no more entries]\n", start+1, end, total)
+		}
+	}
+
+	return NewToolResult(sb.String())
+}
+
+// dirEntryLine formats one list_dir entry. With details, the size in bytes and
+// modification time from entry.Info() follow the name; an entry whose info
+// cannot be read (e.g. it was deleted mid-listing) is marked instead.
+func dirEntryLine(entry os.DirEntry, isDir, details bool) string {
+	line := "FILE: " + entry.Name()
+	if isDir {
+		line = "DIR:  " + entry.Name()
+	}
+	if details {
+		if info, err := entry.Info(); err != nil {
+			line += " (info unavailable)"
 		} else {
-			result += "FILE: " + entry.Name() + "\n"
+			line += fmt.Sprintf(" (%d bytes, modified %s)", info.Size(), info.ModTime().UTC().Format(time.RFC3339))
 		}
 	}
+	return line + "\n"
+}
+
+const (
+	// defaultListDirDepth is how many levels a recursive listing shows when
+	// max_depth is not given.
+	defaultListDirDepth = 3
+	// maxListDirEntries caps how many entries a recursive listing returns.
+	maxListDirEntries = 1000
+)
+
+// dirTree accumulates a recursive listing.
+type dirTree struct {
+	t          *ListDirTool
+	sb         strings.Builder
+	workspace  string // resolved, for the symlink check
+	maxDepth   int
+	showHidden bool
+	details    bool
+	// visited holds the resolved directories already listed, so a symlink
+	// loop or a second link to the same directory is not walked again.
+	visited   map[string]bool
+	entries   int
+	truncated bool
+}
+
+// listTree lists root and its subdirectories up to maxDepth levels, one
+// indent per level. Every directory is re-validated before it is entered, so
+// a symlink inside the tree cannot lead the walk outside the workspace.
+func (t *ListDirTool) listTree(root string, maxDepth int, showHidden, details bool) *ToolResult {
+	tree := &dirTree{t: t, workspace: resolvedWorkspace(t.workspace), maxDepth: maxDepth, showHidden: showHidden, details: details, visited: map[string]bool{root: true}}
+	if t.fsys == nil {
+		if real, err := filepath.EvalSymlinks(root); err == nil {
+			tree.visited[real] = true
+		}
+	}
+	if err := tree.walk(root, 0); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read directory: %v", err))
+	}
+	if tree.truncated {
+		fmt.Fprintf(&tree.sb, "[listing truncated at %d entries; lower max_depth or list a subdirectory]\n", maxListDirEntries)
+	}
+	return NewToolResult(tree.sb.String())
+}
+
+func (d *dirTree) walk(dir string, depth int) error {
+	var entries []os.DirEntry
+	err := retryFS(d.t.fsRetries, func() error {
+		var err error
+		entries, err = fs.ReadDir(d.t.files(), dir)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	indent := strings.Repeat("  ", depth)
+	for _, entry := range entries {
+		if !d.showHidden && isHidden(entry.Name()) {
+			continue
+		}
+		if d.entries == maxListDirEntries {
+			d.truncated = true
+			return nil
+		}
+		d.entries++
 
-	return NewToolResult(result)
+		child := filepath.Join(dir, entry.Name())
+		if d.t.fsys != nil {
+			child = path.Join(dir, entry.Name())
+		}
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := fs.Stat(d.t.files(), child); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		d.sb.WriteString(indent + dirEntryLine(entry, isDir, d.details))
+		if !isDir {
+			continue
+		}
+		if depth+1 >= d.maxDepth {
+			continue
+		}
+
+		resolved, err := d.t.resolve(child)
+		if err != nil {
+			d.sb.WriteString(indent + "  (not listed: " + err.Error() + ")\n")
+			continue
+		}
+		real := resolved
+		if d.t.fsys == nil {
+			if r, err := filepath.EvalSymlinks(resolved); err == nil {
+				real = r
+			}
+		}
+		// validatePathWithMode only resolves symlinks when path_validation
+		// is on; the walk never follows one out of the workspace either way.
+		if d.t.fsys == nil && d.t.restrict && d.workspace != "" && !isWithinWorkspace(real, d.workspace) {
+			d.sb.WriteString(indent + "  (not listed: symlink resolves outside workspace)\n")
+			continue
+		}
+		if d.visited[real] {
+			d.sb.WriteString(indent + "  (not listed: symlink loop or directory already shown)\n")
+			continue
+		}
+		d.visited[real] = true
+		if err := d.walk(resolved, depth+1); err != nil {
+			d.sb.WriteString(indent + "  (not listed: " + err.Error() + ")\n")
+		}
+		if d.truncated {
+			return nil
+		}
+	}
+	return nil
 }
@@ -1,12 +1,19 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/security"
 )
 
@@ -250,6 +257,151 @@ func TestFilesystemTool_ListDir_DefaultPath(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_ListDir_ShowHidden verifies dotfiles are excluded from
+// listings and paging counts by default and included with show_hidden
+func TestFilesystemTool_ListDir_ShowHidden(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("x"), 0644)
+	os.Mkdir(filepath.Join(tmpDir, ".cache"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("x"), 0644)
+
+	result := NewListDirTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": ".", "limit": float64(10)})
+	if result.ForLLM != "FILE: main.go\n[entries 1-1 of 1; no more entries]\n" {
+		t.Errorf("Expected dotfiles to be excluded by default, got:\n%s", result.ForLLM)
+	}
+
+	want := "DIR:  .cache\nFILE: .env\nFILE: main.go\n"
+	result = NewListDirToolWithPolicy(tmpDir, true, PathPolicyOpts{ShowHidden: true}).Execute(context.Background(), map[string]interface{}{"path": "."})
+	if result.ForLLM != want {
+		t.Errorf("Expected dotfiles with tools.show_hidden, got:\n%s", result.ForLLM)
+	}
+	result = NewListDirTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": ".", "show_hidden": true})
+	if result.ForLLM != want {
+		t.Errorf("Expected dotfiles with show_hidden, got:\n%s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ListDir_Recursive verifies recursive listings indent
+// nested entries and stop at max_depth
+func TestFilesystemTool_ListDir_Recursive(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "a", "b", "c"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "a", "b", "c", "deep.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "a", "one.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "top.txt"), []byte("x"), 0644)
+	tool := NewListDirTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": ".", "recursive": true})
+	want := "DIR:  a\n  DIR:  b\n    DIR:  c\n  FILE: one.txt\nFILE: top.txt\n"
+	if result.ForLLM != want {
+		t.Errorf("Unexpected tree at default depth:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": ".", "recursive": true, "max_depth": float64(1)})
+	if result.ForLLM != "DIR:  a\nFILE: top.txt\n" {
+		t.Errorf("Expected only the top level with max_depth 1, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": ".", "recursive": true, "limit": float64(5)})
+	if !result.IsError {
+		t.Errorf("Expected recursive with limit to be rejected, got: %s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ListDir_RecursiveCap verifies a recursive listing stops
+// at maxListDirEntries with a note
+func TestFilesystemTool_ListDir_RecursiveCap(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		dir := filepath.Join(tmpDir, fmt.Sprintf("d%d", i))
+		os.Mkdir(dir, 0755)
+		for j := 0; j < maxListDirEntries/2; j++ {
+			os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%04d", j)), nil, 0644)
+		}
+	}
+
+	result := NewListDirTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": ".", "recursive": true})
+	lines := strings.Split(strings.TrimSuffix(result.ForLLM, "\n"), "\n")
+	if len(lines) != maxListDirEntries+1 || !strings.HasPrefix(lines[len(lines)-1], "[listing truncated at 1000 entries") {
+		t.Errorf("Expected %d entries and a truncation note, got %d lines ending %q", maxListDirEntries, len(lines), lines[len(lines)-1])
+	}
+}
+
+// TestFilesystemTool_ListDir_RecursiveSymlinks verifies the walk neither
+// follows a symlink out of the workspace nor loops on a symlink cycle
+func TestFilesystemTool_ListDir_RecursiveSymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("x"), 0644)
+	os.Mkdir(filepath.Join(tmpDir, "sub"), 0755)
+	if err := os.Symlink(outside, filepath.Join(tmpDir, "escape")); err != nil {
+		t.Skipf("Cannot create symlink: %v", err)
+	}
+	os.Symlink(tmpDir, filepath.Join(tmpDir, "sub", "loop"))
+
+	for _, mode := range []security.PolicyMode{security.ModeOff, security.ModeBlock} {
+		tool := NewListDirToolWithPolicy(tmpDir, true, PathPolicyOpts{PathMode: mode})
+		result := tool.Execute(context.Background(), map[string]interface{}{"path": ".", "recursive": true, "max_depth": float64(10)})
+		if result.IsError || strings.Contains(result.ForLLM, "secret.txt") {
+			t.Errorf("mode %s: expected the escaping symlink not to be followed, got:\n%s", mode, result.ForLLM)
+		}
+		if !strings.Contains(result.ForLLM, "    (not listed: symlink loop or directory already shown)") {
+			t.Errorf("mode %s: expected the loop to be cut, got:\n%s", mode, result.ForLLM)
+		}
+	}
+}
+
+// TestFilesystemTool_ListDir_Details verifies details appends size and
+// mtime only when asked, in flat and recursive listings
+func TestFilesystemTool_ListDir_Details(t *testing.T) {
+	tmpDir := t.TempDir()
+	mtime := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	os.Mkdir(filepath.Join(tmpDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "sub", "a.txt"), []byte("hello"), 0644)
+	os.Chtimes(filepath.Join(tmpDir, "sub", "a.txt"), mtime, mtime)
+	tool := NewListDirTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "sub"})
+	if result.ForLLM != "FILE: a.txt\n" {
+		t.Errorf("Expected the default format to be unchanged, got: %q", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "sub", "details": true})
+	if result.ForLLM != "FILE: a.txt (5 bytes, modified 2025-03-10T12:00:00Z)\n" {
+		t.Errorf("Unexpected details: %q", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": ".", "recursive": true, "details": true})
+	if !strings.HasPrefix(result.ForLLM, "DIR:  sub (") || !strings.Contains(result.ForLLM, "\n  FILE: a.txt (5 bytes, modified 2025-03-10T12:00:00Z)\n") {
+		t.Errorf("Unexpected recursive details:\n%s", result.ForLLM)
+	}
+}
+
+// goneDirEntry is a directory entry whose file disappeared after listing.
+type goneDirEntry struct{ os.DirEntry }
+
+func (goneDirEntry) Info() (os.FileInfo, error) { return nil, os.ErrNotExist }
+
+// TestFilesystemTool_ListDir_DetailsInfoError verifies an entry whose info
+// cannot be read is marked rather than failing the listing
+func TestFilesystemTool_ListDir_DetailsInfoError(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "b.txt"), []byte("xy"), 0644)
+	orig := readDir
+	t.Cleanup(func() { readDir = orig })
+	readDir = func(name string) ([]os.DirEntry, error) {
+		entries, err := orig(name)
+		if err == nil {
+			entries[0] = goneDirEntry{entries[0]}
+		}
+		return entries, err
+	}
+
+	result := NewListDirTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": ".", "details": true})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "FILE: a.txt (info unavailable)\nFILE: b.txt (2 bytes, modified ") {
+		t.Errorf("Expected a per-entry marker, got: %q", result.ForLLM)
+	}
+}
+
 // TestValidatePath_SymlinkEscape verifies that symlinks pointing outside workspace are blocked
 // when path validation mode is "block" (enhanced symlink resolution).
 func TestValidatePath_SymlinkEscape(t *testing.T) {
@@ -263,12 +415,62 @@ func TestValidatePath_SymlinkEscape(t *testing.T) {
 		t.Skipf("Cannot create symlink: %v", err)
 	}
 
-	_, err := validatePathWithMode("escape/secret.txt", workspace, true, security.ModeBlock, nil, "", "")
+	_, err := validatePathWithMode("escape/secret.txt", workspace, nil, true, security.ModeBlock, nil, "", "")
 	if err == nil {
 		t.Error("Expected symlink escape to be blocked, but it was allowed")
 	}
 }
 
+// TestNormalizePath verifies messy model-written paths are cleaned and
+// control characters rejected
+func TestNormalizePath(t *testing.T) {
+	cases := map[string]string{
+		"./foo//bar":      filepath.FromSlash("foo/bar"),
+		"foo/bar/":        filepath.FromSlash("foo/bar"),
+		`foo\bar\baz.txt`: filepath.FromSlash("foo/bar/baz.txt"),
+		"a/./b/../c":      filepath.FromSlash("a/c"),
+		"":                "",
+	}
+	for in, want := range cases {
+		got, err := normalizePath(in)
+		if err != nil || got != want {
+			t.Errorf("normalizePath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"foo\x00.txt", "foo\nbar", "\x1b[31m"} {
+		if _, err := normalizePath(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestValidatePath_NormalizesMessyPaths verifies tools accept messy but valid
+// paths and refuse a NUL byte before touching the filesystem
+func TestValidatePath_NormalizesMessyPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "foo", "bar"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "foo", "bar", "a.txt"), []byte("hello"), 0644)
+	tool := NewReadFileTool(tmpDir, true)
+
+	for _, p := range []string{"./foo//bar/a.txt", `foo\bar\a.txt`, "foo/./bar/a.txt"} {
+		result := tool.Execute(context.Background(), map[string]interface{}{"path": p})
+		if result.IsError || result.ForLLM != "hello" {
+			t.Errorf("Expected %q to read foo/bar/a.txt, got: %s", p, result.ForLLM)
+		}
+	}
+	if _, err := validatePath("foo/bar/", tmpDir, true); err != nil {
+		t.Errorf("Expected a trailing slash to be accepted, got: %v", err)
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "foo/bar/a.txt\x00.png"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "control character") {
+		t.Errorf("Expected a NUL byte to be rejected, got: %s", result.ForLLM)
+	}
+	if _, err := validatePath("..\\escape", tmpDir, true); err == nil {
+		t.Error("Expected a backslash traversal to be blocked")
+	}
+}
+
 func TestValidatePath_PrefixCollision(t *testing.T) {
 	baseDir := t.TempDir()
 	workspace := filepath.Join(baseDir, "workspace")
@@ -285,7 +487,7 @@ func TestValidatePath_PrefixCollision(t *testing.T) {
 func TestValidatePath_AllowsWorkspaceItself(t *testing.T) {
 	workspace := t.TempDir()
 
-	path, err := validatePathWithMode(".", workspace, true, security.ModeBlock, nil, "", "")
+	path, err := validatePathWithMode(".", workspace, nil, true, security.ModeBlock, nil, "", "")
 	if err != nil {
 		t.Errorf("Expected workspace root access to be allowed, got error: %v", err)
 	}
@@ -311,7 +513,7 @@ func TestValidatePath_ModeOff_NoSymlinkResolution(t *testing.T) {
 	testFile := filepath.Join(workspace, "file.txt")
 	os.WriteFile(testFile, []byte("data"), 0644)
 
-	path, err := validatePathWithMode("file.txt", workspace, true, security.ModeOff, nil, "", "")
+	path, err := validatePathWithMode("file.txt", workspace, nil, true, security.ModeOff, nil, "", "")
 	if err != nil {
 		t.Errorf("Expected success, got: %v", err)
 	}
@@ -320,6 +522,35 @@ func TestValidatePath_ModeOff_NoSymlinkResolution(t *testing.T) {
 	}
 }
 
+// TestValidatePath_UnresolvableParent verifies a path under a symlink loop is
+// checked unresolved in lenient mode and denied in strict mode
+func TestValidatePath_UnresolvableParent(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.Symlink("loop", filepath.Join(workspace, "loop")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	lenient := security.NewPolicyEngine(&config.SecurityConfig{}, nil)
+	if _, err := validatePathWithMode("loop/file.txt", workspace, nil, true, security.ModeBlock, lenient, "", ""); err != nil {
+		t.Errorf("Lenient mode should fall back to the unresolved path, got: %v", err)
+	}
+
+	strict := security.NewPolicyEngine(&config.SecurityConfig{StrictSymlinks: true}, nil)
+	_, err := validatePathWithMode("loop/file.txt", workspace, nil, true, security.ModeBlock, strict, "", "")
+	if err == nil || !strings.Contains(err.Error(), "cannot resolve symlinks") {
+		t.Errorf("Strict mode should deny an unresolvable path, got: %v", err)
+	}
+
+	// Resolvable paths are unaffected by strict mode
+	os.WriteFile(filepath.Join(workspace, "ok.txt"), []byte("x"), 0644)
+	if _, err := validatePathWithMode("ok.txt", workspace, nil, true, security.ModeBlock, strict, "", ""); err != nil {
+		t.Errorf("Strict mode should allow resolvable paths, got: %v", err)
+	}
+	if _, err := validatePathWithMode("new/dir/file.txt", workspace, nil, true, security.ModeBlock, strict, "", ""); err != nil {
+		t.Errorf("Strict mode should allow not-yet-existing paths, got: %v", err)
+	}
+}
+
 func TestReadFileTool_SetContext(t *testing.T) {
 	tool := NewReadFileToolWithPolicy("", false, PathPolicyOpts{})
 	tool.SetContext("telegram", "chat-1")
@@ -398,3 +629,655 @@ func TestFilesystemTool_ReadFile_RejectsSymlinkEscape(t *testing.T) {
 		t.Fatalf("expected symlink escape error, got: %s", result.ForLLM)
 	}
 }
+
+// TestFilesystemTool_WriteFile_EnsureFinalNewline verifies a missing newline is
+// added and an existing one is not duplicated
+func TestFilesystemTool_WriteFile_EnsureFinalNewline(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := &WriteFileTool{}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"missing", "hello", "hello\n"},
+		{"present", "hello\n", "hello\n"},
+		{"repeated", "hello\n\n\n", "hello\n"},
+		{"crlf", "a\r\nb", "a\r\nb\r\n"},
+	}
+
+	for _, tt := range tests {
+		testFile := filepath.Join(tmpDir, tt.name+".txt")
+		result := tool.Execute(ctx, map[string]interface{}{
+			"path":                 testFile,
+			"content":              tt.content,
+			"ensure_final_newline": true,
+		})
+		if result.IsError {
+			t.Fatalf("%s: expected success, got: %s", tt.name, result.ForLLM)
+		}
+		got, _ := os.ReadFile(testFile)
+		if string(got) != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, string(got))
+		}
+	}
+}
+
+// TestFilesystemTool_WriteFile_StripTrailingWhitespace verifies per-line stripping
+// and that content is written verbatim when the options are off
+func TestFilesystemTool_WriteFile_StripTrailingWhitespace(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := &WriteFileTool{}
+	ctx := context.Background()
+
+	stripped := filepath.Join(tmpDir, "stripped.txt")
+	tool.Execute(ctx, map[string]interface{}{
+		"path":                      stripped,
+		"content":                   "a  \nb\t\r\nc ",
+		"strip_trailing_whitespace": true,
+	})
+	got, _ := os.ReadFile(stripped)
+	if string(got) != "a\nb\r\nc" {
+		t.Errorf("expected trailing whitespace stripped, got %q", string(got))
+	}
+
+	verbatim := filepath.Join(tmpDir, "verbatim.txt")
+	tool.Execute(ctx, map[string]interface{}{
+		"path":    verbatim,
+		"content": "a  \nb",
+	})
+	got, _ = os.ReadFile(verbatim)
+	if string(got) != "a  \nb" {
+		t.Errorf("expected content written verbatim by default, got %q", string(got))
+	}
+}
+
+// gbkFixture is "中文测试：你好" encoded as GBK.
+var gbkFixture = []byte{0xD6, 0xD0, 0xCE, 0xC4, 0xB2, 0xE2, 0xCA, 0xD4, 0xA3, 0xBA, 0xC4, 0xE3, 0xBA, 0xC3}
+
+// TestFilesystemTool_ReadFile_DetectsGBK verifies GBK content is transcoded to UTF-8
+// and the guessed encoding is noted
+func TestFilesystemTool_ReadFile_DetectsGBK(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "gbk.txt"), gbkFixture, 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "gbk.txt"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.HasSuffix(result.ForLLM, "中文测试：你好") {
+		t.Errorf("Expected transcoded UTF-8 text, got: %q", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "gbk (guessed)") {
+		t.Errorf("Expected guessed encoding note, got: %q", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ReadFile_ExplicitEncoding verifies the encoding override is used
+// without a guess note
+func TestFilesystemTool_ReadFile_ExplicitEncoding(t *testing.T) {
+	tmpDir := t.TempDir()
+	// "café" in Latin-1; also decodes without error as GBK, so only the override gets it right
+	os.WriteFile(filepath.Join(tmpDir, "latin1.txt"), []byte{'c', 'a', 'f', 0xE9}, 0644)
+	os.WriteFile(filepath.Join(tmpDir, "gbk.txt"), gbkFixture, 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": "latin1.txt", "encoding": "latin1"})
+	if result.IsError || result.ForLLM != "café" {
+		t.Errorf("Expected 'café', got: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": "gbk.txt", "encoding": "gbk"})
+	if result.IsError || result.ForLLM != "中文测试：你好" {
+		t.Errorf("Expected exact GBK decode, got: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": "gbk.txt", "encoding": "no-such-charset"})
+	if !result.IsError {
+		t.Error("Expected error for unknown encoding")
+	}
+}
+
+// TestFilesystemTool_ReadFile_UTF8Unchanged verifies UTF-8 files are returned as-is
+func TestFilesystemTool_ReadFile_UTF8Unchanged(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "u.txt"), []byte("héllo 世界"), 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "u.txt"})
+	if result.ForLLM != "héllo 世界" {
+		t.Errorf("Expected unchanged UTF-8, got: %q", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_WriteFile_IfMatch verifies writes succeed with the current
+// hash and are rejected with a conflict once the file changed externally
+func TestFilesystemTool_WriteFile_IfMatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "notes.txt")
+	os.WriteFile(testFile, []byte("v1"), 0644)
+
+	read := NewReadFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path":         "notes.txt",
+		"include_hash": true,
+	})
+	header, body, _ := strings.Cut(read.ForLLM, "\n")
+	if !strings.HasPrefix(header, "[sha256: ") || body != "v1" {
+		t.Fatalf("Expected hash header and content, got: %q", read.ForLLM)
+	}
+	hash := strings.TrimSuffix(strings.TrimPrefix(header, "[sha256: "), "]")
+
+	tool := NewWriteFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":     "notes.txt",
+		"content":  "v2",
+		"if_match": hash,
+	})
+	if result.IsError {
+		t.Fatalf("Expected matching hash to succeed, got: %s", result.ForLLM)
+	}
+
+	// The stale hash no longer matches after the write above.
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path":     "notes.txt",
+		"content":  "v3",
+		"if_match": hash,
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "conflict") {
+		t.Errorf("Expected conflict error, got: %s", result.ForLLM)
+	}
+	if content, _ := os.ReadFile(testFile); string(content) != "v2" {
+		t.Errorf("Conflicting write must not change the file, got %q", content)
+	}
+}
+
+// TestFilesystemTool_WriteFile_Atomic verifies a concurrent reader only ever
+// sees the old or the new content, never a partial write
+func TestFilesystemTool_WriteFile_Atomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	versions := []string{strings.Repeat("a", 256<<10), strings.Repeat("b", 256<<10)}
+	os.WriteFile(filepath.Join(tmpDir, "config.txt"), []byte(versions[0]), 0600)
+	tool := NewWriteFileTool(tmpDir, true)
+
+	done := make(chan struct{})
+	torn := make(chan int, 1)
+	go func() {
+		defer close(torn)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			data, err := os.ReadFile(filepath.Join(tmpDir, "config.txt"))
+			if err == nil && string(data) != versions[0] && string(data) != versions[1] {
+				torn <- len(data)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 30; i++ {
+		if result := tool.Execute(context.Background(), map[string]interface{}{"path": "config.txt", "content": versions[i%2]}); result.IsError {
+			t.Fatalf("Write %d failed: %s", i, result.ForLLM)
+		}
+	}
+	close(done)
+	if n, ok := <-torn; ok {
+		t.Errorf("Reader saw a partially written file of %d bytes", n)
+	}
+	if info, _ := os.Stat(filepath.Join(tmpDir, "config.txt")); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+// TestFilesystemTool_WriteFile_RenameFailureCleansUp verifies the temp file is
+// removed when it cannot be renamed into place
+func TestFilesystemTool_WriteFile_RenameFailureCleansUp(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "target", "child"), 0755)
+
+	result := NewWriteFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "target", "content": "x"})
+	if !result.IsError {
+		t.Fatalf("Expected writing over a directory to fail, got: %s", result.ForLLM)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+		t.Errorf("Expected the temp file to be removed, found %d entries", len(entries))
+	}
+}
+
+// TestFilesystemTool_WriteFile_Backup verifies backup copies the previous
+// content to .bak, then to a timestamped name, and is a no-op for new files
+func TestFilesystemTool_WriteFile_Backup(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewWriteFileTool(tmpDir, true)
+	write := func(content string) *ToolResult {
+		t.Helper()
+		result := tool.Execute(context.Background(), map[string]interface{}{"path": "app.conf", "content": content, "backup": true})
+		if result.IsError {
+			t.Fatalf("Expected success, got: %s", result.ForLLM)
+		}
+		return result
+	}
+
+	if result := write("v1"); strings.Contains(result.ForLLM, "backup") {
+		t.Errorf("Expected no backup for a new file, got: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "app.conf.bak")); !os.IsNotExist(err) {
+		t.Error("Expected no .bak for a new file")
+	}
+
+	if result := write("v2"); !strings.Contains(result.ForLLM, "backup of previous content: app.conf.bak") {
+		t.Errorf("Expected the backup path in the result, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "app.conf.bak")); string(data) != "v1" {
+		t.Errorf("Expected .bak to hold v1, got %q", data)
+	}
+
+	result := write("v3")
+	m := regexp.MustCompile(`backup of previous content: (app\.conf\.\d{8}T\d{6}\.\d{3}Z\.bak)`).FindStringSubmatch(result.ForLLM)
+	if m == nil {
+		t.Fatalf("Expected a timestamped backup once .bak exists, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, m[1])); string(data) != "v2" {
+		t.Errorf("Expected %s to hold v2, got %q", m[1], data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "app.conf.bak")); string(data) != "v1" {
+		t.Errorf("Expected the first backup to be kept, got %q", data)
+	}
+}
+
+// TestFilesystemTool_WriteFile_BackupValidated verifies the backup path goes
+// through path validation and a refused backup stops the write
+func TestFilesystemTool_WriteFile_BackupValidated(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "app.conf"), []byte("original"), 0644)
+	pe := security.NewPolicyEngine(&config.SecurityConfig{DeniedPaths: []string{"*.bak"}}, nil)
+	tool := NewWriteFileToolWithPolicy(tmpDir, true, PathPolicyOpts{PolicyEngine: pe})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "app.conf", "content": "new", "backup": true})
+	if !result.IsError || !strings.Contains(result.ForLLM, "denied_paths") {
+		t.Errorf("Expected the backup to be denied, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "app.conf")); string(data) != "original" {
+		t.Errorf("Expected the file to be left alone, got %q", data)
+	}
+
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "stolen"), []byte("outside"), 0644)
+	if err := os.Symlink(filepath.Join(outside, "stolen"), filepath.Join(tmpDir, "app.conf.bak")); err != nil {
+		t.Skipf("Cannot create symlink: %v", err)
+	}
+	tool = NewWriteFileToolWithPolicy(tmpDir, true, PathPolicyOpts{PathMode: security.ModeBlock})
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "app.conf", "content": "new", "backup": true})
+	if !result.IsError {
+		t.Errorf("Expected a backup symlinked outside the workspace to be refused, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(outside, "stolen")); string(data) != "outside" {
+		t.Errorf("Backup must not be written outside the workspace, found %q", data)
+	}
+}
+
+// TestFilesystemTool_WriteFile_BackupAfterOffsetChecks verifies an invalid
+// offset write is rejected before any backup is taken
+func TestFilesystemTool_WriteFile_BackupAfterOffsetChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "app.conf"), []byte("original"), 0644)
+	tool := NewWriteFileTool(tmpDir, true)
+
+	for _, offset := range []float64{-1, 1.5, maxOffsetWriteSize} {
+		result := tool.Execute(context.Background(), map[string]interface{}{"path": "app.conf", "content": "x", "offset": offset, "backup": true})
+		if !result.IsError {
+			t.Errorf("offset %v: expected the write to be rejected, got: %s", offset, result.ForLLM)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "app.conf.bak")); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup for rejected writes, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "app.conf")); string(data) != "original" {
+		t.Errorf("Expected the file to be left alone, got %q", data)
+	}
+}
+
+// TestFilesystemTool_WriteFile_DiskFull verifies ENOSPC is reported with its
+// error code, the original file is kept and no temp file is left behind
+func TestFilesystemTool_WriteFile_DiskFull(t *testing.T) {
+	for _, tc := range []struct {
+		errno syscall.Errno
+		code  string
+	}{
+		{syscall.ENOSPC, "ENOSPC"},
+		{syscall.EDQUOT, "EDQUOT"},
+	} {
+		t.Run(tc.code, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			testFile := filepath.Join(tmpDir, "data.txt")
+			os.WriteFile(testFile, []byte("original"), 0644)
+
+			orig := writeTemp
+			writeTemp = func(f *os.File, data []byte) (int, error) {
+				n, _ := f.Write(data[:len(data)/2])
+				return n, &os.PathError{Op: "write", Path: f.Name(), Err: tc.errno}
+			}
+			defer func() { writeTemp = orig }()
+
+			result := NewWriteFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+				"path":    "data.txt",
+				"content": "replacement content",
+			})
+			if !result.IsError || !strings.Contains(result.ForLLM, tc.code) {
+				t.Fatalf("Expected %s error, got: %s", tc.code, result.ForLLM)
+			}
+			var dfe *DiskFullError
+			if !errors.As(result.Err, &dfe) || dfe.Code != tc.code {
+				t.Errorf("Expected DiskFullError with code %s, got %v", tc.code, result.Err)
+			}
+			if !strings.Contains(result.ForUser, "disk is full") {
+				t.Errorf("Expected user-facing message, got: %q", result.ForUser)
+			}
+
+			if content, _ := os.ReadFile(testFile); string(content) != "original" {
+				t.Errorf("Original file must be untouched, got %q", content)
+			}
+			entries, _ := os.ReadDir(tmpDir)
+			if len(entries) != 1 {
+				t.Errorf("Expected temp file to be cleaned up, found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+// TestFilesystemTool_ListDir_Paging verifies paging through a large directory
+// returns every entry exactly once, in order, with an accurate summary
+func TestFilesystemTool_ListDir_Paging(t *testing.T) {
+	tmpDir := t.TempDir()
+	const total = 250
+	for i := 0; i < total; i++ {
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("f%04d.txt", i)), nil, 0644)
+	}
+
+	tool := NewListDirTool(tmpDir, true)
+	var seen []string
+	offset := 0
+	for page := 0; page < 10; page++ {
+		result := tool.Execute(context.Background(), map[string]interface{}{
+			"path":   ".",
+			"offset": float64(offset),
+			"limit":  float64(100),
+		})
+		if result.IsError {
+			t.Fatalf("Unexpected error: %s", result.ForLLM)
+		}
+		lines := strings.Split(strings.TrimSpace(result.ForLLM), "\n")
+		summary := lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			seen = append(seen, strings.TrimPrefix(line, "FILE: "))
+		}
+		if !strings.Contains(summary, fmt.Sprintf("of %d", total)) {
+			t.Errorf("Expected total count in summary, got: %s", summary)
+		}
+		if strings.Contains(summary, "no more entries") {
+			break
+		}
+		if !strings.Contains(summary, fmt.Sprintf("next offset=%d", offset+100)) {
+			t.Fatalf("Expected next offset in summary, got: %s", summary)
+		}
+		offset += 100
+	}
+
+	if len(seen) != total {
+		t.Fatalf("Expected %d entries across pages, got %d", total, len(seen))
+	}
+	for i, name := range seen {
+		if want := fmt.Sprintf("f%04d.txt", i); name != want {
+			t.Fatalf("Entry %d: expected %s, got %s", i, want, name)
+		}
+	}
+}
+
+// TestFilesystemTool_WriteFile_ContentRef verifies content_ref writes the
+// staging file's content and cannot be combined with inline content
+func TestFilesystemTool_WriteFile_ContentRef(t *testing.T) {
+	tmpDir := t.TempDir()
+	payload := strings.Repeat("large payload line\n", 1000)
+	os.WriteFile(filepath.Join(tmpDir, "staging.txt"), []byte(payload), 0644)
+
+	tool := NewWriteFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":        "out/final.txt",
+		"content_ref": "staging.txt",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tmpDir, "out", "final.txt")); string(content) != payload {
+		t.Errorf("Expected referenced content to be written, got %d bytes", len(content))
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path":        "out/final.txt",
+		"content":     "inline",
+		"content_ref": "staging.txt",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "not both") {
+		t.Errorf("Expected error for content and content_ref together, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path":        "out/final.txt",
+		"content_ref": "../secret.txt",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "content_ref") {
+		t.Errorf("Expected content_ref outside workspace to be rejected, got: %s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_WriteFile_AtOffset verifies an offset write replaces only
+// the given region and keeps the rest of the file
+func TestFilesystemTool_WriteFile_AtOffset(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "record.bin")
+	os.WriteFile(testFile, []byte("AAAABBBBCCCC"), 0644)
+
+	tool := NewWriteFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":             "record.bin",
+		"content":          "00 ff 10 20",
+		"content_encoding": "hex",
+		"offset":           float64(4),
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+
+	data, _ := os.ReadFile(testFile)
+	if want := []byte("AAAA\x00\xff\x10\x20CCCC"); !bytes.Equal(data, want) {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+	if info, _ := os.Stat(testFile); info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+// TestFilesystemTool_WriteFile_PastEOF verifies writing past the end extends
+// the file with zeros, and creates missing files
+func TestFilesystemTool_WriteFile_PastEOF(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "short.txt"), []byte("abc"), 0644)
+
+	tool := NewWriteFileTool(tmpDir, true)
+	ctx := context.Background()
+	result := tool.Execute(ctx, map[string]interface{}{"path": "short.txt", "content": "xyz", "offset": float64(6)})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "short.txt"))
+	if want := []byte("abc\x00\x00\x00xyz"); !bytes.Equal(data, want) {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": "new.bin", "content": "AQI=", "content_encoding": "base64", "offset": float64(2)})
+	if result.IsError {
+		t.Fatalf("Expected success creating file, got: %s", result.ForLLM)
+	}
+	data, _ = os.ReadFile(filepath.Join(tmpDir, "new.bin"))
+	if want := []byte{0, 0, 1, 2}; !bytes.Equal(data, want) {
+		t.Errorf("Expected %v, got %v", want, data)
+	}
+}
+
+// TestFilesystemTool_WriteFile_OffsetValidation verifies bad offsets and
+// oversized results are rejected without touching the file
+func TestFilesystemTool_WriteFile_OffsetValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "f.txt")
+	os.WriteFile(testFile, []byte("keep"), 0644)
+
+	tool := NewWriteFileTool(tmpDir, true)
+	ctx := context.Background()
+	for name, args := range map[string]map[string]interface{}{
+		"negative":   {"offset": float64(-1)},
+		"fractional": {"offset": 1.5},
+		"too large":  {"offset": float64(maxOffsetWriteSize)},
+		"normalize":  {"offset": float64(0), "ensure_final_newline": true},
+		"bad hex":    {"offset": float64(0), "content_encoding": "hex"},
+	} {
+		args["path"] = "f.txt"
+		args["content"] = "zz"
+		if result := tool.Execute(ctx, args); !result.IsError {
+			t.Errorf("%s: expected error, got: %s", name, result.ForLLM)
+		}
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "keep" {
+		t.Errorf("Expected file to be untouched, got %q", data)
+	}
+}
+
+// TestReadFileTool_MultiReadPartialSuccess verifies a paths read with a mix
+// of good and bad files succeeds overall, with a code per failed file and
+// accurate counts
+func TestReadFileTool_MultiReadPartialSuccess(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("alpha"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "c.txt"), []byte("gamma"), 0644)
+	tool := NewReadFileTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"paths": []interface{}{"a.txt", "missing.txt", "../outside.txt", float64(42), "c.txt"},
+	})
+	if result.IsError {
+		t.Fatalf("Expected partial success, got error: %s", result.ForLLM)
+	}
+	if ok, failed := result.ItemCounts(); ok != 2 || failed != 3 {
+		t.Errorf("Expected 2 succeeded and 3 failed, got %d and %d", ok, failed)
+	}
+
+	want := []ItemResult{
+		{Target: "a.txt", OK: true},
+		{Target: "missing.txt", Code: CodeNotFound},
+		{Target: "../outside.txt", Code: CodePolicyDenied},
+		{Target: "paths[3]", Code: CodeInvalidArgument},
+		{Target: "c.txt", OK: true},
+	}
+	if len(result.Items) != len(want) {
+		t.Fatalf("Expected %d items, got %+v", len(want), result.Items)
+	}
+	for i, w := range want {
+		got := result.Items[i]
+		if got.Target != w.Target || got.OK != w.OK || got.Code != w.Code || (!got.OK && got.Error == "") {
+			t.Errorf("item %d: got %+v, want %+v", i, got, w)
+		}
+	}
+
+	for _, s := range []string{"Read 2 of 5 file(s); 3 failed: missing.txt, ../outside.txt, paths[3]", "=== a.txt ===\nalpha", "=== missing.txt [not_found] ===", "=== c.txt ===\ngamma"} {
+		if !strings.Contains(result.ForLLM, s) {
+			t.Errorf("Expected %q in:\n%s", s, result.ForLLM)
+		}
+	}
+}
+
+// TestReadFileTool_MultiReadAllFail verifies the call is an error only when
+// every file failed
+func TestReadFileTool_MultiReadAllFail(t *testing.T) {
+	tool := NewReadFileTool(t.TempDir(), true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"paths": []interface{}{"nope.txt", "gone.txt"},
+	})
+	if !result.IsError {
+		t.Errorf("Expected an error when every file fails, got: %s", result.ForLLM)
+	}
+	if ok, failed := result.ItemCounts(); ok != 0 || failed != 2 {
+		t.Errorf("Expected 0 succeeded and 2 failed, got %d and %d", ok, failed)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "nope.txt"})
+	if !result.IsError || result.Code != CodeNotFound {
+		t.Errorf("Expected a not_found code on a single read, got %q: %s", result.Code, result.ForLLM)
+	}
+}
+
+// TestReadFileTool_MaxReadBytes verifies files above the limit return only
+// the first maxReadBytes with a truncation notice, and smaller files are
+// returned whole
+func TestReadFileTool_MaxReadBytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "big.log"), []byte(strings.Repeat("0123456789", 10)), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "small.txt"), []byte("tiny"), 0644)
+	tool := NewReadFileToolWithPolicy(tmpDir, true, PathPolicyOpts{MaxReadBytes: 25})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "big.log"})
+	want := "0123456789012345678901234\n[truncated: showing 25 of 100 bytes; use start_line/end_line to read further]"
+	if result.IsError || result.ForLLM != want {
+		t.Errorf("Expected truncated content, got: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "small.txt"})
+	if result.IsError || result.ForLLM != "tiny" {
+		t.Errorf("Expected the whole small file, got: %q", result.ForLLM)
+	}
+
+	// The hash covers the whole file so it still works as if_match.
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "big.log", "include_hash": true})
+	sum, _ := hashFile(filepath.Join(tmpDir, "big.log"))
+	if !strings.HasPrefix(result.ForLLM, "[sha256: "+sum+"]\n") {
+		t.Errorf("Expected the full-file hash, got: %q", result.ForLLM)
+	}
+}
+
+// TestReadFileTool_MaxReadBytesSplitRune verifies truncation inside a
+// multi-byte character drops the partial character instead of garbling it
+func TestReadFileTool_MaxReadBytesSplitRune(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "cn.txt"), []byte("你好世界"), 0644) // 12 bytes
+	tool := NewReadFileToolWithPolicy(tmpDir, true, PathPolicyOpts{MaxReadBytes: 7})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "cn.txt"})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "你好\n[truncated: showing 6 of 12 bytes") {
+		t.Errorf("Expected whole characters only, got: %q", result.ForLLM)
+	}
+}
+
+// TestValidatePath_SessionOverrideTightensOffMode verifies a /policy override
+// switches on symlink checks that path_validation "off" skips
+func TestValidatePath_SessionOverrideTightensOffMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(tmpDir, "escape")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	pe := security.NewPolicyEngine(&config.SecurityConfig{}, nil)
+
+	if _, err := validatePathWithMode("escape/x", tmpDir, nil, true, security.ModeOff, pe, "telegram", "42"); err != nil {
+		t.Fatalf("Expected off mode to skip symlink resolution, got %v", err)
+	}
+	if err := pe.OverrideMode("telegram", "42", "path_validation", security.ModeBlock, time.Hour); err != nil {
+		t.Fatalf("OverrideMode failed: %v", err)
+	}
+	if _, err := validatePathWithMode("escape/x", tmpDir, nil, true, security.ModeOff, pe, "telegram", "42"); err == nil || !strings.Contains(err.Error(), "symlink resolves outside workspace") {
+		t.Errorf("Expected the override to block the symlink escape, got %v", err)
+	}
+}
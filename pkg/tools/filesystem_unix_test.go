@@ -0,0 +1,56 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestFilesystemTool_ReadFile_RefusesFIFO verifies reading a named pipe with
+// no writer fails immediately instead of blocking
+func TestFilesystemTool_ReadFile_RefusesFIFO(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := syscall.Mkfifo(filepath.Join(tmpDir, "pipe"), 0644); err != nil {
+		t.Skipf("mkfifo not available: %v", err)
+	}
+
+	tool := NewReadFileTool(tmpDir, true)
+	done := make(chan *ToolResult, 1)
+	go func() {
+		done <- tool.Execute(context.Background(), map[string]interface{}{"path": "pipe"})
+	}()
+
+	select {
+	case result := <-done:
+		if !result.IsError || !strings.Contains(result.ForLLM, "named pipe (FIFO)") {
+			t.Errorf("Expected FIFO to be refused, got: %s", result.ForLLM)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("read_file blocked on a FIFO")
+	}
+}
+
+// TestFilesystemTool_ReadFile_ForcedDeviceIsBounded verifies a forced read of
+// an endless device stops at the size cap
+func TestFilesystemTool_ReadFile_ForcedDeviceIsBounded(t *testing.T) {
+	tool := NewReadFileTool("", false)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": "/dev/zero"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "character device") {
+		t.Fatalf("Expected /dev/zero to be refused without force, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": "/dev/zero", "force": true})
+	if result.IsError {
+		t.Fatalf("Expected forced read to succeed, got: %s", result.ForLLM)
+	}
+	if len(result.ForLLM) != maxSpecialFileRead {
+		t.Errorf("Expected %d bytes, got %d", maxSpecialFileRead, len(result.ForLLM))
+	}
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// FileTimesTool reads and sets a file's access and modification times.
+type FileTimesTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewFileTimesTool(workspace string, restrict bool) *FileTimesTool {
+	return &FileTimesTool{workspace: workspace, restrict: restrict}
+}
+
+func NewFileTimesToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *FileTimesTool {
+	return &FileTimesTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *FileTimesTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *FileTimesTool) Name() string {
+	return "file_times"
+}
+
+func (t *FileTimesTool) Description() string {
+	return "Get or set a file's access and modification times. Times are RFC3339 (e.g. 2024-01-02T15:04:05Z) or Unix epoch seconds."
+}
+
+func (t *FileTimesTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"get", "set"},
+				"description": "'get' to read the times, 'set' to change them",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file",
+			},
+			"mtime": map[string]interface{}{
+				"type":        []string{"string", "number"},
+				"description": "New modification time (set only). Omit to leave unchanged.",
+			},
+			"atime": map[string]interface{}{
+				"type":        []string{"string", "number"},
+				"description": "New access time (set only). Omit to leave unchanged.",
+			},
+		},
+		"required": []string{"action", "path"},
+	}
+}
+
+func (t *FileTimesTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, ok := args["action"].(string)
+	if !ok {
+		return ErrorResult("action is required")
+	}
+
+	path, ok := args["path"].(string)
+	if !ok {
+		return ErrorResult("path is required")
+	}
+
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	switch action {
+	case "get":
+		return t.get(path, resolvedPath)
+	case "set":
+		return t.set(path, resolvedPath, args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func (t *FileTimesTool) get(path, resolvedPath string) *ToolResult {
+	info, err := os.Stat(resolvedPath)
+	if os.IsNotExist(err) {
+		return ErrorResult(fmt.Sprintf("file not found: %s", path))
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat file: %v", err))
+	}
+
+	out := fmt.Sprintf("path: %s\nmtime: %s", path, info.ModTime().Format(time.RFC3339Nano))
+	if atime, ok := fileAccessTime(info); ok {
+		out += fmt.Sprintf("\natime: %s", atime.Format(time.RFC3339Nano))
+	}
+	return NewToolResult(out)
+}
+
+func (t *FileTimesTool) set(path, resolvedPath string, args map[string]interface{}) *ToolResult {
+	var atime, mtime time.Time
+	var err error
+
+	if raw, ok := args["mtime"]; ok {
+		if mtime, err = parseTimestamp(raw); err != nil {
+			return ErrorResult(fmt.Sprintf("invalid mtime: %v", err))
+		}
+	}
+	if raw, ok := args["atime"]; ok {
+		if atime, err = parseTimestamp(raw); err != nil {
+			return ErrorResult(fmt.Sprintf("invalid atime: %v", err))
+		}
+	}
+	if atime.IsZero() && mtime.IsZero() {
+		return ErrorResult("set requires mtime and/or atime")
+	}
+
+	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
+		return ErrorResult(fmt.Sprintf("file not found: %s", path))
+	}
+
+	// Zero values leave the corresponding time unchanged.
+	if err := os.Chtimes(resolvedPath, atime, mtime); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to set file times: %v", err))
+	}
+
+	return SilentResult(fmt.Sprintf("File times updated: %s", path))
+}
+
+// parseTimestamp accepts RFC3339 strings, or Unix epoch seconds given either
+// as a JSON number or a numeric string.
+func parseTimestamp(raw interface{}) (time.Time, error) {
+	switch v := raw.(type) {
+	case float64:
+		sec := int64(v)
+		nsec := int64((v - float64(sec)) * 1e9)
+		return time.Unix(sec, nsec), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return ts, nil
+		}
+		if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(sec, 0), nil
+		}
+		return time.Time{}, fmt.Errorf("%q is neither RFC3339 nor epoch seconds", v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported timestamp type %T", raw)
+	}
+}
@@ -0,0 +1,16 @@
+package tools
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileAccessTime extracts the access time from a Linux stat result.
+func fileAccessTime(info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)), true
+}
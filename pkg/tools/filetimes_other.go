@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tools
+
+import (
+	"os"
+	"time"
+)
+
+// fileAccessTime is a stub for non-Linux platforms, where the stat layout differs.
+func fileAccessTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
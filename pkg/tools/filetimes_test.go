@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFileTimesTool_SetAndGet verifies an mtime set via RFC3339 or epoch reads back
+func TestFileTimesTool_SetAndGet(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "a.txt")
+	os.WriteFile(testFile, []byte("a"), 0644)
+
+	tool := NewFileTimesTool(tmpDir, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{
+		"action": "set",
+		"path":   "a.txt",
+		"mtime":  "2020-05-06T07:08:09Z",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+
+	info, _ := os.Stat(testFile)
+	want := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+	if !info.ModTime().Equal(want) {
+		t.Errorf("Expected mtime %v, got %v", want, info.ModTime())
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "get", "path": "a.txt"})
+	if result.IsError || !strings.Contains(result.ForLLM, "mtime: 2020-05-06T07:08:09Z") {
+		t.Errorf("Expected get to report the new mtime, got: %s", result.ForLLM)
+	}
+
+	// Epoch seconds as a JSON number
+	result = tool.Execute(ctx, map[string]interface{}{
+		"action": "set",
+		"path":   "a.txt",
+		"mtime":  float64(1000000000),
+	})
+	if result.IsError {
+		t.Fatalf("Expected epoch input to succeed, got: %s", result.ForLLM)
+	}
+	info, _ = os.Stat(testFile)
+	if info.ModTime().Unix() != 1000000000 {
+		t.Errorf("Expected epoch mtime, got %v", info.ModTime())
+	}
+}
+
+// TestFileTimesTool_InvalidTimestamp verifies malformed input is rejected
+func TestFileTimesTool_InvalidTimestamp(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "a.txt")
+	os.WriteFile(testFile, []byte("a"), 0644)
+	before, _ := os.Stat(testFile)
+
+	tool := NewFileTimesTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "set",
+		"path":   "a.txt",
+		"mtime":  "yesterday at noon",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "invalid mtime") {
+		t.Errorf("Expected invalid timestamp error, got: %s", result.ForLLM)
+	}
+	after, _ := os.Stat(testFile)
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Error("mtime must not change on invalid input")
+	}
+}
+
+// TestFileTimesTool_OutsideWorkspace verifies validatePath is applied
+func TestFileTimesTool_OutsideWorkspace(t *testing.T) {
+	tool := NewFileTimesTool(t.TempDir(), true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "set",
+		"path":   "../outside.txt",
+		"mtime":  "0",
+	})
+	if !result.IsError {
+		t.Error("Expected error for path outside workspace")
+	}
+}
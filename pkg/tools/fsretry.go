@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// maxFSRetries bounds the configured retry count so a misconfiguration cannot
+// stall a tool call for long.
+const maxFSRetries = 5
+
+// fsRetryBackoff is the delay before the first retry; it doubles on each
+// further attempt. Tests set it to zero.
+var fsRetryBackoff = 50 * time.Millisecond
+
+// readFile and readDir are the filesystem reads the file tools retry. Tests
+// replace them to simulate a flaky network mount.
+var (
+	readFile = os.ReadFile
+	readDir  = os.ReadDir
+)
+
+// isTransientFSError reports whether err is one that network filesystems
+// (NFS, SMB) return spuriously and that usually succeeds on retry.
+func isTransientFSError(err error) bool {
+	return errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN)
+}
+
+// retryFS runs op, retrying up to retries more times with exponential backoff
+// while it fails with a transient error. Any other error, such as ENOENT or
+// EACCES, is returned at once.
+func retryFS(retries int, op func() error) error {
+	retries = min(retries, maxFSRetries)
+	delay := fsRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= retries || !isTransientFSError(err) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// flakyOnce returns an error of errno on the first call and delegates to
+// next afterwards, counting calls in *calls.
+func flakyOnce[T any](errno syscall.Errno, calls *int, next func(string) (T, error)) func(string) (T, error) {
+	return func(name string) (T, error) {
+		*calls++
+		if *calls == 1 {
+			var zero T
+			return zero, &fs.PathError{Op: "open", Path: name, Err: errno}
+		}
+		return next(name)
+	}
+}
+
+func stubFSRetry(t *testing.T) {
+	t.Helper()
+	origRead, origDir, origBackoff := readFile, readDir, fsRetryBackoff
+	fsRetryBackoff = 0
+	t.Cleanup(func() {
+		readFile, readDir, fsRetryBackoff = origRead, origDir, origBackoff
+	})
+}
+
+// TestFilesystemTool_RetriesTransientErrors verifies read_file and list_dir
+// succeed after a single ESTALE when retries are enabled
+func TestFilesystemTool_RetriesTransientErrors(t *testing.T) {
+	stubFSRetry(t)
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("hello"), 0644)
+	opts := PathPolicyOpts{FSRetries: 2}
+	ctx := context.Background()
+
+	calls := 0
+	readFile = flakyOnce(syscall.ESTALE, &calls, os.ReadFile)
+	result := NewReadFileToolWithPolicy(tmpDir, true, opts).Execute(ctx, map[string]interface{}{"path": "a.txt"})
+	if result.IsError || result.ForLLM != "hello" || calls != 2 {
+		t.Errorf("Expected read to succeed on retry, got %q after %d calls", result.ForLLM, calls)
+	}
+
+	calls = 0
+	readDir = flakyOnce(syscall.ESTALE, &calls, os.ReadDir)
+	result = NewListDirToolWithPolicy(tmpDir, true, opts).Execute(ctx, map[string]interface{}{"path": "."})
+	if result.IsError || !strings.Contains(result.ForLLM, "a.txt") || calls != 2 {
+		t.Errorf("Expected listing to succeed on retry, got %q after %d calls", result.ForLLM, calls)
+	}
+}
+
+// TestFilesystemTool_WriteRetriesTransientErrors verifies write_file retries
+// a transient failure of the atomic write
+func TestFilesystemTool_WriteRetriesTransientErrors(t *testing.T) {
+	stubFSRetry(t)
+	origWrite := writeTemp
+	t.Cleanup(func() { writeTemp = origWrite })
+	calls := 0
+	writeTemp = func(f *os.File, data []byte) (int, error) {
+		if calls++; calls == 1 {
+			return 0, &fs.PathError{Op: "write", Path: f.Name(), Err: syscall.EINTR}
+		}
+		return origWrite(f, data)
+	}
+
+	tmpDir := t.TempDir()
+	tool := NewWriteFileToolWithPolicy(tmpDir, true, PathPolicyOpts{FSRetries: 1})
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "out.txt", "content": "data"})
+	if result.IsError {
+		t.Fatalf("Expected write to succeed on retry, got: %s", result.ForLLM)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tmpDir, "out.txt")); string(content) != "data" {
+		t.Errorf("Expected written content, got %q", content)
+	}
+}
+
+// TestFilesystemTool_NoRetryByDefaultOrForPermanentErrors verifies retries are
+// off by default and never applied to errors like EACCES
+func TestFilesystemTool_NoRetryByDefaultOrForPermanentErrors(t *testing.T) {
+	stubFSRetry(t)
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("hello"), 0644)
+	ctx := context.Background()
+
+	calls := 0
+	readFile = flakyOnce(syscall.ESTALE, &calls, os.ReadFile)
+	result := NewReadFileTool(tmpDir, true).Execute(ctx, map[string]interface{}{"path": "a.txt"})
+	if !result.IsError || calls != 1 {
+		t.Errorf("Expected no retry by default, got %q after %d calls", result.ForLLM, calls)
+	}
+
+	calls = 0
+	readFile = flakyOnce(syscall.EACCES, &calls, os.ReadFile)
+	result = NewReadFileToolWithPolicy(tmpDir, true, PathPolicyOpts{FSRetries: 3}).Execute(ctx, map[string]interface{}{"path": "a.txt"})
+	if !result.IsError || calls != 1 {
+		t.Errorf("Expected EACCES not to be retried, got %q after %d calls", result.ForLLM, calls)
+	}
+}
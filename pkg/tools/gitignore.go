@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one pattern line from a .gitignore file.
+type ignoreRule struct {
+	segs    []string // pattern split on "/", "**" matches any number of segments
+	negate  bool     // "!pattern" re-includes a match
+	dirOnly bool     // "pattern/" matches directories only
+}
+
+// ignoreRules holds the rules of a root .gitignore. Nested .gitignore files
+// and global excludes are not consulted.
+type ignoreRules []ignoreRule
+
+// loadGitignore reads root/.gitignore. A missing file yields no rules.
+func loadGitignore(root string) ignoreRules {
+	f, err := os.Open(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules ignoreRules
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A pattern with no inner slash matches at any depth; otherwise it is
+	// anchored to the directory holding the .gitignore.
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	rule.segs = strings.Split(strings.TrimPrefix(line, "/"), "/")
+	return rule, true
+}
+
+// Ignored reports whether rel (slash-separated, relative to the .gitignore
+// directory) is ignored. The last matching rule wins.
+func (r ignoreRules) Ignored(rel string, isDir bool) bool {
+	segs := strings.Split(rel, "/")
+	ignored := false
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(rule.segs, segs) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// stands for zero or more segments and others use path.Match.
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// maxGlobMatches caps how many paths a single glob returns.
+const maxGlobMatches = 1000
+
+// GlobTool expands a glob pattern inside the workspace without running
+// anything, so the concrete targets of a command can be previewed.
+type GlobTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	showHidden   bool
+	channel      string
+	chatID       string
+}
+
+func NewGlobTool(workspace string, restrict bool) *GlobTool {
+	return &GlobTool{workspace: workspace, restrict: restrict}
+}
+
+func NewGlobToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *GlobTool {
+	return &GlobTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, showHidden: opts.ShowHidden}
+}
+
+func (t *GlobTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *GlobTool) Name() string {
+	return "glob"
+}
+
+func (t *GlobTool) Description() string {
+	return "Expand a glob pattern (e.g. 'build/*.tmp', 'src/**/*.go') and list the matching paths without running anything. Use it to preview what a command like 'rm build/*.tmp' would touch."
+}
+
+func (t *GlobTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Glob pattern relative to path. Supports *, ?, [...] and ** for any number of directories. Unless show_hidden is set, wildcards do not match names starting with '.', as in a shell; a segment that itself starts with '.' still matches them",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to expand the pattern in (default: workspace root)",
+			},
+			"relative_to": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory the returned paths are relative to (default: path). Use '.' for workspace-relative paths",
+			},
+			"show_hidden": showHiddenParam(t.showHidden),
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GlobTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	pattern = filepath.ToSlash(pattern)
+	if path.IsAbs(pattern) || filepath.IsAbs(pattern) {
+		return ErrorResult("pattern must be relative; use path to choose the directory")
+	}
+	segs := strings.Split(path.Clean(pattern), "/")
+	for _, seg := range segs {
+		if seg == ".." {
+			return ErrorResult("pattern must not contain '..'")
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+		}
+	}
+
+	base, _ := args["path"].(string)
+	if base == "" {
+		base = "."
+	}
+	resolvedBase, err := validatePathWithMode(base, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if info, err := os.Stat(resolvedBase); err != nil || !info.IsDir() {
+		return ErrorResult(fmt.Sprintf("not a directory: %s", base))
+	}
+
+	relTo, _ := args["relative_to"].(string)
+	if relTo == "" {
+		relTo = base
+	}
+	resolvedRelTo, err := validatePathWithMode(relTo, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	g := &globber{showHidden: showHiddenArg(args, t.showHidden), seen: make(map[string]bool)}
+	if err := g.walk(ctx, resolvedBase, "", segs); err != nil {
+		return ErrorResult(err.Error())
+	}
+	seen := g.seen
+	if len(seen) == 0 {
+		return NewToolResult(fmt.Sprintf("No files match %q in %s", pattern, base))
+	}
+
+	matches := make([]string, 0, len(seen))
+	for rel := range seen {
+		matches = append(matches, relativeMatch(resolvedBase, rel, resolvedRelTo))
+	}
+	sort.Strings(matches)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d match(es) for %q (relative to %s):\n", len(matches), pattern, relTo)
+	for i, m := range matches {
+		if i == maxGlobMatches {
+			fmt.Fprintf(&sb, "... (%d more not shown)\n", len(matches)-maxGlobMatches)
+			break
+		}
+		sb.WriteString(m + "\n")
+	}
+	return NewToolResult(sb.String())
+}
+
+// relativeMatch re-expresses rel, found under base, relative to relTo. It
+// falls back to the absolute path when no relative form exists.
+func relativeMatch(base, rel, relTo string) string {
+	abs := filepath.Join(base, filepath.FromSlash(rel))
+	if r, err := filepath.Rel(relTo, abs); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return abs
+}
+
+// globber expands one pattern. Hidden names are matched only by a segment
+// that itself starts with '.', unless showHidden is set; ** never descends
+// into .git either way.
+type globber struct {
+	showHidden bool
+	seen       map[string]bool
+}
+
+// walk matches segs against the tree under dir, recording matches in g.seen
+// as slash-separated paths relative to the starting directory. Symlinked
+// directories are not descended into, so expansion cannot leave the tree.
+func (g *globber) walk(ctx context.Context, dir, rel string, segs []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(segs) == 0 {
+		if rel != "" {
+			g.seen[rel] = true
+		}
+		return nil
+	}
+	if len(g.seen) > maxGlobMatches*10 {
+		return fmt.Errorf("pattern matches too many paths; narrow it down")
+	}
+
+	seg, rest := segs[0], segs[1:]
+	join := func(name string) string {
+		if rel == "" {
+			return name
+		}
+		return rel + "/" + name
+	}
+
+	if seg == "**" {
+		// Zero directories, then one or more.
+		if err := g.walk(ctx, dir, rel, rest); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if !e.IsDir() || name == ".git" || (isHidden(name) && !g.showHidden) {
+				continue
+			}
+			if err := g.walk(ctx, filepath.Join(dir, name), join(name), segs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if seg == "." {
+		return g.walk(ctx, dir, rel, rest)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if isHidden(name) && !g.showHidden && !isHidden(seg) {
+			continue
+		}
+		if ok, _ := path.Match(seg, name); !ok {
+			continue
+		}
+		if len(rest) == 0 {
+			g.seen[join(name)] = true
+		} else if e.IsDir() {
+			if err := g.walk(ctx, filepath.Join(dir, name), join(name), rest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
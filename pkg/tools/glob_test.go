@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func makeGlobTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range []string{
+		"build/a.tmp",
+		"build/b.tmp",
+		"build/keep.txt",
+		"build/.hidden.tmp",
+		"src/main.go",
+		"src/pkg/util.go",
+		"src/pkg/deep/more.go",
+		".git/config.go",
+	} {
+		p := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+	}
+	return dir
+}
+
+// TestGlobTool_Expands verifies patterns expand to exactly the matching files
+func TestGlobTool_Expands(t *testing.T) {
+	dir := makeGlobTree(t)
+	tool := NewGlobTool(dir, true)
+
+	tests := []struct {
+		pattern    string
+		path       string
+		relativeTo string
+		want       []string
+	}{
+		{"build/*.tmp", "", "", []string{"build/a.tmp", "build/b.tmp"}},
+		{"src/**/*.go", "", "", []string{"src/main.go", "src/pkg/deep/more.go", "src/pkg/util.go"}},
+		{"*.go", "src/pkg", "", []string{"util.go"}},
+		{"**/*.go", "src/pkg", ".", []string{"src/pkg/deep/more.go", "src/pkg/util.go"}},
+		{"*.go", "src/pkg", "src", []string{"pkg/util.go"}},
+	}
+	for _, tc := range tests {
+		args := map[string]interface{}{"pattern": tc.pattern}
+		if tc.path != "" {
+			args["path"] = tc.path
+		}
+		if tc.relativeTo != "" {
+			args["relative_to"] = tc.relativeTo
+		}
+		result := tool.Execute(context.Background(), args)
+		if result.IsError {
+			t.Fatalf("%s: unexpected error: %s", tc.pattern, result.ForLLM)
+		}
+		lines := strings.Split(strings.TrimSpace(result.ForLLM), "\n")[1:]
+		if strings.Join(lines, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: got %v, want %v", tc.pattern, lines, tc.want)
+		}
+	}
+}
+
+// TestGlobTool_NoMatch verifies an empty expansion is reported clearly
+func TestGlobTool_NoMatch(t *testing.T) {
+	dir := makeGlobTree(t)
+	result := NewGlobTool(dir, true).Execute(context.Background(), map[string]interface{}{"pattern": "build/*.log"})
+	if result.IsError || !strings.Contains(result.ForLLM, `No files match "build/*.log"`) {
+		t.Errorf("Expected clear no-match message, got: %s", result.ForLLM)
+	}
+}
+
+// TestGlobTool_StaysInWorkspace verifies patterns and base paths cannot escape
+func TestGlobTool_StaysInWorkspace(t *testing.T) {
+	tool := NewGlobTool(makeGlobTree(t), true)
+	for _, args := range []map[string]interface{}{
+		{"pattern": "../*"},
+		{"pattern": "/etc/*"},
+		{"pattern": "*", "path": "../"},
+	} {
+		if result := tool.Execute(context.Background(), args); !result.IsError {
+			t.Errorf("Expected %v to be rejected, got: %s", args, result.ForLLM)
+		}
+	}
+}
+
+// TestGlobTool_ShowHidden verifies wildcards skip dotfiles by default and
+// match them, except inside .git, when show_hidden is set
+func TestGlobTool_ShowHidden(t *testing.T) {
+	dir := makeGlobTree(t)
+	os.MkdirAll(filepath.Join(dir, ".config"), 0755)
+	os.WriteFile(filepath.Join(dir, ".config", "app.go"), []byte("x"), 0644)
+
+	hiddenOff := NewGlobTool(dir, true)
+	hiddenOn := NewGlobToolWithPolicy(dir, true, PathPolicyOpts{ShowHidden: true})
+
+	result := hiddenOff.Execute(context.Background(), map[string]interface{}{"pattern": "**/*"})
+	if strings.Contains(result.ForLLM, ".hidden.tmp") || strings.Contains(result.ForLLM, ".config") {
+		t.Errorf("Expected dotfiles to be excluded by default, got:\n%s", result.ForLLM)
+	}
+
+	for _, r := range []*ToolResult{
+		hiddenOn.Execute(context.Background(), map[string]interface{}{"pattern": "**/*"}),
+		hiddenOff.Execute(context.Background(), map[string]interface{}{"pattern": "**/*", "show_hidden": true}),
+	} {
+		for _, want := range []string{"build/.hidden.tmp", ".config/app.go"} {
+			if !strings.Contains(r.ForLLM, want) {
+				t.Errorf("Expected %s with show_hidden, got:\n%s", want, r.ForLLM)
+			}
+		}
+		if strings.Contains(r.ForLLM, ".git/") {
+			t.Errorf("Expected ** to skip .git, got:\n%s", r.ForLLM)
+		}
+	}
+
+	result = hiddenOn.Execute(context.Background(), map[string]interface{}{"pattern": "build/*", "show_hidden": false})
+	if strings.Contains(result.ForLLM, ".hidden.tmp") {
+		t.Errorf("Expected the call to override the configured default, got:\n%s", result.ForLLM)
+	}
+}
@@ -0,0 +1,258 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+const (
+	// maxGrepMatches caps how many matching lines a single search returns.
+	maxGrepMatches = 200
+	// maxGrepLineDisplay bounds how much of a matching line is shown.
+	maxGrepLineDisplay = 300
+	// grepBinarySniff is how much of a file is checked for NUL bytes.
+	grepBinarySniff = 8000
+)
+
+// errGrepLimit stops the walk once enough matches are collected.
+var errGrepLimit = errors.New("match limit reached")
+
+// GrepTool searches file contents under a directory for a regular
+// expression, so the agent can find code without reading every file.
+type GrepTool struct {
+	workspace     string
+	restrict      bool
+	roots         map[string]string
+	pathMode      security.PolicyMode
+	policyEngine  *security.PolicyEngine
+	maxLineLength int
+	channel       string
+	chatID        string
+}
+
+func NewGrepTool(workspace string, restrict bool) *GrepTool {
+	return &GrepTool{workspace: workspace, restrict: restrict}
+}
+
+func NewGrepToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *GrepTool {
+	return &GrepTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, maxLineLength: opts.MaxLineLength}
+}
+
+func (t *GrepTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *GrepTool) Name() string {
+	return "grep"
+}
+
+func (t *GrepTool) Description() string {
+	return fmt.Sprintf("Search file contents for a regular expression (Go RE2 syntax, use (?i) for case-insensitive) and list matching lines as file:line: text. Binary files, .git and symlinks are skipped. Returns at most %d matches.", maxGrepMatches)
+}
+
+func (t *GrepTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression to search for",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File or directory to search (default: workspace root)",
+			},
+			"glob": map[string]interface{}{
+				"type":        "string",
+				"description": "Only search files matching this pattern, e.g. '*.go'. A pattern with '/' matches the path relative to path and may use **",
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GrepTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+
+	var globSegs []string
+	if g, _ := args["glob"].(string); g != "" {
+		g = filepath.ToSlash(g)
+		if !strings.Contains(g, "/") {
+			g = "**/" + g
+		}
+		globSegs = strings.Split(strings.TrimPrefix(g, "/"), "/")
+		for _, seg := range globSegs {
+			if _, err := path.Match(seg, ""); err != nil {
+				return ErrorResult(fmt.Sprintf("invalid glob: %v", err))
+			}
+		}
+	}
+
+	dir, _ := args["path"].(string)
+	if dir == "" {
+		dir = "."
+	}
+	root, err := validatePathWithMode(dir, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if _, err := os.Stat(root); err != nil {
+		return ErrorResult(fmt.Sprintf("cannot search %s: %v", dir, err))
+	}
+
+	// The root is the only path that may prompt for approval. Files under it
+	// are checked without prompting: they must resolve inside the root and
+	// not be denied, so one approval covers the whole search.
+	realRoot := root
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		realRoot = resolved
+	}
+	ws := resolvedWorkspace(t.workspace)
+	var matches []string
+	filesSearched, skipped := 0, 0
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && p != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = d.Name()
+		}
+		if globSegs != nil && !matchSegments(globSegs, strings.Split(rel, "/")) {
+			return nil
+		}
+		// Denied paths are skipped quietly rather than prompting once per file.
+		real, err := filepath.EvalSymlinks(p)
+		if err != nil || !isWithinWorkspace(real, realRoot) {
+			skipped++
+			return nil
+		}
+		if t.isDenied(p, ws) || (real != p && t.isDenied(real, ws)) {
+			skipped++
+			return nil
+		}
+
+		filesSearched++
+		return t.grepFile(p, grepDisplayPath(p, ws), re, &matches)
+	})
+	truncated := errors.Is(err, errGrepLimit)
+	if err != nil && !truncated {
+		return ErrorResult(fmt.Sprintf("search failed: %v", err))
+	}
+
+	if len(matches) == 0 {
+		return NewToolResult(fmt.Sprintf("No matches for %q in %s (%d files searched)", pattern, dir, filesSearched))
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.Join(matches, "\n"))
+	if truncated {
+		fmt.Fprintf(&sb, "\n[results truncated at %d matches; narrow the pattern, path or glob]", maxGrepMatches)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(&sb, "\n[%d files skipped by path policy]", skipped)
+	}
+	return NewToolResult(sb.String())
+}
+
+// grepFile appends the lines of file p matching re to matches, returning
+// errGrepLimit once there are maxGrepMatches. Binary files are skipped.
+func (t *GrepTool) grepFile(p, name string, re *regexp.Regexp, matches *[]string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, grepBinarySniff)
+	head, err := br.Peek(grepBinarySniff)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	// Over-long lines are matched on their first maxLineLength bytes.
+	lr := newLineReader(br, t.maxLineLength)
+	for lr.Next() {
+		line := lr.Line()
+		if !re.MatchString(line) {
+			continue
+		}
+		if len(*matches) == maxGrepMatches {
+			return errGrepLimit
+		}
+		if len(line) > maxGrepLineDisplay {
+			line = string(trimPartialRune([]byte(line[:maxGrepLineDisplay]))) + "..."
+		}
+		*matches = append(*matches, fmt.Sprintf("%s:%d: %s", name, lr.Number(), line))
+	}
+	return nil
+}
+
+// resolvedWorkspace returns the absolute, symlink-free form of workspace,
+// matching the paths validatePathWithMode returns. Empty stays empty.
+func resolvedWorkspace(workspace string) string {
+	if workspace == "" {
+		return ""
+	}
+	ws, err := filepath.Abs(workspace)
+	if err != nil {
+		return workspace
+	}
+	if real, err := filepath.EvalSymlinks(ws); err == nil {
+		ws = real
+	}
+	return ws
+}
+
+// isDenied reports whether security.denied_paths covers p.
+func (t *GrepTool) isDenied(p, ws string) bool {
+	_, denied := deniedPathRule(t.policyEngine.DeniedPaths(), p, ws)
+	return denied
+}
+
+// grepDisplayPath names p relative to the workspace ws when it lies inside
+// it, so results can be passed straight to read_file.
+func grepDisplayPath(p, ws string) string {
+	if ws != "" && isWithinWorkspace(p, ws) {
+		if rel, err := filepath.Rel(ws, p); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(p)
+}
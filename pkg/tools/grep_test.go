@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+func makeGrepTree(t *testing.T) string {
+	t.Helper()
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	files := map[string]string{
+		"main.go":          "package main\n\nfunc main() {\n\tTODO()\n}\n",
+		"pkg/util/util.go": "package util\n\n// TODO: tidy\nfunc Util() {}\n",
+		"docs/notes.md":    "TODO write docs\n",
+		"bin/app":          "TODO\x00\x01\x02",
+		".git/HEAD":        "TODO ref",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	return dir
+}
+
+// TestGrepTool_FindsMatches verifies matching lines are listed as
+// file:lineno: text, skipping binary files and .git
+func TestGrepTool_FindsMatches(t *testing.T) {
+	dir := makeGrepTree(t)
+	result := NewGrepTool(dir, true).Execute(context.Background(), map[string]interface{}{"pattern": `TODO`})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := "docs/notes.md:1: TODO write docs\nmain.go:4: \tTODO()\npkg/util/util.go:3: // TODO: tidy"
+	if result.ForLLM != want {
+		t.Errorf("Unexpected matches:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+}
+
+// TestGrepTool_GlobAndPath verifies glob filters file names and path narrows
+// the search, with results still relative to the workspace
+func TestGrepTool_GlobAndPath(t *testing.T) {
+	dir := makeGrepTree(t)
+	tool := NewGrepTool(dir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"pattern": `TODO`, "glob": "*.go"})
+	if result.ForLLM != "main.go:4: \tTODO()\npkg/util/util.go:3: // TODO: tidy" {
+		t.Errorf("Expected only .go files, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"pattern": `func \w+`, "path": "pkg"})
+	if result.ForLLM != "pkg/util/util.go:4: func Util() {}" {
+		t.Errorf("Expected matches under pkg only, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"pattern": `nothing-here`})
+	if result.IsError || !strings.Contains(result.ForLLM, "No matches") {
+		t.Errorf("Expected a no-match message, got: %s", result.ForLLM)
+	}
+}
+
+// TestGrepTool_CapsMatches verifies results stop at the match limit with a
+// truncation note
+func TestGrepTool_CapsMatches(t *testing.T) {
+	dir := t.TempDir()
+	var sb strings.Builder
+	for i := 0; i < maxGrepMatches+50; i++ {
+		fmt.Fprintf(&sb, "hit %d\n", i)
+	}
+	os.WriteFile(filepath.Join(dir, "many.txt"), []byte(sb.String()), 0644)
+
+	result := NewGrepTool(dir, true).Execute(context.Background(), map[string]interface{}{"pattern": `^hit`})
+	if got := strings.Count(result.ForLLM, "many.txt:"); got != maxGrepMatches {
+		t.Errorf("Expected %d matches, got %d", maxGrepMatches, got)
+	}
+	if !strings.Contains(result.ForLLM, "[results truncated at 200 matches") {
+		t.Errorf("Expected a truncation note, got tail: %s", result.ForLLM[len(result.ForLLM)-120:])
+	}
+}
+
+// TestGrepTool_RespectsPathPolicy verifies denied paths are not searched and
+// the search cannot start outside the workspace
+func TestGrepTool_RespectsPathPolicy(t *testing.T) {
+	dir := makeGrepTree(t)
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("TODO_SECRET=1\n"), 0644)
+	pe := security.NewPolicyEngine(&config.SecurityConfig{DeniedPaths: []string{".env"}}, nil)
+	tool := NewGrepToolWithPolicy(dir, true, PathPolicyOpts{PolicyEngine: pe})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"pattern": `TODO`})
+	if strings.Contains(result.ForLLM, "SECRET") || !strings.Contains(result.ForLLM, "[1 files skipped by path policy]") {
+		t.Errorf("Expected .env to be skipped, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"pattern": `x`, "path": "../"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside") {
+		t.Errorf("Expected an outside-workspace error, got: %s", result.ForLLM)
+	}
+}
+
+// TestGrepTool_InvalidPattern verifies a bad regexp is reported
+func TestGrepTool_InvalidPattern(t *testing.T) {
+	result := NewGrepTool(t.TempDir(), true).Execute(context.Background(), map[string]interface{}{"pattern": `(`})
+	if !result.IsError || !strings.Contains(result.ForLLM, "invalid pattern") {
+		t.Errorf("Expected an invalid pattern error, got: %s", result.ForLLM)
+	}
+}
+
+// TestGrepTool_ApprovesSearchRootOnce verifies searching an out-of-policy
+// tree in approve mode asks once for the root, not once per file
+func TestGrepTool_ApprovesSearchRootOnce(t *testing.T) {
+	workspace := t.TempDir()
+	outside := makeGrepTree(t)
+	msgBus := bus.NewMessageBus()
+	pe := security.NewPolicyEngine(&config.SecurityConfig{ApprovalTimeout: 5}, msgBus)
+	tool := NewGrepToolWithPolicy(workspace, true, PathPolicyOpts{PathMode: security.ModeApprove, PolicyEngine: pe})
+	tool.SetContext("telegram", "chat1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prompts := make(chan int, 1)
+	go func() {
+		n := 0
+		for {
+			if _, ok := msgBus.SubscribeOutbound(ctx); !ok {
+				prompts <- n
+				return
+			}
+			n++
+			msgBus.PublishInbound(bus.InboundMessage{Channel: "telegram", ChatID: "chat1", Content: "approve"})
+		}
+	}()
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"pattern": `TODO`, "path": outside})
+	cancel()
+	if result.IsError || !strings.Contains(result.ForLLM, "main.go:4:") || !strings.Contains(result.ForLLM, "notes.md:1:") {
+		t.Errorf("Expected matches from the approved tree, got:\n%s", result.ForLLM)
+	}
+	if n := <-prompts; n != 1 {
+		t.Errorf("Expected one approval prompt, got %d", n)
+	}
+}
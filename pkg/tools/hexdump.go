@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+const (
+	defaultHexDumpBytes = 256
+	maxHexDumpBytes     = 4096
+)
+
+// HexDumpTool shows the first bytes of a file as an offset/hex/ASCII dump.
+type HexDumpTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewHexDumpTool(workspace string, restrict bool) *HexDumpTool {
+	return &HexDumpTool{workspace: workspace, restrict: restrict}
+}
+
+func NewHexDumpToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *HexDumpTool {
+	return &HexDumpTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *HexDumpTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *HexDumpTool) Name() string {
+	return "hex_dump"
+}
+
+func (t *HexDumpTool) Description() string {
+	return "Show the first bytes of a file as a hex dump (offset, hex bytes, ASCII). Useful for identifying file types, encodings and corruption."
+}
+
+func (t *HexDumpTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file",
+			},
+			"length": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of bytes to dump (default %d, max %d)", defaultHexDumpBytes, maxHexDumpBytes),
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *HexDumpTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok {
+		return ErrorResult("path is required")
+	}
+
+	length := defaultHexDumpBytes
+	if l, ok := args["length"].(float64); ok {
+		if l < 1 {
+			return ErrorResult("length must be at least 1")
+		}
+		length = min(int(l), maxHexDumpBytes)
+	}
+
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	f, err := os.Open(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open file: %v", err))
+	}
+	defer f.Close()
+
+	buf := make([]byte, length)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	if n == 0 {
+		return NewToolResult(fmt.Sprintf("%s is empty", path))
+	}
+
+	return NewToolResult(fmt.Sprintf("First %d bytes of %s:\n%s", n, path, hex.Dump(buf[:n])))
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHexDumpTool_Format verifies the offset/hex/ASCII layout for known bytes
+func TestHexDumpTool_Format(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR!")
+	os.WriteFile(filepath.Join(tmpDir, "img.png"), data, 0644)
+
+	result := NewHexDumpTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path":   "img.png",
+		"length": float64(16),
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := "First 16 bytes of img.png:\n" +
+		"00000000  89 50 4e 47 0d 0a 1a 0a  00 00 00 0d 49 48 44 52  |.PNG........IHDR|\n"
+	if result.ForLLM != want {
+		t.Errorf("Unexpected dump:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+}
+
+// TestHexDumpTool_ShortFile verifies a file shorter than the requested length
+// is dumped in full without error
+func TestHexDumpTool_ShortFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "short.txt"), []byte("hi\n"), 0644)
+
+	result := NewHexDumpTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path": "short.txt",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.HasPrefix(result.ForLLM, "First 3 bytes of short.txt:\n") ||
+		!strings.Contains(result.ForLLM, "00000000  68 69 0a") || !strings.Contains(result.ForLLM, "|hi.|") {
+		t.Errorf("Unexpected dump: %s", result.ForLLM)
+	}
+}
+
+// TestHexDumpTool_OutsideWorkspace verifies path validation applies
+func TestHexDumpTool_OutsideWorkspace(t *testing.T) {
+	result := NewHexDumpTool(t.TempDir(), true).Execute(context.Background(), map[string]interface{}{
+		"path": "../etc/passwd",
+	})
+	if !result.IsError {
+		t.Error("Expected error for path outside workspace")
+	}
+}
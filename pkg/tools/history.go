@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// DefaultHistorySize is how many tool calls are kept per session when no
+// size is configured.
+const DefaultHistorySize = 50
+
+// HistoryEntry is one tool call recorded in a session's history.
+type HistoryEntry struct {
+	Time    time.Time
+	Tool    string
+	Args    string // JSON with secrets redacted and long values truncated
+	Outcome string // "ok", "error" or "async"
+	Detail  string // first line of the error, if any
+}
+
+// CommandHistory keeps the most recent tool calls of each session. It is
+// separate from the security audit log and only meant for the user's own
+// convenience, e.g. "rerun the last command".
+type CommandHistory struct {
+	mu       sync.Mutex
+	size     int
+	sessions map[string][]HistoryEntry
+}
+
+// NewCommandHistory keeps up to size calls per session (DefaultHistorySize
+// if size <= 0).
+func NewCommandHistory(size int) *CommandHistory {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &CommandHistory{size: size, sessions: make(map[string][]HistoryEntry)}
+}
+
+// Record appends a finished tool call to sessionKey's history, evicting the
+// oldest entry once the history is full.
+func (h *CommandHistory) Record(sessionKey, tool string, args map[string]interface{}, result *ToolResult) {
+	entry := HistoryEntry{
+		Time:    time.Now(),
+		Tool:    tool,
+		Args:    redactArgs(args),
+		Outcome: "ok",
+	}
+	switch {
+	case result == nil:
+	case result.IsError:
+		entry.Outcome = "error"
+		detail, _, _ := strings.Cut(result.ForLLM, "\n")
+		entry.Detail = utils.Truncate(redactText(detail), 120)
+	case result.Async:
+		entry.Outcome = "async"
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entries := append(h.sessions[sessionKey], entry)
+	if len(entries) > h.size {
+		entries = entries[len(entries)-h.size:]
+	}
+	h.sessions[sessionKey] = entries
+}
+
+// Entries returns a copy of sessionKey's history, oldest first.
+func (h *CommandHistory) Entries(sessionKey string) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]HistoryEntry(nil), h.sessions[sessionKey]...)
+}
+
+// sensitiveArgNames are substrings of argument names whose values are never
+// recorded.
+var sensitiveArgNames = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "authorization", "credential", "private_key"}
+
+// secretTextPatterns match secrets embedded in free text such as shell
+// commands; the first group is kept and the rest replaced.
+var secretTextPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(\bbearer\s+)[^\s'"]+`),
+	regexp.MustCompile(`(?i)(\b[\w-]*(?:password|passwd|secret|token|api[_-]?key)[\w-]*\s*[=:]\s*)[^\s'"&]+`),
+	regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+(@)`),
+}
+
+func redactText(s string) string {
+	for _, re := range secretTextPatterns {
+		if re.NumSubexp() == 2 {
+			s = re.ReplaceAllString(s, "${1}[REDACTED]${2}")
+		} else {
+			s = re.ReplaceAllString(s, "${1}[REDACTED]")
+		}
+	}
+	return s
+}
+
+func redactValue(key string, v interface{}) interface{} {
+	lower := strings.ToLower(key)
+	for _, name := range sensitiveArgNames {
+		if strings.Contains(lower, name) {
+			return "[REDACTED]"
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		return utils.Truncate(redactText(val), 200)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = redactValue(k, item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = redactValue(key, item)
+		}
+		return out
+	}
+	return v
+}
+
+// redactArgs renders tool arguments for the history.
+func redactArgs(args map[string]interface{}) string {
+	data, err := json.Marshal(redactValue("", args))
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// CommandHistoryTool lets the agent look up the tool calls made earlier in
+// the current session.
+type CommandHistoryTool struct {
+	history    *CommandHistory
+	sessionKey string
+}
+
+func NewCommandHistoryTool(history *CommandHistory) *CommandHistoryTool {
+	return &CommandHistoryTool{history: history}
+}
+
+// SetSession selects the session whose history is reported.
+func (t *CommandHistoryTool) SetSession(sessionKey string) {
+	t.sessionKey = sessionKey
+}
+
+func (t *CommandHistoryTool) Name() string {
+	return "command_history"
+}
+
+func (t *CommandHistoryTool) Description() string {
+	return "List the tool calls made earlier in this session, most recent last, with their arguments (secrets redacted) and outcome. Use to rerun or review a previous command."
+}
+
+func (t *CommandHistoryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "How many of the most recent calls to return. Default: 10",
+			},
+			"tool": map[string]interface{}{
+				"type":        "string",
+				"description": "Only list calls of this tool (e.g. 'exec')",
+			},
+		},
+	}
+}
+
+func (t *CommandHistoryTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	limit := 10
+	if l, ok := args["limit"].(float64); ok {
+		limit = max(int(l), 1)
+	}
+	toolFilter, _ := args["tool"].(string)
+
+	var entries []HistoryEntry
+	for _, e := range t.history.Entries(t.sessionKey) {
+		if toolFilter == "" || e.Tool == toolFilter {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return NewToolResult("No tool calls recorded in this session yet.")
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Last %d tool call(s) in this session:\n", len(entries))
+	for i, e := range entries {
+		fmt.Fprintf(&sb, "%d. [%s] %s %s -> %s", i+1, e.Time.Format("15:04:05"), e.Tool, e.Args, e.Outcome)
+		if e.Detail != "" {
+			fmt.Fprintf(&sb, ": %s", e.Detail)
+		}
+		sb.WriteByte('\n')
+	}
+	return NewToolResult(strings.TrimRight(sb.String(), "\n"))
+}
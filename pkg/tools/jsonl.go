@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	defaultJSONLLimit = 50
+	maxJSONLLimit     = 1000
+	// maxJSONLLine bounds a single record; longer lines are reported as
+	// malformed.
+	maxJSONLLine = 4 * 1024 * 1024
+	// maxJSONLMalformed bounds how many malformed lines are itemized.
+	maxJSONLMalformed = 20
+)
+
+// jsonlPage is a window of records read from newline-delimited JSON.
+type jsonlPage struct {
+	Offset    int
+	Records   []string // compacted JSON, one per record
+	Malformed []string // "line N: error" for lines skipped within the window
+	Skipped   int      // malformed lines beyond maxJSONLMalformed
+	More      bool     // another non-blank line follows the window
+}
+
+// readJSONL streams r and returns up to limit valid records starting at
+// record index offset. Blank lines are ignored; malformed lines do not count
+// as records and are reported if they fall inside the window. Reading stops
+// as soon as the window is full, so large files are not loaded whole.
+func readJSONL(r io.Reader, offset, limit int) (*jsonlPage, error) {
+	lr := newLineReader(r, maxJSONLLine)
+
+	page := &jsonlPage{Offset: offset}
+	index := 0
+	for lr.Next() {
+		raw := bytes.TrimSpace(lr.line)
+		if len(raw) == 0 {
+			continue
+		}
+		if len(page.Records) == limit {
+			page.More = true
+			break
+		}
+
+		var buf bytes.Buffer
+		var err error
+		if lr.Truncated() {
+			err = fmt.Errorf("record is %d bytes, longer than %d", lr.Len(), maxJSONLLine)
+		} else {
+			err = json.Compact(&buf, raw)
+		}
+		if err != nil {
+			if index >= offset {
+				if len(page.Malformed) < maxJSONLMalformed {
+					page.Malformed = append(page.Malformed, fmt.Sprintf("line %d: %v", lr.Number(), err))
+				} else {
+					page.Skipped++
+				}
+			}
+			continue
+		}
+		if index >= offset {
+			page.Records = append(page.Records, buf.String())
+		}
+		index++
+	}
+	if err := lr.Err(); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Format renders the page with a header describing the window.
+func (p *jsonlPage) Format() string {
+	var sb strings.Builder
+	switch {
+	case len(p.Records) == 0:
+		fmt.Fprintf(&sb, "[jsonl: no records at index %d or later]\n", p.Offset)
+	default:
+		fmt.Fprintf(&sb, "[jsonl: records %d-%d", p.Offset, p.Offset+len(p.Records)-1)
+		if p.More {
+			sb.WriteString("; more records follow")
+		}
+		sb.WriteString("]\n")
+	}
+	for _, rec := range p.Records {
+		sb.WriteString(rec)
+		sb.WriteByte('\n')
+	}
+	if len(p.Malformed) > 0 {
+		fmt.Fprintf(&sb, "[skipped %d malformed line(s)]\n", len(p.Malformed)+p.Skipped)
+		for _, m := range p.Malformed {
+			sb.WriteString(m)
+			sb.WriteByte('\n')
+		}
+		if p.Skipped > 0 {
+			fmt.Fprintf(&sb, "... and %d more\n", p.Skipped)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadFileTool_JSONLFirstRecords verifies only the first N records are
+// returned, compacted, with a note that more follow
+func TestReadFileTool_JSONLFirstRecords(t *testing.T) {
+	tmpDir := t.TempDir()
+	var sb strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&sb, "{\"id\": %d, \"name\": \"row%d\"}\n", i, i)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "data.jsonl"), []byte(sb.String()), 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "data.jsonl", "format": "jsonl", "limit": float64(3)})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := "[jsonl: records 0-2; more records follow]\n" +
+		`{"id":0,"name":"row0"}` + "\n" +
+		`{"id":1,"name":"row1"}` + "\n" +
+		`{"id":2,"name":"row2"}`
+	if result.ForLLM != want {
+		t.Errorf("Unexpected output:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "data.jsonl", "format": "jsonl", "offset": float64(98), "limit": float64(5)})
+	if !strings.HasPrefix(result.ForLLM, "[jsonl: records 98-99]\n") || strings.Contains(result.ForLLM, "more records") {
+		t.Errorf("Expected final two records, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "data.jsonl", "format": "jsonl", "offset": float64(500)})
+	if result.IsError || !strings.Contains(result.ForLLM, "no records at index 500") {
+		t.Errorf("Expected empty window note, got: %s", result.ForLLM)
+	}
+}
+
+// TestReadFileTool_JSONLReportsMalformedLines verifies malformed lines are
+// skipped without counting as records and reported by line number
+func TestReadFileTool_JSONLReportsMalformedLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "{\"a\":1}\n\n{\"a\":2\n{\"a\":3}\nnot json\n{\"a\":4}\n"
+	os.WriteFile(filepath.Join(tmpDir, "bad.ndjson"), []byte(content), 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "bad.ndjson", "format": "jsonl"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{
+		"[jsonl: records 0-2]",
+		`{"a":1}` + "\n" + `{"a":3}` + "\n" + `{"a":4}`,
+		"[skipped 2 malformed line(s)]",
+		"line 3: unexpected end of JSON input",
+		"line 5: invalid character",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in output:\n%s", want, result.ForLLM)
+		}
+	}
+
+	// Record indexes skip malformed lines, so offset 1 starts at {"a":3}.
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "bad.ndjson", "format": "jsonl", "offset": float64(1), "limit": float64(1)})
+	if !strings.Contains(result.ForLLM, `{"a":3}`) || strings.Contains(result.ForLLM, `{"a":1}`) {
+		t.Errorf("Expected record 1 to be {\"a\":3}, got:\n%s", result.ForLLM)
+	}
+}
+
+// TestReadFileTool_UnknownFormat verifies unsupported formats are rejected
+func TestReadFileTool_UnknownFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("x"), 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "a.txt", "format": "csv"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "unknown format") {
+		t.Errorf("Expected unknown format error, got: %s", result.ForLLM)
+	}
+}
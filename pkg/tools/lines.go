@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultMaxLineLength is the longest line, in bytes, that line-based reads
+// return when tools.max_line_length is not set.
+const DefaultMaxLineLength = 1024 * 1024
+
+// lineReader reads lines of any length in bounded memory: at most max bytes
+// of each line are kept, the rest is counted and discarded, so one huge line
+// (minified JS, a JSON dump) cannot exhaust memory the way an unbounded
+// read or bufio.Scanner's ErrTooLong would.
+type lineReader struct {
+	r   *bufio.Reader
+	max int
+	err error
+
+	line    []byte // kept part of the current line, without "\n" or "\r\n"
+	fullLen int    // length of the whole line
+	number  int    // 1-based number of the current line
+}
+
+func newLineReader(r io.Reader, max int) *lineReader {
+	if max <= 0 {
+		max = DefaultMaxLineLength
+	}
+	return &lineReader{r: bufio.NewReaderSize(r, 64*1024), max: max}
+}
+
+// Next advances to the next line and reports whether there was one.
+func (lr *lineReader) Next() bool {
+	lr.line, lr.fullLen = lr.line[:0], 0
+	var tail [2]byte // last two bytes of the line, to spot "\r\n"
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		lr.fullLen += len(chunk)
+		if len(chunk) >= 2 {
+			copy(tail[:], chunk[len(chunk)-2:])
+		} else if len(chunk) == 1 {
+			tail[0], tail[1] = tail[1], chunk[0]
+		}
+		if room := lr.max - len(lr.line); room > 0 {
+			lr.line = append(lr.line, chunk[:min(len(chunk), room)]...)
+		}
+		switch {
+		case err == nil:
+			lr.number++
+			lr.trimNewline(tail[0] == '\r')
+			return true
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err == io.EOF:
+			if lr.fullLen == 0 {
+				return false
+			}
+			lr.number++
+			return true
+		default:
+			lr.err = err
+			return false
+		}
+	}
+}
+
+// trimNewline drops the line terminator from the kept text and the length.
+func (lr *lineReader) trimNewline(crlf bool) {
+	n := 1
+	if crlf && lr.fullLen >= 2 {
+		n = 2
+	}
+	lr.fullLen -= n
+	lr.line = lr.line[:min(len(lr.line), lr.fullLen)]
+}
+
+// Line returns the kept part of the current line.
+func (lr *lineReader) Line() string { return string(lr.line) }
+
+// Len returns the full length of the current line in bytes.
+func (lr *lineReader) Len() int { return lr.fullLen }
+
+// Truncated reports whether the current line exceeds the maximum length.
+func (lr *lineReader) Truncated() bool { return lr.fullLen > lr.max }
+
+// Number returns the 1-based number of the current line.
+func (lr *lineReader) Number() int { return lr.number }
+
+// Err returns the first read error other than io.EOF.
+func (lr *lineReader) Err() error { return lr.err }
+
+// linePage is a range of lines read from a text file.
+type linePage struct {
+	Start int
+	Lines []string
+	More  bool // another line follows the range
+}
+
+// readLineRange returns lines start..end (1-based, inclusive) of r. A line in
+// the range longer than maxLen is an error explaining how to read it instead.
+func readLineRange(r io.Reader, start, end, maxLen int) (*linePage, error) {
+	lr := newLineReader(r, maxLen)
+	page := &linePage{Start: start}
+	for lr.Next() {
+		n := lr.Number()
+		if n < start {
+			continue
+		}
+		if n > end {
+			page.More = true
+			break
+		}
+		if lr.Truncated() {
+			return nil, &lineTooLongError{line: n, length: lr.Len(), max: lr.max}
+		}
+		page.Lines = append(page.Lines, lr.Line())
+	}
+	if err := lr.Err(); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// lineTooLongError reports a line in a requested range above the line limit.
+type lineTooLongError struct {
+	line, length, max int
+}
+
+func (e *lineTooLongError) Error() string {
+	return fmt.Sprintf("line %d is %d bytes, longer than the %d-byte line limit (tools.max_line_length); the file is probably minified or binary. Use hex_dump to inspect it, or read_file without start_line/end_line to get the raw content", e.line, e.length, e.max)
+}
+
+// Format renders the page with a header describing the range.
+func (p *linePage) Format() string {
+	var sb strings.Builder
+	if len(p.Lines) == 0 {
+		fmt.Fprintf(&sb, "[no lines at line %d or later]", p.Start)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "[lines %d-%d", p.Start, p.Start+len(p.Lines)-1)
+	if p.More {
+		sb.WriteString("; more lines follow")
+	}
+	sb.WriteString("]\n")
+	sb.WriteString(strings.Join(p.Lines, "\n"))
+	return sb.String()
+}
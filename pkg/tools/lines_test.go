@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeMinifiedFixture writes a small file whose second line is 3 MiB long,
+// like a minified bundle, and returns its name.
+func writeMinifiedFixture(t *testing.T, dir string) string {
+	t.Helper()
+	content := "// header\n" + strings.Repeat("var a=1;", 3*1024*1024/8) + "\n// footer\r\nlast"
+	if err := os.WriteFile(filepath.Join(dir, "bundle.min.js"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "bundle.min.js"
+}
+
+// TestLineReader_BoundsLongLines verifies a huge line is cut to the limit
+// while its full length and the following lines are still reported
+func TestLineReader_BoundsLongLines(t *testing.T) {
+	content := "short\r\n" + strings.Repeat("x", 200000) + "\nexact\nend"
+	lr := newLineReader(strings.NewReader(content), 5)
+
+	want := []struct {
+		line      string
+		length    int
+		truncated bool
+	}{
+		{"short", 5, false},
+		{"xxxxx", 200000, true},
+		{"exact", 5, false},
+		{"end", 3, false},
+	}
+	for i, w := range want {
+		if !lr.Next() {
+			t.Fatalf("line %d: unexpected end: %v", i+1, lr.Err())
+		}
+		if lr.Line() != w.line || lr.Len() != w.length || lr.Truncated() != w.truncated || lr.Number() != i+1 {
+			t.Errorf("line %d: got %q len=%d truncated=%v", i+1, lr.Line(), lr.Len(), lr.Truncated())
+		}
+		if cap(lr.line) > 64*1024 {
+			t.Errorf("line %d: buffer grew to %d bytes", i+1, cap(lr.line))
+		}
+	}
+	if lr.Next() {
+		t.Errorf("expected end of input, got %q", lr.Line())
+	}
+}
+
+// TestReadFileTool_LineRange verifies start_line/end_line return just those
+// lines and report whether more follow
+func TestReadFileTool_LineRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "log.txt"), []byte("one\ntwo\nthree\nfour\n"), 0644)
+	tool := NewReadFileTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path": "log.txt", "start_line": float64(2), "end_line": float64(3),
+	})
+	if result.IsError || result.ForLLM != "[lines 2-3; more lines follow]\ntwo\nthree" {
+		t.Errorf("Unexpected range read: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "log.txt", "start_line": float64(4)})
+	if result.IsError || result.ForLLM != "[lines 4-4]\nfour" {
+		t.Errorf("Unexpected tail read: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "log.txt", "start_line": float64(3), "end_line": float64(1)})
+	if !result.IsError || !strings.Contains(result.ForLLM, "invalid line range") {
+		t.Errorf("Expected invalid range error, got: %s", result.ForLLM)
+	}
+}
+
+// TestReadFileTool_LineRangeLongLine verifies a pathologically long line in
+// the range errors with guidance, while the lines around it stay readable
+func TestReadFileTool_LineRangeLongLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	name := writeMinifiedFixture(t, tmpDir)
+	tool := NewReadFileToolWithPolicy(tmpDir, true, PathPolicyOpts{MaxLineLength: 64 * 1024})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path": name, "start_line": float64(1), "end_line": float64(2),
+	})
+	if !result.IsError {
+		t.Fatalf("Expected an error for the long line, got %d bytes", len(result.ForLLM))
+	}
+	for _, want := range []string{"line 2 is 3145728 bytes", "65536-byte line limit", "hex_dump"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in: %s", want, result.ForLLM)
+		}
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path": name, "start_line": float64(3), "end_line": float64(4),
+	})
+	if result.IsError || result.ForLLM != "[lines 3-4]\n// footer\nlast" {
+		t.Errorf("Expected the lines after the long one, got: %q", result.ForLLM)
+	}
+}
+
+// TestReadJSONL_LongRecordIsMalformed verifies an over-long record is
+// skipped as malformed instead of failing the whole read
+func TestReadJSONL_LongRecordIsMalformed(t *testing.T) {
+	content := `{"a":1}` + "\n" + `{"big":"` + strings.Repeat("x", maxJSONLLine) + `"}` + "\n" + `{"b":2}` + "\n"
+	page, err := readJSONL(strings.NewReader(content), 0, 10)
+	if err != nil {
+		t.Fatalf("Expected the read to succeed, got: %v", err)
+	}
+	if len(page.Records) != 2 || len(page.Malformed) != 1 || !strings.Contains(page.Malformed[0], "line 2: record is") {
+		t.Errorf("Unexpected page: records=%v malformed=%v", page.Records, page.Malformed)
+	}
+}
@@ -0,0 +1,258 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// Manifest maps slash-separated paths relative to the manifest root to the
+// hex-encoded SHA-256 of the file contents.
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// ManifestTool generates and verifies SHA-256 manifests of a directory tree.
+type ManifestTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewManifestTool(workspace string, restrict bool) *ManifestTool {
+	return &ManifestTool{workspace: workspace, restrict: restrict}
+}
+
+func NewManifestToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *ManifestTool {
+	return &ManifestTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *ManifestTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *ManifestTool) Name() string {
+	return "manifest"
+}
+
+func (t *ManifestTool) Description() string {
+	return "Generate a SHA-256 manifest of a directory tree, or verify a tree against a manifest and report added, removed and changed files"
+}
+
+func (t *ManifestTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"generate", "verify"},
+				"description": "'generate' to hash the tree, 'verify' to compare it against a manifest",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Root directory of the tree",
+			},
+			"manifest_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional manifest file: written by 'generate', read by 'verify'",
+			},
+			"manifest": map[string]interface{}{
+				"type":        "string",
+				"description": "Inline manifest JSON for 'verify' (alternative to manifest_path)",
+			},
+		},
+		"required": []string{"action", "path"},
+	}
+}
+
+func (t *ManifestTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, ok := args["action"].(string)
+	if !ok {
+		return ErrorResult("action is required")
+	}
+
+	path, ok := args["path"].(string)
+	if !ok {
+		return ErrorResult("path is required")
+	}
+
+	root, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var manifestFile string
+	if mp, ok := args["manifest_path"].(string); ok && mp != "" {
+		manifestFile, err = validatePathWithMode(mp, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+	}
+
+	switch action {
+	case "generate":
+		return t.generate(ctx, root, manifestFile)
+	case "verify":
+		inline, _ := args["manifest"].(string)
+		return t.verify(ctx, root, manifestFile, inline)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func (t *ManifestTool) generate(ctx context.Context, root, manifestFile string) *ToolResult {
+	m, err := buildManifest(ctx, root, manifestFile)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to build manifest: %v", err))
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to encode manifest: %v", err))
+	}
+
+	if manifestFile != "" {
+		if err := os.MkdirAll(filepath.Dir(manifestFile), 0755); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
+		}
+		if err := os.WriteFile(manifestFile, data, 0600); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to write manifest: %v", err))
+		}
+		return SilentResult(fmt.Sprintf("Manifest of %d files written to %s", len(m.Files), manifestFile))
+	}
+
+	return NewToolResult(string(data))
+}
+
+func (t *ManifestTool) verify(ctx context.Context, root, manifestFile, inline string) *ToolResult {
+	var data []byte
+	switch {
+	case inline != "":
+		data = []byte(inline)
+	case manifestFile != "":
+		var err error
+		data, err = os.ReadFile(manifestFile)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read manifest: %v", err))
+		}
+	default:
+		return ErrorResult("verify requires manifest or manifest_path")
+	}
+
+	var expected Manifest
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return ErrorResult(fmt.Sprintf("invalid manifest: %v", err))
+	}
+
+	actual, err := buildManifest(ctx, root, manifestFile)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to build manifest: %v", err))
+	}
+
+	added, removed, changed := diffManifests(&expected, actual)
+	if len(added) == 0 && len(removed) == 0 && len(changed) == 0 {
+		return NewToolResult(fmt.Sprintf("OK: %d files match the manifest", len(actual.Files)))
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("MISMATCH: %d added, %d removed, %d changed\n", len(added), len(removed), len(changed)))
+	for _, p := range added {
+		b.WriteString("ADDED:   " + p + "\n")
+	}
+	for _, p := range removed {
+		b.WriteString("REMOVED: " + p + "\n")
+	}
+	for _, p := range changed {
+		b.WriteString("CHANGED: " + p + "\n")
+	}
+	return NewToolResult(b.String())
+}
+
+// buildManifest hashes every regular file under root. WalkDir does not follow
+// symlinks, so the walk cannot loop or leave the tree; symlinks are skipped.
+// The file at exclude (typically the manifest itself) is left out.
+func buildManifest(ctx context.Context, root, exclude string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]string)}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.Type().IsRegular() || p == exclude {
+			return nil
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		m.Files[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// hashFile streams a file through SHA-256.
+func hashFile(path string) (string, error) {
+	return hashFSFile(osFS{}, path)
+}
+
+// hashFSFile returns the hex SHA-256 of the file name in fsys.
+func hashFSFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// diffManifests returns sorted lists of paths added to, removed from and
+// changed in actual relative to expected.
+func diffManifests(expected, actual *Manifest) (added, removed, changed []string) {
+	for p, sum := range actual.Files {
+		want, ok := expected.Files[p]
+		if !ok {
+			added = append(added, p)
+		} else if want != sum {
+			changed = append(changed, p)
+		}
+	}
+	for p := range expected.Files {
+		if _, ok := actual.Files[p]; !ok {
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestManifestTool_GenerateAndVerify verifies that a generated manifest detects
+// modified, deleted and added files on verify
+func TestManifestTool_GenerateAndVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	tree := filepath.Join(tmpDir, "tree")
+	os.MkdirAll(filepath.Join(tree, "sub"), 0755)
+	os.WriteFile(filepath.Join(tree, "a.txt"), []byte("alpha"), 0644)
+	os.WriteFile(filepath.Join(tree, "sub", "b.txt"), []byte("bravo"), 0644)
+	os.WriteFile(filepath.Join(tree, "sub", "c.txt"), []byte("charlie"), 0644)
+
+	tool := NewManifestTool(tmpDir, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{
+		"action":        "generate",
+		"path":          "tree",
+		"manifest_path": "tree.manifest.json",
+	})
+	if result.IsError {
+		t.Fatalf("generate failed: %s", result.ForLLM)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "tree.manifest.json"))
+	if err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+	if len(m.Files) != 3 || m.Files["sub/b.txt"] == "" {
+		t.Fatalf("unexpected manifest contents: %v", m.Files)
+	}
+
+	// Unchanged tree verifies cleanly
+	result = tool.Execute(ctx, map[string]interface{}{
+		"action":        "verify",
+		"path":          "tree",
+		"manifest_path": "tree.manifest.json",
+	})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "OK") {
+		t.Fatalf("expected clean verify, got: %s", result.ForLLM)
+	}
+
+	os.WriteFile(filepath.Join(tree, "a.txt"), []byte("tampered"), 0644)
+	os.Remove(filepath.Join(tree, "sub", "c.txt"))
+	os.WriteFile(filepath.Join(tree, "new.txt"), []byte("new"), 0644)
+
+	result = tool.Execute(ctx, map[string]interface{}{
+		"action":        "verify",
+		"path":          "tree",
+		"manifest_path": "tree.manifest.json",
+	})
+	if result.IsError {
+		t.Fatalf("verify failed: %s", result.ForLLM)
+	}
+	for _, want := range []string{"MISMATCH", "CHANGED: a.txt", "REMOVED: sub/c.txt", "ADDED:   new.txt"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("expected %q in verify report, got:\n%s", want, result.ForLLM)
+		}
+	}
+	if strings.Contains(result.ForLLM, "b.txt") {
+		t.Errorf("unchanged file should not be reported, got:\n%s", result.ForLLM)
+	}
+}
+
+// TestManifestTool_InlineManifest verifies verify accepts an inline manifest
+func TestManifestTool_InlineManifest(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("alpha"), 0644)
+
+	tool := NewManifestTool(tmpDir, true)
+	gen := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "generate",
+		"path":   ".",
+	})
+	if gen.IsError {
+		t.Fatalf("generate failed: %s", gen.ForLLM)
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action":   "verify",
+		"path":     ".",
+		"manifest": gen.ForLLM,
+	})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "OK") {
+		t.Errorf("expected clean verify, got: %s", result.ForLLM)
+	}
+}
+
+// TestManifestTool_SkipsSymlinks verifies symlinks are not followed
+func TestManifestTool_SkipsSymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("alpha"), 0644)
+	if err := os.Symlink(tmpDir, filepath.Join(tmpDir, "loop")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	m, err := buildManifest(context.Background(), tmpDir, "")
+	if err != nil {
+		t.Fatalf("buildManifest failed: %v", err)
+	}
+	if len(m.Files) != 1 {
+		t.Errorf("expected only a.txt in manifest, got: %v", m.Files)
+	}
+}
+
+// TestManifestTool_OutsideWorkspace verifies the root must be inside the workspace
+func TestManifestTool_OutsideWorkspace(t *testing.T) {
+	tool := NewManifestTool(t.TempDir(), true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "generate",
+		"path":   t.TempDir(),
+	})
+	if !result.IsError {
+		t.Errorf("expected error for path outside workspace, got: %s", result.ForLLM)
+	}
+}
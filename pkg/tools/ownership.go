@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// FileOwnerTool reads a file's owner and group and changes its group.
+type FileOwnerTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewFileOwnerTool(workspace string, restrict bool) *FileOwnerTool {
+	return &FileOwnerTool{workspace: workspace, restrict: restrict}
+}
+
+func NewFileOwnerToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *FileOwnerTool {
+	return &FileOwnerTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *FileOwnerTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *FileOwnerTool) Name() string {
+	return "file_owner"
+}
+
+func (t *FileOwnerTool) Description() string {
+	return "Get a file's owner and group, or change its group (chgrp). Group changes are limited to files inside the workspace. Not supported on Windows."
+}
+
+func (t *FileOwnerTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"get", "chgrp"},
+				"description": "'get' to read owner and group, 'chgrp' to change the group",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file or directory",
+			},
+			"group": map[string]interface{}{
+				"type":        "string",
+				"description": "New group name or numeric gid (chgrp only)",
+			},
+		},
+		"required": []string{"action", "path"},
+	}
+}
+
+func (t *FileOwnerTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, ok := args["action"].(string)
+	if !ok {
+		return ErrorResult("action is required")
+	}
+
+	path, ok := args["path"].(string)
+	if !ok {
+		return ErrorResult("path is required")
+	}
+
+	switch action {
+	case "get":
+		return t.get(path)
+	case "chgrp":
+		group, _ := args["group"].(string)
+		return t.chgrp(path, group)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func (t *FileOwnerTool) get(path string) *ToolResult {
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	info, err := os.Stat(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat file: %v", err))
+	}
+	uid, gid, err := fileOwner(info)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	return NewToolResult(fmt.Sprintf("%s: uid=%d(%s) gid=%d(%s) mode=%s",
+		path, uid, userName(uid), gid, groupName(gid), info.Mode().Perm()))
+}
+
+func (t *FileOwnerTool) chgrp(path, group string) *ToolResult {
+	if group == "" {
+		return ErrorResult("group is required for chgrp")
+	}
+
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, true, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	// Ownership changes never leave the workspace, whatever restrict and
+	// path_validation say. validatePathWithMode does not resolve symlinks
+	// when path_validation is off, and chown follows them, so resolve the
+	// path fully here and check it again.
+	realPath, err := t.resolveWithinBoundary(path, resolvedPath)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	gid, err := lookupGroupID(group)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if err := changeGroup(realPath, gid); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to change group: %v", err))
+	}
+
+	return SilentResult(fmt.Sprintf("Changed group of %s to %s", path, group))
+}
+
+// resolveWithinBoundary resolves every symlink in resolvedPath and returns the
+// result if it is inside the boundary path was checked against: its named
+// root, or the workspace.
+func (t *FileOwnerTool) resolveWithinBoundary(path, resolvedPath string) (string, error) {
+	boundary := t.workspace
+	if root, _, err := resolveRoot(path, t.roots); err == nil && root != "" {
+		boundary = root
+	}
+	absBoundary, err := filepath.Abs(boundary)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace path: %w", err)
+	}
+	if real, err := filepath.EvalSymlinks(absBoundary); err == nil {
+		absBoundary = real
+	}
+
+	realPath, err := filepath.EvalSymlinks(resolvedPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", path, err)
+	}
+	if !isWithinWorkspace(realPath, absBoundary) {
+		return "", fmt.Errorf("access denied: symlink resolves outside workspace")
+	}
+	return realPath, nil
+}
+
+// lookupGroupID accepts a numeric gid or a group name.
+func lookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		if gid < 0 {
+			return 0, fmt.Errorf("invalid gid: %d", gid)
+		}
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %v", group, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+func userName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return "?"
+}
+
+func groupName(gid int) string {
+	if g, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+		return g.Name
+	}
+	return "?"
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// TestFileOwnerTool_Get verifies the owner and group of a workspace file are reported
+func TestFileOwnerTool_Get(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file ownership is not supported on Windows")
+	}
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "f.txt"), []byte("x"), 0644)
+
+	result := NewFileOwnerTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"action": "get",
+		"path":   "f.txt",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := fmt.Sprintf("uid=%d(", os.Getuid())
+	if !strings.Contains(result.ForLLM, want) || !strings.Contains(result.ForLLM, fmt.Sprintf("gid=%d(", os.Getgid())) {
+		t.Errorf("Expected current uid/gid in %q", result.ForLLM)
+	}
+}
+
+// TestFileOwnerTool_ChgrpOwnGroup verifies changing to the current group succeeds
+func TestFileOwnerTool_ChgrpOwnGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file ownership is not supported on Windows")
+	}
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "f.txt"), []byte("x"), 0644)
+
+	result := NewFileOwnerTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"action": "chgrp",
+		"path":   "f.txt",
+		"group":  fmt.Sprint(os.Getgid()),
+	})
+	if result.IsError {
+		t.Errorf("Expected success, got: %s", result.ForLLM)
+	}
+}
+
+// TestFileOwnerTool_ChgrpOutsideWorkspace verifies chgrp is denied outside the
+// workspace even when the tool itself is unrestricted
+func TestFileOwnerTool_ChgrpOutsideWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "other.txt")
+	os.WriteFile(outside, []byte("x"), 0644)
+
+	result := NewFileOwnerTool(workspace, false).Execute(context.Background(), map[string]interface{}{
+		"action": "chgrp",
+		"path":   outside,
+		"group":  "0",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside") {
+		t.Errorf("Expected chgrp outside workspace to be denied, got: %s", result.ForLLM)
+	}
+}
+
+// TestFileOwnerTool_ChgrpSymlinkOutsideWorkspace verifies chgrp refuses a
+// workspace symlink to an outside file even with path validation off, where
+// the path itself is not resolved
+func TestFileOwnerTool_ChgrpSymlinkOutsideWorkspace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file ownership is not supported on Windows")
+	}
+	workspace := t.TempDir()
+	outsideDir := t.TempDir()
+	outside := filepath.Join(outsideDir, "other.txt")
+	os.WriteFile(outside, []byte("x"), 0644)
+	if err := os.Symlink(outside, filepath.Join(workspace, "link.txt")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(workspace, "linkdir")); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewFileOwnerToolWithPolicy(workspace, false, PathPolicyOpts{PathMode: security.ModeOff})
+	for _, path := range []string{"link.txt", "linkdir/other.txt"} {
+		result := tool.Execute(context.Background(), map[string]interface{}{
+			"action": "chgrp",
+			"path":   path,
+			"group":  fmt.Sprint(os.Getgid()),
+		})
+		if !result.IsError || !strings.Contains(result.ForLLM, "outside workspace") {
+			t.Errorf("Expected chgrp through %s to be denied, got: %s", path, result.ForLLM)
+		}
+	}
+
+	os.WriteFile(filepath.Join(workspace, "real.txt"), []byte("x"), 0644)
+	os.Symlink("real.txt", filepath.Join(workspace, "inside.txt"))
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "chgrp",
+		"path":   "inside.txt",
+		"group":  fmt.Sprint(os.Getgid()),
+	})
+	if result.IsError {
+		t.Errorf("Expected a symlink within the workspace to be allowed, got: %s", result.ForLLM)
+	}
+}
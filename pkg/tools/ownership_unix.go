@@ -0,0 +1,24 @@
+//go:build !windows
+
+package tools
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the numeric owner and group of a stat result.
+func fileOwner(info os.FileInfo) (uid, gid int, err error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fmt.Errorf("ownership is not available on this platform")
+	}
+	return int(st.Uid), int(st.Gid), nil
+}
+
+// changeGroup sets the group of path, leaving the owner unchanged. Like
+// chown(1) it follows a symlink, so path must already be fully resolved.
+func changeGroup(path string, gid int) error {
+	return os.Chown(path, -1, gid)
+}
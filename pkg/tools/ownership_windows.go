@@ -0,0 +1,15 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+)
+
+// Windows files have ACLs rather than POSIX owner and group ids.
+func fileOwner(info os.FileInfo) (uid, gid int, err error) {
+	return 0, 0, fmt.Errorf("file ownership is not supported on Windows")
+}
+
+func changeGroup(path string, gid int) error {
+	return fmt.Errorf("changing file ownership is not supported on Windows")
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// maxPreviewFileSize bounds the current file read for a write preview.
+const maxPreviewFileSize = 4 << 20
+
+// PreviewWriteTool shows what write_file would change, as a unified diff
+// between a file's current content and proposed content, without writing.
+type PreviewWriteTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewPreviewWriteTool(workspace string, restrict bool) *PreviewWriteTool {
+	return &PreviewWriteTool{workspace: workspace, restrict: restrict}
+}
+
+func NewPreviewWriteToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *PreviewWriteTool {
+	return &PreviewWriteTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *PreviewWriteTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *PreviewWriteTool) Name() string {
+	return "preview_write"
+}
+
+func (t *PreviewWriteTool) Description() string {
+	return "Show a unified diff between a file's current content and proposed new content, without writing anything. A missing file is shown as entirely added. Use to show the user what a write_file call would change."
+}
+
+func (t *PreviewWriteTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path of the file that would be written",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Proposed new content of the file",
+			},
+		},
+		"required": []string{"path", "content"},
+	}
+}
+
+func (t *PreviewWriteTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+	content, ok := args["content"].(string)
+	if !ok {
+		return ErrorResult("content is required")
+	}
+
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	diff, err := PreviewWrite(resolvedPath, path, content)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return NewToolResult(diff)
+}
+
+// PreviewWrite describes how writing content to the file at resolvedPath
+// would change it, labelled with name: a short summary followed by a unified
+// diff. Approval prompts for writes can use it to show the change.
+func PreviewWrite(resolvedPath, name, content string) (string, error) {
+	info, err := os.Stat(resolvedPath)
+	isNew := os.IsNotExist(err)
+	var current []byte
+	switch {
+	case isNew:
+	case err != nil:
+		return "", fmt.Errorf("failed to stat file: %v", err)
+	case info.IsDir():
+		return "", fmt.Errorf("%s is a directory", name)
+	case info.Size() > maxPreviewFileSize:
+		return "", fmt.Errorf("%s is too large to preview (%d bytes, max %d)", name, info.Size(), maxPreviewFileSize)
+	default:
+		if current, err = os.ReadFile(resolvedPath); err != nil {
+			return "", fmt.Errorf("failed to read file: %v", err)
+		}
+	}
+	if bytes.IndexByte(current, 0) >= 0 || strings.IndexByte(content, 0) >= 0 {
+		return "", fmt.Errorf("cannot preview binary content for %s", name)
+	}
+	if isNew && content == "" {
+		return fmt.Sprintf("%s: new empty file (not written)", name), nil
+	}
+	if !isNew && string(current) == content {
+		return fmt.Sprintf("No changes: %s already has this content", name), nil
+	}
+
+	diff, err := UnifiedDiff(name, string(current), content, isNew)
+	if err != nil {
+		return "", fmt.Errorf("cannot preview %s: %v", name, err)
+	}
+	added, removed := 0, 0
+	// Skip the two file header lines.
+	for _, line := range strings.Split(diff, "\n")[2:] {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	summary := fmt.Sprintf("%s: %d line(s) added, %d removed (not written)", name, added, removed)
+	if isNew {
+		summary = fmt.Sprintf("%s: new file, %d line(s) (not written)", name, added)
+	}
+	return summary + "\n" + strings.TrimSuffix(diff, "\n"), nil
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPreviewWriteTool_ModifiedFile verifies additions and deletions of a
+// modified file are shown as a unified diff and nothing is written
+func TestPreviewWriteTool_ModifiedFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	original := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	os.WriteFile(filepath.Join(tmpDir, "list.txt"), []byte(original), 0644)
+
+	proposed := "one\nTWO\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	result := NewPreviewWriteTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path":    "list.txt",
+		"content": proposed,
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := "list.txt: 2 line(s) added, 1 removed (not written)\n" +
+		"--- a/list.txt\n" +
+		"+++ b/list.txt\n" +
+		"@@ -1,5 +1,5 @@\n" +
+		" one\n" +
+		"-two\n" +
+		"+TWO\n" +
+		" three\n" +
+		" four\n" +
+		" five\n" +
+		"@@ -8,3 +8,4 @@\n" +
+		" eight\n" +
+		" nine\n" +
+		" ten\n" +
+		"+eleven"
+	if result.ForLLM != want {
+		t.Errorf("Unexpected preview:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "list.txt")); string(data) != original {
+		t.Error("preview_write must not modify the file")
+	}
+}
+
+// TestPreviewWriteTool_NewFile verifies a missing file is shown as a full add
+func TestPreviewWriteTool_NewFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	result := NewPreviewWriteTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path":    "notes/new.md",
+		"content": "# Title\nbody",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	want := "notes/new.md: new file, 2 line(s) (not written)\n" +
+		"--- /dev/null\n" +
+		"+++ b/notes/new.md\n" +
+		"@@ -0,0 +1,2 @@\n" +
+		"+# Title\n" +
+		"+body\n" +
+		"\\ No newline at end of file"
+	if result.ForLLM != want {
+		t.Errorf("Unexpected preview:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "notes")); !os.IsNotExist(err) {
+		t.Error("preview_write must not create anything")
+	}
+}
+
+// TestPreviewWriteTool_NoChanges verifies identical content is reported as
+// unchanged and paths outside the workspace are refused
+func TestPreviewWriteTool_NoChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "same.txt"), []byte("x\n"), 0644)
+	tool := NewPreviewWriteTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "same.txt", "content": "x\n"})
+	if result.ForLLM != "No changes: same.txt already has this content" {
+		t.Errorf("Expected no changes, got: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "../escape.txt", "content": "x"})
+	if !result.IsError {
+		t.Errorf("Expected path outside workspace to be refused, got: %s", result.ForLLM)
+	}
+}
+
+// TestUnifiedDiff_AppliesBack verifies random edits produce a diff whose
+// kept and added lines rebuild the new text
+func TestUnifiedDiff_AppliesBack(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		var a, b []string
+		for j := 0; j < rng.Intn(30); j++ {
+			a = append(a, fmt.Sprintf("line %d\n", rng.Intn(8)))
+		}
+		for j := 0; j < rng.Intn(30); j++ {
+			b = append(b, fmt.Sprintf("line %d\n", rng.Intn(8)))
+		}
+		ops, ok := diffLines(a, b)
+		if !ok {
+			t.Fatal("unexpected edit limit")
+		}
+		var gotA, gotB strings.Builder
+		for _, op := range ops {
+			if op.kind != '+' {
+				gotA.WriteString(op.text)
+			}
+			if op.kind != '-' {
+				gotB.WriteString(op.text)
+			}
+		}
+		if gotA.String() != strings.Join(a, "") || gotB.String() != strings.Join(b, "") {
+			t.Fatalf("edit script does not rebuild inputs:\na=%q\nb=%q\nops=%v", a, b, ops)
+		}
+	}
+}
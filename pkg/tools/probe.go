@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// probeSniffBytes is how much of the body a ranged GET fetches to identify
+// content the server did not label.
+const probeSniffBytes = 512
+
+// ProbeURLToolOptions configures ProbeURLTool.
+type ProbeURLToolOptions struct {
+	PolicyEngine *security.PolicyEngine
+	SSRFMode     security.PolicyMode
+	Timeout      time.Duration // Whole probe including redirects, default 30s
+}
+
+// ProbeURLTool reports what a URL points at (final status, content type,
+// length and kind) without downloading the body.
+type ProbeURLTool struct {
+	policyEngine *security.PolicyEngine
+	ssrfMode     security.PolicyMode
+	timeout      time.Duration
+	channel      string
+	chatID       string
+}
+
+func NewProbeURLTool() *ProbeURLTool {
+	return NewProbeURLToolWithPolicy(ProbeURLToolOptions{})
+}
+
+func NewProbeURLToolWithPolicy(opts ProbeURLToolOptions) *ProbeURLTool {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return &ProbeURLTool{
+		policyEngine: opts.PolicyEngine,
+		ssrfMode:     opts.SSRFMode,
+		timeout:      opts.Timeout,
+	}
+}
+
+// SetContext implements ContextualTool for IM-based approval.
+func (t *ProbeURLTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *ProbeURLTool) Name() string {
+	return "probe_url"
+}
+
+func (t *ProbeURLTool) Description() string {
+	return "Check what a URL points at without downloading it: final status and URL after redirects, content type, length, and whether it is html, json, xml, text or binary. Use before web_fetch or download when unsure."
+}
+
+func (t *ProbeURLTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "http(s) URL to probe",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// probeSummary is the structured result returned to the agent.
+type probeSummary struct {
+	URL           string `json:"url"`
+	FinalURL      string `json:"final_url"`
+	Status        int    `json:"status"`
+	Redirects     int    `json:"redirects"`
+	Method        string `json:"method"`
+	ContentType   string `json:"content_type,omitempty"`
+	ContentLength *int64 `json:"content_length,omitempty"`
+	Kind          string `json:"kind"`
+}
+
+func (t *ProbeURLTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	urlStr, ok := args["url"].(string)
+	if !ok || urlStr == "" {
+		return ErrorResult("url is required")
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid URL: %v", err))
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return ErrorResult(fmt.Sprintf("only http/https URLs are supported, got: %s", parsedURL.Scheme))
+	}
+	if parsedURL.Host == "" {
+		return ErrorResult("missing domain in URL")
+	}
+
+	ssrfMode := t.policyEngine.EffectiveMode("ssrf", t.ssrfMode, t.channel, t.chatID)
+
+	// Same checks as download: an approved URL is not re-checked at dial
+	// time, but redirects are always validated while protection is on.
+	checkDial := !ssrfMode.IsOff()
+	if !ssrfMode.IsOff() {
+		if err := utils.ValidateURLWithPolicy(urlStr, ssrfPolicy(t.policyEngine)); err != nil {
+			if t.policyEngine == nil {
+				return ErrorResult(fmt.Sprintf("URL blocked: %v", err))
+			}
+			if pErr := t.policyEngine.Evaluate(ctx, ssrfMode, security.Violation{
+				Category: "ssrf",
+				Tool:     "probe_url",
+				Action:   urlStr,
+				Reason:   err.Error(),
+			}, t.channel, t.chatID); pErr != nil {
+				return ErrorResult(fmt.Sprintf("URL blocked: %v", pErr))
+			}
+			checkDial = false
+		}
+	}
+
+	client := newDownloadClient(checkDial, ssrfMode, ssrfPolicy(t.policyEngine))
+	client.Timeout = t.timeout
+
+	summary, err := probe(ctx, client, urlStr)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	out, _ := json.MarshalIndent(summary, "", "  ")
+	return NewToolResult(string(out))
+}
+
+// probe sends a HEAD request, falling back to a GET for the first few bytes
+// when the server rejects HEAD or does not say what it serves.
+func probe(ctx context.Context, client *http.Client, urlStr string) (*probeSummary, error) {
+	resp, err := probeRequest(ctx, client, http.MethodHead, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	var head []byte
+	headIgnored := resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented
+	if headIgnored || (resp.StatusCode < 300 && resp.Header.Get("Content-Type") == "") {
+		resp, err = probeRequest(ctx, client, http.MethodGet, urlStr)
+		if err != nil {
+			return nil, err
+		}
+		head, _ = io.ReadAll(io.LimitReader(resp.Body, probeSniffBytes))
+		resp.Body.Close()
+	}
+
+	summary := &probeSummary{
+		URL:         urlStr,
+		FinalURL:    resp.Request.URL.String(),
+		Status:      resp.StatusCode,
+		Method:      resp.Request.Method,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		summary.Redirects++
+	}
+	if n := probeLength(resp); n >= 0 {
+		summary.ContentLength = &n
+	}
+	if summary.ContentType == "" && len(head) > 0 {
+		summary.ContentType = http.DetectContentType(head)
+	}
+	summary.Kind = contentKind(summary.ContentType)
+	return summary, nil
+}
+
+func probeRequest(ctx context.Context, client *http.Client, method, urlStr string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if method == http.MethodGet {
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", probeSniffBytes-1))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	return resp, nil
+}
+
+// probeLength returns the full size of the resource, reading it from
+// Content-Range when the server answered a ranged GET, or -1 if unknown.
+func probeLength(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		cr := resp.Header.Get("Content-Range")
+		if i := strings.LastIndexByte(cr, '/'); i >= 0 {
+			if n, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				return n
+			}
+		}
+		return -1
+	}
+	// A HEAD answer, or a GET whose range was ignored, describes the whole
+	// body; ContentLength is already -1 when the server did not say.
+	return resp.ContentLength
+}
+
+// contentKind classifies a Content-Type as html, json, xml, text or binary;
+// an empty type is unknown.
+func contentKind(contentType string) string {
+	if contentType == "" {
+		return "unknown"
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	switch {
+	case mt == "text/html" || mt == "application/xhtml+xml":
+		return "html"
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return "json"
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		return "xml"
+	case strings.HasPrefix(mt, "text/") || mt == "application/javascript":
+		return "text"
+	default:
+		return "binary"
+	}
+}
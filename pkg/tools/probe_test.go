@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+func runProbe(t *testing.T, tool *ProbeURLTool, url string) probeSummary {
+	t.Helper()
+	result := tool.Execute(context.Background(), map[string]interface{}{"url": url})
+	if result.IsError {
+		t.Fatalf("Expected success for %s, got: %s", url, result.ForLLM)
+	}
+	var s probeSummary
+	if err := json.Unmarshal([]byte(result.ForLLM), &s); err != nil {
+		t.Fatalf("Expected JSON summary, got %q: %v", result.ForLLM, err)
+	}
+	return s
+}
+
+// TestProbeURLTool_ContentKinds verifies HEAD responses are summarized by
+// content type without fetching the body
+func TestProbeURLTool_ContentKinds(t *testing.T) {
+	var gets int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets++
+		}
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body>hi</body></html>"))
+		case "/api":
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			w.Write([]byte(`{"ok":true}`))
+		case "/blob":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", "4096")
+			w.Write(make([]byte, 4096))
+		}
+	}))
+	defer server.Close()
+
+	tool := NewProbeURLTool()
+	for path, want := range map[string]string{"/page": "html", "/api": "json", "/blob": "binary"} {
+		s := runProbe(t, tool, server.URL+path)
+		if s.Kind != want || s.Status != http.StatusOK || s.Method != http.MethodHead {
+			t.Errorf("%s: got kind=%s status=%d method=%s, want %s via HEAD", path, s.Kind, s.Status, s.Method, want)
+		}
+		if path == "/blob" && (s.ContentLength == nil || *s.ContentLength != 4096) {
+			t.Errorf("%s: expected content_length 4096, got %v", path, s.ContentLength)
+		}
+	}
+	if gets != 0 {
+		t.Errorf("Expected no GET requests, got %d", gets)
+	}
+}
+
+// TestProbeURLTool_FallsBackToRangedGet verifies a server rejecting HEAD is
+// probed with a ranged GET and the total size comes from Content-Range
+func TestProbeURLTool_FallsBackToRangedGet(t *testing.T) {
+	body := strings.Repeat("x", 2000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		http.ServeContent(w, r, "data.txt", time.Time{}, strings.NewReader(body))
+	}))
+	defer server.Close()
+
+	s := runProbe(t, NewProbeURLTool(), server.URL+"/data.txt")
+	if s.Method != http.MethodGet || s.Status != http.StatusPartialContent || s.Kind != "text" {
+		t.Errorf("Expected ranged GET of text, got %+v", s)
+	}
+	if s.ContentLength == nil || *s.ContentLength != 2000 {
+		t.Errorf("Expected content_length 2000, got %v", s.ContentLength)
+	}
+}
+
+// TestProbeURLTool_FollowsRedirects verifies the final URL and redirect count
+func TestProbeURLTool_FollowsRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+		}
+	}))
+	defer server.Close()
+
+	s := runProbe(t, NewProbeURLTool(), server.URL+"/old")
+	if s.FinalURL != server.URL+"/final" || s.Redirects != 2 || s.Kind != "json" {
+		t.Errorf("Expected 2 redirects to /final, got %+v", s)
+	}
+}
+
+// TestProbeURLTool_BlocksRedirectToPrivateIP verifies an approved URL still
+// cannot redirect to a blocked address
+func TestProbeURLTool_BlocksRedirectToPrivateIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	// The loopback test server itself is let through by a trusted chat.
+	pe := security.NewPolicyEngine(&config.SecurityConfig{SSRFProtection: "approve", TrustedChats: []string{"cli:direct"}}, nil)
+	tool := NewProbeURLToolWithPolicy(ProbeURLToolOptions{PolicyEngine: pe, SSRFMode: security.ModeApprove})
+	tool.SetContext("cli", "direct")
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	if !result.IsError || !strings.Contains(result.ForLLM, "redirect blocked") {
+		t.Errorf("Expected the redirect to be blocked, got: %s", result.ForLLM)
+	}
+}
+
+// TestProbeURLTool_BlocksPrivateIP verifies SSRF protection rejects private addresses
+func TestProbeURLTool_BlocksPrivateIP(t *testing.T) {
+	tool := NewProbeURLToolWithPolicy(ProbeURLToolOptions{SSRFMode: security.ModeBlock})
+	result := tool.Execute(context.Background(), map[string]interface{}{"url": "http://10.0.0.1/"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "blocked") {
+		t.Errorf("Expected private IP to be blocked, got: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// ReadLinkTool reports where a symbolic link points without following it for
+// any other purpose.
+type ReadLinkTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewReadLinkTool(workspace string, restrict bool) *ReadLinkTool {
+	return &ReadLinkTool{workspace: workspace, restrict: restrict}
+}
+
+func NewReadLinkToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *ReadLinkTool {
+	return &ReadLinkTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *ReadLinkTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *ReadLinkTool) Name() string {
+	return "read_link"
+}
+
+func (t *ReadLinkTool) Description() string {
+	return "Show the target of a symbolic link and whether it resolves inside or outside the workspace."
+}
+
+func (t *ReadLinkTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the symbolic link",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ReadLinkTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+
+	// Validate the directory holding the link rather than the link itself,
+	// which would be resolved to its target.
+	dir, err := validatePathWithMode(filepath.Dir(path), t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	linkPath := filepath.Join(dir, filepath.Base(path))
+
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat link: %v", err))
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return ErrorResult(fmt.Sprintf("%s is not a symbolic link", path))
+	}
+
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read link: %v", err))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s -> %s\n", path, target)
+
+	resolved, err := filepath.EvalSymlinks(linkPath)
+	dangling := err != nil
+	if dangling {
+		// Report where the link would point even though nothing is there.
+		resolved = target
+		if !filepath.IsAbs(resolved) {
+			resolved = filepath.Join(filepath.Dir(linkPath), resolved)
+		}
+		resolved = filepath.Clean(resolved)
+	}
+	fmt.Fprintf(&sb, "Resolves to: %s", resolved)
+	if dangling {
+		sb.WriteString(" (does not exist)")
+	}
+
+	if t.workspace != "" {
+		workspace, err := filepath.Abs(t.workspace)
+		if err == nil {
+			if real, err := filepath.EvalSymlinks(workspace); err == nil {
+				workspace = real
+			}
+			if isWithinWorkspace(resolved, workspace) {
+				sb.WriteString("\nTarget is inside the workspace")
+			} else {
+				sb.WriteString("\nTarget is OUTSIDE the workspace")
+				if t.restrict {
+					sb.WriteString("; file tools will refuse to follow it")
+				}
+			}
+		}
+	}
+
+	return NewToolResult(sb.String())
+}
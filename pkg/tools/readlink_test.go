@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadLinkTool_InsideWorkspace verifies a relative link's target is shown
+// and recognised as inside the workspace
+func TestReadLinkTool_InsideWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "real"), 0755)
+	if err := os.Symlink("real", filepath.Join(tmpDir, "current")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tool := NewReadLinkTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "current"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.HasPrefix(result.ForLLM, "current -> real\n") {
+		t.Errorf("Expected raw link target, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "inside the workspace") {
+		t.Errorf("Expected target flagged as inside, got: %s", result.ForLLM)
+	}
+}
+
+// TestReadLinkTool_FlagsEscapingTarget verifies a link pointing out of the
+// workspace is readable but flagged, including when it dangles
+func TestReadLinkTool_FlagsEscapingTarget(t *testing.T) {
+	tmpDir := t.TempDir()
+	workspace := filepath.Join(tmpDir, "ws")
+	os.MkdirAll(workspace, 0755)
+	if err := os.Symlink(tmpDir, filepath.Join(workspace, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	os.Symlink("../missing", filepath.Join(workspace, "dangling"))
+
+	tool := NewReadLinkTool(workspace, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": "escape"})
+	if result.IsError || !strings.Contains(result.ForLLM, "OUTSIDE the workspace") {
+		t.Errorf("Expected escaping target to be flagged, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": "dangling"})
+	if result.IsError || !strings.Contains(result.ForLLM, "does not exist") || !strings.Contains(result.ForLLM, "OUTSIDE") {
+		t.Errorf("Expected dangling escaping target to be flagged, got: %s", result.ForLLM)
+	}
+}
+
+// TestReadLinkTool_Rejects verifies non-links and links outside the workspace
+// are refused
+func TestReadLinkTool_Rejects(t *testing.T) {
+	tmpDir := t.TempDir()
+	workspace := filepath.Join(tmpDir, "ws")
+	os.MkdirAll(workspace, 0755)
+	os.WriteFile(filepath.Join(workspace, "plain.txt"), []byte("x"), 0644)
+	os.Symlink(workspace, filepath.Join(tmpDir, "outside-link"))
+
+	tool := NewReadLinkTool(workspace, true)
+	ctx := context.Background()
+
+	if result := tool.Execute(ctx, map[string]interface{}{"path": "plain.txt"}); !result.IsError || !strings.Contains(result.ForLLM, "not a symbolic link") {
+		t.Errorf("Expected regular file to be rejected, got: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"path": "../outside-link"}); !result.IsError {
+		t.Errorf("Expected link outside workspace to be rejected, got: %s", result.ForLLM)
+	}
+}
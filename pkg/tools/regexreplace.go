@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// maxRegexPatternLen bounds pattern size. Go's RE2 engine runs in linear time,
+// so there is no catastrophic backtracking, but huge patterns still cost
+// memory at compile time.
+const maxRegexPatternLen = 4096
+
+// RegexReplaceTool replaces regular expression matches in a single file.
+// Replacements may reference capture groups as $1 or ${name}.
+type RegexReplaceTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewRegexReplaceTool(workspace string, restrict bool) *RegexReplaceTool {
+	return &RegexReplaceTool{workspace: workspace, restrict: restrict}
+}
+
+func NewRegexReplaceToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *RegexReplaceTool {
+	return &RegexReplaceTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *RegexReplaceTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *RegexReplaceTool) Name() string {
+	return "regex_replace"
+}
+
+func (t *RegexReplaceTool) Description() string {
+	return "Replace regular expression matches in a file (Go RE2 syntax). The replacement may use $1 or ${name} to reference capture groups. Returns the number of replacements."
+}
+
+func (t *RegexReplaceTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "The file path to edit",
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression to match",
+			},
+			"replacement": map[string]interface{}{
+				"type":        "string",
+				"description": "Replacement text; $1, ${name} expand to capture groups",
+			},
+			"global": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace every match (default true); false replaces only the first",
+			},
+			"ignore_case": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Match case-insensitively (default false)",
+			},
+		},
+		"required": []string{"path", "pattern", "replacement"},
+	}
+}
+
+func (t *RegexReplaceTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok {
+		return ErrorResult("path is required")
+	}
+
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+
+	replacement, ok := args["replacement"].(string)
+	if !ok {
+		return ErrorResult("replacement is required")
+	}
+
+	global := true
+	if g, ok := args["global"].(bool); ok {
+		global = g
+	}
+	ignoreCase, _ := args["ignore_case"].(bool)
+
+	if len(pattern) > maxRegexPatternLen {
+		return ErrorResult(fmt.Sprintf("pattern too long (%d bytes, max %d)", len(pattern), maxRegexPatternLen))
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	info, err := os.Stat(resolvedPath)
+	if os.IsNotExist(err) {
+		return ErrorResult(fmt.Sprintf("file not found: %s", path))
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat file: %v", err))
+	}
+
+	content, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+
+	newContent, count := regexReplace(re, content, []byte(replacement), global)
+	if count == 0 {
+		return NewToolResult(fmt.Sprintf("No matches for pattern in %s; file unchanged", path))
+	}
+
+	// Preserve original file permissions
+	if err := writeFileAtomic(resolvedPath, newContent, info.Mode().Perm()); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
+	}
+
+	return SilentResult(fmt.Sprintf("Replaced %d match(es) in %s", count, path))
+}
+
+// regexReplace expands template for each match of re in src, or only the
+// first match when global is false, and returns the result with the count.
+func regexReplace(re *regexp.Regexp, src, template []byte, global bool) ([]byte, int) {
+	n := -1
+	if !global {
+		n = 1
+	}
+	matches := re.FindAllSubmatchIndex(src, n)
+	if len(matches) == 0 {
+		return src, 0
+	}
+
+	out := make([]byte, 0, len(src))
+	last := 0
+	for _, m := range matches {
+		out = append(out, src[last:m[0]]...)
+		out = re.Expand(out, template, src, m)
+		last = m[1]
+	}
+	out = append(out, src[last:]...)
+	return out, len(matches)
+}
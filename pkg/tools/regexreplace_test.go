@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRegexReplaceTool_Backreference verifies capture groups are expanded in the replacement
+func TestRegexReplaceTool_Backreference(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "names.txt")
+	os.WriteFile(testFile, []byte("Smith, John\nDoe, Jane\n"), 0640)
+
+	tool := NewRegexReplaceTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":        "names.txt",
+		"pattern":     `(?m)^(\w+), (\w+)$`,
+		"replacement": "$2 $1",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Replaced 2 match") {
+		t.Errorf("Expected count of 2, got: %s", result.ForLLM)
+	}
+
+	content, _ := os.ReadFile(testFile)
+	if string(content) != "John Smith\nJane Doe\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+	if info, _ := os.Stat(testFile); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected permissions preserved, got %v", info.Mode().Perm())
+	}
+}
+
+// TestRegexReplaceTool_FirstOnlyIgnoreCase verifies the global and ignore_case flags
+func TestRegexReplaceTool_FirstOnlyIgnoreCase(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "a.txt")
+	os.WriteFile(testFile, []byte("Foo foo FOO"), 0644)
+
+	tool := NewRegexReplaceTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":        "a.txt",
+		"pattern":     "foo",
+		"replacement": "bar",
+		"global":      false,
+		"ignore_case": true,
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	content, _ := os.ReadFile(testFile)
+	if string(content) != "bar foo FOO" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+}
+
+// TestRegexReplaceTool_NoMatchUnchanged verifies a pattern without matches leaves the file untouched
+func TestRegexReplaceTool_NoMatchUnchanged(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "a.txt")
+	os.WriteFile(testFile, []byte("hello world"), 0644)
+	before, _ := os.Stat(testFile)
+
+	tool := NewRegexReplaceTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":        "a.txt",
+		"pattern":     `\d+`,
+		"replacement": "N",
+	})
+	if result.IsError {
+		t.Fatalf("Expected non-error result, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "No matches") {
+		t.Errorf("Expected no-match message, got: %s", result.ForLLM)
+	}
+
+	after, _ := os.Stat(testFile)
+	content, _ := os.ReadFile(testFile)
+	if string(content) != "hello world" || !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("File should be unchanged, got: %q", content)
+	}
+}
+
+// TestRegexReplaceTool_InvalidPattern verifies patterns that fail to compile are rejected
+func TestRegexReplaceTool_InvalidPattern(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("x"), 0644)
+
+	tool := NewRegexReplaceTool(tmpDir, true)
+	for _, pattern := range []string{`(unclosed`, `(a+)+\1`, strings.Repeat("a", maxRegexPatternLen+1)} {
+		result := tool.Execute(context.Background(), map[string]interface{}{
+			"path":        "a.txt",
+			"pattern":     pattern,
+			"replacement": "y",
+		})
+		if !result.IsError {
+			t.Errorf("Expected error for pattern %.20q", pattern)
+		}
+	}
+}
@@ -3,16 +3,19 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/security"
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools        map[string]Tool
+	mu           sync.RWMutex
+	policyEngine *security.PolicyEngine
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -27,6 +30,14 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// SetPolicyEngine sets the engine whose custom guards are consulted before
+// every tool call.
+func (r *ToolRegistry) SetPolicyEngine(pe *security.PolicyEngine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policyEngine = pe
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -57,6 +68,32 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	var aliases map[string][]string
+	if a, ok := tool.(argAliasTool); ok {
+		aliases = a.argAliases()
+	}
+	if violations := validateArgs(tool.Parameters(), args, aliases); len(violations) > 0 {
+		logger.WarnCF("tool", "Tool call rejected: invalid arguments",
+			map[string]interface{}{
+				"tool":       name,
+				"violations": violations,
+			})
+		msg := fmt.Sprintf("invalid arguments for %s:\n- %s", name, strings.Join(violations, "\n- "))
+		return ErrorResult(msg).WithCode(CodeInvalidArgument).WithError(fmt.Errorf("invalid arguments"))
+	}
+
+	r.mu.RLock()
+	pe := r.policyEngine
+	r.mu.RUnlock()
+	if err := pe.CheckGuards(ctx, name, args, channel, chatID); err != nil {
+		logger.WarnCF("tool", "Tool call denied by guard",
+			map[string]interface{}{
+				"tool":  name,
+				"error": err.Error(),
+			})
+		return ErrorResult(err.Error()).WithError(err)
+	}
+
 	// If tool implements ContextualTool, set context
 	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
 		contextualTool.SetContext(channel, chatID)
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// TestToolRegistry_CustomGuardRunsBeforeExecute verifies a registered guard
+// stops a tool call before it has any effect
+func TestToolRegistry_CustomGuardRunsBeforeExecute(t *testing.T) {
+	tmpDir := t.TempDir()
+	pe := security.NewPolicyEngine(&config.SecurityConfig{}, nil)
+	pe.RegisterGuard(security.GuardFunc(func(ctx context.Context, toolName string, args map[string]interface{}) (*security.Violation, bool) {
+		if path, _ := args["path"].(string); toolName == "write_file" && path == "prod.conf" {
+			return &security.Violation{Reason: "prod config is read-only"}, true
+		}
+		return nil, false
+	}), security.ModeBlock)
+
+	registry := NewToolRegistry()
+	registry.SetPolicyEngine(pe)
+	registry.Register(NewWriteFileTool(tmpDir, true))
+
+	result := registry.Execute(context.Background(), "write_file", map[string]interface{}{"path": "prod.conf", "content": "x"})
+	if !result.IsError {
+		t.Fatal("Expected guard to deny the call")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "prod.conf")); !os.IsNotExist(err) {
+		t.Error("Denied call must not write the file")
+	}
+
+	result = registry.Execute(context.Background(), "write_file", map[string]interface{}{"path": "dev.conf", "content": "x"})
+	if result.IsError {
+		t.Errorf("Expected other paths to pass, got: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,282 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+const (
+	defaultSummaryDepth = 2
+	maxSummaryDepth     = 5
+	defaultSummaryBytes = 4000
+	maxSummaryBytes     = 32000
+	maxSummaryEntries   = 50000 // stop walking huge trees
+	maxTreeDirEntries   = 20    // entries shown per directory in the tree
+	maxKeyFiles         = 20
+)
+
+// keyFileNames are files that say most about a project, highlighted at any depth.
+var keyFileNames = map[string]bool{
+	"README": true, "README.md": true, "README.rst": true, "README.txt": true,
+	"go.mod": true, "package.json": true, "Cargo.toml": true, "pyproject.toml": true,
+	"requirements.txt": true, "setup.py": true, "pom.xml": true, "build.gradle": true,
+	"Makefile": true, "Dockerfile": true, "docker-compose.yml": true, "CMakeLists.txt": true,
+	"LICENSE": true, "AGENTS.md": true, "CLAUDE.md": true,
+}
+
+// RepoSummaryTool produces a compact overview of a project tree for the
+// model's context: key files, file counts by extension and a shallow tree.
+type RepoSummaryTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	showHidden   bool
+	channel      string
+	chatID       string
+}
+
+func NewRepoSummaryTool(workspace string, restrict bool) *RepoSummaryTool {
+	return &RepoSummaryTool{workspace: workspace, restrict: restrict}
+}
+
+func NewRepoSummaryToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *RepoSummaryTool {
+	return &RepoSummaryTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, showHidden: opts.ShowHidden}
+}
+
+func (t *RepoSummaryTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *RepoSummaryTool) Name() string {
+	return "repo_summary"
+}
+
+func (t *RepoSummaryTool) Description() string {
+	return "Summarize a project directory for orientation: key files (README, go.mod, package.json...), file counts by extension and a depth-limited tree. Respects the root .gitignore, hides dotfiles unless show_hidden is set and fits in max_bytes."
+}
+
+func (t *RepoSummaryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Project directory (default: workspace root)",
+			},
+			"depth": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Tree depth (default %d, max %d)", defaultSummaryDepth, maxSummaryDepth),
+			},
+			"max_bytes": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Size budget for the summary (default %d, max %d)", defaultSummaryBytes, maxSummaryBytes),
+			},
+			"show_hidden": showHiddenParam(t.showHidden),
+		},
+	}
+}
+
+// summaryNode is a directory in the summary tree.
+type summaryNode struct {
+	dirs  []string
+	files []string
+}
+
+func (t *RepoSummaryTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	dir, _ := args["path"].(string)
+	if dir == "" {
+		dir = "."
+	}
+	depth := defaultSummaryDepth
+	if d, ok := args["depth"].(float64); ok {
+		depth = min(max(int(d), 1), maxSummaryDepth)
+	}
+	budget := defaultSummaryBytes
+	if b, ok := args["max_bytes"].(float64); ok {
+		budget = min(max(int(b), 200), maxSummaryBytes)
+	}
+
+	root, err := validatePathWithMode(dir, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return ErrorResult(fmt.Sprintf("not a directory: %s", dir))
+	}
+
+	ignore := loadGitignore(root)
+	showHidden := showHiddenArg(args, t.showHidden)
+	nodes := map[string]*summaryNode{".": {}}
+	extCounts := make(map[string]int)
+	var keyFiles []string
+	fileCount, seen := 0, 0
+	truncatedWalk := false
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if p == root {
+			return nil
+		}
+		if seen++; seen > maxSummaryEntries {
+			truncatedWalk = true
+			return filepath.SkipAll
+		}
+
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		// .gitignore applies whether or not hidden entries are shown.
+		if d.Name() == ".git" || (isHidden(d.Name()) && !showHidden) || ignore.Ignored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		parent := filepath.ToSlash(filepath.Dir(rel))
+		level := strings.Count(rel, "/") + 1
+		if d.IsDir() {
+			if level <= depth {
+				nodes[parent].dirs = append(nodes[parent].dirs, d.Name())
+				nodes[rel] = &summaryNode{}
+			}
+			return nil
+		}
+
+		fileCount++
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+		if ext == "" {
+			ext = "(none)"
+		}
+		extCounts[ext]++
+		if keyFileNames[d.Name()] && len(keyFiles) < maxKeyFiles {
+			keyFiles = append(keyFiles, rel)
+		}
+		if level <= depth {
+			nodes[parent].files = append(nodes[parent].files, d.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to walk directory: %v", err))
+	}
+
+	var head strings.Builder
+	fmt.Fprintf(&head, "Project: %s (%d files", dir, fileCount)
+	if truncatedWalk {
+		fmt.Fprintf(&head, ", stopped after %d entries", maxSummaryEntries)
+	}
+	head.WriteString(")\n")
+	if len(keyFiles) > 0 {
+		head.WriteString("Key files: " + strings.Join(keyFiles, ", ") + "\n")
+	}
+	if len(extCounts) > 0 {
+		head.WriteString("By extension: " + formatExtCounts(extCounts, 12) + "\n")
+	}
+	fmt.Fprintf(&head, "Tree (depth %d):\n", depth)
+
+	var tree []string
+	writeSummaryTree(nodes, ".", "", &tree)
+
+	return NewToolResult(fitSummary(head.String(), tree, budget))
+}
+
+// formatExtCounts lists the most common extensions first, folding the rest
+// into an "other" count.
+func formatExtCounts(counts map[string]int, limit int) string {
+	exts := make([]string, 0, len(counts))
+	for ext := range counts {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if counts[exts[i]] != counts[exts[j]] {
+			return counts[exts[i]] > counts[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+
+	parts := make([]string, 0, limit+1)
+	other := 0
+	for i, ext := range exts {
+		if i < limit {
+			parts = append(parts, fmt.Sprintf("%s %d", ext, counts[ext]))
+		} else {
+			other += counts[ext]
+		}
+	}
+	if other > 0 {
+		parts = append(parts, fmt.Sprintf("other %d", other))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeSummaryTree renders directories first, then files, each sorted, with
+// at most maxTreeDirEntries entries per directory.
+func writeSummaryTree(nodes map[string]*summaryNode, rel, indent string, out *[]string) {
+	node := nodes[rel]
+	sort.Strings(node.dirs)
+	sort.Strings(node.files)
+
+	shown := 0
+	total := len(node.dirs) + len(node.files)
+	for _, name := range node.dirs {
+		if shown == maxTreeDirEntries {
+			break
+		}
+		shown++
+		*out = append(*out, indent+name+"/")
+		child := name
+		if rel != "." {
+			child = rel + "/" + name
+		}
+		writeSummaryTree(nodes, child, indent+"  ", out)
+	}
+	for _, name := range node.files {
+		if shown == maxTreeDirEntries {
+			break
+		}
+		shown++
+		*out = append(*out, indent+name)
+	}
+	if total > shown {
+		*out = append(*out, fmt.Sprintf("%s... %d more", indent, total-shown))
+	}
+}
+
+// fitSummary appends tree lines to head until budget bytes would be exceeded.
+func fitSummary(head string, tree []string, budget int) string {
+	const cut = "... (tree truncated to fit budget)\n"
+
+	var sb strings.Builder
+	if len(head)+len(cut) > budget {
+		head = head[:max(budget-len(cut), 0)]
+		sb.WriteString(head)
+		sb.WriteString(cut)
+		return sb.String()
+	}
+	sb.WriteString(head)
+	for i, line := range tree {
+		remaining := budget - sb.Len()
+		needCut := i < len(tree)-1
+		if len(line)+1 > remaining || (needCut && len(line)+1+len(cut) > remaining) {
+			sb.WriteString(cut)
+			break
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
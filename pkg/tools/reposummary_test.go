@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRepoSummaryTool_KeyFilesAndIgnore verifies key files are highlighted,
+// extensions are counted and .gitignore'd paths are left out
+func TestRepoSummaryTool_KeyFilesAndIgnore(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "cmd", "app"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "node_modules", "dep"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("node_modules/\n*.log\n!keep.log\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("# app"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module app"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "cmd", "app", "main.go"), []byte("package main"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "node_modules", "dep", "package.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "debug.log"), []byte("noise"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "keep.log"), []byte("kept"), 0644)
+
+	tool := NewRepoSummaryTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"depth": float64(3)})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	out := result.ForLLM
+
+	if !strings.Contains(out, "Key files: README.md, go.mod") {
+		t.Errorf("Expected key files to be listed, got:\n%s", out)
+	}
+	if !strings.Contains(out, ".go 1") || !strings.Contains(out, ".log 1") {
+		t.Errorf("Expected extension counts, got:\n%s", out)
+	}
+	if !strings.Contains(out, "    main.go") {
+		t.Errorf("Expected nested file in tree, got:\n%s", out)
+	}
+	for _, hidden := range []string{"node_modules", "package.json", "debug.log"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("Expected %s to be ignored, got:\n%s", hidden, out)
+		}
+	}
+	if !strings.Contains(out, "keep.log") {
+		t.Errorf("Expected negated pattern to keep keep.log, got:\n%s", out)
+	}
+}
+
+// TestRepoSummaryTool_FitsBudget verifies large trees are cut to max_bytes
+func TestRepoSummaryTool_FitsBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 30; i++ {
+		dir := filepath.Join(tmpDir, fmt.Sprintf("pkg%02d", i))
+		os.MkdirAll(dir, 0755)
+		for j := 0; j < 30; j++ {
+			os.WriteFile(filepath.Join(dir, fmt.Sprintf("file_with_a_long_name_%02d.go", j)), nil, 0644)
+		}
+	}
+
+	tool := NewRepoSummaryTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"max_bytes": float64(1000)})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if len(result.ForLLM) > 1000 {
+		t.Errorf("Expected summary within 1000 bytes, got %d", len(result.ForLLM))
+	}
+	if !strings.Contains(result.ForLLM, "(900 files)") || !strings.Contains(result.ForLLM, "tree truncated to fit budget") {
+		t.Errorf("Expected file count and truncation marker, got:\n%s", result.ForLLM)
+	}
+}
+
+// TestIgnoreRules_Match verifies anchored, unanchored and dir-only patterns
+func TestIgnoreRules_Match(t *testing.T) {
+	var rules ignoreRules
+	for _, line := range []string{"# comment", "/dist", "build/", "docs/**/*.tmp"} {
+		if rule, ok := parseIgnoreLine(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	cases := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"dist", true, true},
+		{"sub/dist", true, false},
+		{"build", true, true},
+		{"sub/build", true, true},
+		{"build", false, false},
+		{"docs/a/b/x.tmp", false, true},
+		{"docs/x.tmp", false, true},
+		{"other/x.tmp", false, false},
+	}
+	for _, c := range cases {
+		if got := rules.Ignored(c.rel, c.isDir); got != c.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", c.rel, c.isDir, got, c.want)
+		}
+	}
+}
+
+// TestRepoSummaryTool_ShowHidden verifies dotfiles are left out unless
+// show_hidden is set, and that .gitignore still applies when it is
+func TestRepoSummaryTool_ShowHidden(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".github", "workflows"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte(".env\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("SECRET=1"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, ".github", "workflows", "ci.yml"), []byte("on: push"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main"), 0644)
+	tool := NewRepoSummaryTool(tmpDir, true)
+
+	out := tool.Execute(context.Background(), map[string]interface{}{"depth": float64(3)}).ForLLM
+	if !strings.Contains(out, "(1 files)") || strings.Contains(out, ".github") || strings.Contains(out, ".gitignore") {
+		t.Errorf("Expected dotfiles to be excluded by default, got:\n%s", out)
+	}
+
+	out = tool.Execute(context.Background(), map[string]interface{}{"depth": float64(3), "show_hidden": true}).ForLLM
+	for _, want := range []string{".github/", "ci.yml", ".gitignore"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %s with show_hidden, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, ".env") {
+		t.Errorf("Expected .gitignore'd .env to stay hidden, got:\n%s", out)
+	}
+}
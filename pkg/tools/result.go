@@ -27,11 +27,32 @@ type ToolResult struct {
 	// When true, the tool will complete later and notify via callback.
 	Async bool `json:"async"`
 
+	// Artifacts lists the files the tool created or replaced, so the next
+	// tool call or a channel adapter can act on them without parsing
+	// ForLLM or ForUser.
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+
+	// Code classifies a failure (see CodeNotFound and friends). Empty when
+	// the tool did not classify it.
+	Code string `json:"code,omitempty"`
+
+	// Items holds the per-target outcome of a multi-target call, which can
+	// partly succeed; IsError is then set only if every item failed.
+	Items []ItemResult `json:"items,omitempty"`
+
 	// Err is the underlying error (not JSON serialized).
 	// Used for internal error handling and logging.
 	Err error `json:"-"`
 }
 
+// ItemResult is the outcome for one target of a multi-target call.
+type ItemResult struct {
+	Target string `json:"target"`
+	OK     bool   `json:"ok"`
+	Code   string `json:"code,omitempty"`  // error code when !OK
+	Error  string `json:"error,omitempty"` // error message when !OK
+}
+
 // NewToolResult creates a basic ToolResult with content for the LLM.
 // Use this when you need a simple result with default behavior.
 //
@@ -120,6 +141,36 @@ func UserResult(content string) *ToolResult {
 	}
 }
 
+// BatchResult creates a ToolResult for a multi-target call. It is an error
+// only when every item failed, so partial success still reaches the LLM as
+// a result whose Items say which targets to retry.
+//
+// Example:
+//
+//	result := BatchResult(summary, items)
+func BatchResult(forLLM string, items []ItemResult) *ToolResult {
+	result := &ToolResult{
+		ForLLM: forLLM,
+		Items:  items,
+	}
+	if ok, failed := result.ItemCounts(); ok == 0 && failed > 0 {
+		result.IsError = true
+	}
+	return result
+}
+
+// ItemCounts returns how many items succeeded and how many failed.
+func (tr *ToolResult) ItemCounts() (succeeded, failed int) {
+	for _, item := range tr.Items {
+		if item.OK {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+	return succeeded, failed
+}
+
 // MarshalJSON implements custom JSON serialization.
 // The Err field is excluded from JSON output via the json:"-" tag.
 func (tr *ToolResult) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rootNamePattern matches workspace root names. At least two characters, so
+// Windows drive letters ("C:") are never taken for a root prefix.
+var rootNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]+$`)
+
+// ResolveWorkspaceRoots validates named roots for the file tools and returns
+// them with absolute directories, ready for PathPolicyOpts.Roots. A path such
+// as "data:foo/bar.csv" then resolves inside the "data" root and may never
+// leave it.
+func ResolveWorkspaceRoots(roots map[string]string) (map[string]string, error) {
+	abs := make(map[string]string, len(roots))
+	for name, dir := range roots {
+		if !rootNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid workspace root name %q: use letters, digits, '-' and '_', at least 2 characters", name)
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("workspace root %q has no directory", name)
+		}
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("workspace root %q: %w", name, err)
+		}
+		abs[name] = absDir
+	}
+	return abs, nil
+}
+
+// resolveRoot splits a "name:rest" path against roots and returns the root's
+// directory, or "" for a path without a root prefix. A name that looks like a
+// root but is not configured is an error rather than part of a file name, so
+// a typo cannot land in the main workspace. Drive letters are too short to be
+// root names, and URL-like paths ("name://...") are left alone.
+func resolveRoot(path string, roots map[string]string) (root, rest string, err error) {
+	if len(roots) == 0 {
+		return "", "", nil
+	}
+	name, rest, found := strings.Cut(path, ":")
+	if !found || !rootNamePattern.MatchString(name) || strings.HasPrefix(rest, "//") {
+		return "", "", nil
+	}
+	root, ok := roots[name]
+	if !ok {
+		names := make([]string, 0, len(roots))
+		for n := range roots {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return "", "", fmt.Errorf("unknown workspace root %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	if rest == "" {
+		rest = "."
+	}
+	return root, rest, nil
+}
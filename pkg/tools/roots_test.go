@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// setupRoots creates a workspace with "repo" and "data" roots and returns
+// them resolved for PathPolicyOpts.Roots.
+func setupRoots(t *testing.T) (workspace, repo, data string, roots map[string]string) {
+	t.Helper()
+	base := t.TempDir()
+	workspace = filepath.Join(base, "ws")
+	repo = filepath.Join(base, "repo")
+	data = filepath.Join(base, "data")
+	for _, dir := range []string{workspace, repo, data} {
+		os.MkdirAll(dir, 0755)
+	}
+	roots, err := ResolveWorkspaceRoots(map[string]string{"repo": repo, "data": data})
+	if err != nil {
+		t.Fatalf("ResolveWorkspaceRoots failed: %v", err)
+	}
+	return workspace, repo, data, roots
+}
+
+// TestWorkspaceRoots_PrefixesResolveIntoRoots verifies "data:" and "repo:"
+// paths land in their own roots
+func TestWorkspaceRoots_PrefixesResolveIntoRoots(t *testing.T) {
+	workspace, repo, data, roots := setupRoots(t)
+	os.WriteFile(filepath.Join(data, "bar.csv"), []byte("a,b\n"), 0644)
+	opts := PathPolicyOpts{Roots: roots}
+
+	read := NewReadFileToolWithPolicy(workspace, true, opts)
+	result := read.Execute(context.Background(), map[string]interface{}{"path": "data:bar.csv"})
+	if result.IsError || result.ForLLM != "a,b\n" {
+		t.Errorf("Expected data root file, got: %s", result.ForLLM)
+	}
+
+	write := NewWriteFileToolWithPolicy(workspace, true, opts)
+	result = write.Execute(context.Background(), map[string]interface{}{"path": "repo:src/main.go", "content": "package main\n"})
+	if result.IsError {
+		t.Fatalf("Expected write into repo root, got: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "src", "main.go")); err != nil {
+		t.Errorf("Expected file in repo root: %v", err)
+	}
+
+	// Unprefixed paths still use the main workspace.
+	if got, err := validatePathWithMode("notes.txt", workspace, roots, true, security.ModeOff, nil, "", ""); err != nil || got != filepath.Join(workspace, "notes.txt") {
+		t.Errorf("Expected workspace path, got %q, %v", got, err)
+	}
+
+	// Tools built without the roots treat the prefix as part of a file name.
+	if got, err := validatePath("data:bar.csv", workspace, true); err != nil || got != filepath.Join(workspace, "data:bar.csv") {
+		t.Errorf("Expected a plain workspace path without roots, got %q, %v", got, err)
+	}
+}
+
+// TestWorkspaceRoots_CrossRootTraversalBlocked verifies a prefixed path can
+// never leave its root, even with restrict off
+func TestWorkspaceRoots_CrossRootTraversalBlocked(t *testing.T) {
+	workspace, repo, _, roots := setupRoots(t)
+	os.WriteFile(filepath.Join(repo, "secret.txt"), []byte("s"), 0644)
+
+	for _, restrict := range []bool{true, false} {
+		for _, path := range []string{"data:../repo/secret.txt", "data:" + filepath.Join(repo, "secret.txt")} {
+			if _, err := validatePathWithMode(path, workspace, roots, restrict, security.ModeOff, nil, "", ""); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+				t.Errorf("restrict=%v %s: expected traversal to be blocked, got %v", restrict, path, err)
+			}
+		}
+	}
+}
+
+// TestWorkspaceRoots_DotDotCannotCancelPrefix verifies ".." is cleaned within
+// the root's remainder only, so it can neither drop the prefix nor climb out
+func TestWorkspaceRoots_DotDotCannotCancelPrefix(t *testing.T) {
+	workspace, _, data, roots := setupRoots(t)
+
+	got, err := validatePathWithMode("data:sub/../x", workspace, roots, false, security.ModeOff, nil, "", "")
+	if err != nil || got != filepath.Join(data, "x") {
+		t.Errorf("Expected data:sub/../x in the data root, got %q, %v", got, err)
+	}
+
+	for _, restrict := range []bool{true, false} {
+		for _, mode := range []security.PolicyMode{security.ModeOff, security.ModeApprove} {
+			if got, err := validatePathWithMode("data:x/../../y", workspace, roots, restrict, mode, nil, "", ""); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+				t.Errorf("restrict=%v mode=%s: expected data:x/../../y to be denied, got %q, %v", restrict, mode, got, err)
+			}
+		}
+	}
+}
+
+// TestWorkspaceRoots_UnknownRootErrors verifies a root-like prefix that is
+// not configured is rejected, while drive letters and URL-like paths are not
+// taken for root names
+func TestWorkspaceRoots_UnknownRootErrors(t *testing.T) {
+	workspace, _, _, roots := setupRoots(t)
+
+	for _, path := range []string{"notes:2024.txt", "dta:bar.csv", "logs:"} {
+		_, err := validatePathWithMode(path, workspace, roots, true, security.ModeOff, nil, "", "")
+		if err == nil || !strings.Contains(err.Error(), "unknown workspace root") || !strings.Contains(err.Error(), "available: data, repo") {
+			t.Errorf("%s: expected an unknown root error, got %v", path, err)
+		}
+	}
+
+	for _, path := range []string{"a:b", "1a:b", "http://example.com/x"} {
+		if _, _, err := resolveRoot(path, roots); err != nil {
+			t.Errorf("%s: expected no root prefix, got %v", path, err)
+		}
+	}
+}
+
+func TestResolveWorkspaceRoots_RejectsBadNames(t *testing.T) {
+	for _, name := range []string{"C", "1data", "da ta", ""} {
+		if _, err := ResolveWorkspaceRoots(map[string]string{name: t.TempDir()}); err == nil {
+			t.Errorf("Expected root name %q to be rejected", name)
+		}
+	}
+}
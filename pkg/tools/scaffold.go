@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// scaffoldEntry is one file or directory to create.
+type scaffoldEntry struct {
+	path     string
+	resolved string
+	isDir    bool
+	content  string
+}
+
+// ScaffoldTool creates a layout of directories and files in one call. Every
+// path is validated before anything is touched, and everything created is
+// removed again if a later entry fails.
+type ScaffoldTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	maxAffected  int
+	channel      string
+	chatID       string
+}
+
+func NewScaffoldTool(workspace string, restrict bool) *ScaffoldTool {
+	return &ScaffoldTool{workspace: workspace, restrict: restrict}
+}
+
+func NewScaffoldToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *ScaffoldTool {
+	return &ScaffoldTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine, maxAffected: opts.MaxAffectedFiles}
+}
+
+func (t *ScaffoldTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *ScaffoldTool) Name() string {
+	return "scaffold"
+}
+
+func (t *ScaffoldTool) Description() string {
+	return "Create a project layout of directories and files in one call. Existing files are never overwritten; on failure everything created is rolled back."
+}
+
+func (t *ScaffoldTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"entries": map[string]interface{}{
+				"type":        "array",
+				"description": "Entries to create, in order. Parent directories are created automatically.",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Relative path of the entry",
+						},
+						"type": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"file", "dir"},
+							"description": "Entry type. Default: 'file', or 'dir' when path ends with '/'",
+						},
+						"content": map[string]interface{}{
+							"type":        "string",
+							"description": "File content (files only)",
+						},
+					},
+					"required": []string{"path"},
+				},
+			},
+		},
+		"required": []string{"entries"},
+	}
+}
+
+func (t *ScaffoldTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	rawEntries, ok := args["entries"].([]interface{})
+	if !ok || len(rawEntries) == 0 {
+		return ErrorResult("entries is required")
+	}
+	if t.maxAffected > 0 && len(rawEntries) > t.maxAffected {
+		return ErrorResult(fmt.Sprintf("scaffold would create %d entries, above the limit of %d per call; split it into smaller calls", len(rawEntries), t.maxAffected))
+	}
+
+	entries := make([]scaffoldEntry, 0, len(rawEntries))
+	for i, raw := range rawEntries {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return ErrorResult(fmt.Sprintf("entry %d must be an object", i))
+		}
+		path, _ := m["path"].(string)
+		if path == "" {
+			return ErrorResult(fmt.Sprintf("entry %d: path is required", i))
+		}
+		kind, _ := m["type"].(string)
+		if kind == "" {
+			kind = "file"
+			if path[len(path)-1] == '/' {
+				kind = "dir"
+			}
+		}
+		if kind != "file" && kind != "dir" {
+			return ErrorResult(fmt.Sprintf("entry %d: unknown type %q", i, kind))
+		}
+		content, _ := m["content"].(string)
+
+		resolved, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("entry %d (%s): %v", i, path, err))
+		}
+		if kind == "file" {
+			if _, err := os.Lstat(resolved); err == nil {
+				return ErrorResult(fmt.Sprintf("entry %d (%s): file already exists", i, path))
+			}
+		}
+
+		entries = append(entries, scaffoldEntry{path: path, resolved: resolved, isDir: kind == "dir", content: content})
+	}
+
+	// created records paths in creation order so rollback can undo them in reverse.
+	var created []string
+	rollback := func() {
+		for i := len(created) - 1; i >= 0; i-- {
+			os.Remove(created[i])
+		}
+	}
+
+	mkdirs := func(dir string) error {
+		// Record every missing ancestor so rollback removes exactly what we made.
+		var missing []string
+		for d := dir; ; d = filepath.Dir(d) {
+			if _, err := os.Lstat(d); err == nil {
+				break
+			}
+			missing = append(missing, d)
+			if filepath.Dir(d) == d {
+				break
+			}
+		}
+		for i := len(missing) - 1; i >= 0; i-- {
+			if err := os.Mkdir(missing[i], 0755); err != nil {
+				return err
+			}
+			created = append(created, missing[i])
+		}
+		return nil
+	}
+
+	files, dirs := 0, 0
+	var written []string
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			rollback()
+			return ErrorResult(fmt.Sprintf("scaffold cancelled: %v", ctx.Err()))
+		}
+
+		if e.isDir {
+			if err := mkdirs(e.resolved); err != nil {
+				rollback()
+				return ErrorResult(fmt.Sprintf("failed to create directory %s: %v (rolled back)", e.path, err))
+			}
+			dirs++
+			continue
+		}
+
+		if err := mkdirs(filepath.Dir(e.resolved)); err != nil {
+			rollback()
+			return ErrorResult(fmt.Sprintf("failed to create directory for %s: %v (rolled back)", e.path, err))
+		}
+		f, err := os.OpenFile(e.resolved, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			rollback()
+			return ErrorResult(fmt.Sprintf("failed to create file %s: %v (rolled back)", e.path, err))
+		}
+		created = append(created, e.resolved)
+		_, err = f.WriteString(e.content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			rollback()
+			return ErrorResult(fmt.Sprintf("failed to write file %s: %v (rolled back)", e.path, err))
+		}
+		files++
+		written = append(written, e.resolved)
+	}
+
+	result := SilentResult(fmt.Sprintf("Scaffold created: %d files, %d directories", files, dirs))
+	for _, p := range written {
+		result.WithArtifact(p)
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestScaffoldTool_CreatesLayout verifies a multi-level layout is created
+func TestScaffoldTool_CreatesLayout(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewScaffoldTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"entries": []interface{}{
+			map[string]interface{}{"path": "cmd/app/main.go", "content": "package main\n"},
+			map[string]interface{}{"path": "pkg/lib/", "type": "dir"},
+			map[string]interface{}{"path": "docs"},
+			map[string]interface{}{"path": "README.md", "content": "# App\n"},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "cmd", "app", "main.go"))
+	if err != nil || string(content) != "package main\n" {
+		t.Errorf("main.go not created correctly: %q, %v", content, err)
+	}
+	if info, err := os.Stat(filepath.Join(tmpDir, "pkg", "lib")); err != nil || !info.IsDir() {
+		t.Errorf("pkg/lib should be a directory: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(tmpDir, "docs")); err != nil || info.IsDir() {
+		t.Errorf("docs without trailing slash should be an empty file: %v", err)
+	}
+	if !strings.Contains(result.ForLLM, "3 files, 1 directories") {
+		t.Errorf("unexpected summary: %s", result.ForLLM)
+	}
+}
+
+// TestScaffoldTool_InvalidPathCreatesNothing verifies validation happens before any change
+func TestScaffoldTool_InvalidPathCreatesNothing(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewScaffoldTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"entries": []interface{}{
+			map[string]interface{}{"path": "src/a.txt", "content": "a"},
+			map[string]interface{}{"path": "../escape.txt", "content": "x"},
+		},
+	})
+	if !result.IsError {
+		t.Fatal("Expected error for path outside workspace")
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing to be created, found %d entries", len(entries))
+	}
+}
+
+// TestScaffoldTool_RollbackOnFailure verifies entries created before a failure are removed
+func TestScaffoldTool_RollbackOnFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "keep.txt"), []byte("keep"), 0644)
+	tool := NewScaffoldTool(tmpDir, true)
+
+	// "conflict" is created as a file, so creating "conflict/inner.txt" must fail.
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"entries": []interface{}{
+			map[string]interface{}{"path": "new/dir/a.txt", "content": "a"},
+			map[string]interface{}{"path": "conflict", "content": "file"},
+			map[string]interface{}{"path": "conflict/inner.txt", "content": "b"},
+		},
+	})
+	if !result.IsError {
+		t.Fatal("Expected error when a parent is a file")
+	}
+	if !strings.Contains(result.ForLLM, "rolled back") {
+		t.Errorf("Expected rollback to be reported, got: %s", result.ForLLM)
+	}
+
+	for _, p := range []string{"new", "conflict"} {
+		if _, err := os.Lstat(filepath.Join(tmpDir, p)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be rolled back, got err=%v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "keep.txt")); err != nil {
+		t.Errorf("Pre-existing file must survive rollback: %v", err)
+	}
+}
+
+// TestScaffoldTool_RefusesOverwrite verifies existing files are not clobbered
+func TestScaffoldTool_RefusesOverwrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("original"), 0644)
+	tool := NewScaffoldTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"entries": []interface{}{
+			map[string]interface{}{"path": "a.txt", "content": "new"},
+		},
+	})
+	if !result.IsError {
+		t.Fatal("Expected error for existing file")
+	}
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "a.txt"))
+	if string(content) != "original" {
+		t.Errorf("Existing file was modified: %q", content)
+	}
+}
+
+// TestScaffoldTool_MaxAffectedFiles verifies calls over the cap are refused before any change
+func TestScaffoldTool_MaxAffectedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewScaffoldToolWithPolicy(tmpDir, true, PathPolicyOpts{MaxAffectedFiles: 2})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"entries": []interface{}{
+			map[string]interface{}{"path": "a.txt"},
+			map[string]interface{}{"path": "b.txt"},
+			map[string]interface{}{"path": "c.txt"},
+		},
+	})
+	if !result.IsError {
+		t.Fatal("Expected error above the cap")
+	}
+	if !strings.Contains(result.ForLLM, "3 entries") || !strings.Contains(result.ForLLM, "limit of 2") {
+		t.Errorf("Expected count and limit in error, got: %s", result.ForLLM)
+	}
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing to be created, found %d entries", len(entries))
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"entries": []interface{}{
+			map[string]interface{}{"path": "a.txt"},
+			map[string]interface{}{"path": "b.txt"},
+		},
+	})
+	if result.IsError {
+		t.Errorf("Expected call at the cap to succeed, got: %s", result.ForLLM)
+	}
+}
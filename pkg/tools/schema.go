@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// argAliasTool is implemented by tools that still accept renamed parameters
+// under their old names; an alias satisfies "required" for its parameter.
+type argAliasTool interface {
+	argAliases() map[string][]string
+}
+
+// validateArgs checks args against a tool's Parameters() using the JSON
+// Schema keywords the tools declare: type, required, enum, minimum, maximum
+// and pattern, descending into object properties and array items. Unknown
+// keywords and undeclared arguments are ignored. It returns one message per
+// violation, in a stable order.
+func validateArgs(schema, args map[string]interface{}, aliases map[string][]string) []string {
+	var errs []string
+	validateObject(schema, args, "", aliases, &errs)
+	return errs
+}
+
+func validateObject(schema, obj map[string]interface{}, prefix string, aliases map[string][]string, errs *[]string) {
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := obj[name]; ok {
+			continue
+		}
+		if slices.ContainsFunc(aliases[name], func(alias string) bool { _, ok := obj[alias]; return ok }) {
+			continue
+		}
+		*errs = append(*errs, fmt.Sprintf("%s: is required", prefix+name))
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		value, ok := obj[name]
+		if !ok || value == nil {
+			continue
+		}
+		if prop, ok := props[name].(map[string]interface{}); ok {
+			validateValue(prop, value, prefix+name, errs)
+		}
+	}
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string, errs *[]string) {
+	if types := stringList(schema["type"]); len(types) > 0 {
+		if !slices.ContainsFunc(types, func(t string) bool { return matchesType(t, value) }) {
+			*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value)))
+			return
+		}
+	}
+
+	if enum := enumValues(schema["enum"]); enum != nil && !slices.ContainsFunc(enum, func(e interface{}) bool { return enumEqual(e, value) }) {
+		quoted := make([]string, len(enum))
+		for i, e := range enum {
+			quoted[i] = fmt.Sprintf("%v", e)
+		}
+		*errs = append(*errs, fmt.Sprintf("%s: %v is not one of %s", path, value, strings.Join(quoted, ", ")))
+	}
+
+	if n, ok := toFloat(value); ok {
+		if min, ok := toFloat(schema["minimum"]); ok && n < min {
+			*errs = append(*errs, fmt.Sprintf("%s: %v is less than the minimum %v", path, value, schema["minimum"]))
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && n > max {
+			*errs = append(*errs, fmt.Sprintf("%s: %v is greater than the maximum %v", path, value, schema["maximum"]))
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+				*errs = append(*errs, fmt.Sprintf("%s: %q does not match the pattern %s", path, s, pattern))
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(schema, v, path+".", nil, errs)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+}
+
+// matchesType reports whether value, as decoded from JSON or built in Go,
+// is of JSON Schema type t. An integer may be any whole number.
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		n, ok := toFloat(value)
+		return ok && n == math.Trunc(n)
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		switch value.(type) {
+		case []interface{}, []string, []int, []float64, []map[string]interface{}:
+			return true
+		}
+		return false
+	case "null":
+		return value == nil
+	}
+	return true // unknown types are not enforced
+}
+
+func jsonTypeName(value interface{}) string {
+	for _, t := range []string{"boolean", "integer", "number", "string", "object", "array"} {
+		if matchesType(t, value) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// stringList reads a keyword written either as a single string or as a list.
+func stringList(v interface{}) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	case []interface{}:
+		out := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func enumValues(v interface{}) []interface{} {
+	switch e := v.(type) {
+	case []string:
+		out := make([]interface{}, len(e))
+		for i, s := range e {
+			out[i] = s
+		}
+		return out
+	case []int:
+		out := make([]interface{}, len(e))
+		for i, n := range e {
+			out[i] = n
+		}
+		return out
+	case []interface{}:
+		return e
+	}
+	return nil
+}
+
+func enumEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return a == b
+}
@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// schemaTool declares one parameter of each constrained kind and records
+// whether it ran.
+type schemaTool struct{ ran bool }
+
+func (t *schemaTool) Name() string        { return "schema_tool" }
+func (t *schemaTool) Description() string { return "test tool" }
+func (t *schemaTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"mode":  map[string]interface{}{"type": "string", "enum": []string{"fast", "slow"}},
+			"count": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+			"name":  map[string]interface{}{"type": "string", "pattern": `^[a-z][a-z0-9_]*$`},
+			"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []string{"mode"},
+	}
+}
+func (t *schemaTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.ran = true
+	return NewToolResult("ok")
+}
+
+func executeSchemaTool(t *testing.T, args map[string]interface{}) (*ToolResult, bool) {
+	t.Helper()
+	tool := &schemaTool{}
+	registry := NewToolRegistry()
+	registry.Register(tool)
+	return registry.Execute(context.Background(), "schema_tool", args), tool.ran
+}
+
+// TestValidateArgs_Enum verifies a value outside the enum is rejected before
+// the tool runs, naming the allowed values
+func TestValidateArgs_Enum(t *testing.T) {
+	result, ran := executeSchemaTool(t, map[string]interface{}{"mode": "medium"})
+	if !result.IsError || ran {
+		t.Fatalf("expected the call to be rejected before Execute, got %+v", result)
+	}
+	if result.Code != CodeInvalidArgument || !strings.Contains(result.ForLLM, "mode: medium is not one of fast, slow") {
+		t.Errorf("unexpected result: [%s] %s", result.Code, result.ForLLM)
+	}
+
+	if result, ran := executeSchemaTool(t, map[string]interface{}{"mode": "fast"}); result.IsError || !ran {
+		t.Errorf("expected an allowed value to pass, got %s", result.ForLLM)
+	}
+}
+
+// TestValidateArgs_NumericRange verifies minimum and maximum are enforced on
+// JSON numbers and Go integers alike, and that integers must be whole
+func TestValidateArgs_NumericRange(t *testing.T) {
+	tests := []struct {
+		count interface{}
+		want  string
+	}{
+		{float64(0), "count: 0 is less than the minimum 1"},
+		{11, "count: 11 is greater than the maximum 10"},
+		{2.5, "count: expected integer, got number"},
+		{"3", "count: expected integer, got string"},
+		{float64(10), ""},
+		{1, ""},
+	}
+	for _, tc := range tests {
+		result, ran := executeSchemaTool(t, map[string]interface{}{"mode": "fast", "count": tc.count})
+		if tc.want == "" {
+			if result.IsError || !ran {
+				t.Errorf("count=%v: expected success, got %s", tc.count, result.ForLLM)
+			}
+			continue
+		}
+		if ran || !strings.Contains(result.ForLLM, tc.want) {
+			t.Errorf("count=%v: expected %q, got %s", tc.count, tc.want, result.ForLLM)
+		}
+	}
+}
+
+// TestValidateArgs_Pattern verifies strings must match the declared pattern
+func TestValidateArgs_Pattern(t *testing.T) {
+	result, ran := executeSchemaTool(t, map[string]interface{}{"mode": "fast", "name": "Bad Name"})
+	if ran || !strings.Contains(result.ForLLM, `name: "Bad Name" does not match the pattern ^[a-z][a-z0-9_]*$`) {
+		t.Errorf("expected a pattern violation, got %s", result.ForLLM)
+	}
+	if result, ran := executeSchemaTool(t, map[string]interface{}{"mode": "fast", "name": "good_name2"}); result.IsError || !ran {
+		t.Errorf("expected a matching name to pass, got %s", result.ForLLM)
+	}
+}
+
+// TestValidateArgs_ReportsEveryViolation verifies each violation gets its own
+// line, including missing required arguments and bad array items
+func TestValidateArgs_ReportsEveryViolation(t *testing.T) {
+	result, _ := executeSchemaTool(t, map[string]interface{}{
+		"count": 20,
+		"name":  "9lives",
+		"tags":  []interface{}{"ok", 3},
+	})
+	for _, want := range []string{
+		"- mode: is required",
+		"- count: 20 is greater than the maximum 10",
+		`- name: "9lives" does not match`,
+		"- tags[1]: expected string, got integer",
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("expected %q in:\n%s", want, result.ForLLM)
+		}
+	}
+}
+
+// TestValidateArgs_RealTools verifies the built-in schemas accept the argument
+// forms the tools document, including renamed parameters
+func TestValidateArgs_RealTools(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry := NewToolRegistry()
+	registry.Register(NewEditFileTool(dir, true))
+	registry.Register(NewFileTimesTool(dir, true))
+
+	result := registry.Execute(context.Background(), "edit_file", map[string]interface{}{
+		"path": path, "old_text": "hello", "new_text": "bye",
+	})
+	if result.IsError {
+		t.Errorf("expected old_text/new_text to satisfy required, got %s", result.ForLLM)
+	}
+	result = registry.Execute(context.Background(), "file_times", map[string]interface{}{
+		"action": "set", "path": path, "mtime": float64(1700000000),
+	})
+	if result.IsError {
+		t.Errorf("expected an epoch-seconds mtime to be accepted, got %s", result.ForLLM)
+	}
+	result = registry.Execute(context.Background(), "file_times", map[string]interface{}{"action": "touch", "path": path})
+	if !result.IsError || result.Code != CodeInvalidArgument {
+		t.Errorf("expected an unknown action to be rejected, got %s", result.ForLLM)
+	}
+}
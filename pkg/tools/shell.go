@@ -3,13 +3,18 @@ package tools
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/security"
@@ -17,18 +22,30 @@ import (
 
 // ExecToolConfig holds configurable options for ExecTool.
 type ExecToolConfig struct {
-	DenyPatterns  []string // Additional regex deny patterns from config
-	AllowPatterns []string // If set, only matching commands are allowed
-	MaxTimeout    int      // Seconds, default 60
-	PolicyEngine  *security.PolicyEngine
-	ExecGuardMode security.PolicyMode
+	DenyPatterns    []string // Additional regex deny patterns from config
+	AllowPatterns   []string // If set, only matching commands are allowed
+	ShadowPatterns  []string // Audited as would-block but never enforced
+	MaxTimeout      int      // Seconds, default 60
+	KillGracePeriod int      // Seconds between SIGTERM and SIGKILL on timeout, default 5
+	ScrubEnv        []string // Extra env var names or globs to remove, merged with defaultScrubEnv
+	CleanEnv        bool     // Start commands with only cleanEnvKeys from the environment
+	// KillOnOutputLimit stops a command once a stream exceeds maxExecOutput
+	// bytes; otherwise it keeps running and further output is discarded.
+	KillOnOutputLimit bool
+	PolicyEngine      *security.PolicyEngine
+	ExecGuardMode     security.PolicyMode
 }
 
 type ExecTool struct {
 	workingDir          string
 	timeout             time.Duration
+	killGrace           time.Duration
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
+	shadowPatterns      []*regexp.Regexp
+	scrubEnv            []string
+	cleanEnv            bool
+	killOnOutputLimit   bool
 	restrictToWorkspace bool
 	policyEngine        *security.PolicyEngine
 	execGuardMode       security.PolicyMode
@@ -88,6 +105,53 @@ var defaultDenyPatterns = []*regexp.Regexp{
 	regexp.MustCompile(`\bsource\s+.*\.sh\b`),
 }
 
+// defaultScrubEnv names environment variables that commonly hold secrets.
+// Entries are matched case-insensitively and may use path.Match globs.
+var defaultScrubEnv = []string{
+	"*_API_KEY",
+	"*_APIKEY",
+	"*_TOKEN",
+	"*_SECRET",
+	"*_SECRET_KEY",
+	"*_PASSWORD",
+	"*_ACCESS_KEY_ID",
+	"*_SECRET_ACCESS_KEY",
+}
+
+// cleanEnvKeys are the only variables passed through when cleanEnv is set.
+var cleanEnvKeys = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "LC_CTYPE", "TERM", "TZ", "TMPDIR",
+	"SYSTEMROOT", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERPROFILE", "WINDIR",
+}
+
+// childEnv builds the environment for a spawned command from env (os.Environ
+// format), dropping scrubbed variables and, in clean mode, everything not in
+// cleanEnvKeys.
+func (t *ExecTool) childEnv(env []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		upper := strings.ToUpper(name)
+		if t.cleanEnv && !slices.Contains(cleanEnvKeys, upper) {
+			continue
+		}
+		if envNameMatches(upper, t.scrubEnv) {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func envNameMatches(upper string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(p), upper); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func NewExecTool(workingDir string, restrict bool) *ExecTool {
 	return NewExecToolWithConfig(workingDir, restrict, ExecToolConfig{})
 }
@@ -111,16 +175,34 @@ func NewExecToolWithConfig(workingDir string, restrict bool, cfg ExecToolConfig)
 		}
 	}
 
+	var shadowPatterns []*regexp.Regexp
+	for _, p := range cfg.ShadowPatterns {
+		re, err := regexp.Compile(p)
+		if err == nil {
+			shadowPatterns = append(shadowPatterns, re)
+		}
+	}
+
 	timeout := 60 * time.Second
 	if cfg.MaxTimeout > 0 {
 		timeout = time.Duration(cfg.MaxTimeout) * time.Second
 	}
 
+	killGrace := 5 * time.Second
+	if cfg.KillGracePeriod > 0 {
+		killGrace = time.Duration(cfg.KillGracePeriod) * time.Second
+	}
+
 	return &ExecTool{
 		workingDir:          workingDir,
 		timeout:             timeout,
+		killGrace:           killGrace,
 		denyPatterns:        denyPatterns,
 		allowPatterns:       allowPatterns,
+		shadowPatterns:      shadowPatterns,
+		scrubEnv:            append(append([]string{}, defaultScrubEnv...), cfg.ScrubEnv...),
+		cleanEnv:            cfg.CleanEnv,
+		killOnOutputLimit:   cfg.KillOnOutputLimit,
 		restrictToWorkspace: restrict,
 		policyEngine:        cfg.PolicyEngine,
 		execGuardMode:       cfg.ExecGuardMode,
@@ -154,6 +236,10 @@ func (t *ExecTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Optional working directory for the command",
 			},
+			"separate_streams": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return stdout and stderr in separately labelled sections, each truncated on its own, plus the exit code. Default: false",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -200,36 +286,95 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
+	cmd.Env = t.childEnv(os.Environ())
+
+	// On timeout or cancellation, ask the whole process group to terminate
+	// and only SIGKILL it if it is still around after the grace period.
+	done := make(chan struct{})
+	defer close(done)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		err := terminateProcessGroup(cmd)
+		go func() {
+			select {
+			case <-time.After(t.killGrace):
+				killProcessGroup(cmd)
+			case <-done:
+			}
+		}()
+		return err
+	}
+
+	// Output beyond the cap is discarded as it arrives instead of being
+	// buffered and trimmed afterwards.
+	var limitOnce sync.Once
+	onLimit := func() {}
+	if t.killOnOutputLimit {
+		onLimit = func() { limitOnce.Do(cancel) }
+	}
+	stdout := &cappedWriter{limit: maxExecOutput, onLimit: onLimit}
+	stderr := &cappedWriter{limit: maxExecOutput, onLimit: onLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Stop waiting for output once the process is gone (or the kill grace
+	// period is over) even if a background child still holds the pipes open,
+	// so Run always returns and its copying goroutines are joined.
+	cmd.WaitDelay = t.killGrace + time.Second
 
 	err := cmd.Run()
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		output += "\nSTDERR:\n" + stderr.String()
+	if err != nil && cmdCtx.Err() == context.DeadlineExceeded {
+		msg := fmt.Sprintf("Command timed out after %v", t.timeout)
+		return &ToolResult{
+			ForLLM:  msg,
+			ForUser: msg,
+			IsError: true,
+		}
 	}
-
-	if err != nil {
-		if cmdCtx.Err() == context.DeadlineExceeded {
-			msg := fmt.Sprintf("Command timed out after %v", t.timeout)
-			return &ToolResult{
-				ForLLM:  msg,
-				ForUser: msg,
-				IsError: true,
-			}
+	if err != nil && ctx.Err() != nil {
+		msg := "Command cancelled"
+		if out := stdout.String() + stderr.String(); out != "" {
+			msg += "; partial output:\n" + truncateDropped(out, maxExecOutput, stdout.dropped+stderr.dropped)
+		}
+		return &ToolResult{
+			ForLLM:  msg,
+			ForUser: msg,
+			IsError: true,
 		}
-		output += fmt.Sprintf("\nExit code: %v", err)
 	}
-
-	if output == "" {
-		output = "(no output)"
+	// Run only reports ErrWaitDelay or a pipe read error once the command has
+	// exited successfully; either way everything read so far is kept and the
+	// command is not treated as failed.
+	heldOpen := errors.Is(err, exec.ErrWaitDelay)
+	pipeClosed := !heldOpen && pipeClosedEarly(err)
+	if heldOpen || pipeClosed {
+		err = nil
 	}
 
-	maxLen := 10000
-	if len(output) > maxLen {
-		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
+	var output string
+	if separate, _ := args["separate_streams"].(bool); separate {
+		output = formatSeparateStreams(stdout, stderr, err)
+	} else {
+		output = stdout.String()
+		if stderr.buf.Len() > 0 {
+			output += "\nSTDERR:\n" + stderr.String()
+		}
+		if err != nil {
+			output += fmt.Sprintf("\nExit code: %v", err)
+		}
+		if output == "" {
+			output = "(no output)"
+		}
+		output = truncateDropped(output, maxExecOutput, stdout.dropped+stderr.dropped)
+	}
+	if heldOpen {
+		output += "\n(stopped reading output: a background process kept it open after the command exited)"
+	}
+	if pipeClosed {
+		output += "\n(output may be incomplete: the output pipe closed before it was fully read)"
+	}
+	if t.killOnOutputLimit && stdout.dropped+stderr.dropped > 0 {
+		output += fmt.Sprintf("\n(command stopped: output exceeded %d bytes)", maxExecOutput)
 	}
 
 	if err != nil {
@@ -247,13 +392,124 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 }
 
+// maxExecOutput caps the characters of command output returned, per stream
+// when streams are separated.
+const maxExecOutput = 10000
+
+// cappedWriter keeps the first limit bytes written to it and counts the rest.
+// It never fails a write, so the command is not disturbed by a broken pipe;
+// onLimit runs whenever output is discarded.
+type cappedWriter struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int64
+	onLimit func()
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	room := w.limit - w.buf.Len()
+	if len(p) <= room {
+		return w.buf.Write(p)
+	}
+	w.buf.Write(p[:max(room, 0)])
+	w.dropped += int64(len(p) - max(room, 0))
+	w.onLimit()
+	return len(p), nil
+}
+
+func (w *cappedWriter) String() string {
+	return w.buf.String()
+}
+
+// pipeClosedEarly reports whether err from Run comes from reading a command's
+// output pipe after it was closed, rather than from the command itself.
+func pipeClosedEarly(err error) bool {
+	if err == nil {
+		return false
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return false
+	}
+	return errors.Is(err, io.ErrClosedPipe) || errors.Is(err, os.ErrClosed)
+}
+
+func truncateOutput(s string, maxLen int) string {
+	return truncateDropped(s, maxLen, 0)
+}
+
+// truncateDropped truncates s like truncateOutput, counting dropped bytes that
+// were discarded before s was captured as truncated too.
+func truncateDropped(s string, maxLen int, dropped int64) string {
+	if int64(len(s))+dropped > int64(maxLen) {
+		kept := min(len(s), maxLen)
+		return s[:kept] + fmt.Sprintf("\n... (truncated, %d more chars)", int64(len(s)-kept)+dropped)
+	}
+	return s
+}
+
+// formatSeparateStreams labels stdout and stderr, truncating each on its own,
+// and always reports the exit code.
+func formatSeparateStreams(stdoutW, stderrW *cappedWriter, runErr error) string {
+	exitCode := 0
+	if runErr != nil {
+		exitCode = -1
+		if exitErr, ok := runErr.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+
+	stdout := truncateDropped(stdoutW.String(), maxExecOutput, stdoutW.dropped)
+	if stdout == "" {
+		stdout = "(empty)"
+	}
+	stderr := truncateDropped(stderrW.String(), maxExecOutput, stderrW.dropped)
+	if stderr == "" {
+		stderr = "(empty)"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("STDOUT:\n" + stdout)
+	sb.WriteString("\nSTDERR:\n" + stderr)
+	fmt.Fprintf(&sb, "\nExit code: %d", exitCode)
+	if runErr != nil && exitCode == -1 {
+		fmt.Fprintf(&sb, " (%v)", runErr)
+	}
+	return sb.String()
+}
+
 func (t *ExecTool) guardCommand(ctx context.Context, command, cwd string) string {
-	mode := t.execGuardMode
+	mode := t.policyEngine.EffectiveMode("exec_guard", t.execGuardMode, t.channel, t.chatID)
 	cmd := strings.TrimSpace(command)
 	lower := strings.ToLower(cmd)
 
-	// Deny-pattern check (mode-aware)
-	if !mode.IsOff() {
+	// Shadow patterns only record what they would have blocked, so new rules
+	// can be tried against real traffic before they are enforced.
+	for _, pattern := range t.shadowPatterns {
+		if pattern.MatchString(lower) {
+			t.policyEngine.RecordShadow(security.Violation{
+				Category: "exec_guard",
+				Tool:     "exec",
+				Action:   command,
+				Reason:   "shadow pattern matched: " + pattern.String(),
+				RuleName: pattern.String(),
+			}, t.channel, t.chatID)
+			break
+		}
+	}
+
+	// first_seen asks once for every distinct command in the session, giving
+	// the deny or allowlist reason when one applies, and reruns pass.
+	if mode == security.ModeFirstSeen {
+		reason, rule := "first run of this command in this session", "first_seen"
+		if r, ok := t.guardViolation(lower); ok {
+			reason, rule = r.reason, r.rule
+		}
+		if err := t.evaluatePolicy(ctx, mode, command, reason, rule); err != nil {
+			return err.Error()
+		}
+	} else if !mode.IsOff() {
+		// Deny-pattern check (mode-aware)
 		for _, pattern := range t.denyPatterns {
 			if pattern.MatchString(lower) {
 				reason := "dangerous pattern detected: " + pattern.String()
@@ -331,6 +587,30 @@ func (t *ExecTool) guardCommand(ctx context.Context, command, cwd string) string
 	return ""
 }
 
+// guardRule is why the deny or allow patterns object to a command.
+type guardRule struct {
+	reason, rule string
+}
+
+// guardViolation returns the first deny pattern matching the lowercased
+// command or, failing that, the allowlist it is missing from.
+func (t *ExecTool) guardViolation(lower string) (guardRule, bool) {
+	for _, pattern := range t.denyPatterns {
+		if pattern.MatchString(lower) {
+			return guardRule{"dangerous pattern detected: " + pattern.String(), pattern.String()}, true
+		}
+	}
+	if len(t.allowPatterns) == 0 {
+		return guardRule{}, false
+	}
+	for _, pattern := range t.allowPatterns {
+		if pattern.MatchString(lower) {
+			return guardRule{}, false
+		}
+	}
+	return guardRule{"command not in allowlist", "allowlist"}, true
+}
+
 // evaluatePolicy delegates to the PolicyEngine when available.
 func (t *ExecTool) evaluatePolicy(ctx context.Context, mode security.PolicyMode, action, reason, ruleName string) error {
 	if t.policyEngine == nil {
@@ -345,9 +625,22 @@ func (t *ExecTool) evaluatePolicy(ctx context.Context, mode security.PolicyMode,
 		Action:   action,
 		Reason:   reason,
 		RuleName: ruleName,
+		Details:  t.Explain(action).Format(),
 	}, t.channel, t.chatID)
 }
 
+// Explain describes what command would do and how the guard sees it, without
+// running it.
+func (t *ExecTool) Explain(command string) CommandExplanation {
+	return ExplainCommand(command, t.denyPatterns, t.allowPatterns)
+}
+
+// SetKillGracePeriod sets how long a timed-out command may take to exit after
+// SIGTERM before it is killed.
+func (t *ExecTool) SetKillGracePeriod(grace time.Duration) {
+	t.killGrace = grace
+}
+
 func (t *ExecTool) SetTimeout(timeout time.Duration) {
 	t.timeout = timeout
 }
@@ -2,12 +2,16 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/security"
 )
 
@@ -88,6 +92,65 @@ func TestShellTool_Timeout(t *testing.T) {
 	}
 }
 
+// TestShellTool_TimeoutSendsSIGTERMFirst verifies a timed-out command receives
+// SIGTERM and can exit cleanly before the grace period ends
+func TestShellTool_TimeoutSendsSIGTERMFirst(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+	tmpDir := t.TempDir()
+	tool := NewExecTool(tmpDir, false)
+	tool.SetTimeout(200 * time.Millisecond)
+	tool.SetKillGracePeriod(5 * time.Second)
+
+	start := time.Now()
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "trap 'echo term > marker; exit 0' TERM; while true; do sleep 0.05; done",
+	})
+	elapsed := time.Since(start)
+
+	if !result.IsError || !strings.Contains(result.ForLLM, "timed out") {
+		t.Errorf("Expected timeout error, got: %s", result.ForLLM)
+	}
+	if data, err := os.ReadFile(filepath.Join(tmpDir, "marker")); err != nil || strings.TrimSpace(string(data)) != "term" {
+		t.Errorf("Expected SIGTERM trap to run, marker=%q err=%v", data, err)
+	}
+	if elapsed >= 5*time.Second {
+		t.Errorf("Command exiting on SIGTERM should not wait for the grace period, took %v", elapsed)
+	}
+}
+
+// TestShellTool_TimeoutSIGKILLAfterGrace verifies a command that ignores SIGTERM
+// is killed once the grace period expires
+func TestShellTool_TimeoutSIGKILLAfterGrace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+	tmpDir := t.TempDir()
+	tool := NewExecTool(tmpDir, false)
+	tool.SetTimeout(200 * time.Millisecond)
+	tool.SetKillGracePeriod(500 * time.Millisecond)
+
+	start := time.Now()
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "trap 'echo term > marker' TERM; while true; do sleep 0.05; done",
+	})
+	elapsed := time.Since(start)
+
+	if !result.IsError || !strings.Contains(result.ForLLM, "timed out") {
+		t.Errorf("Expected timeout error, got: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "marker")); err != nil {
+		t.Errorf("Expected SIGTERM to be delivered before SIGKILL: %v", err)
+	}
+	if elapsed < 700*time.Millisecond {
+		t.Errorf("Expected SIGKILL only after the grace period, took %v", elapsed)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("Expected command to be killed shortly after the grace period, took %v", elapsed)
+	}
+}
+
 // TestShellTool_WorkingDir verifies custom working directory
 func TestShellTool_WorkingDir(t *testing.T) {
 	// Create temp directory
@@ -367,3 +430,259 @@ func TestExecTool_GuardOff_DangerousAllowed(t *testing.T) {
 		t.Errorf("Expected dangerous command to pass through when exec_guard is off, got: %s", msg)
 	}
 }
+
+func TestExecTool_ChannelModeOverride(t *testing.T) {
+	pe := security.NewPolicyEngine(&config.SecurityConfig{
+		ChannelModes: map[string]map[string]string{
+			"admin": {"exec_guard": "off"},
+		},
+	}, nil)
+	tool := NewExecToolWithConfig("", false, ExecToolConfig{
+		PolicyEngine:  pe,
+		ExecGuardMode: security.ModeBlock,
+	})
+	ctx := context.Background()
+
+	tool.SetContext("telegram", "chat1")
+	if msg := tool.guardCommand(ctx, "sudo ls", ""); msg == "" {
+		t.Error("Expected global block mode to apply on telegram")
+	}
+
+	tool.SetContext("admin", "chat1")
+	if msg := tool.guardCommand(ctx, "sudo ls", ""); msg != "" {
+		t.Errorf("Expected admin channel override to disable the guard, got: %s", msg)
+	}
+}
+
+// auditRecorder collects audit entries for assertions.
+type auditRecorder struct {
+	mu      sync.Mutex
+	entries []security.AuditEntry
+}
+
+func (r *auditRecorder) Record(e security.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+// TestExecTool_ShadowPatternAuditsButAllows verifies a shadow-only pattern
+// records a would_block entry while the command is still allowed
+func TestExecTool_ShadowPatternAuditsButAllows(t *testing.T) {
+	pe := security.NewPolicyEngine(&config.SecurityConfig{}, nil)
+	rec := &auditRecorder{}
+	pe.SetAuditSink(rec)
+
+	tool := NewExecToolWithConfig("", false, ExecToolConfig{
+		ShadowPatterns: []string{`\bmake\s+deploy\b`},
+		PolicyEngine:   pe,
+		ExecGuardMode:  security.ModeBlock,
+	})
+	tool.SetContext("telegram", "chat1")
+	ctx := context.Background()
+
+	if msg := tool.guardCommand(ctx, "make deploy", ""); msg != "" {
+		t.Fatalf("Shadow pattern must not block, got: %s", msg)
+	}
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected one audit entry, got %d", len(rec.entries))
+	}
+	e := rec.entries[0]
+	if e.Decision != security.DecisionWouldBlock || e.RuleName != `\bmake\s+deploy\b` || e.Action != "make deploy" || e.ChatID != "chat1" {
+		t.Errorf("Unexpected audit entry: %+v", e)
+	}
+
+	// Enforced patterns keep blocking alongside the shadow set.
+	if msg := tool.guardCommand(ctx, "sudo ls", ""); msg == "" {
+		t.Error("Expected deny pattern to still block")
+	}
+	if msg := tool.guardCommand(ctx, "make build", ""); msg != "" {
+		t.Errorf("Expected unmatched command to pass, got: %s", msg)
+	}
+	shadow := 0
+	for _, e := range rec.entries {
+		if e.Decision == security.DecisionWouldBlock {
+			shadow++
+		}
+	}
+	if shadow != 1 {
+		t.Errorf("Expected exactly one would_block entry, got %d", shadow)
+	}
+}
+
+// TestShellTool_SeparateStreams verifies stdout and stderr are labelled
+// separately and the exit code is reported
+func TestShellTool_SeparateStreams(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell redirection")
+	}
+	tool := NewExecTool("", false)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command":          "echo to-out; echo to-err >&2; exit 3",
+		"separate_streams": true,
+	})
+	if !result.IsError {
+		t.Error("Expected non-zero exit to be an error")
+	}
+	out, errPart, found := strings.Cut(result.ForLLM, "\nSTDERR:\n")
+	if !found {
+		t.Fatalf("Expected labelled STDERR section, got: %s", result.ForLLM)
+	}
+	if !strings.HasPrefix(out, "STDOUT:\n") || !strings.Contains(out, "to-out") || strings.Contains(out, "to-err") {
+		t.Errorf("Unexpected STDOUT section: %q", out)
+	}
+	if !strings.Contains(errPart, "to-err") || strings.Contains(errPart, "to-out") {
+		t.Errorf("Unexpected STDERR section: %q", errPart)
+	}
+	if !strings.HasSuffix(result.ForLLM, "Exit code: 3") {
+		t.Errorf("Expected exit code 3, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"command":          "echo ok",
+		"separate_streams": true,
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "Exit code: 0") {
+		t.Errorf("Expected exit code 0 on success, got: %s", result.ForLLM)
+	}
+}
+
+// TestShellTool_SeparateStreamsTruncatesEach verifies the size cap applies per stream
+func TestShellTool_SeparateStreamsTruncatesEach(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell redirection")
+	}
+	tool := NewExecTool("", false)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command":          "head -c 20000 /dev/zero | tr '\\0' o; echo tail-err >&2",
+		"separate_streams": true,
+	})
+	if !strings.Contains(result.ForLLM, "truncated") {
+		t.Error("Expected stdout to be truncated")
+	}
+	if !strings.Contains(result.ForLLM, "tail-err") {
+		t.Errorf("Expected stderr to survive stdout truncation, got tail: %q", result.ForLLM[len(result.ForLLM)-100:])
+	}
+}
+
+// TestExecTool_ScrubsSecretEnv verifies default and configured scrub entries
+// hide variables from commands while others stay visible
+func TestExecTool_ScrubsSecretEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX env")
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-secret")
+	t.Setenv("PICOCLAW_TEST_CUSTOM", "custom-secret")
+	t.Setenv("PICOCLAW_TEST_VISIBLE", "visible-value")
+
+	tool := NewExecToolWithConfig("", false, ExecToolConfig{ScrubEnv: []string{"picoclaw_test_custom"}})
+	result := tool.Execute(context.Background(), map[string]interface{}{"command": "env"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "sk-secret") || strings.Contains(result.ForLLM, "custom-secret") {
+		t.Errorf("Scrubbed variables leaked into command env:\n%s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "PICOCLAW_TEST_VISIBLE=visible-value") {
+		t.Error("Expected non-scrubbed variable to stay visible")
+	}
+}
+
+// TestExecTool_CleanEnv verifies clean mode passes only the minimal environment
+func TestExecTool_CleanEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX env")
+	}
+	t.Setenv("PICOCLAW_TEST_VISIBLE", "visible-value")
+
+	tool := NewExecToolWithConfig("", false, ExecToolConfig{CleanEnv: true})
+	result := tool.Execute(context.Background(), map[string]interface{}{"command": "printenv PICOCLAW_TEST_VISIBLE; printenv PATH"})
+	if strings.Contains(result.ForLLM, "visible-value") {
+		t.Errorf("Clean env should drop unlisted variables, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "/") {
+		t.Errorf("Expected PATH to be kept, got: %s", result.ForLLM)
+	}
+}
+
+// TestShellTool_OutputLimitKillsCommand verifies an endless producer is
+// stopped as soon as it overruns the output cap when kill-on-limit is set
+func TestShellTool_OutputLimitKillsCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("yes is not available on windows")
+	}
+	tool := NewExecToolWithConfig("", false, ExecToolConfig{KillOnOutputLimit: true})
+	tool.SetTimeout(30 * time.Second)
+
+	start := time.Now()
+	result := tool.Execute(context.Background(), map[string]interface{}{"command": "yes"})
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("Expected command to be stopped promptly, took %v", elapsed)
+	}
+	if !strings.Contains(result.ForLLM, "command stopped: output exceeded") {
+		t.Errorf("Expected output limit notice, got tail: %q", result.ForLLM[max(len(result.ForLLM)-200, 0):])
+	}
+	if len(result.ForLLM) > maxExecOutput+500 {
+		t.Errorf("Expected output capped near %d bytes, got %d", maxExecOutput, len(result.ForLLM))
+	}
+}
+
+// TestShellTool_OutputLimitKeepsRunning verifies excess output is discarded
+// while the command runs to completion by default
+func TestShellTool_OutputLimitKeepsRunning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("head and tr are not available on windows")
+	}
+	tmpDir := t.TempDir()
+	tool := NewExecTool(tmpDir, false)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "head -c 1000000 /dev/zero | tr '\\0' a; echo done > marker",
+	})
+	if result.IsError {
+		t.Fatalf("Expected command to finish, got: %s", result.ForLLM[max(len(result.ForLLM)-200, 0):])
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "marker")); err != nil {
+		t.Errorf("Expected command to run to completion: %v", err)
+	}
+	want := fmt.Sprintf("truncated, %d more chars", 1000000-maxExecOutput)
+	if !strings.Contains(result.ForLLM, want) {
+		t.Errorf("Expected %q, got tail: %q", want, result.ForLLM[max(len(result.ForLLM)-200, 0):])
+	}
+}
+
+// TestShellTool_FirstSeenAsksForEveryNewCommand verifies first_seen mode
+// holds even a harmless command the first time, reporting the deny reason
+// when a pattern matches
+func TestShellTool_FirstSeenAsksForEveryNewCommand(t *testing.T) {
+	pe := security.NewPolicyEngine(&config.SecurityConfig{}, nil)
+	tool := NewExecToolWithConfig(t.TempDir(), false, ExecToolConfig{PolicyEngine: pe, ExecGuardMode: security.ModeFirstSeen})
+
+	// The CLI cannot prompt, so approval falls back to blocking.
+	result := tool.Execute(context.Background(), map[string]interface{}{"command": "echo hi"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "first run of this command") {
+		t.Errorf("Expected a new command to need approval, got: %s", result.ForLLM)
+	}
+	result = tool.Execute(context.Background(), map[string]interface{}{"command": "rm -rf build"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "dangerous pattern detected") {
+		t.Errorf("Expected the deny reason for a dangerous command, got: %s", result.ForLLM)
+	}
+}
+
+// TestShellTool_SessionOverrideTightensOffMode verifies a /policy override to
+// block is enforced even when exec_guard is configured off
+func TestShellTool_SessionOverrideTightensOffMode(t *testing.T) {
+	pe := security.NewPolicyEngine(&config.SecurityConfig{}, nil)
+	if err := pe.OverrideMode("telegram", "42", "exec_guard", security.ModeBlock, time.Hour); err != nil {
+		t.Fatalf("OverrideMode failed: %v", err)
+	}
+	tool := NewExecToolWithConfig("", false, ExecToolConfig{PolicyEngine: pe, ExecGuardMode: security.ModeOff})
+	tool.SetContext("telegram", "42")
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"command": "rm -rf /tmp/picoclaw-override-test"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "blocked by security policy") {
+		t.Errorf("Expected the override to block the command, got: %s", result.ForLLM)
+	}
+}
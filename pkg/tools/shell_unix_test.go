@@ -0,0 +1,122 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestShellTool_CancelStopsCommand verifies cancelling the context ends a
+// long command promptly, kills its whole process group and leaks no goroutines
+func TestShellTool_CancelStopsCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewExecTool(tmpDir, false)
+	tool.SetTimeout(0)
+	tool.SetKillGracePeriod(200 * time.Millisecond)
+
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+
+	start := time.Now()
+	result := tool.Execute(ctx, map[string]interface{}{
+		"command": "sleep 30 & echo $! > bg.pid; echo started; sleep 30",
+	})
+	elapsed := time.Since(start)
+
+	if !result.IsError || !strings.Contains(result.ForLLM, "Command cancelled") || !strings.Contains(result.ForLLM, "started") {
+		t.Errorf("Expected cancellation with partial output, got: %s", result.ForLLM)
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("Expected command to stop promptly after cancel, took %v", elapsed)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "bg.pid"))
+	if err != nil {
+		t.Fatalf("Failed to read background pid: %v", err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	deadline := time.Now().Add(2 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			t.Errorf("Background process %d survived cancellation", pid)
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Errorf("Goroutine leak: %d before, %d after", before, runtime.NumGoroutine())
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestShellTool_BackgroundChildHoldingOutput verifies the tool returns once
+// the shell exits even if a detached child keeps stdout open
+func TestShellTool_BackgroundChildHoldingOutput(t *testing.T) {
+	tool := NewExecTool(t.TempDir(), false)
+	tool.SetKillGracePeriod(200 * time.Millisecond)
+
+	start := time.Now()
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "echo hello; (sleep 2 &)",
+	})
+	if elapsed := time.Since(start); elapsed > 1900*time.Millisecond {
+		t.Errorf("Expected not to wait for the background child, took %v", elapsed)
+	}
+	if result.IsError || !strings.Contains(result.ForLLM, "hello") || !strings.Contains(result.ForLLM, "background process kept it open") {
+		t.Errorf("Expected output with a held-open note, got: %s", result.ForLLM)
+	}
+}
+
+// TestShellTool_CapturesOutputOfFastExit verifies output written just before
+// the command exits is captured in full, including output a short-lived
+// background child writes after the shell is gone
+func TestShellTool_CapturesOutputOfFastExit(t *testing.T) {
+	tool := NewExecTool(t.TempDir(), false)
+	want := strings.Repeat("x", 8000)
+
+	for i := 0; i < 20; i++ {
+		result := tool.Execute(context.Background(), map[string]interface{}{
+			"command": "printf '%8000s' '' | tr ' ' x; exit 0",
+		})
+		if result.IsError || result.ForLLM != want {
+			t.Fatalf("Run %d: expected all %d bytes without error, got %d bytes (error=%v)", i, len(want), len(result.ForLLM), result.IsError)
+		}
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "echo early; (sleep 0.2; echo late) &",
+	})
+	if result.IsError || result.ForLLM != "early\nlate\n" {
+		t.Errorf("Expected output from the exited shell and its child, got: %q", result.ForLLM)
+	}
+}
+
+// TestPipeClosedEarly verifies only pipe read errors, not exit statuses, are
+// treated as an output pipe closing early
+func TestPipeClosedEarly(t *testing.T) {
+	if !pipeClosedEarly(fmt.Errorf("read |0: %w", os.ErrClosed)) || !pipeClosedEarly(io.ErrClosedPipe) {
+		t.Error("Expected closed-pipe errors to be recognized")
+	}
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	if pipeClosedEarly(exitErr) || pipeClosedEarly(nil) || pipeClosedEarly(errors.New("boom")) {
+		t.Error("Expected exit errors and other errors not to be treated as a closed pipe")
+	}
+}
@@ -0,0 +1,18 @@
+package tools
+
+import "os/exec"
+
+// Windows has no SIGTERM or POSIX process groups; timed-out commands are
+// killed immediately.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return killProcessGroup(cmd)
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// StatFileTool reports a path's type, size, permissions and modification
+// time without reading or listing it.
+type StatFileTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewStatFileTool(workspace string, restrict bool) *StatFileTool {
+	return &StatFileTool{workspace: workspace, restrict: restrict}
+}
+
+func NewStatFileToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *StatFileTool {
+	return &StatFileTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *StatFileTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *StatFileTool) Name() string {
+	return "stat_file"
+}
+
+func (t *StatFileTool) Description() string {
+	return "Show whether a path exists and what it is: type (file, directory, symlink, ...), size, permission bits and modification time. Symlinks are reported, with their target, rather than followed."
+}
+
+func (t *StatFileTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file or directory",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *StatFileTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required").WithCode(CodeInvalidArgument)
+	}
+
+	// As with read_link, validate the containing directory so that a link
+	// is described rather than resolved to its target.
+	dir, err := validatePathWithMode(filepath.Dir(path), t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error()).WithCode(CodePolicyDenied)
+	}
+	target := filepath.Join(dir, filepath.Base(path))
+
+	info, err := os.Lstat(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrorResult(fmt.Sprintf("%s does not exist", path)).WithCode(CodeNotFound)
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to stat %s: %v", path, err)).WithCode(errorCode(err))
+	}
+
+	var linkTarget string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if linkTarget, err = os.Readlink(target); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read link: %v", err)).WithCode(errorCode(err))
+		}
+	} else if _, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID); err != nil {
+		// Anything other than a link gets the same check as a direct read,
+		// so denied_paths cannot be probed through stat_file.
+		return ErrorResult(err.Error()).WithCode(CodePolicyDenied)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Path: %s\n", path)
+	fmt.Fprintf(&sb, "Type: %s\n", fileTypeName(info.Mode()))
+	fmt.Fprintf(&sb, "Size: %d bytes\n", info.Size())
+	fmt.Fprintf(&sb, "Mode: %04o (%s)\n", info.Mode().Perm(), info.Mode())
+	fmt.Fprintf(&sb, "Modified: %s", info.ModTime().UTC().Format(time.RFC3339))
+	if linkTarget != "" {
+		fmt.Fprintf(&sb, "\nSymlink target: %s", linkTarget)
+	}
+	return NewToolResult(sb.String())
+}
+
+// fileTypeName names the kind of file a mode describes.
+func fileTypeName(mode fs.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return "file"
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "block device"
+	default:
+		return "other"
+	}
+}
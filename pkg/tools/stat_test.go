@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// TestStatFileTool_FileAndDirectory verifies type, size, mode and mtime are
+// reported for regular files and directories
+func TestStatFileTool_FileAndDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("hello"), 0640)
+	os.Chmod(filepath.Join(tmpDir, "a.txt"), 0640)
+	os.Mkdir(filepath.Join(tmpDir, "sub"), 0755)
+	tool := NewStatFileTool(tmpDir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "a.txt"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{"Path: a.txt\n", "Type: file\n", "Size: 5 bytes\n", "Mode: 0640 (-rw-r-----)\n", "Modified: "} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("Expected %q in result, got:\n%s", want, result.ForLLM)
+		}
+	}
+	if strings.Contains(result.ForLLM, "Symlink target") {
+		t.Errorf("Expected no symlink target for a file, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "sub"})
+	if result.IsError || !strings.Contains(result.ForLLM, "Type: directory\n") {
+		t.Errorf("Expected a directory, got: %s", result.ForLLM)
+	}
+}
+
+// TestStatFileTool_Symlink verifies a link is described with its target
+// rather than followed, even when it points out of the workspace
+func TestStatFileTool_Symlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	workspace := filepath.Join(tmpDir, "ws")
+	os.Mkdir(workspace, 0755)
+	if err := os.Symlink(tmpDir, filepath.Join(workspace, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	result := NewStatFileTool(workspace, true).Execute(context.Background(), map[string]interface{}{"path": "escape"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Type: symlink\n") || !strings.Contains(result.ForLLM, "Symlink target: "+tmpDir) {
+		t.Errorf("Expected the link and its target, got:\n%s", result.ForLLM)
+	}
+}
+
+// TestStatFileTool_Errors verifies missing paths, escaping paths and denied
+// paths produce clean errors
+func TestStatFileTool_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("SECRET=1"), 0644)
+	pe := security.NewPolicyEngine(&config.SecurityConfig{DeniedPaths: []string{".env"}}, nil)
+	tool := NewStatFileToolWithPolicy(tmpDir, true, PathPolicyOpts{PolicyEngine: pe})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "missing.txt"})
+	if !result.IsError || result.ForLLM != "missing.txt does not exist" || result.Code != CodeNotFound {
+		t.Errorf("Expected a clean not-found error, got: %s (%s)", result.ForLLM, result.Code)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "../outside"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside") {
+		t.Errorf("Expected an outside-workspace error, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": ".env"})
+	if !result.IsError || strings.Contains(result.ForLLM, "Size:") {
+		t.Errorf("Expected .env to be denied, got: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// SwapFilesTool exchanges two files. Each path is replaced by a single
+// rename, so a reader always finds a complete file at both paths: first the
+// original, then the other file's content.
+type SwapFilesTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+}
+
+func NewSwapFilesTool(workspace string, restrict bool) *SwapFilesTool {
+	return &SwapFilesTool{workspace: workspace, restrict: restrict}
+}
+
+func NewSwapFilesToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *SwapFilesTool {
+	return &SwapFilesTool{workspace: workspace, restrict: restrict, roots: opts.Roots, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+func (t *SwapFilesTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *SwapFilesTool) Name() string {
+	return "swap_files"
+}
+
+func (t *SwapFilesTool) Description() string {
+	return "Atomically exchange two files, e.g. to switch between a blue and a green config. Neither path is ever missing or partially written during the swap. Both files must be regular files on the same filesystem."
+}
+
+func (t *SwapFilesTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path_a": map[string]interface{}{
+				"type":        "string",
+				"description": "First file",
+			},
+			"path_b": map[string]interface{}{
+				"type":        "string",
+				"description": "Second file",
+			},
+		},
+		"required": []string{"path_a", "path_b"},
+	}
+}
+
+func (t *SwapFilesTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	pathA, okA := args["path_a"].(string)
+	pathB, okB := args["path_b"].(string)
+	if !okA || !okB || pathA == "" || pathB == "" {
+		return ErrorResult("path_a and path_b are required")
+	}
+
+	resolvedA, err := validatePathWithMode(pathA, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	resolvedB, err := validatePathWithMode(pathB, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	for _, p := range []struct{ name, resolved string }{{pathA, resolvedA}, {pathB, resolvedB}} {
+		info, err := os.Lstat(p.resolved)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("cannot swap %s: %v", p.name, err))
+		}
+		if !info.Mode().IsRegular() {
+			return ErrorResult(fmt.Sprintf("cannot swap %s: only regular files can be swapped", p.name))
+		}
+	}
+	infoA, _ := os.Stat(resolvedA)
+	infoB, _ := os.Stat(resolvedB)
+	if os.SameFile(infoA, infoB) {
+		return ErrorResult(fmt.Sprintf("%s and %s are the same file", pathA, pathB))
+	}
+
+	if err := swapFiles(resolvedA, resolvedB); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to swap %s and %s: %v", pathA, pathB, err))
+	}
+	return NewToolResult(fmt.Sprintf("Swapped %s and %s", pathA, pathB))
+}
+
+// swapFiles exchanges a and b without either path ever being missing: each
+// file gets a temporary hard link beside the other path, and each link is
+// then renamed over the other path. Hard links cannot cross filesystems, so
+// that case fails before anything is changed.
+func swapFiles(a, b string) error {
+	tmpA := swapTempName(b) // a's content, renamed over b
+	tmpB := swapTempName(a) // b's content, renamed over a
+
+	if err := os.Link(a, tmpA); err != nil {
+		return swapLinkError(err)
+	}
+	if err := os.Link(b, tmpB); err != nil {
+		os.Remove(tmpA)
+		return swapLinkError(err)
+	}
+	if err := os.Rename(tmpB, a); err != nil {
+		os.Remove(tmpA)
+		os.Remove(tmpB)
+		return err
+	}
+	if err := os.Rename(tmpA, b); err != nil {
+		// a already holds b's content; put a's original back.
+		if rbErr := os.Rename(tmpA, a); rbErr != nil {
+			return fmt.Errorf("%v (rollback failed, original of %s is at %s: %v)", err, a, tmpA, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func swapTempName(beside string) string {
+	return filepath.Join(filepath.Dir(beside), fmt.Sprintf(".%s.swap-%d", filepath.Base(beside), rand.Int63()))
+}
+
+func swapLinkError(err error) error {
+	if errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("the files are on different filesystems, so they cannot be swapped atomically; copy one of them first")
+	}
+	return fmt.Errorf("cannot create temporary link: %v", err)
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestSwapFilesTool_Swaps verifies each path holds the other's original
+// content afterwards and no temporary links are left behind
+func TestSwapFilesTool_Swaps(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "green"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "app.conf"), []byte("color=blue\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "green", "app.conf"), []byte("color=green\nreplicas=3\n"), 0600)
+
+	result := NewSwapFilesTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{
+		"path_a": "app.conf",
+		"path_b": "green/app.conf",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "app.conf")); string(data) != "color=green\nreplicas=3\n" {
+		t.Errorf("app.conf should hold the green config, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "green", "app.conf")); string(data) != "color=blue\n" {
+		t.Errorf("green/app.conf should hold the blue config, got %q", data)
+	}
+	for _, dir := range []string{tmpDir, filepath.Join(tmpDir, "green")} {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if strings.Contains(e.Name(), ".swap-") {
+				t.Errorf("Temporary link left behind: %s", e.Name())
+			}
+		}
+	}
+}
+
+// TestSwapFilesTool_Rejects verifies missing files, directories, the same
+// file twice and paths outside the workspace are refused without changes
+func TestSwapFilesTool_Rejects(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("a"), 0644)
+	os.Mkdir(filepath.Join(tmpDir, "dir"), 0755)
+	tool := NewSwapFilesTool(tmpDir, true)
+
+	cases := []struct {
+		b, want string
+	}{
+		{"missing.txt", "cannot swap missing.txt"},
+		{"dir", "only regular files can be swapped"},
+		{"./a.txt", "are the same file"},
+		{"../outside.txt", "outside the workspace"},
+	}
+	for _, c := range cases {
+		result := tool.Execute(context.Background(), map[string]interface{}{"path_a": "a.txt", "path_b": c.b})
+		if !result.IsError || !strings.Contains(result.ForLLM, c.want) {
+			t.Errorf("%s: expected error containing %q, got: %s", c.b, c.want, result.ForLLM)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "a.txt")); string(data) != "a" {
+		t.Errorf("a.txt should be unchanged, got %q", data)
+	}
+}
+
+func TestSwapLinkError_CrossFilesystem(t *testing.T) {
+	err := swapLinkError(&os.LinkError{Op: "link", Old: "/a", New: "/mnt/b", Err: syscall.EXDEV})
+	if !strings.Contains(err.Error(), "different filesystems") {
+		t.Errorf("Expected a cross-filesystem error, got: %v", err)
+	}
+}
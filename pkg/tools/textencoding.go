@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+// decodeText converts raw file bytes to UTF-8.
+//
+// With an explicit encoding name (any WHATWG label such as "gbk", "latin1" or
+// "utf-16le") the bytes are decoded with that charset. Otherwise UTF-8 input
+// and binary data are returned unchanged, a UTF-16 BOM is honoured, and other
+// input is guessed as GBK or Windows-1252. The returned name is non-empty only
+// when a transcoding happened; guessed reports whether it was a heuristic.
+func decodeText(data []byte, name string) (text, used string, guessed bool, err error) {
+	if name != "" {
+		enc, err := htmlindex.Get(name)
+		if err != nil {
+			return "", "", false, fmt.Errorf("unsupported encoding %q", name)
+		}
+		out, err := enc.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to decode as %s: %v", name, err)
+		}
+		return string(out), name, false, nil
+	}
+
+	if utf8.Valid(data) {
+		return string(data), "", false, nil
+	}
+
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		dec := textunicode.UTF16(textunicode.BigEndian, textunicode.ExpectBOM).NewDecoder()
+		if out, err := dec.Bytes(data); err == nil {
+			return string(out), "utf-16", false, nil
+		}
+	}
+
+	// NUL bytes mean binary content; leave it alone rather than invent text.
+	if bytes.IndexByte(data, 0) >= 0 {
+		return string(data), "", false, nil
+	}
+
+	if out, ok := tryDecode(simplifiedchinese.GBK, data); ok && containsHan(out) {
+		return out, "gbk", true, nil
+	}
+
+	out, _ := tryDecode(charmap.Windows1252, data)
+	return out, "windows-1252", true, nil
+}
+
+// tryDecode decodes data and reports whether it decoded without replacement
+// characters.
+func tryDecode(enc encoding.Encoding, data []byte) (string, bool) {
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", false
+	}
+	return string(out), !bytes.ContainsRune(out, utf8.RuneError)
+}
+
+func containsHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// osFS serves the read-only file tools from the OS filesystem. Its names are
+// the absolute paths returned by validatePathWithMode rather than fs.FS-style
+// relative names, so tools without an injected filesystem keep the workspace,
+// symlink and policy checks they always had.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return readFile(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return readDir(name) }
+
+// fsName converts a tool path to a name in an injected filesystem:
+// slash-separated, relative to its root, and never leaving it. The root
+// itself is ".".
+func fsName(path string) (string, error) {
+	p, err := normalizePath(path)
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return ".", nil
+	}
+	if filepath.IsAbs(p) || strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("access denied: absolute path %s is not supported on this filesystem", path)
+	}
+	name := filepath.ToSlash(p)
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("access denied: path is outside the filesystem root")
+	}
+	return name, nil
+}
+
+// checkDeniedName enforces security.denied_paths on name, a path inside an
+// injected filesystem, the way checkDeniedPaths does for workspace paths.
+func checkDeniedName(fsys fs.FS, path, name string, pathMode security.PolicyMode, pe *security.PolicyEngine, channel, chatID string) error {
+	if name == "." {
+		return nil
+	}
+	isDir := false
+	if info, err := fs.Stat(fsys, name); err == nil {
+		isDir = info.IsDir()
+	}
+	pattern, ok := deniedRelRule(pe.DeniedPaths(), name, isDir)
+	if !ok {
+		return nil
+	}
+	return evaluateDeniedPath(path, pattern, pathMode, pe, channel, chatID)
+}
+
+// fsSpecialKind is specialFileKind for a name in fsys.
+func fsSpecialKind(fsys fs.FS, name string) (string, error) {
+	if _, ok := fsys.(osFS); ok {
+		return specialFileKind(name)
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	return modeKind(info.Mode()), nil
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+func newMapFS() fstest.MapFS {
+	return fstest.MapFS{
+		"README.md":           {Data: []byte("# Demo\n")},
+		"src/main.go":         {Data: []byte("package main\n\nfunc main() {}\n")},
+		"src/util/strings.go": {Data: []byte("package util\n")},
+		"data/events.jsonl":   {Data: []byte("{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n")},
+		".env":                {Data: []byte("SECRET=1\n")},
+	}
+}
+
+// TestReadFileTool_FS verifies read_file reads from an injected filesystem,
+// including line ranges, jsonl and hashes
+func TestReadFileTool_FS(t *testing.T) {
+	tool := NewReadFileToolWithFS(newMapFS(), PathPolicyOpts{})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "src/main.go"})
+	if result.IsError || result.ForLLM != "package main\n\nfunc main() {}\n" {
+		t.Fatalf("Expected file content, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "./src//main.go", "start_line": float64(3)})
+	if result.IsError || !strings.Contains(result.ForLLM, "func main() {}") || strings.Contains(result.ForLLM, "package main") {
+		t.Errorf("Expected only line 3, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "data/events.jsonl", "format": "jsonl", "offset": float64(1), "limit": float64(1)})
+	if result.IsError || !strings.Contains(result.ForLLM, `{"n":2}`) || strings.Contains(result.ForLLM, `{"n":3}`) {
+		t.Errorf("Expected the second record only, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "README.md", "include_hash": true})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "[sha256: ") {
+		t.Errorf("Expected a hash prefix, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "missing.txt"})
+	if !result.IsError || result.Code != CodeNotFound {
+		t.Errorf("Expected a not-found error, got: %s (%s)", result.ForLLM, result.Code)
+	}
+}
+
+// TestReadFileTool_FSConfinesPaths verifies paths cannot leave the injected
+// filesystem and denied_paths still apply
+func TestReadFileTool_FSConfinesPaths(t *testing.T) {
+	pe := security.NewPolicyEngine(&config.SecurityConfig{DeniedPaths: []string{".env"}}, nil)
+	tool := NewReadFileToolWithFS(newMapFS(), PathPolicyOpts{PolicyEngine: pe})
+
+	for _, path := range []string{"../etc/passwd", "src/../../x", "/etc/passwd"} {
+		result := tool.Execute(context.Background(), map[string]interface{}{"path": path})
+		if !result.IsError || !strings.Contains(result.ForLLM, "access denied") {
+			t.Errorf("Expected %q to be denied, got: %s", path, result.ForLLM)
+		}
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": ".env"})
+	if !result.IsError || strings.Contains(result.ForLLM, "SECRET") {
+		t.Errorf("Expected .env to be denied, got: %s", result.ForLLM)
+	}
+}
+
+// TestListDirTool_FS verifies list_dir lists an injected filesystem, flat and
+// recursively
+func TestListDirTool_FS(t *testing.T) {
+	tool := NewListDirToolWithFS(newMapFS(), PathPolicyOpts{})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "."})
+	if result.IsError || result.ForLLM != "FILE: README.md\nDIR:  data\nDIR:  src\n" {
+		t.Errorf("Unexpected listing:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "src", "recursive": true})
+	want := "FILE: main.go\nDIR:  util\n  FILE: strings.go\n"
+	if result.IsError || result.ForLLM != want {
+		t.Errorf("Unexpected tree:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": ".."})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the filesystem root") {
+		t.Errorf("Expected an outside-root error, got: %s", result.ForLLM)
+	}
+}
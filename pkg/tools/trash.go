@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/security"
+)
+
+// defaultTrashDir is the workspace-relative trash location.
+const defaultTrashDir = ".trash"
+
+// trashIndexFile records where each trashed entry came from.
+const trashIndexFile = "index.json"
+
+// trashRecord is one entry of the trash index.
+type trashRecord struct {
+	Original  string    `json:"original"`
+	TrashedAt time.Time `json:"trashed_at"`
+}
+
+// TrashTool moves files into a trash directory instead of deleting them, and
+// restores them to their original location.
+type TrashTool struct {
+	workspace    string
+	restrict     bool
+	roots        map[string]string
+	trashDir     string
+	pathMode     security.PolicyMode
+	policyEngine *security.PolicyEngine
+	channel      string
+	chatID       string
+	mu           sync.Mutex // serialises index updates
+}
+
+func NewTrashTool(workspace string, restrict bool) *TrashTool {
+	return &TrashTool{workspace: workspace, restrict: restrict, trashDir: defaultTrashDir}
+}
+
+func NewTrashToolWithPolicy(workspace string, restrict bool, opts PathPolicyOpts) *TrashTool {
+	return &TrashTool{workspace: workspace, restrict: restrict, roots: opts.Roots, trashDir: defaultTrashDir, pathMode: opts.PathMode, policyEngine: opts.PolicyEngine}
+}
+
+// SetTrashDir changes the trash location. Relative paths are resolved against
+// the workspace; the location is validated like any other path on use.
+func (t *TrashTool) SetTrashDir(dir string) {
+	if dir != "" {
+		t.trashDir = dir
+	}
+}
+
+func (t *TrashTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *TrashTool) Name() string {
+	return "trash"
+}
+
+func (t *TrashTool) Description() string {
+	return "Move a file or directory to the trash instead of deleting it, list trashed entries, or restore one to its original path."
+}
+
+func (t *TrashTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"trash", "restore", "list"},
+				"description": "'trash' to move path to the trash, 'restore' to move it back, 'list' to show trashed entries",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File or directory to trash; for restore, the original path (restores its most recent trashed copy)",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Trash entry to restore, as shown by list (alternative to path)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *TrashTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, ok := args["action"].(string)
+	if !ok {
+		return ErrorResult("action is required")
+	}
+
+	trashPath, err := validatePathWithMode(t.trashDir, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid trash directory: %v", err))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch action {
+	case "trash":
+		return t.trash(args, trashPath)
+	case "restore":
+		return t.restore(args, trashPath)
+	case "list":
+		return t.list(trashPath)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func (t *TrashTool) trash(args map[string]interface{}, trashPath string) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+
+	resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if resolvedPath == trashPath || isWithinWorkspace(resolvedPath, trashPath) {
+		return ErrorResult("cannot trash the trash directory or its contents")
+	}
+	if _, err := os.Lstat(resolvedPath); err != nil {
+		if os.IsNotExist(err) {
+			return ErrorResult(fmt.Sprintf("path not found: %s", path))
+		}
+		return ErrorResult(fmt.Sprintf("failed to stat path: %v", err))
+	}
+
+	if err := os.MkdirAll(trashPath, 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create trash directory: %v", err))
+	}
+	index, err := readTrashIndex(trashPath)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	now := time.Now()
+	name := now.UTC().Format("20060102T150405.000000000") + "-" + filepath.Base(resolvedPath)
+	if err := os.Rename(resolvedPath, filepath.Join(trashPath, name)); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to move to trash: %v", err))
+	}
+
+	index[name] = trashRecord{Original: resolvedPath, TrashedAt: now}
+	if err := writeTrashIndex(trashPath, index); err != nil {
+		// Put the entry back so it is never left in the trash untracked.
+		os.Rename(filepath.Join(trashPath, name), resolvedPath)
+		return ErrorResult(err.Error())
+	}
+
+	return SilentResult(fmt.Sprintf("Moved %s to trash as %s", path, name))
+}
+
+func (t *TrashTool) restore(args map[string]interface{}, trashPath string) *ToolResult {
+	index, err := readTrashIndex(trashPath)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	name, _ := args["name"].(string)
+	if name == "" {
+		path, ok := args["path"].(string)
+		if !ok || path == "" {
+			return ErrorResult("name or path is required for restore")
+		}
+		resolvedPath, err := validatePathWithMode(path, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		var latest time.Time
+		for n, rec := range index {
+			if rec.Original == resolvedPath && rec.TrashedAt.After(latest) {
+				name, latest = n, rec.TrashedAt
+			}
+		}
+		if name == "" {
+			return ErrorResult(fmt.Sprintf("no trashed copy of %s", path))
+		}
+	}
+
+	rec, ok := index[name]
+	if !ok {
+		return ErrorResult(fmt.Sprintf("trash entry not found: %s", name))
+	}
+
+	// The original location must still pass the current path policy.
+	dest, err := validatePathWithMode(rec.Original, t.workspace, t.roots, t.restrict, t.pathMode, t.policyEngine, t.channel, t.chatID)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return ErrorResult(fmt.Sprintf("cannot restore: %s already exists", rec.Original))
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
+	}
+	if err := os.Rename(filepath.Join(trashPath, name), dest); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to restore: %v", err))
+	}
+
+	delete(index, name)
+	if err := writeTrashIndex(trashPath, index); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	return SilentResult(fmt.Sprintf("Restored %s to %s", name, rec.Original))
+}
+
+func (t *TrashTool) list(trashPath string) *ToolResult {
+	index, err := readTrashIndex(trashPath)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if len(index) == 0 {
+		return NewToolResult("Trash is empty")
+	}
+
+	names := make([]string, 0, len(index))
+	for name := range index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		rec := index[name]
+		fmt.Fprintf(&sb, "%s  (from %s, trashed %s)\n", name, rec.Original, rec.TrashedAt.Format(time.RFC3339))
+	}
+	return NewToolResult(sb.String())
+}
+
+// readTrashIndex loads the trash index, returning an empty index when the
+// trash has not been used yet.
+func readTrashIndex(trashPath string) (map[string]trashRecord, error) {
+	index := make(map[string]trashRecord)
+	data, err := os.ReadFile(filepath.Join(trashPath, trashIndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash index: %v", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse trash index: %v", err)
+	}
+	return index, nil
+}
+
+func writeTrashIndex(trashPath string, index map[string]trashRecord) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode trash index: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(trashPath, trashIndexFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write trash index: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTrashTool_TrashAndRestore verifies a trashed file keeps its content and
+// restore returns it to the original path
+func TestTrashTool_TrashAndRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "docs"), 0755)
+	original := filepath.Join(tmpDir, "docs", "notes.txt")
+	os.WriteFile(original, []byte("keep me"), 0644)
+
+	tool := NewTrashTool(tmpDir, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "trash", "path": "docs/notes.txt"})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(original); !os.IsNotExist(err) {
+		t.Fatal("Expected original to be moved away")
+	}
+
+	entries, _ := filepath.Glob(filepath.Join(tmpDir, ".trash", "*-notes.txt"))
+	if len(entries) != 1 {
+		t.Fatalf("Expected one trashed copy, found %d", len(entries))
+	}
+	if content, _ := os.ReadFile(entries[0]); string(content) != "keep me" {
+		t.Errorf("Trashed copy lost content, got %q", content)
+	}
+
+	list := tool.Execute(ctx, map[string]interface{}{"action": "list"})
+	if !strings.Contains(list.ForLLM, filepath.Base(entries[0])) || !strings.Contains(list.ForLLM, original) {
+		t.Errorf("Expected list to show entry and origin, got: %s", list.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "restore", "path": "docs/notes.txt"})
+	if result.IsError {
+		t.Fatalf("Expected restore to succeed, got: %s", result.ForLLM)
+	}
+	if content, err := os.ReadFile(original); err != nil || string(content) != "keep me" {
+		t.Errorf("Expected restored content, got %q, err=%v", content, err)
+	}
+	if list := tool.Execute(ctx, map[string]interface{}{"action": "list"}); list.ForLLM != "Trash is empty" {
+		t.Errorf("Expected empty trash after restore, got: %s", list.ForLLM)
+	}
+}
+
+// TestTrashTool_RestoreRefusesOverwrite verifies restore never clobbers a file
+// recreated at the original path
+func TestTrashTool_RestoreRefusesOverwrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "a.txt")
+	os.WriteFile(target, []byte("old"), 0644)
+
+	tool := NewTrashTool(tmpDir, true)
+	ctx := context.Background()
+	tool.Execute(ctx, map[string]interface{}{"action": "trash", "path": "a.txt"})
+	os.WriteFile(target, []byte("new"), 0644)
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "restore", "path": "a.txt"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "already exists") {
+		t.Errorf("Expected restore to refuse overwrite, got: %s", result.ForLLM)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "new" {
+		t.Errorf("Existing file must be untouched, got %q", content)
+	}
+}
+
+// TestTrashTool_TrashDirValidated verifies a trash location outside the
+// workspace is rejected and the trash itself cannot be trashed
+func TestTrashTool_TrashDirValidated(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "f.txt"), []byte("x"), 0644)
+	ctx := context.Background()
+
+	tool := NewTrashTool(tmpDir, true)
+	tool.SetTrashDir("../outside-trash")
+	result := tool.Execute(ctx, map[string]interface{}{"action": "trash", "path": "f.txt"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "invalid trash directory") {
+		t.Errorf("Expected trash directory outside workspace to be rejected, got: %s", result.ForLLM)
+	}
+
+	tool = NewTrashTool(tmpDir, true)
+	tool.SetTrashDir("bin")
+	os.MkdirAll(filepath.Join(tmpDir, "bin"), 0755)
+	result = tool.Execute(ctx, map[string]interface{}{"action": "trash", "path": "bin"})
+	if !result.IsError {
+		t.Error("Expected trashing the trash directory to fail")
+	}
+}
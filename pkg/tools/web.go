@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -409,11 +410,16 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("url is required")
 	}
 
-	// SSRF protection (mode-aware)
-	if !t.ssrfMode.IsOff() {
-		if err := utils.ValidateURL(urlStr); err != nil {
+	ssrfMode := t.policyEngine.EffectiveMode("ssrf", t.ssrfMode, t.channel, t.chatID)
+
+	// SSRF protection (mode-aware). An approved URL is not re-checked at dial
+	// time; otherwise connections are pinned to the addresses validated then,
+	// so a DNS answer cannot change between the check and the fetch.
+	checkDial := !ssrfMode.IsOff()
+	if !ssrfMode.IsOff() {
+		if err := utils.ValidateURLWithPolicy(urlStr, ssrfPolicy(t.policyEngine)); err != nil {
 			if t.policyEngine != nil {
-				pErr := t.policyEngine.Evaluate(ctx, t.ssrfMode, security.Violation{
+				pErr := t.policyEngine.Evaluate(ctx, ssrfMode, security.Violation{
 					Category: "ssrf",
 					Tool:     "web_fetch",
 					Action:   urlStr,
@@ -423,6 +429,7 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 					return ErrorResult(fmt.Sprintf("URL blocked: %v", pErr))
 				}
 				// approved by user
+				checkDial = false
 			} else {
 				return ErrorResult(fmt.Sprintf("URL blocked: %v", err))
 			}
@@ -452,25 +459,19 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 
 	req.Header.Set("User-Agent", userAgent)
 
+	transport := &http.Transport{
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+		DisableCompression:  false,
+		TLSHandshakeTimeout: 15 * time.Second,
+	}
+	if checkDial {
+		transport.DialContext = utils.PinnedDialContext(&net.Dialer{Timeout: 30 * time.Second}, t.policyEngine.SSRFAllowlist())
+	}
 	client := &http.Client{
-		Timeout: 60 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			DisableCompression:  false,
-			TLSHandshakeTimeout: 15 * time.Second,
-		},
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 5 {
-				return fmt.Errorf("stopped after 5 redirects")
-			}
-			if !t.ssrfMode.IsOff() {
-				if err := utils.ValidateURL(req.URL.String()); err != nil {
-					return fmt.Errorf("redirect blocked: %w", err)
-				}
-			}
-			return nil
-		},
+		Timeout:       60 * time.Second,
+		Transport:     transport,
+		CheckRedirect: redirectPolicy(ssrfMode, ssrfPolicy(t.policyEngine)),
 	}
 
 	resp, err := client.Do(req)
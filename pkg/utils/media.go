@@ -103,6 +103,12 @@ func DownloadFile(url, filename string, opts DownloadOptions) string {
 	}
 
 	client := &http.Client{Timeout: opts.Timeout}
+	if !opts.SkipURLValidation {
+		// Connect only to addresses validated at dial time, so the host
+		// cannot be re-pointed at an internal address after ValidateURL.
+		client.Transport = NewPinnedTransport(nil)
+		client.CheckRedirect = CheckRedirect(URLPolicy{}, MaxRedirects)
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		logger.ErrorCF(opts.LoggerPrefix, "Failed to download file", map[string]interface{}{
@@ -1,15 +1,47 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// lookupHost resolves host names; tests replace it to avoid real DNS.
+var lookupHost = net.LookupHost
+
 // ValidateURL checks that a URL is safe to fetch, blocking private/internal IPs,
 // localhost, link-local addresses, and cloud metadata endpoints.
 func ValidateURL(urlStr string) error {
+	return ValidateURLWithPolicy(urlStr, URLPolicy{})
+}
+
+// URLPolicy adjusts the checks made by ValidateURLWithPolicy. The zero value
+// gives ValidateURL's defaults.
+type URLPolicy struct {
+	// Allow lists destinations that bypass the private, loopback and
+	// link-local checks. Entries are IPs, CIDRs such as "10.1.0.0/16", or
+	// host names, which are resolved in turn.
+	Allow []string
+	// Ports, when not empty, restricts URLs to ports 80, 443 and these. An
+	// empty list allows any port.
+	Ports []int
+}
+
+// defaultAllowedPorts are always allowed once a port policy is configured.
+var defaultAllowedPorts = []int{80, 443}
+
+// ValidateURLWithPolicy is ValidateURL adjusted by policy. The URL's host is
+// resolved first and every address it resolves to must be allowed, so an
+// allowed name cannot be used to reach some other internal address. The
+// port is the explicit one or, when the URL has none, the scheme default.
+func ValidateURLWithPolicy(urlStr string, policy URLPolicy) error {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return fmt.Errorf("invalid URL: %w", err)
@@ -25,14 +57,20 @@ func ValidateURL(urlStr string) error {
 		return fmt.Errorf("missing host in URL")
 	}
 
-	// Block localhost variants
+	if err := checkPort(parsedURL, policy.Ports); err != nil {
+		return err
+	}
+
+	// Block localhost variants; with an allowlist they fall through to the
+	// address checks, which pass only if every loopback address is listed.
 	lowerHost := strings.ToLower(host)
-	if lowerHost == "localhost" || lowerHost == "ip6-localhost" || lowerHost == "ip6-loopback" {
+	allow := policy.Allow
+	if len(allow) == 0 && (lowerHost == "localhost" || lowerHost == "ip6-localhost" || lowerHost == "ip6-loopback") {
 		return fmt.Errorf("access to localhost is blocked")
 	}
 
 	// Resolve host to IP addresses
-	ips, err := net.LookupHost(host)
+	ips, err := lookupHost(host)
 	if err != nil {
 		// If DNS resolution fails, try parsing as IP directly
 		ip := net.ParseIP(host)
@@ -48,7 +86,7 @@ func ValidateURL(urlStr string) error {
 			continue
 		}
 
-		if err := validateIP(ip); err != nil {
+		if err := ValidateIPWithPolicy(ip, allow); err != nil {
 			return err
 		}
 	}
@@ -56,8 +94,89 @@ func ValidateURL(urlStr string) error {
 	return nil
 }
 
-// validateIP checks whether an IP address is safe to access.
-func validateIP(ip net.IP) error {
+// checkPort rejects a URL whose port is neither a default one nor in ports.
+// An empty ports list allows every port.
+func checkPort(u *url.URL, ports []int) error {
+	if len(ports) == 0 {
+		return nil
+	}
+	portStr := u.Port()
+	if portStr == "" {
+		portStr = "80"
+		if u.Scheme == "https" {
+			portStr = "443"
+		}
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port %q in URL", portStr)
+	}
+	if slices.Contains(defaultAllowedPorts, port) || slices.Contains(ports, port) {
+		return nil
+	}
+	return fmt.Errorf("access to port %d is blocked (allowed: 80, 443 and security.ssrf_allowed_ports)", port)
+}
+
+// ValidateIPWithPolicy is ValidateIP with the allowlist described at
+// URLPolicy.Allow. The cloud metadata endpoint stays blocked unless it
+// is listed by its exact address.
+func ValidateIPWithPolicy(ip net.IP, allow []string) error {
+	if ipAllowed(ip, allow) {
+		return nil
+	}
+	return ValidateIP(ip)
+}
+
+// ipAllowed reports whether ip matches an allowlist entry.
+func ipAllowed(ip net.IP, allow []string) bool {
+	metadata := ip.Equal(net.ParseIP("169.254.169.254"))
+	for _, entry := range allow {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if listed := net.ParseIP(entry); listed != nil {
+			if listed.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if !metadata && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		resolved, err := lookupHost(entry)
+		if err != nil {
+			continue
+		}
+		for _, addr := range resolved {
+			if listed := net.ParseIP(addr); listed != nil && !metadata && listed.Equal(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// uniqueLocalNet is the IPv6 unique-local range, the IPv6 counterpart of the
+// private IPv4 networks.
+var uniqueLocalNet = &net.IPNet{IP: net.ParseIP("fc00::"), Mask: net.CIDRMask(7, 128)}
+
+// ValidateIP checks whether an IP address is safe to access.
+func ValidateIP(ip net.IP) error {
+	// Judge IPv4-mapped IPv6 addresses (::ffff:127.0.0.1) by the IPv4
+	// address they embed, so they cannot re-expose a blocked one.
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	// Block IPv6 unique-local addresses (fc00::/7)
+	if uniqueLocalNet.Contains(ip) {
+		return fmt.Errorf("access to unique-local address %s is blocked", ip)
+	}
+
 	// Block loopback (127.0.0.0/8, ::1)
 	if ip.IsLoopback() {
 		return fmt.Errorf("access to loopback address %s is blocked", ip)
@@ -86,3 +205,100 @@ func validateIP(ip net.IP) error {
 
 	return nil
 }
+
+// PinnedDialContext returns a DialContext for http.Transport that closes the
+// DNS-rebinding gap left by validating a URL before fetching it: each host is
+// resolved once, the connection is refused if any address is blocked, and
+// only those validated addresses are dialed. The address actually dialed is
+// validated again in the dialer's Control hook as a second line of defense.
+// allow is the allowlist described at URLPolicy.Allow.
+func PinnedDialContext(dialer *net.Dialer, allow []string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	d := *dialer
+	d.Control = func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("connection blocked: unexpected unresolved address %s", host)
+		}
+		if err := ValidateIPWithPolicy(ip, allow); err != nil {
+			return fmt.Errorf("connection blocked: %w", err)
+		}
+		return nil
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		var ips []net.IP
+		if ip := net.ParseIP(host); ip != nil {
+			ips = []net.IP{ip}
+		} else {
+			addrs, err := lookupHost(host)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve host: %w", err)
+			}
+			for _, a := range addrs {
+				if ip := net.ParseIP(a); ip != nil {
+					ips = append(ips, ip)
+				}
+			}
+			if len(ips) == 0 {
+				return nil, fmt.Errorf("failed to resolve host: no addresses for %s", host)
+			}
+		}
+		for _, ip := range ips {
+			if err := ValidateIPWithPolicy(ip, allow); err != nil {
+				return nil, fmt.Errorf("connection blocked: %w", err)
+			}
+		}
+
+		var firstErr error
+		for _, ip := range ips {
+			conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}
+}
+
+// NewPinnedTransport returns an http.Transport whose connections go through
+// PinnedDialContext. It does not use a proxy, since a proxy would resolve
+// the host itself.
+func NewPinnedTransport(allow []string) *http.Transport {
+	return &http.Transport{
+		DialContext:         PinnedDialContext(&net.Dialer{Timeout: 30 * time.Second}, allow),
+		TLSHandshakeTimeout: 15 * time.Second,
+	}
+}
+
+// MaxRedirects is how many redirects CheckRedirect follows by default.
+const MaxRedirects = 5
+
+// CheckRedirect returns an http.Client CheckRedirect hook that validates every
+// redirect target with ValidateURLWithPolicy, so a server cannot bounce a
+// request to an internal address, and stops after maxRedirects hops
+// (MaxRedirects when maxRedirects is 0 or less).
+func CheckRedirect(policy URLPolicy, maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	if maxRedirects <= 0 {
+		maxRedirects = MaxRedirects
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if err := ValidateURLWithPolicy(req.URL.String(), policy); err != nil {
+			return fmt.Errorf("redirect blocked: %w", err)
+		}
+		return nil
+	}
+}
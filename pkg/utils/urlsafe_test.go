@@ -1,7 +1,15 @@
 package utils
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateURL_AllowsPublicURLs(t *testing.T) {
@@ -31,6 +39,11 @@ func TestValidateURL_BlocksPrivateIPs(t *testing.T) {
 		"http://169.254.169.254/latest/meta-data/",
 		"http://0.0.0.0",
 		"http://[::1]",
+		"http://[fc00::1]",
+		"http://[fd12:3456::1]",
+		"http://[::ffff:10.0.0.1]",
+		"http://[::ffff:127.0.0.1]",
+		"http://[::ffff:169.254.169.254]",
 	}
 
 	for _, u := range blockedURLs {
@@ -59,3 +72,181 @@ func TestValidateURL_BlocksMissingHost(t *testing.T) {
 		t.Error("Expected URL with missing host to be blocked")
 	}
 }
+
+// stubLookup replaces DNS resolution with a fixed table for the test.
+func stubLookup(t *testing.T, table map[string][]string) {
+	t.Helper()
+	orig := lookupHost
+	lookupHost = func(host string) ([]string, error) {
+		if addrs, ok := table[host]; ok {
+			return addrs, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	t.Cleanup(func() { lookupHost = orig })
+}
+
+func TestValidateURLWithPolicy_AllowsListedDestinations(t *testing.T) {
+	stubLookup(t, map[string][]string{
+		"svc.internal":  {"10.1.2.3"},
+		"wiki.internal": {"10.20.5.6"},
+		"localhost":     {"127.0.0.1"},
+	})
+	allow := []string{"10.1.2.3", "10.20.0.0/16", "localhost"}
+
+	for _, u := range []string{
+		"http://10.1.2.3:8080/api",
+		"http://svc.internal/health",
+		"http://wiki.internal/page",
+		"http://10.20.9.9",
+		"http://localhost:3000",
+	} {
+		if err := ValidateURLWithPolicy(u, URLPolicy{Allow: allow}); err != nil {
+			t.Errorf("Expected %q to be allowed, got: %v", u, err)
+		}
+	}
+
+	for _, u := range []string{"http://10.1.2.4", "http://192.168.1.1", "http://169.254.169.254/latest/meta-data/"} {
+		if err := ValidateURLWithPolicy(u, URLPolicy{Allow: allow}); err == nil {
+			t.Errorf("Expected %q to stay blocked", u)
+		}
+	}
+	if err := ValidateURL("http://10.1.2.3"); err == nil {
+		t.Error("Expected ValidateURL to stay strict")
+	}
+}
+
+func TestValidateURLWithPolicy_MatchesResolvedAddresses(t *testing.T) {
+	stubLookup(t, map[string][]string{
+		"svc.internal":    {"10.1.2.3"},
+		"rebind.example":  {"10.9.9.9"},
+		"partial.example": {"10.1.2.3", "10.9.9.9"},
+	})
+	allow := []string{"svc.internal"}
+
+	// A public-looking name resolving to an unlisted private address.
+	if err := ValidateURLWithPolicy("http://rebind.example", URLPolicy{Allow: allow}); err == nil {
+		t.Error("Expected a name resolving to an unlisted address to be blocked")
+	}
+	// Every resolved address must be allowed, not just one of them.
+	if err := ValidateURLWithPolicy("http://partial.example", URLPolicy{Allow: allow}); err == nil {
+		t.Error("Expected a name with one unlisted address to be blocked")
+	}
+	if err := ValidateURLWithPolicy("http://svc.internal", URLPolicy{Allow: allow}); err != nil {
+		t.Errorf("Expected the listed name to be allowed, got: %v", err)
+	}
+}
+
+func TestValidateIPWithPolicy_MetadataNeedsExactEntry(t *testing.T) {
+	metadata := net.ParseIP("169.254.169.254")
+	if err := ValidateIPWithPolicy(metadata, []string{"169.254.0.0/16"}); err == nil {
+		t.Error("Expected a CIDR not to exempt the metadata endpoint")
+	}
+	if err := ValidateIPWithPolicy(metadata, []string{"169.254.169.254"}); err != nil {
+		t.Errorf("Expected the exact address to exempt it, got: %v", err)
+	}
+}
+
+func TestPinnedDialContext_DialsValidatedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "pinned")
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// The name only resolves through the stub, so a successful fetch proves
+	// the connection used the address that was validated.
+	stubLookup(t, map[string][]string{"svc.internal": {"127.0.0.1"}})
+	client := &http.Client{Transport: NewPinnedTransport([]string{"127.0.0.1"})}
+	resp, err := client.Get("http://svc.internal:" + port + "/")
+	if err != nil {
+		t.Fatalf("Expected the allowlisted address to be dialed, got: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pinned" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestPinnedDialContext_BlocksRebinding(t *testing.T) {
+	// The name passed an earlier check but now resolves to the metadata
+	// endpoint; the dial must re-check what it connects to.
+	stubLookup(t, map[string][]string{"rebind.test": {"169.254.169.254"}})
+	dial := PinnedDialContext(&net.Dialer{Timeout: time.Second}, nil)
+
+	if _, err := dial(context.Background(), "tcp", "rebind.test:80"); err == nil || !strings.Contains(err.Error(), "connection blocked") {
+		t.Errorf("Expected the rebound address to be blocked, got: %v", err)
+	}
+	if _, err := dial(context.Background(), "tcp", "10.0.0.1:80"); err == nil || !strings.Contains(err.Error(), "connection blocked") {
+		t.Errorf("Expected a private literal address to be blocked, got: %v", err)
+	}
+}
+
+func TestValidateIP_UnwrapsMappedAddresses(t *testing.T) {
+	err := ValidateIP(net.ParseIP("::ffff:169.254.169.254"))
+	if err == nil || !strings.Contains(err.Error(), "169.254.169.254") || strings.Contains(err.Error(), "::ffff") {
+		t.Errorf("Expected the embedded IPv4 address to be reported, got: %v", err)
+	}
+	if err := ValidateIP(net.ParseIP("fc00::1")); err == nil || !strings.Contains(err.Error(), "unique-local") {
+		t.Errorf("Expected a unique-local error, got: %v", err)
+	}
+	if err := ValidateIP(net.ParseIP("2001:4860:4860::8888")); err != nil {
+		t.Errorf("Expected a public IPv6 address to be allowed, got: %v", err)
+	}
+}
+
+func TestValidateURLWithPolicy_Ports(t *testing.T) {
+	stubLookup(t, map[string][]string{"example.com": {"93.184.216.34"}})
+	policy := URLPolicy{Ports: []int{8443}}
+
+	for _, u := range []string{"http://example.com", "https://example.com/x", "http://example.com:443", "https://example.com:8443"} {
+		if err := ValidateURLWithPolicy(u, policy); err != nil {
+			t.Errorf("Expected %q to be allowed, got: %v", u, err)
+		}
+	}
+	for _, u := range []string{"http://example.com:25", "http://example.com:6379", "https://example.com:8080"} {
+		if err := ValidateURLWithPolicy(u, policy); err == nil || !strings.Contains(err.Error(), "port") {
+			t.Errorf("Expected %q to be blocked by port, got: %v", u, err)
+		}
+	}
+	// No port policy keeps the permissive default.
+	if err := ValidateURLWithPolicy("http://example.com:6379", URLPolicy{}); err != nil {
+		t.Errorf("Expected any port without a port policy, got: %v", err)
+	}
+}
+
+func TestCheckRedirect_RefusesBlockedTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: CheckRedirect(URLPolicy{}, 0)}
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the redirect to the metadata endpoint to be refused")
+	}
+	if !strings.Contains(err.Error(), "redirect blocked") || !strings.Contains(err.Error(), "169.254.169.254") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCheckRedirect_CapsHops(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+"/again", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: CheckRedirect(URLPolicy{Allow: []string{"127.0.0.1"}}, 2)}
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the redirect loop to be stopped")
+	}
+	if !strings.Contains(err.Error(), "stopped after 2 redirects") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}